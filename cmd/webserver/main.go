@@ -1,25 +1,86 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"teya_home_assignment/internal/app/webserver/controllers"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"teya_home_assignment/internal/app/webserver/config"
+	"teya_home_assignment/internal/app/webserver/server"
+	"teya_home_assignment/internal/pkg/logging"
+	"teya_home_assignment/internal/pkg/tracing"
+	// embed the time zone database, reports are bucketed in the client's time zone
+	_ "time/tzdata"
 
-	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+// serviceName identifies the service in the traces
+const serviceName = "ledger"
+
+// Exit codes of the webserver
+const (
+	// exitOK is returned once the webserver shut down cleanly
+	exitOK = 0
+	// exitFailure is returned when the webserver failed to start or to serve
+	exitFailure = 1
+	// exitInvalidConfig is returned when the configuration is invalid, like for command line usage errors
+	exitInvalidConfig = 2
+	// exitUncleanShutdown is returned when requests were aborted or the storage could not be flushed on shutdown
+	exitUncleanShutdown = 3
 )
 
 func main() {
-	fmt.Println("Starting webserver...")
-	app := fiber.New()
-	apiGroup := app.Group(controllers.APIRouteBasePath)
-	APIControllers, err := controllers.InitControllers()
+	os.Exit(run())
+}
+
+func run() int {
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
 	if err != nil {
-		panic(fmt.Errorf("error setting up controllers: %w", err))
+		fmt.Fprintf(os.Stderr, "error loading configuration: %v\n", err)
+		return exitInvalidConfig
 	}
-	if err := controllers.SetupRoutes(apiGroup, APIControllers); err != nil {
-		panic(fmt.Errorf("error setting up routes: %w", err))
+	// the configuration was validated, it parses
+	logConfig, _ := cfg.Logging.Parse()
+	slog.SetDefault(logging.New(os.Stdout, logConfig))
+	slog.Info("loaded configuration", "config", cfg)
+
+	traceConfig, _ := cfg.Tracing.Parse()
+	traceConfig.ServiceName = serviceName
+	shutdownTracing, err := tracing.Setup(context.Background(), traceConfig)
+	if err != nil {
+		slog.Error("failed to set up tracing", "error", err)
+		return exitFailure
 	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("failed to shut down tracing", "error", err)
+		}
+	}()
 
-	if err := app.Listen(":8000"); err != nil {
-		panic(fmt.Errorf("error starting server: %w", err))
+	slog.Info("starting webserver")
+	webserver, err := server.New(cfg)
+	if err != nil {
+		slog.Error("failed to start webserver", "error", err)
+		return exitFailure
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err = webserver.Run(ctx)
+	switch {
+	case err == nil:
+		slog.Info("webserver shut down")
+		return exitOK
+	case ctx.Err() != nil:
+		slog.Error("webserver did not shut down cleanly", "error", err)
+		return exitUncleanShutdown
 	}
+	slog.Error("webserver failed", "error", err)
+	return exitFailure
 }
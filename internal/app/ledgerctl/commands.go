@@ -0,0 +1,361 @@
+package ledgerctl
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"teya_home_assignment/internal/app/webserver/config"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/pkg/client"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// historyPageSize is the page size the history is read with, the largest one the webserver accepts by default
+var historyPageSize = config.Default().Pagination.MaxLimit
+
+// metadataFlag collects the repeated key=value metadata flags
+type metadataFlag map[string]string
+
+func (m metadataFlag) String() string {
+	return ""
+}
+
+func (m metadataFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return errors.Errorf("%q is not formatted as key=value", value)
+	}
+	m[key] = val
+	return nil
+}
+
+func runPost(ctx context.Context, e *env, args []string) error {
+	flags := newFlagSet(e, "post")
+	body := client.NewTransactionReqBody{Metadata: metadataFlag{}}
+	flags.StringVar(&body.Amount, "amount", "", "amount of the transaction, negative for a debit")
+	flags.StringVar(&body.Reference, "reference", "", "external reference of the transaction")
+	flags.StringVar(&body.Description, "description", "", "description of the transaction")
+	flags.Var(metadataFlag(body.Metadata), "metadata", "key=value metadata of the transaction, repeatable")
+	idempotencyKey := flags.String("idempotency-key", "",
+		"posts the transaction once whatever the number of calls, generated when empty")
+	if err := parseFlags(flags, args, 0); err != nil {
+		return err
+	}
+	if body.Amount == "" {
+		return usageErrorf("-amount is required")
+	}
+	if len(body.Metadata) == 0 {
+		body.Metadata = nil
+	}
+	var opts []client.CallOption
+	if *idempotencyKey != "" {
+		opts = append(opts, client.WithIdempotencyKey(*idempotencyKey))
+	}
+	result, err := e.client.CreateTransaction(ctx, body, opts...)
+	if err != nil {
+		return err
+	}
+	if result.Replayed {
+		fmt.Fprintln(e.stderr, "the transaction was already posted with this idempotency key")
+	}
+	if result.Review != nil {
+		fmt.Fprintln(e.stderr, "the transaction is held for review")
+		return reviewTable(*result.Review).render(e.stdout, e.outputFormat(outputTable))
+	}
+	entries := append([]client.Transaction{result.Posted.Transaction}, result.Posted.Fees...)
+	t := transactionsTable(entries)
+	t.value = result.Posted
+	return t.render(e.stdout, e.outputFormat(outputTable))
+}
+
+func reviewTable(review client.Review) table {
+	rules := make([]string, 0, len(review.Matches))
+	for _, match := range review.Matches {
+		rules = append(rules, match.Name)
+	}
+	return table{
+		header: []string{"review_id", "status", "amount", "rules"},
+		rows: [][]string{{
+			review.ID.String(), review.Status, review.Transaction.Amount.String(), strings.Join(rules, ", "),
+		}},
+		value: review,
+	}
+}
+
+// historyFilter selects transactions of the history. The category is filtered by the webserver, the other
+// criteria by the tool.
+type historyFilter struct {
+	category  string
+	kind      string
+	reference string
+	from      time.Time
+	to        time.Time
+	minAmount *decimal.Decimal
+	maxAmount *decimal.Decimal
+}
+
+func (f *historyFilter) register(flags *flag.FlagSet) {
+	flags.StringVar(&f.category, "category", "", "only the transactions of the category")
+	flags.StringVar(&f.kind, "kind", "", "only the entries of the kind, e.g. fee or interest")
+	flags.StringVar(&f.reference, "reference", "", "only the transactions with the reference")
+	flags.Func("from", "only the transactions created from the date or RFC 3339 timestamp", func(value string) error {
+		from, _, err := parseTime(value)
+		f.from = from
+		return err
+	})
+	flags.Func("to", "only the transactions created until the date, inclusive, or before the RFC 3339 timestamp",
+		func(value string) error {
+			to, isDate, err := parseTime(value)
+			if isDate {
+				to = to.AddDate(0, 0, 1)
+			}
+			f.to = to
+			return err
+		})
+	flags.Func("min-amount", "only the transactions of at least the amount", func(value string) error {
+		amount, err := decimal.NewFromString(value)
+		f.minAmount = &amount
+		return err
+	})
+	flags.Func("max-amount", "only the transactions of at most the amount", func(value string) error {
+		amount, err := decimal.NewFromString(value)
+		f.maxAmount = &amount
+		return err
+	})
+}
+
+// local tells whether the filter selects more than the webserver filters
+func (f *historyFilter) local() bool {
+	return f.kind != "" || f.reference != "" || !f.from.IsZero() || !f.to.IsZero() ||
+		f.minAmount != nil || f.maxAmount != nil
+}
+
+func (f *historyFilter) matches(transaction client.Transaction) bool {
+	switch {
+	case f.kind != "" && transaction.Kind != f.kind:
+		return false
+	case f.reference != "" && transaction.Reference != f.reference:
+		return false
+	case !f.from.IsZero() && transaction.CreatedAt.Before(f.from):
+		return false
+	case !f.to.IsZero() && !transaction.CreatedAt.Before(f.to):
+		return false
+	case f.minAmount != nil && transaction.Amount.LessThan(*f.minAmount):
+		return false
+	case f.maxAmount != nil && transaction.Amount.GreaterThan(*f.maxAmount):
+		return false
+	}
+	return true
+}
+
+func parseTime(value string) (time.Time, bool, error) {
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		return date, true, nil
+	}
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, errors.New("must be a date formatted as YYYY-MM-DD or an RFC 3339 timestamp")
+	}
+	return timestamp, false, nil
+}
+
+// readHistory returns the transactions matching the filter, skipping the first offset ones and up to limit of them
+// unless 0
+func readHistory(ctx context.Context, e *env, filter historyFilter, offset, limit int) ([]client.Transaction, error) {
+	query := client.TransactionQuery{Limit: historyPageSize, Category: filter.category}
+	if !filter.local() {
+		// the webserver skips the transactions itself
+		query.Offset, offset = offset, 0
+	}
+	transactions := []client.Transaction{}
+	for transaction, err := range e.client.Transactions(ctx, query) {
+		if err != nil {
+			return nil, err
+		}
+		if !filter.matches(transaction) {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		transactions = append(transactions, transaction)
+		if len(transactions) == limit {
+			break
+		}
+	}
+	return transactions, nil
+}
+
+func runList(ctx context.Context, e *env, args []string) error {
+	flags := newFlagSet(e, "list")
+	var filter historyFilter
+	filter.register(flags)
+	offset := flags.Int("offset", 0, "number of matching transactions to skip, from the oldest")
+	limit := flags.Int("limit", 20, "number of transactions to list, all of them when 0")
+	if err := parseFlags(flags, args, 0); err != nil {
+		return err
+	}
+	if *offset < 0 || *limit < 0 {
+		return usageErrorf("-offset and -limit must not be negative")
+	}
+	transactions, err := readHistory(ctx, e, filter, *offset, *limit)
+	if err != nil {
+		return err
+	}
+	return transactionsTable(transactions).render(e.stdout, e.outputFormat(outputTable))
+}
+
+func runExport(ctx context.Context, e *env, args []string) error {
+	flags := newFlagSet(e, "export")
+	var filter historyFilter
+	filter.register(flags)
+	file := flags.String("file", "", "file the history is written to, the standard output when empty")
+	if err := parseFlags(flags, args, 0); err != nil {
+		return err
+	}
+	transactions, err := readHistory(ctx, e, filter, 0, 0)
+	if err != nil {
+		return err
+	}
+	if *file == "" {
+		return transactionsTable(transactions).render(e.stdout, e.outputFormat(outputCSV))
+	}
+	out, err := os.Create(*file)
+	if err != nil {
+		return errors.Wrap(err, "failed to create export file")
+	}
+	if err := transactionsTable(transactions).render(out, e.outputFormat(outputCSV)); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return errors.Wrap(err, "failed to write export file")
+	}
+	fmt.Fprintf(e.stderr, "exported %d transactions to %v\n", len(transactions), *file)
+	return nil
+}
+
+func runBalance(ctx context.Context, e *env, args []string) error {
+	if err := parseFlags(newFlagSet(e, "balance"), args, 0); err != nil {
+		return err
+	}
+	balance, err := e.client.GetBalance(ctx)
+	if err != nil {
+		return err
+	}
+	t := table{header: []string{"balance"}, rows: [][]string{{balance.Balance}}, value: balance}
+	return t.render(e.stdout, e.outputFormat(outputTable))
+}
+
+// verifyReport is the outcome of the verification of the ledger
+type verifyReport struct {
+	Entries int `json:"entries"`
+	// Balance is the balance of the account, which must be the sum of the entries
+	Balance    string            `json:"balance"`
+	Sum        string            `json:"sum"`
+	Violations []verifyViolation `json:"violations"`
+}
+
+type verifyViolation struct {
+	Index   int    `json:"index"`
+	ID      string `json:"id"`
+	Problem string `json:"problem"`
+}
+
+// runVerify checks the invariants of the history and that the balance is the sum of the entries. Reading a data
+// directory checks the whole entries, the API does not expose their internal IDs nor idempotency keys.
+func runVerify(ctx context.Context, e *env, args []string) error {
+	if err := parseFlags(newFlagSet(e, "verify"), args, 0); err != nil {
+		return err
+	}
+	report, err := verify(ctx, e)
+	if err != nil {
+		return err
+	}
+	t := table{
+		header: []string{"index", "id", "problem"},
+		rows:   make([][]string, 0, len(report.Violations)),
+		value:  report,
+	}
+	for _, violation := range report.Violations {
+		t.rows = append(t.rows, []string{fmt.Sprint(violation.Index), violation.ID, violation.Problem})
+	}
+	format := e.outputFormat(outputTable)
+	verified := len(report.Violations) == 0
+	if format == outputTable && verified {
+		fmt.Fprintf(e.stdout, "verified %d entries, balance %v\n", report.Entries, report.Balance)
+		return nil
+	}
+	if err := t.render(e.stdout, format); err != nil {
+		return err
+	}
+	if !verified {
+		fmt.Fprintf(e.stderr, "found %d violations in %d entries\n", len(report.Violations), report.Entries)
+		return errFailed
+	}
+	return nil
+}
+
+func verify(ctx context.Context, e *env) (*verifyReport, error) {
+	before, err := e.client.GetBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var history []ledger.Transaction
+	if e.ledger != nil {
+		history, err = e.ledger.GetTransactionHistory(0, e.ledger.GetStats().TransactionCount)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read history")
+		}
+	} else {
+		for transaction, err := range e.client.Transactions(ctx, client.TransactionQuery{Limit: historyPageSize}) {
+			if err != nil {
+				return nil, err
+			}
+			entry := ledger.Transaction{
+				ExternalID: transaction.ID,
+				Amount:     transaction.Amount,
+				CreatedAt:  transaction.CreatedAt,
+				Kind:       transaction.Kind,
+			}
+			if transaction.LinkedTo != nil {
+				entry.LinkedTo = *transaction.LinkedTo
+			}
+			history = append(history, entry)
+		}
+		after, err := e.client.GetBalance(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if after.Balance != before.Balance {
+			return nil, errors.New("transactions were posted while verifying, verify again")
+		}
+	}
+
+	report := &verifyReport{Entries: len(history), Balance: before.Balance, Violations: []verifyViolation{}}
+	sum := decimal.Zero
+	for _, transaction := range history {
+		sum = sum.Add(transaction.Amount)
+	}
+	report.Sum = sum.String()
+	for _, violation := range ledger.VerifyHistory(history) {
+		report.Violations = append(report.Violations, verifyViolation{
+			Index:   violation.Index,
+			ID:      violation.ExternalID.String(),
+			Problem: violation.Problem,
+		})
+	}
+	if balance, err := decimal.NewFromString(before.Balance); err != nil || !balance.Equal(sum) {
+		report.Violations = append(report.Violations, verifyViolation{
+			Index:   -1,
+			Problem: fmt.Sprintf("balance %v is not the sum %v of the entries", before.Balance, sum),
+		})
+	}
+	return report, nil
+}
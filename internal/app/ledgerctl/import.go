@@ -0,0 +1,268 @@
+package ledgerctl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"teya_home_assignment/pkg/client"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	importFormatCSV  = "csv"
+	importFormatJSON = "json"
+
+	// metadataColumnPrefix prefixes the CSV columns holding the metadata of the transactions
+	metadataColumnPrefix = "metadata."
+)
+
+// The statuses of the imported transactions
+const (
+	importPosted   = "posted"
+	importReplayed = "replayed"
+	importHeld     = "held"
+	importFailed   = "failed"
+	// importPending is the status of the transactions of a dry run
+	importPending = "pending"
+)
+
+// importEntry is a transaction of an import file
+type importEntry struct {
+	// Line is the line of the transaction in a CSV file, or its position in a JSON file, from 1
+	Line        int
+	Transaction client.NewTransactionReqBody
+	// IdempotencyKey is the key given in the file, or derived from the transaction otherwise
+	IdempotencyKey string
+}
+
+// jsonImportEntry is a transaction of a JSON import file
+type jsonImportEntry struct {
+	client.NewTransactionReqBody
+	IdempotencyKey string `json:"idempotency_key"`
+}
+
+// readImport reads the transactions of an import file. A CSV file has a header naming its columns: amount,
+// reference, description, idempotency_key and metadata.<key>, of which only amount is required. A JSON file is an
+// array of the transactions as posted to the API, along with their idempotency_key.
+func readImport(r io.Reader, format string) ([]importEntry, error) {
+	var entries []importEntry
+	switch format {
+	case importFormatCSV:
+		reader := csv.NewReader(r)
+		header, err := reader.Read()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read CSV header")
+		}
+		if !slices.Contains(header, "amount") {
+			return nil, errors.New("CSV header has no amount column")
+		}
+		for _, column := range header {
+			known := slices.Contains([]string{"amount", "reference", "description", "idempotency_key"}, column)
+			if !known && !strings.HasPrefix(column, metadataColumnPrefix) {
+				return nil, errors.Errorf("unknown CSV column %q", column)
+			}
+		}
+		for line := 2; ; line++ {
+			record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read CSV file")
+			}
+			entry := importEntry{Line: line}
+			for i, column := range header {
+				switch column {
+				case "amount":
+					entry.Transaction.Amount = record[i]
+				case "reference":
+					entry.Transaction.Reference = record[i]
+				case "description":
+					entry.Transaction.Description = record[i]
+				case "idempotency_key":
+					entry.IdempotencyKey = record[i]
+				default:
+					if record[i] == "" {
+						continue
+					}
+					if entry.Transaction.Metadata == nil {
+						entry.Transaction.Metadata = make(map[string]string)
+					}
+					entry.Transaction.Metadata[strings.TrimPrefix(column, metadataColumnPrefix)] = record[i]
+				}
+			}
+			entries = append(entries, entry)
+		}
+	case importFormatJSON:
+		var jsonEntries []jsonImportEntry
+		if err := json.NewDecoder(r).Decode(&jsonEntries); err != nil {
+			return nil, errors.Wrap(err, "failed to read JSON file")
+		}
+		for i, jsonEntry := range jsonEntries {
+			entries = append(entries, importEntry{
+				Line:           i + 1,
+				Transaction:    jsonEntry.NewTransactionReqBody,
+				IdempotencyKey: jsonEntry.IdempotencyKey,
+			})
+		}
+	default:
+		return nil, errors.Errorf("unknown import format %q", format)
+	}
+	deriveIdempotencyKeys(entries)
+	return entries, nil
+}
+
+// deriveIdempotencyKeys derives the idempotency keys of the transactions without one from their content, and the
+// number of identical transactions before them in the file. Importing a file again, or a file made of more
+// transactions appended to an imported one, posts the transactions which were not posted yet only.
+func deriveIdempotencyKeys(entries []importEntry) {
+	occurrences := make(map[string]int)
+	for i, entry := range entries {
+		if entry.IdempotencyKey != "" {
+			continue
+		}
+		hash := sha256.New()
+		transaction := entry.Transaction
+		fields := []string{transaction.Amount, transaction.Reference, transaction.Description}
+		for _, key := range slices.Sorted(maps.Keys(transaction.Metadata)) {
+			fields = append(fields, key, transaction.Metadata[key])
+		}
+		for _, field := range fields {
+			// the length prefix keeps the fields apart
+			fmt.Fprintf(hash, "%d:%v", len(field), field)
+		}
+		digest := hex.EncodeToString(hash.Sum(nil))[:32]
+		entries[i].IdempotencyKey = "import-" + digest + "-" + strconv.Itoa(occurrences[digest])
+		occurrences[digest]++
+	}
+}
+
+// importResult is the outcome of an imported transaction
+type importResult struct {
+	Line           int    `json:"line"`
+	Status         string `json:"status"`
+	IdempotencyKey string `json:"idempotency_key"`
+	Amount         string `json:"amount"`
+	// ID is the ID of the posted transaction, or of the review it is held for
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// runImport posts the transactions of a file in order, carrying on after the transactions the ledger rejects. The
+// import stops on the other errors, e.g. when the webserver is unreachable, and can be run again.
+func runImport(ctx context.Context, e *env, args []string) error {
+	flags := newFlagSet(e, "import")
+	format := flags.String("format", "", "format of the file: csv or json, from its extension when empty")
+	dryRun := flags.Bool("dry-run", false, "read the file without posting its transactions")
+	if err := parseFlags(flags, args, 1); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return usageErrorf("the file to import is required")
+	}
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	if *format != importFormatCSV && *format != importFormatJSON {
+		return usageErrorf("unknown format %q: must be csv or json", *format)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to open import file")
+	}
+	defer file.Close()
+	entries, err := readImport(file, *format)
+	if err != nil {
+		return errors.Wrapf(err, "invalid import file %v", path)
+	}
+
+	results := make([]importResult, 0, len(entries))
+	var importErr error
+	for _, entry := range entries {
+		result := importResult{
+			Line:           entry.Line,
+			Status:         importPending,
+			IdempotencyKey: entry.IdempotencyKey,
+			Amount:         entry.Transaction.Amount,
+		}
+		if !*dryRun {
+			importErr = postEntry(ctx, e, entry, &result)
+			if importErr != nil {
+				break
+			}
+		}
+		results = append(results, result)
+	}
+
+	t := table{
+		header: []string{"line", "status", "id", "amount", "idempotency_key", "error"},
+		rows:   make([][]string, 0, len(results)),
+		value:  results,
+	}
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Status]++
+		t.rows = append(t.rows, []string{
+			strconv.Itoa(result.Line), result.Status, result.ID, result.Amount, result.IdempotencyKey, result.Error,
+		})
+	}
+	if err := t.render(e.stdout, e.outputFormat(outputTable)); err != nil {
+		return err
+	}
+	if importErr != nil {
+		return errors.Wrapf(importErr, "import stopped after %d of %d transactions", len(results), len(entries))
+	}
+	if *dryRun {
+		fmt.Fprintf(e.stderr, "read %d transactions, none was posted\n", len(results))
+		return nil
+	}
+	fmt.Fprintf(e.stderr, "imported %d transactions: %d posted, %d already posted, %d held for review, %d failed\n",
+		len(results), counts[importPosted], counts[importReplayed], counts[importHeld], counts[importFailed])
+	if counts[importFailed] > 0 {
+		return errFailed
+	}
+	return nil
+}
+
+// postEntry posts an imported transaction and records its outcome. The errors of the transaction itself are
+// recorded in the result, the others are returned.
+func postEntry(ctx context.Context, e *env, entry importEntry, result *importResult) error {
+	created, err := e.client.CreateTransaction(ctx, entry.Transaction, client.WithIdempotencyKey(entry.IdempotencyKey))
+	var apiErr *client.Error
+	switch {
+	case errors.As(err, &apiErr) && apiErr.Status < 500:
+		result.Status = importFailed
+		result.Error = apiErr.Detail
+		if result.Error == "" {
+			result.Error = string(apiErr.Code)
+		}
+		for _, fieldErr := range apiErr.Errors {
+			result.Error += fmt.Sprintf("; %v: %v", fieldErr.Field, fieldErr.Message)
+		}
+		return nil
+	case err != nil:
+		return err
+	case created.Review != nil:
+		result.Status = importHeld
+		result.ID = created.Review.ID.String()
+	case created.Replayed:
+		result.Status = importReplayed
+		result.ID = created.Posted.Transaction.ID.String()
+	default:
+		result.Status = importPosted
+		result.ID = created.Posted.Transaction.ID.String()
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+// Package ledgerctl is the command line tool operating the ledger, either through the API of a running webserver or
+// directly on the data directory of a stopped one
+package ledgerctl
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/pkg/client"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Exit codes of the tool
+const (
+	ExitOK = 0
+	// ExitFailure is returned when a command failed, e.g. the ledger did not verify or an import line was rejected
+	ExitFailure = 1
+	// ExitUsage is returned on command line usage errors
+	ExitUsage = 2
+)
+
+const (
+	// DefaultServer is the base URL of the webserver called unless configured otherwise
+	DefaultServer = "http://localhost:8000"
+	// ServerEnv is the environment variable configuring the base URL of the webserver
+	ServerEnv = "LEDGER_SERVER"
+)
+
+// errFailed is returned by the commands which printed why they failed already
+var errFailed = errors.New("command failed")
+
+// usageError is a command line usage error, printed along with the usage of the command unless empty
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usageErrorf(format string, args ...any) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// env is what the commands run with
+type env struct {
+	client *client.Client
+	// ledger is the ledger of the data directory, nil when calling a webserver
+	ledger *ledger.Ledger
+	// output is the output format requested, the default one of the command when empty
+	output string
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// outputFormat returns the output format requested, or the given default one
+func (e *env) outputFormat(defaultFormat string) string {
+	if e.output != "" {
+		return e.output
+	}
+	return defaultFormat
+}
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(ctx context.Context, e *env, args []string) error
+}
+
+var commands = []command{
+	{name: "post", args: "-amount AMOUNT [flags]", summary: "post a transaction", run: runPost},
+	{name: "list", args: "[flags]", summary: "list the transaction history, optionally filtered", run: runList},
+	{name: "export", args: "[flags]", summary: "export the transaction history, as CSV by default", run: runExport},
+	{name: "balance", summary: "show the balance of the account", run: runBalance},
+	{name: "verify", summary: "verify the integrity of the ledger", run: runVerify},
+	{name: "import", args: "[flags] FILE", summary: "post the transactions of a CSV or JSON file", run: runImport},
+}
+
+// Run runs the tool with the given command line arguments, without the program name, and returns its exit code
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer, lookupEnv func(string) (string, bool)) int {
+	server := DefaultServer
+	if value, ok := lookupEnv(ServerEnv); ok && value != "" {
+		server = value
+	}
+	flags := flag.NewFlagSet("ledgerctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&server, "server", server, "base URL of the webserver, also set by "+ServerEnv)
+	dataDir := flags.String("data-dir", "",
+		"data directory of a stopped webserver, opened directly rather than calling the webserver")
+	output := flags.String("output", "", "output format: table, json or csv")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of the command, none when 0")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: ledgerctl [flags] COMMAND [command flags]\n\nCommands:\n")
+		for _, cmd := range commands {
+			fmt.Fprintf(stderr, "  %-8v %v\n", cmd.name, cmd.summary)
+		}
+		fmt.Fprintf(stderr, "\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return ExitUsage
+	}
+	index := slices.IndexFunc(commands, func(cmd command) bool {
+		return cmd.name == flags.Arg(0)
+	})
+	if index < 0 {
+		fmt.Fprintf(stderr, "unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		return ExitUsage
+	}
+	if !slices.Contains([]string{"", outputTable, outputJSON, outputCSV}, *output) {
+		fmt.Fprintf(stderr, "invalid output format %q: must be table, json or csv\n", *output)
+		return ExitUsage
+	}
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	e := &env{output: *output, stdout: stdout, stderr: stderr}
+	if *dataDir != "" {
+		local, err := openDataDir(*dataDir, lookupEnv)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return ExitFailure
+		}
+		defer func() {
+			if err := local.close(); err != nil {
+				fmt.Fprintf(stderr, "error: %v\n", err)
+			}
+		}()
+		e.client, e.ledger = local.client, local.ledger
+	} else {
+		remote, err := client.New(server)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return ExitUsage
+		}
+		e.client = remote
+	}
+
+	cmd := commands[index]
+	err := cmd.run(ctx, e, flags.Args()[1:])
+	var usageErr *usageError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.As(err, &usageErr):
+		if usageErr.message != "" {
+			fmt.Fprintf(stderr, "%v\nUsage: ledgerctl %v %v\n", usageErr.message, cmd.name, cmd.args)
+		}
+		return ExitUsage
+	case errors.Is(err, errFailed):
+		return ExitFailure
+	}
+	fmt.Fprintf(stderr, "error: %v\n", err)
+	var apiErr *client.Error
+	if errors.As(err, &apiErr) {
+		for _, fieldErr := range apiErr.Errors {
+			fmt.Fprintf(stderr, "  %v: %v\n", fieldErr.Field, fieldErr.Message)
+		}
+	}
+	return ExitFailure
+}
+
+// newFlagSet returns the flag set of a command, whose errors are returned as usage errors
+func newFlagSet(e *env, name string) *flag.FlagSet {
+	flags := flag.NewFlagSet("ledgerctl "+name, flag.ContinueOnError)
+	flags.SetOutput(e.stderr)
+	return flags
+}
+
+// parseFlags parses the flags of a command, which takes no positional arguments unless allowed
+func parseFlags(flags *flag.FlagSet, args []string, positional int) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		// the flag package printed the error along with the usage
+		return &usageError{}
+	}
+	if flags.NArg() > positional {
+		return usageErrorf("unexpected arguments %v", strings.Join(flags.Args()[positional:], " "))
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package ledgerctl_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"teya_home_assignment/internal/app/ledgerctl"
+	"teya_home_assignment/internal/app/webserver/config"
+	"teya_home_assignment/internal/app/webserver/server"
+	"teya_home_assignment/internal/pkg/journal"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/pkg/client"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type result struct {
+	code   int
+	stdout string
+	stderr string
+}
+
+// merchantFees charges 1.5% + 0.20, at least 0.25, on the credits
+const merchantFees = `[{name: Merchant service charge, currency: EUR, applies_to: credits, percentage: 0.015, fixed: 0.20,
+  min: 0.25, rounding: half_up}]`
+
+func run(t *testing.T, args ...string) result {
+	t.Helper()
+	return runWithEnv(t, nil, args...)
+}
+
+func runWithEnv(t *testing.T, env map[string]string, args ...string) result {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	lookupEnv := func(key string) (string, bool) {
+		value, exists := env[key]
+		return value, exists
+	}
+	code := ledgerctl.Run(context.Background(), args, &stdout, &stderr, lookupEnv)
+	return result{code: code, stdout: stdout.String(), stderr: stderr.String()}
+}
+
+// serve serves a webserver with an in-memory ledger until the test ends and returns its base URL
+func serve(t *testing.T) string {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Server.GRPCListenAddress = ""
+	require.NoError(t, cfg.Fees.Schedules.Set(merchantFees))
+	webserver, err := server.New(cfg)
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, shutdown := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- webserver.Serve(ctx, listener, nil)
+	}()
+	t.Cleanup(func() {
+		shutdown()
+		<-served
+	})
+	return "http://" + listener.Addr().String()
+}
+
+func TestRun__PostsAndListsOnDataDirectory(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	posted := runWithEnv(t, map[string]string{"FEE_SCHEDULES": merchantFees},
+		"-data-dir", dataDir, "post", "-amount", "100", "-reference", "INV-1", "-metadata", "channel=pos")
+	require.Equal(t, ledgerctl.ExitOK, posted.code, posted.stderr)
+
+	// Act
+	listed := run(t, "-data-dir", dataDir, "-output", "json", "list", "-limit", "0")
+	balance := run(t, "-data-dir", dataDir, "balance")
+	fees := run(t, "-data-dir", dataDir, "-output", "csv", "list", "-kind", "fee")
+
+	// Assert
+	assert.Contains(t, posted.stdout, "INV-1")
+	require.Equal(t, ledgerctl.ExitOK, listed.code, listed.stderr)
+	var transactions []client.Transaction
+	require.NoError(t, json.Unmarshal([]byte(listed.stdout), &transactions))
+	require.Len(t, transactions, 2)
+	assert.Equal(t, "INV-1", transactions[0].Reference)
+	assert.Equal(t, map[string]string{"channel": "pos"}, transactions[0].Metadata)
+	assert.Equal(t, "fee", transactions[1].Kind)
+	assert.Equal(t, "BALANCE\n98.3\n", balance.stdout)
+	records, err := csv.NewReader(strings.NewReader(fees.stdout)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, []string{"id", "created_at", "amount", "kind", "category", "reference", "description", "linked_to"},
+		records[0])
+	assert.Equal(t, "-1.7", records[1][2])
+	assert.Equal(t, transactions[0].ID.String(), records[1][7])
+}
+
+func TestRun__RefusesDataDirectoryOfRunningWebserver(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	ledgerJournal, err := journal.Open(dataDir)
+	require.NoError(t, err)
+	defer ledgerJournal.Close()
+
+	// Act
+	res := run(t, "-data-dir", dataDir, "balance")
+
+	// Assert
+	assert.Equal(t, ledgerctl.ExitFailure, res.code)
+	assert.Contains(t, res.stderr, "used by a running webserver")
+}
+
+func TestRun__CallsWebserver(t *testing.T) {
+	// Arrange
+	serverURL := serve(t)
+	posted := run(t, "-server", serverURL, "post", "-amount", "100", "-idempotency-key", "order-1")
+	require.Equal(t, ledgerctl.ExitOK, posted.code, posted.stderr)
+
+	// Act
+	replayed := run(t, "-server", serverURL, "post", "-amount", "100", "-idempotency-key", "order-1")
+	verified := run(t, "-server", serverURL, "verify")
+
+	// Assert
+	assert.Equal(t, ledgerctl.ExitOK, replayed.code, replayed.stderr)
+	assert.Contains(t, replayed.stderr, "already posted")
+	assert.Equal(t, ledgerctl.ExitOK, verified.code, verified.stderr)
+	assert.Equal(t, "verified 2 entries, balance 98.3\n", verified.stdout)
+}
+
+func TestRun__ImportsOnce(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	file := filepath.Join(t.TempDir(), "settlements.csv")
+	content := "amount,reference,metadata.channel\n100,INV-1,pos\n100,INV-1,pos\nlots,INV-2,\n"
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+	first := run(t, "-data-dir", dataDir, "-output", "json", "import", file)
+
+	// Act
+	second := run(t, "-data-dir", dataDir, "-output", "json", "import", file)
+
+	// Assert
+	statuses := func(res result) []string {
+		var results []struct {
+			Line   int    `json:"line"`
+			Status string `json:"status"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(res.stdout), &results))
+		var statuses []string
+		for _, result := range results {
+			statuses = append(statuses, fmt.Sprintf("%d:%v", result.Line, result.Status))
+		}
+		return statuses
+	}
+	assert.Equal(t, ledgerctl.ExitFailure, first.code)
+	assert.Equal(t, []string{"2:posted", "3:posted", "4:failed"}, statuses(first))
+	assert.Equal(t, ledgerctl.ExitFailure, second.code)
+	assert.Equal(t, []string{"2:replayed", "3:replayed", "4:failed"}, statuses(second))
+	balance := run(t, "-data-dir", dataDir, "balance")
+	assert.Equal(t, "BALANCE\n200\n", balance.stdout)
+}
+
+func TestRun__VerifyReportsViolations(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	now := time.Now().UTC()
+	var lines []string
+	for i, createdAt := range []time.Time{now, now.Add(-time.Hour)} {
+		entry := ledger.Transaction{
+			ID: uint64(i + 1), Amount: decimal.NewFromInt(10), ExternalID: uuid.New(), CreatedAt: createdAt,
+		}
+		line, err := json.Marshal([]ledger.Transaction{entry})
+		require.NoError(t, err)
+		lines = append(lines, string(line))
+	}
+	content := strings.Join(lines, "\n") + "\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, journal.FileName), []byte(content), 0o600))
+
+	// Act
+	res := run(t, "-data-dir", dataDir, "verify")
+
+	// Assert
+	assert.Equal(t, ledgerctl.ExitFailure, res.code)
+	assert.Contains(t, res.stdout, "before the previous entry")
+	assert.Contains(t, res.stderr, "found 1 violations in 2 entries")
+}
+
+func TestRun__UsageErrors(t *testing.T) {
+	tests := map[string][]string{
+		"no command":       {},
+		"unknown command":  {"transfer"},
+		"unknown output":   {"-output", "xml", "balance"},
+		"missing amount":   {"post"},
+		"unknown flag":     {"list", "-sort", "amount"},
+		"extra arguments":  {"balance", "now"},
+		"missing file":     {"import"},
+		"unknown format":   {"import", "settlements.xlsx"},
+		"negative offset":  {"list", "-offset", "-1"},
+		"invalid from":     {"list", "-from", "yesterday"},
+		"invalid metadata": {"post", "-amount", "1", "-metadata", "channel"},
+	}
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			res := run(t, append([]string{"-data-dir", t.TempDir()}, args...)...)
+
+			// Assert
+			assert.Equal(t, ledgerctl.ExitUsage, res.code, res.stderr)
+			assert.Empty(t, res.stdout)
+		})
+	}
+}
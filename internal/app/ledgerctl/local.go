@@ -0,0 +1,82 @@
+package ledgerctl
+
+import (
+	"net/http"
+	"os"
+	"teya_home_assignment/internal/app/webserver/config"
+	"teya_home_assignment/internal/app/webserver/controllers"
+	"teya_home_assignment/internal/pkg/feed"
+	"teya_home_assignment/internal/pkg/health"
+	"teya_home_assignment/internal/pkg/journal"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/metrics"
+	"teya_home_assignment/pkg/client"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+// localLedger is the ledger of a data directory, whose API is served in process so the commands run the same way
+// whether they call a webserver or not
+type localLedger struct {
+	client  *client.Client
+	ledger  *ledger.Ledger
+	journal *journal.Journal
+	feed    *feed.Feed
+}
+
+// openDataDir restores the ledger of the data directory and serves its API in process. Unlike the webserver, the
+// background workers are not started, so neither scheduled transactions nor interest are posted. The limits and
+// rules are not enforced either, as they are not persisted. The fees are charged as configured for the webserver,
+// by its configuration file and environment.
+func openDataDir(dataDir string, lookupEnv func(string) (string, bool)) (*localLedger, error) {
+	// the journal would create a missing data directory, most likely a typo here
+	if info, err := os.Stat(dataDir); err != nil || !info.IsDir() {
+		return nil, errors.Errorf("data directory %v does not exist", dataDir)
+	}
+	cfg, err := config.Load(nil, lookupEnv)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the webserver configuration")
+	}
+	cfg.Storage = config.Storage{Backend: config.StorageFile, DataDir: dataDir}
+	ledgerJournal, err := journal.Open(dataDir)
+	if errors.Is(err, journal.ErrLocked) {
+		return nil, errors.Errorf("data directory %v is used by a running webserver, call it with -server instead",
+			dataDir)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open data directory")
+	}
+	apiControllers, services, err := controllers.InitControllers(cfg, metrics.New(), ledgerJournal, health.NewChecker())
+	if err != nil {
+		ledgerJournal.Close()
+		return nil, errors.Wrap(err, "failed to set up ledger")
+	}
+	if err := services.Restore(); err != nil {
+		ledgerJournal.Close()
+		return nil, err
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: controllers.ErrorHandler})
+	if err := controllers.SetupRoutes(app.Group(controllers.APIRouteBasePath), apiControllers); err != nil {
+		ledgerJournal.Close()
+		return nil, errors.Wrap(err, "failed to set up API")
+	}
+	local := &localLedger{ledger: services.Ledger, journal: ledgerJournal, feed: services.Feed}
+	local.client, err = client.New("http://ledgerctl",
+		client.WithDoer(client.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			return app.Test(req, -1)
+		})),
+		// the API is served in process, there are no network errors to retry
+		client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		ledgerJournal.Close()
+		return nil, errors.Wrap(err, "failed to set up API")
+	}
+	return local, nil
+}
+
+// close flushes and closes the journal
+func (l *localLedger) close() error {
+	l.feed.Close()
+	return errors.Wrap(l.journal.Close(), "failed to close data directory")
+}
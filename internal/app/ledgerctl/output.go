@@ -0,0 +1,88 @@
+package ledgerctl
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"teya_home_assignment/pkg/client"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The output formats of the commands
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+// table is the output of a command, rendered as an aligned table or as CSV from its rows, or as JSON from its value
+type table struct {
+	// header names the columns in snake case, as in the CSV output
+	header []string
+	rows   [][]string
+	value  any
+}
+
+func (t table) render(w io.Writer, format string) error {
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return errors.Wrap(encoder.Encode(t.value), "failed to write output")
+	case outputCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(t.header); err != nil {
+			return errors.Wrap(err, "failed to write output")
+		}
+		if err := writer.WriteAll(t.rows); err != nil {
+			return errors.Wrap(err, "failed to write output")
+		}
+		return nil
+	}
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := make([]string, len(t.header))
+	for i, name := range t.header {
+		header[i] = strings.ToUpper(name)
+	}
+	fmt.Fprintln(writer, strings.Join(header, "\t"))
+	for _, row := range t.rows {
+		// the cells are kept on a single line so the rows stay aligned
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = strings.Join(strings.Fields(cell), " ")
+		}
+		fmt.Fprintln(writer, strings.Join(cells, "\t"))
+	}
+	return errors.Wrap(writer.Flush(), "failed to write output")
+}
+
+var transactionHeader = []string{
+	"id", "created_at", "amount", "kind", "category", "reference", "description", "linked_to",
+}
+
+// transactionsTable lists the transactions, the JSON output being the transactions as answered by the API
+func transactionsTable(transactions []client.Transaction) table {
+	t := table{header: transactionHeader, value: transactions, rows: make([][]string, 0, len(transactions))}
+	for _, transaction := range transactions {
+		linkedTo := ""
+		if transaction.LinkedTo != nil {
+			linkedTo = transaction.LinkedTo.String()
+		}
+		t.rows = append(t.rows, []string{
+			transaction.ID.String(),
+			transaction.CreatedAt.Format(time.RFC3339),
+			transaction.Amount.String(),
+			transaction.Kind,
+			transaction.Category,
+			transaction.Reference,
+			transaction.Description,
+			linkedTo,
+		})
+	}
+	return t
+}
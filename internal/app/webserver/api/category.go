@@ -0,0 +1,100 @@
+package api
+
+import (
+	"teya_home_assignment/internal/pkg/categories"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+type CategoryRuleReqBody struct {
+	Category           string `json:"category" validate:"required,max=64"`
+	Priority           int    `json:"priority" validate:"gte=0"`
+	DescriptionPattern string `json:"description_pattern" validate:"max=256"`
+	Counterparty       string `json:"counterparty" validate:"max=140"`
+	MinAmount          string `json:"min_amount"`
+	MaxAmount          string `json:"max_amount"`
+}
+
+type CategoryRule struct {
+	ID                 uuid.UUID `json:"id"`
+	Category           string    `json:"category"`
+	Priority           int       `json:"priority"`
+	DescriptionPattern string    `json:"description_pattern,omitempty"`
+	Counterparty       string    `json:"counterparty,omitempty"`
+	MinAmount          string    `json:"min_amount,omitempty"`
+	MaxAmount          string    `json:"max_amount,omitempty"`
+}
+
+type CategoryRulesRespBody struct {
+	Rules []CategoryRule `json:"rules"`
+}
+
+type CategoryReqBody struct {
+	Category string `json:"category" validate:"required,max=64"`
+}
+
+type TransactionCategory struct {
+	TransactionID uuid.UUID  `json:"transaction_id"`
+	Category      string     `json:"category,omitempty"`
+	Source        string     `json:"source,omitempty"`
+	RuleID        *uuid.UUID `json:"rule_id,omitempty"`
+}
+
+func (b CategoryRuleReqBody) ToModel() (categories.Rule, error) {
+	rule := categories.Rule{
+		Category:           b.Category,
+		Priority:           b.Priority,
+		DescriptionPattern: b.DescriptionPattern,
+		Counterparty:       b.Counterparty,
+	}
+	var err error
+	if rule.MinAmount, err = parseOptionalAmount(b.MinAmount); err != nil {
+		return categories.Rule{}, errors.Wrap(err, "invalid min_amount")
+	}
+	if rule.MaxAmount, err = parseOptionalAmount(b.MaxAmount); err != nil {
+		return categories.Rule{}, errors.Wrap(err, "invalid max_amount")
+	}
+	return rule, nil
+}
+
+func FromCategoryRuleModel(rule categories.Rule) CategoryRule {
+	resp := CategoryRule{
+		ID:                 rule.ID,
+		Category:           rule.Category,
+		Priority:           rule.Priority,
+		DescriptionPattern: rule.DescriptionPattern,
+		Counterparty:       rule.Counterparty,
+	}
+	if rule.MinAmount.Valid {
+		resp.MinAmount = rule.MinAmount.Decimal.String()
+	}
+	if rule.MaxAmount.Valid {
+		resp.MaxAmount = rule.MaxAmount.Decimal.String()
+	}
+	return resp
+}
+
+func FromCategoryModel(transactionID uuid.UUID, category categories.Category) TransactionCategory {
+	resp := TransactionCategory{
+		TransactionID: transactionID,
+		Category:      category.Name,
+		Source:        string(category.Source),
+	}
+	if category.RuleID != uuid.Nil {
+		resp.RuleID = &category.RuleID
+	}
+	return resp
+}
+
+func parseOptionalAmount(value string) (decimal.NullDecimal, error) {
+	if value == "" {
+		return decimal.NullDecimal{}, nil
+	}
+	amount, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.NullDecimal{}, err
+	}
+	return decimal.NewNullDecimal(amount), nil
+}
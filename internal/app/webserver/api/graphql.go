@@ -0,0 +1,30 @@
+package api
+
+// GraphQLReqBody is a GraphQL request, as defined by the GraphQL over HTTP specification
+type GraphQLReqBody struct {
+	Query string `json:"query" validate:"required"`
+	// OperationName names the operation to execute, required when the query holds several operations
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// GraphQLRespBody is the result of a GraphQL request. Data is null when the request failed before being executed,
+// e.g. when the query is invalid.
+type GraphQLRespBody struct {
+	Data   map[string]any `json:"data"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+type GraphQLError struct {
+	Message   string            `json:"message"`
+	Locations []GraphQLLocation `json:"locations,omitempty"`
+	// Path is the path of the field which failed to resolve, made of field names and list indices
+	Path []any `json:"path,omitempty"`
+	// Extensions holds the error code of the HTTP API along with its status, e.g. query_too_complex
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
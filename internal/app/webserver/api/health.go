@@ -0,0 +1,26 @@
+package api
+
+import (
+	"teya_home_assignment/internal/pkg/health"
+)
+
+type HealthRespBody struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components,omitempty"`
+}
+
+type ComponentHealth struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func FromHealthReport(report health.Report) HealthRespBody {
+	resp := HealthRespBody{
+		Status:     string(report.Status),
+		Components: make(map[string]ComponentHealth, len(report.Components)),
+	}
+	for name, component := range report.Components {
+		resp.Components[name] = ComponentHealth{Status: string(component.Status), Error: component.Error}
+	}
+	return resp
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"teya_home_assignment/internal/pkg/interest"
+	"time"
+)
+
+type InterestAccrualRespBody struct {
+	From    time.Time              `json:"from"`
+	To      time.Time              `json:"to"`
+	Days    []InterestDailyAccrual `json:"days"`
+	Total   string                 `json:"total"`
+	Rounded string                 `json:"rounded"`
+	Posted  *Transaction           `json:"posted,omitempty"`
+}
+
+type InterestDailyAccrual struct {
+	Date     string `json:"date"`
+	Balance  string `json:"balance"`
+	Rate     string `json:"rate"`
+	Interest string `json:"interest"`
+}
+
+func FromAccrualModel(accrual *interest.Accrual) InterestAccrualRespBody {
+	resp := InterestAccrualRespBody{
+		From:    accrual.From,
+		To:      accrual.To,
+		Days:    make([]InterestDailyAccrual, len(accrual.Days)),
+		Total:   accrual.Total.String(),
+		Rounded: accrual.Posted.String(),
+	}
+	for i, day := range accrual.Days {
+		resp.Days[i] = InterestDailyAccrual{
+			Date:     day.Date.Format(time.DateOnly),
+			Balance:  day.Balance.String(),
+			Rate:     day.Rate.String(),
+			Interest: day.Interest.String(),
+		}
+	}
+	return resp
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"teya_home_assignment/internal/pkg/limits"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// LimitsBody holds the spending limits, an empty or zero value disables a limit
+type LimitsBody struct {
+	MaxTransactionAmount     string `json:"max_transaction_amount"`
+	MaxDailyDebits           string `json:"max_daily_debits"`
+	MaxWeeklyDebits          string `json:"max_weekly_debits"`
+	MaxTransactionsPerMinute int    `json:"max_transactions_per_minute" validate:"gte=0"`
+}
+
+func FromLimitsModel(rules limits.Rules) LimitsBody {
+	return LimitsBody{
+		MaxTransactionAmount:     rules.MaxTransactionAmount.String(),
+		MaxDailyDebits:           rules.MaxDailyDebits.String(),
+		MaxWeeklyDebits:          rules.MaxWeeklyDebits.String(),
+		MaxTransactionsPerMinute: rules.MaxTransactionsPerMinute,
+	}
+}
+
+func (b LimitsBody) ToModel() (limits.Rules, error) {
+	rules := limits.Rules{MaxTransactionsPerMinute: b.MaxTransactionsPerMinute}
+	var err error
+	if rules.MaxTransactionAmount, err = parseLimit(b.MaxTransactionAmount); err != nil {
+		return limits.Rules{}, errors.Wrap(err, "invalid max_transaction_amount")
+	}
+	if rules.MaxDailyDebits, err = parseLimit(b.MaxDailyDebits); err != nil {
+		return limits.Rules{}, errors.Wrap(err, "invalid max_daily_debits")
+	}
+	if rules.MaxWeeklyDebits, err = parseLimit(b.MaxWeeklyDebits); err != nil {
+		return limits.Rules{}, errors.Wrap(err, "invalid max_weekly_debits")
+	}
+	return rules, nil
+}
+
+func parseLimit(value string) (decimal.Decimal, error) {
+	if value == "" {
+		return decimal.Zero, nil
+	}
+	return decimal.NewFromString(value)
+}
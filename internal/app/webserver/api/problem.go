@@ -0,0 +1,186 @@
+package api
+
+import (
+	"net/http"
+	"teya_home_assignment/internal/pkg/amounts"
+	"teya_home_assignment/internal/pkg/categories"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/limits"
+	"teya_home_assignment/internal/pkg/rules"
+	"teya_home_assignment/internal/pkg/scheduler"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// MIMEApplicationProblemJSON is the media type of the error responses, see RFC 7807
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// ProblemTypePrefix prefixes the error code in the problem type URI
+const ProblemTypePrefix = "urn:problem-type:ledger:"
+
+// ErrorCode identifies the kind of error in a Problem. The codes are stable, clients may rely on them.
+type ErrorCode string
+
+const (
+	CodeMalformedBody        ErrorCode = "malformed_body"
+	CodeValidationFailed     ErrorCode = "validation_failed"
+	CodeInvalidParameter     ErrorCode = "invalid_parameter"
+	CodeInvalidRequest       ErrorCode = "invalid_request"
+	CodeNotFound             ErrorCode = "not_found"
+	CodeRuleNotFound         ErrorCode = "rule_not_found"
+	CodeReviewNotFound       ErrorCode = "review_not_found"
+	CodeReviewClosed         ErrorCode = "review_closed"
+	CodeDuplicate            ErrorCode = "duplicate_transaction"
+	CodeIdempotencyKeyReused ErrorCode = "idempotency_key_reused"
+	CodeVersionMismatch      ErrorCode = "version_mismatch"
+	CodeCategoryRuleNotFound ErrorCode = "category_rule_not_found"
+	CodeAnnotationNotFound   ErrorCode = "category_annotation_not_found"
+	CodeTransactionNotFound  ErrorCode = "transaction_not_found"
+	CodeScheduleNotFound     ErrorCode = "schedule_not_found"
+	CodeScheduleExists       ErrorCode = "schedule_exists"
+	CodeLimitExceeded        ErrorCode = "limit_exceeded"
+	CodeRuleViolation        ErrorCode = "rule_violation"
+	CodeMethodNotAllowed     ErrorCode = "method_not_allowed"
+	CodeBodyTooLarge         ErrorCode = "body_too_large"
+	CodeQueryTooDeep         ErrorCode = "query_too_deep"
+	CodeQueryTooComplex      ErrorCode = "query_too_complex"
+	CodeServiceUnavailable   ErrorCode = "service_unavailable"
+	CodeInternal             ErrorCode = "internal_error"
+)
+
+// ErrTransactionNotFound is returned when the transaction a request refers to does not exist
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// Problem is the body of the error responses, following RFC 7807
+type Problem struct {
+	Type   string    `json:"type"`
+	Title  string    `json:"title"`
+	Status int       `json:"status"`
+	Detail string    `json:"detail,omitempty"`
+	Code   ErrorCode `json:"code"`
+	// Instance is the path of the request which failed
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	// Rule and Limit tell the limit exceeded, or the rule violated along with RuleID
+	Rule   string     `json:"rule,omitempty"`
+	Limit  string     `json:"limit,omitempty"`
+	RuleID *uuid.UUID `json:"rule_id,omitempty"`
+}
+
+// FieldError tells why a field of the request body, or a parameter of the request, is invalid
+type FieldError struct {
+	// Field is the JSON path of the field in the request body, or the name of the parameter
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ProblemError is returned by the handlers to fail a request with the given problem
+type ProblemError struct {
+	Status int
+	Code   ErrorCode
+	Detail string
+	Errors []FieldError
+	// Err is the cause of the problem, logged but not sent to the client
+	Err error
+}
+
+func (e *ProblemError) Error() string {
+	if e.Err == nil || e.Err.Error() == e.Detail {
+		return e.Detail
+	}
+	return e.Detail + ": " + e.Err.Error()
+}
+
+func (e *ProblemError) Unwrap() error {
+	return e.Err
+}
+
+// sentinelProblems are the statuses and codes of the errors returned by the services
+var sentinelProblems = []struct {
+	err    error
+	status int
+	code   ErrorCode
+}{
+	{rules.ErrRuleNotFound, http.StatusNotFound, CodeRuleNotFound},
+	{rules.ErrReviewNotFound, http.StatusNotFound, CodeReviewNotFound},
+	{rules.ErrReviewClosed, http.StatusConflict, CodeReviewClosed},
+	{ledger.ErrDuplicateTransaction, http.StatusConflict, CodeDuplicate},
+	{ledger.ErrVersionMismatch, http.StatusPreconditionFailed, CodeVersionMismatch},
+	{categories.ErrRuleNotFound, http.StatusNotFound, CodeCategoryRuleNotFound},
+	{categories.ErrAnnotationNotFound, http.StatusNotFound, CodeAnnotationNotFound},
+	{ErrTransactionNotFound, http.StatusNotFound, CodeTransactionNotFound},
+	{scheduler.ErrScheduleNotFound, http.StatusNotFound, CodeScheduleNotFound},
+	{scheduler.ErrScheduleExists, http.StatusConflict, CodeScheduleExists},
+}
+
+// FromError converts the error a request failed with. The sentinel errors of the services take precedence over the
+// ProblemError they are wrapped in, so the handlers may fail with a bad request on any service error. Unknown errors
+// are internal errors whose details are not disclosed.
+func FromError(err error) Problem {
+	var limitErr *limits.LimitExceededError
+	if errors.As(err, &limitErr) {
+		problem := newProblem(http.StatusUnprocessableEntity, CodeLimitExceeded, limitErr.Error())
+		problem.Rule, problem.Limit = limitErr.Rule, limitErr.Limit
+		return problem
+	}
+	var amountErr *amounts.InvalidAmountError
+	if errors.As(err, &amountErr) {
+		problem := newProblem(http.StatusBadRequest, CodeValidationFailed, "invalid amount")
+		problem.Errors = []FieldError{{Field: "amount", Code: amountErr.Reason, Message: amountErr.Message()}}
+		return problem
+	}
+	var violationErr *rules.RuleViolationError
+	if errors.As(err, &violationErr) {
+		problem := newProblem(http.StatusUnprocessableEntity, CodeRuleViolation, violationErr.Error())
+		problem.Rule, problem.RuleID = violationErr.Match.Name, &violationErr.Match.RuleID
+		return problem
+	}
+	for _, sentinel := range sentinelProblems {
+		if errors.Is(err, sentinel.err) {
+			return newProblem(sentinel.status, sentinel.code, sentinel.err.Error())
+		}
+	}
+	var problemErr *ProblemError
+	if errors.As(err, &problemErr) {
+		problem := newProblem(problemErr.Status, problemErr.Code, problemErr.Detail)
+		problem.Errors = problemErr.Errors
+		return problem
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return newProblem(fiberErr.Code, fiberErrorCode(fiberErr.Code), fiberErr.Message)
+	}
+	return newProblem(http.StatusInternalServerError, CodeInternal, "")
+}
+
+func newProblem(status int, code ErrorCode, detail string) Problem {
+	return Problem{
+		Type:   ProblemTypePrefix + string(code),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// fiberErrorCode tells the code of the errors returned by fiber itself, like for unknown routes
+func fiberErrorCode(status int) ErrorCode {
+	switch status {
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusRequestEntityTooLarge:
+		return CodeBodyTooLarge
+	case http.StatusServiceUnavailable:
+		return CodeServiceUnavailable
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeInvalidRequest
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"teya_home_assignment/internal/pkg/reconciliation"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type ReconciliationReportRespBody struct {
+	Matched           []ReconciliationMatch `json:"matched"`
+	AmountMismatches  []ReconciliationMatch `json:"amount_mismatches"`
+	UnmatchedInLedger []Transaction         `json:"unmatched_in_ledger"`
+	UnmatchedInFile   []SettlementLine      `json:"unmatched_in_file"`
+}
+
+type ReconciliationMatch struct {
+	SettlementLine SettlementLine `json:"settlement_line"`
+	Transaction    Transaction    `json:"transaction"`
+}
+
+type SettlementLine struct {
+	Line      int             `json:"line"`
+	Reference string          `json:"reference"`
+	Amount    decimal.Decimal `json:"amount"`
+	Date      time.Time       `json:"date"`
+}
+
+func FromReconciliationReportModel(report *reconciliation.Report) ReconciliationReportRespBody {
+	resp := ReconciliationReportRespBody{
+		Matched:           fromMatchModels(report.Matched),
+		AmountMismatches:  fromMatchModels(report.AmountMismatches),
+		UnmatchedInLedger: make([]Transaction, len(report.UnmatchedInLedger)),
+		UnmatchedInFile:   make([]SettlementLine, len(report.UnmatchedInFile)),
+	}
+	for i, transaction := range report.UnmatchedInLedger {
+		resp.UnmatchedInLedger[i] = FromTransactionModel(transaction)
+	}
+	for i, line := range report.UnmatchedInFile {
+		resp.UnmatchedInFile[i] = fromSettlementLineModel(line)
+	}
+	return resp
+}
+
+func fromMatchModels(matches []reconciliation.Match) []ReconciliationMatch {
+	result := make([]ReconciliationMatch, len(matches))
+	for i, match := range matches {
+		result[i] = ReconciliationMatch{
+			SettlementLine: fromSettlementLineModel(match.Line),
+			Transaction:    FromTransactionModel(match.Transaction),
+		}
+	}
+	return result
+}
+
+func fromSettlementLineModel(line reconciliation.SettlementLine) SettlementLine {
+	return SettlementLine{
+		Line:      line.Line,
+		Reference: line.Reference,
+		Amount:    line.Amount,
+		Date:      line.Date,
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"teya_home_assignment/internal/pkg/report"
+	"time"
+)
+
+type AggregateReportRespBody struct {
+	Interval string            `json:"interval"`
+	TimeZone string            `json:"time_zone"`
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Buckets  []AggregateBucket `json:"buckets"`
+}
+
+type AggregateBucket struct {
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	Count          int       `json:"count"`
+	Credits        string    `json:"credits"`
+	Debits         string    `json:"debits"`
+	Net            string    `json:"net"`
+	ClosingBalance string    `json:"closing_balance"`
+}
+
+func FromBucketsModel(interval report.Interval, location *time.Location, from, to time.Time,
+	buckets []report.Bucket) AggregateReportRespBody {
+	resp := AggregateReportRespBody{
+		Interval: string(interval),
+		TimeZone: location.String(),
+		From:     from.In(location),
+		To:       to.In(location),
+		Buckets:  make([]AggregateBucket, len(buckets)),
+	}
+	for i, bucket := range buckets {
+		resp.Buckets[i] = AggregateBucket{
+			From:           bucket.From,
+			To:             bucket.To,
+			Count:          bucket.Count,
+			Credits:        bucket.Credits.String(),
+			Debits:         bucket.Debits.String(),
+			Net:            bucket.Net().String(),
+			ClosingBalance: bucket.ClosingBalance.String(),
+		}
+	}
+	return resp
+}
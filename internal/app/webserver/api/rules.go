@@ -0,0 +1,172 @@
+package api
+
+import (
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/rules"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+type RuleReqBody struct {
+	Name       string `json:"name" validate:"required,max=100"`
+	Expression string `json:"expression" validate:"required,max=4096"`
+	Action     string `json:"action" validate:"required,oneof=reject review flag"`
+}
+
+type Rule struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	Expression string    `json:"expression"`
+	Action     string    `json:"action"`
+}
+
+type RulesRespBody struct {
+	Rules []Rule `json:"rules"`
+}
+
+// RuleTestReqBody evaluates a sample transaction against the given rule, or against the registered rules
+// if none is given. The account state defaults to the current one.
+type RuleTestReqBody struct {
+	Rule        *RuleReqBody        `json:"rule"`
+	Transaction RuleTestTransaction `json:"transaction"`
+	Account     *RuleTestAccount    `json:"account"`
+}
+
+type RuleTestTransaction struct {
+	Amount      string            `json:"amount" validate:"required"`
+	Reference   string            `json:"reference"`
+	Description string            `json:"description"`
+	Metadata    map[string]string `json:"metadata"`
+}
+
+type RuleTestAccount struct {
+	Balance          string `json:"balance"`
+	TransactionCount int    `json:"transaction_count"`
+}
+
+type RuleTestRespBody struct {
+	Decision string                `json:"decision"`
+	Matches  []RuleMatch           `json:"matches"`
+	Errors   []RuleEvaluationError `json:"errors"`
+}
+
+type RuleMatch struct {
+	RuleID uuid.UUID `json:"rule_id"`
+	Name   string    `json:"name"`
+	Action string    `json:"action"`
+}
+
+type RuleEvaluationError struct {
+	RuleID uuid.UUID `json:"rule_id"`
+	Name   string    `json:"name"`
+	Error  string    `json:"error"`
+}
+
+type Review struct {
+	ID          uuid.UUID   `json:"id"`
+	Status      string      `json:"status"`
+	Transaction Transaction `json:"transaction"`
+	Matches     []RuleMatch `json:"matches"`
+	// Posted is the transaction posted once the review was approved
+	Posted *uuid.UUID `json:"posted_transaction_id,omitempty"`
+}
+
+type ReviewsRespBody struct {
+	Reviews []Review `json:"reviews"`
+}
+
+type FlaggedTransaction struct {
+	TransactionID uuid.UUID   `json:"transaction_id"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Matches       []RuleMatch `json:"matches"`
+}
+
+type FlaggedTransactionsRespBody struct {
+	Flagged []FlaggedTransaction `json:"flagged"`
+}
+
+func (b RuleReqBody) ToModel() rules.Rule {
+	return rules.Rule{
+		Name:       b.Name,
+		Expression: b.Expression,
+		Action:     rules.Action(b.Action),
+	}
+}
+
+// ToModel converts the sample transaction and account state, defaulting to the given account state
+func (b RuleTestReqBody) ToModel(current rules.Account) (ledger.Transaction, rules.Account, error) {
+	amount, err := decimal.NewFromString(b.Transaction.Amount)
+	if err != nil {
+		return ledger.Transaction{}, rules.Account{}, errors.New("invalid transaction amount")
+	}
+	transaction := ledger.Transaction{
+		Amount:      amount,
+		Reference:   b.Transaction.Reference,
+		Description: b.Transaction.Description,
+		Metadata:    b.Transaction.Metadata,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if b.Account == nil {
+		return transaction, current, nil
+	}
+	account := rules.Account{TransactionCount: b.Account.TransactionCount}
+	if b.Account.Balance != "" {
+		if account.Balance, err = decimal.NewFromString(b.Account.Balance); err != nil {
+			return ledger.Transaction{}, rules.Account{}, errors.New("invalid account balance")
+		}
+	}
+	return transaction, account, nil
+}
+
+func FromRuleModel(rule rules.Rule) Rule {
+	return Rule{
+		ID:         rule.ID,
+		Name:       rule.Name,
+		Expression: rule.Expression,
+		Action:     string(rule.Action),
+	}
+}
+
+func FromRuleResultModel(result rules.Result) RuleTestRespBody {
+	resp := RuleTestRespBody{
+		Decision: result.Decision,
+		Matches:  fromMatchesModel(result.Matches),
+		Errors:   make([]RuleEvaluationError, len(result.Errors)),
+	}
+	for i, evalErr := range result.Errors {
+		resp.Errors[i] = RuleEvaluationError{RuleID: evalErr.RuleID, Name: evalErr.Name, Error: evalErr.Err.Error()}
+	}
+	return resp
+}
+
+func FromReviewModel(review rules.Review) Review {
+	resp := Review{
+		ID:          review.ID,
+		Status:      string(review.Status),
+		Transaction: FromTransactionModel(review.Transaction),
+		Matches:     fromMatchesModel(review.Matches),
+	}
+	if review.Posted != uuid.Nil {
+		resp.Posted = &review.Posted
+	}
+	return resp
+}
+
+func FromFlagModel(flag rules.Flag) FlaggedTransaction {
+	return FlaggedTransaction{
+		TransactionID: flag.TransactionID,
+		CreatedAt:     flag.CreatedAt,
+		Matches:       fromMatchesModel(flag.Matches),
+	}
+}
+
+func fromMatchesModel(matches []rules.Match) []RuleMatch {
+	resp := make([]RuleMatch, len(matches))
+	for i, match := range matches {
+		resp[i] = RuleMatch{RuleID: match.RuleID, Name: match.Name, Action: string(match.Action)}
+	}
+	return resp
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"teya_home_assignment/internal/pkg/scheduler"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ScheduleReqBody struct {
+	Amount      string     `json:"amount" validate:"required,numeric"`
+	Description string     `json:"description" validate:"max=140"`
+	Cron        string     `json:"cron" validate:"required_without=Interval,excluded_with=Interval"`
+	Interval    string     `json:"interval" validate:"required_without=Cron"`
+	StartAt     time.Time  `json:"start_at" validate:"required"`
+	EndAt       *time.Time `json:"end_at"`
+}
+
+type Schedule struct {
+	ID          uuid.UUID  `json:"id"`
+	Amount      string     `json:"amount"`
+	Description string     `json:"description,omitempty"`
+	Cron        string     `json:"cron,omitempty"`
+	Interval    string     `json:"interval,omitempty"`
+	StartAt     time.Time  `json:"start_at"`
+	EndAt       *time.Time `json:"end_at,omitempty"`
+	NextRun     *time.Time `json:"next_run,omitempty"`
+}
+
+type SchedulesRespBody struct {
+	Schedules []Schedule `json:"schedules"`
+}
+
+type ScheduleNextRunsRespBody struct {
+	NextRuns []time.Time `json:"next_runs"`
+}
+
+func FromScheduleModel(schedule scheduler.Schedule, now time.Time) Schedule {
+	resp := Schedule{
+		ID:          schedule.ID,
+		Amount:      schedule.Amount.String(),
+		Description: schedule.Description,
+		Cron:        schedule.Cron,
+		StartAt:     schedule.StartAt,
+	}
+	if schedule.Interval != 0 {
+		resp.Interval = schedule.Interval.String()
+	}
+	if !schedule.EndAt.IsZero() {
+		resp.EndAt = &schedule.EndAt
+	}
+	if nextRun, ok := schedule.Next(now); ok {
+		resp.NextRun = &nextRun
+	}
+	return resp
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"teya_home_assignment/internal/pkg/statement"
+	"time"
+)
+
+type StatementRespBody struct {
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	Currency       string          `json:"currency"`
+	OpeningBalance string          `json:"opening_balance"`
+	ClosingBalance string          `json:"closing_balance"`
+	Totals         StatementTotals `json:"totals"`
+	Transactions   []Transaction   `json:"transactions"`
+}
+
+type StatementTotals struct {
+	CreditsCount int    `json:"credits_count"`
+	Credits      string `json:"credits"`
+	DebitsCount  int    `json:"debits_count"`
+	Debits       string `json:"debits"`
+}
+
+func FromStatementModel(s *statement.Statement) StatementRespBody {
+	transactions := make([]Transaction, len(s.Transactions))
+	for i, transaction := range s.Transactions {
+		transactions[i] = FromTransactionModel(transaction)
+	}
+	return StatementRespBody{
+		From:           s.From,
+		To:             s.To,
+		Currency:       s.Account.Currency,
+		OpeningBalance: s.OpeningBalance.String(),
+		ClosingBalance: s.ClosingBalance.String(),
+		Totals: StatementTotals{
+			CreditsCount: s.Totals.CreditsCount,
+			Credits:      s.Totals.Credits.String(),
+			DebitsCount:  s.Totals.DebitsCount,
+			Debits:       s.Totals.Debits.String(),
+		},
+		Transactions: transactions,
+	}
+}
@@ -1,19 +1,121 @@
 package api
 
 import (
+	"maps"
+	"net/http"
+	"regexp"
 	"teya_home_assignment/internal/pkg/ledger"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
+const (
+	// IdempotencyKeyHeader carries the key making the creation of a transaction safe to retry: a transaction
+	// submitted again with the key of a posted one is answered with the original response instead of being posted
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on the responses replayed for a known idempotency key
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// clientIdempotencyKeyPrefix namespaces the idempotency keys sent by the clients in the ledger, apart from the keys
+// of the transactions posted by the services, like the scheduled ones
+const clientIdempotencyKeyPrefix = "client:"
+
+// idempotencyKeyPattern restricts the idempotency keys to printable ASCII
+var idempotencyKeyPattern = regexp.MustCompile(`^[\x21-\x7E]{1,255}$`)
+
 type Transaction struct {
-	ID     uuid.UUID       `json:"id"`
-	Amount decimal.Decimal `json:"amount"`
+	ID          uuid.UUID         `json:"id"`
+	Amount      decimal.Decimal   `json:"amount"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Reference   string            `json:"reference,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Category    string            `json:"category,omitempty"`
+	Kind        string            `json:"kind,omitempty"`
+	LinkedTo    *uuid.UUID        `json:"linked_to,omitempty"`
 }
 
 type NewTransactionReqBody struct {
-	Amount string `json:"amount" validate:"required,number"`
+	Amount      string            `json:"amount" validate:"required,numeric"`
+	Reference   string            `json:"reference" validate:"omitempty,max=35"`
+	Description string            `json:"description" validate:"max=140"`
+	Metadata    map[string]string `json:"metadata" validate:"max=20,dive,keys,max=64,endkeys,max=256"`
+}
+
+// NewTransaction is a transaction requested by a client, whatever the API it was requested over
+type NewTransaction struct {
+	Amount decimal.Decimal
+	// IdempotencyKey is the key of the transaction in the ledger, empty unless the client sent one
+	IdempotencyKey string
+	// Options are the options the transaction is posted to the ledger with
+	Options []ledger.TransactionOption
+}
+
+// ParseNewTransaction validates the transaction requested by a client and builds the options it is posted with.
+// The idempotency key sent by the client, if any, is namespaced apart from the keys of the services. The errors are
+// *ProblemError.
+func ParseNewTransaction(reqBody NewTransactionReqBody, idempotencyKey string) (NewTransaction, error) {
+	if err := Validate(reqBody); err != nil {
+		return NewTransaction{}, err
+	}
+	amount, err := decimal.NewFromString(reqBody.Amount)
+	if err != nil {
+		return NewTransaction{}, &ProblemError{
+			Status: http.StatusBadRequest,
+			Code:   CodeInvalidRequest,
+			Detail: "invalid transaction amount",
+			Err:    err,
+		}
+	}
+	transaction := NewTransaction{Amount: amount}
+	if reqBody.Reference != "" {
+		transaction.Options = append(transaction.Options, ledger.WithReference(reqBody.Reference))
+	}
+	if reqBody.Description != "" {
+		transaction.Options = append(transaction.Options, ledger.WithDescription(reqBody.Description))
+	}
+	if len(reqBody.Metadata) > 0 {
+		transaction.Options = append(transaction.Options, ledger.WithMetadata(reqBody.Metadata))
+	}
+	if idempotencyKey != "" {
+		if !idempotencyKeyPattern.MatchString(idempotencyKey) {
+			return NewTransaction{}, &ProblemError{
+				Status: http.StatusBadRequest,
+				Code:   CodeInvalidParameter,
+				Detail: "invalid " + IdempotencyKeyHeader + " parameter",
+				Errors: []FieldError{{
+					Field:   IdempotencyKeyHeader,
+					Code:    string(CodeInvalidParameter),
+					Message: "must be 1 to 255 printable ASCII characters",
+				}},
+			}
+		}
+		transaction.IdempotencyKey = clientIdempotencyKeyPrefix + idempotencyKey
+		transaction.Options = append(transaction.Options, ledger.WithIdempotencyKey(transaction.IdempotencyKey))
+	}
+	return transaction, nil
+}
+
+// CheckReplay tells whether a transaction requested again with the idempotency key of the original one is the same
+// transaction, and fails with an idempotency_key_reused *ProblemError otherwise
+func CheckReplay(original ledger.Transaction, transaction NewTransaction, reqBody NewTransactionReqBody) error {
+	if !original.Amount.Equal(transaction.Amount) || original.Reference != reqBody.Reference ||
+		original.Description != reqBody.Description || !maps.Equal(original.Metadata, reqBody.Metadata) {
+		return &ProblemError{
+			Status: http.StatusUnprocessableEntity,
+			Code:   CodeIdempotencyKeyReused,
+			Detail: "idempotency key was used for another transaction",
+		}
+	}
+	return nil
+}
+
+type NewTransactionRespBody struct {
+	Transaction Transaction   `json:"transaction"`
+	Fees        []Transaction `json:"fees"`
 }
 
 type GetBalanceRespBody struct {
@@ -28,11 +130,40 @@ type PaginatedTransactionsResponse struct {
 type Pagination struct {
 	Offset int `json:"offset"`
 	Limit  int `json:"limit"`
+	// Total is the number of transactions over all the pages
+	Total   int  `json:"total"`
+	HasMore bool `json:"has_more"`
+	// Next and Prev are the URLs of the next and previous pages, also sent in the Link header, see RFC 8288. They
+	// are omitted on the last and first pages.
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
 }
 
 func FromTransactionModel(transaction ledger.Transaction) Transaction {
-	return Transaction{
-		ID:     transaction.ExternalID,
-		Amount: transaction.Amount,
+	resp := Transaction{
+		ID:          transaction.ExternalID,
+		Amount:      transaction.Amount,
+		CreatedAt:   transaction.CreatedAt,
+		Reference:   transaction.Reference,
+		Description: transaction.Description,
+		Metadata:    transaction.Metadata,
+		Kind:        transaction.Kind,
+	}
+	if transaction.LinkedTo != uuid.Nil {
+		resp.LinkedTo = &transaction.LinkedTo
+	}
+	return resp
+}
+
+// FromPostedTransactionsModel converts the entries returned by ledger.PostTransaction, the posted transaction
+// followed by its linked fees
+func FromPostedTransactionsModel(posted []ledger.Transaction) NewTransactionRespBody {
+	resp := NewTransactionRespBody{
+		Transaction: FromTransactionModel(posted[0]),
+		Fees:        make([]Transaction, 0, len(posted)-1),
+	}
+	for _, entry := range posted[1:] {
+		resp.Fees = append(resp.Fees, FromTransactionModel(entry))
 	}
+	return resp
 }
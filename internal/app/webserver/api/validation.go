@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+)
+
+// validate validates the request bodies, reporting the fields by their JSON name
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// Validate checks a request body against its validate tags. The fields failing the validation are reported in a
+// ProblemError.
+func Validate(reqBody any) error {
+	err := validate.Struct(reqBody)
+	if err == nil {
+		return nil
+	}
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return &ProblemError{Status: http.StatusBadRequest, Code: CodeInvalidRequest, Detail: err.Error(), Err: err}
+	}
+	problemErr := &ProblemError{
+		Status: http.StatusBadRequest,
+		Code:   CodeValidationFailed,
+		Detail: "invalid request body",
+		Err:    err,
+	}
+	for _, fieldErr := range validationErrs {
+		// the namespace starts with the name of the request body type
+		_, field, _ := strings.Cut(fieldErr.Namespace(), ".")
+		problemErr.Errors = append(problemErr.Errors, FieldError{
+			Field:   field,
+			Code:    fieldErr.Tag(),
+			Message: validationMessage(fieldErr),
+		})
+	}
+	return problemErr
+}
+
+func validationMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "required_without":
+		return fmt.Sprintf("is required when %v is not set", strings.ToLower(fieldErr.Param()))
+	case "excluded_with":
+		return fmt.Sprintf("must not be set along with %v", strings.ToLower(fieldErr.Param()))
+	case "numeric":
+		return "must be a number"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %v", fieldErr.Param())
+	case "max":
+		if fieldErr.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %v characters long", fieldErr.Param())
+		}
+		return fmt.Sprintf("must have at most %v entries", fieldErr.Param())
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %v", fieldErr.Param())
+	}
+	return fmt.Sprintf("failed the %v validation", fieldErr.Tag())
+}
@@ -0,0 +1,521 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"teya_home_assignment/internal/pkg/amounts"
+	"teya_home_assignment/internal/pkg/fees"
+	"teya_home_assignment/internal/pkg/interest"
+	"teya_home_assignment/internal/pkg/logging"
+	"teya_home_assignment/internal/pkg/tracing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// Storage backends of the ledger
+const (
+	StorageMemory = "memory"
+	StorageFile   = "file"
+)
+
+// fileEnv is the environment variable giving the path of the YAML file, when the -config flag is not set
+const fileEnv = "CONFIG_FILE"
+
+// masked replaces the value of secrets when the configuration is printed
+const masked = "****"
+
+// Config is the configuration of the webserver. Every setting is read, by increasing precedence, from its default,
+// the YAML file, the environment and the command line. The environment variable of a setting is its flag name in
+// upper snake case, e.g. LISTEN_ADDRESS for -listen-address.
+type Config struct {
+	Server     Server     `yaml:"server"`
+	Pagination Pagination `yaml:"pagination"`
+	GraphQL    GraphQL    `yaml:"graphql"`
+	Storage    Storage    `yaml:"storage"`
+	Fees       Fees       `yaml:"fees"`
+	Interest   Interest   `yaml:"interest"`
+	Logging    Logging    `yaml:"logging"`
+	Tracing    Tracing    `yaml:"tracing"`
+}
+
+type Server struct {
+	ListenAddress string `yaml:"listen_address"`
+	// GRPCListenAddress is the address the gRPC API listens on, the gRPC API is disabled when empty
+	GRPCListenAddress string        `yaml:"grpc_listen_address"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout bounds the time given to the requests in flight to complete when shutting down
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+// Pagination bounds the pages of the transaction history
+type Pagination struct {
+	DefaultLimit int `yaml:"default_limit"`
+	MaxLimit     int `yaml:"max_limit"`
+}
+
+// GraphQL bounds the queries of the GraphQL API, which are rejected before being executed when over a limit
+type GraphQL struct {
+	// MaxDepth is the deepest nesting of fields of a query
+	MaxDepth int `yaml:"max_depth"`
+	// MaxComplexity is the largest number of fields a query may resolve, the fields of a page of transactions
+	// being counted once per transaction of the page
+	MaxComplexity int `yaml:"max_complexity"`
+}
+
+type Storage struct {
+	Backend string `yaml:"backend"`
+	// DataDir holds the journal of the file backend
+	DataDir string `yaml:"data_dir"`
+}
+
+// Fees are the fees charged on the transactions posted to the ledger, none by default
+type Fees struct {
+	Schedules FeeSchedules `yaml:"schedules"`
+}
+
+// FeeSchedules is set from the command line and the environment as a YAML or JSON list of fee schedules
+type FeeSchedules []FeeSchedule
+
+// FeeSchedule is a fees.Schedule, its amounts being decimal strings or numbers
+type FeeSchedule struct {
+	Name       string          `yaml:"name"`
+	Currency   string          `yaml:"currency"`
+	AppliesTo  string          `yaml:"applies_to"`
+	Percentage decimal.Decimal `yaml:"percentage"`
+	Fixed      decimal.Decimal `yaml:"fixed"`
+	Tiers      []FeeTier       `yaml:"tiers,omitempty"`
+	Min        decimal.Decimal `yaml:"min"`
+	Max        decimal.Decimal `yaml:"max"`
+	Rounding   string          `yaml:"rounding"`
+}
+
+type FeeTier struct {
+	UpTo       decimal.Decimal `yaml:"up_to"`
+	Percentage decimal.Decimal `yaml:"percentage"`
+	Fixed      decimal.Decimal `yaml:"fixed"`
+}
+
+// Interest is the interest.Config of the interest posted on the balance. Interest is disabled unless a rate is set.
+type Interest struct {
+	OverdraftRate decimal.Decimal `yaml:"overdraft_rate"`
+	CreditRate    decimal.Decimal `yaml:"credit_rate"`
+	DayCount      string          `yaml:"day_count"`
+	Cadence       string          `yaml:"cadence"`
+	StartAt       time.Time       `yaml:"start_at"`
+	Places        int             `yaml:"places"`
+	Rounding      string          `yaml:"rounding"`
+}
+
+type Logging struct {
+	Format string `yaml:"format"`
+	Level  string `yaml:"level"`
+}
+
+type Tracing struct {
+	Exporter string `yaml:"exporter"`
+	File     string `yaml:"file"`
+	// OTLPEndpoint is the URL of the collector, defaults to the standard OTEL_EXPORTER_OTLP_* environment variables
+	OTLPEndpoint string `yaml:"otlp_endpoint"`
+	// OTLPHeaders are sent to the collector, e.g. its API key, as comma separated key=value pairs. It is a secret.
+	OTLPHeaders string `yaml:"otlp_headers"`
+}
+
+func Default() Config {
+	return Config{
+		Server: Server{
+			ListenAddress:     ":8000",
+			GRPCListenAddress: ":9090",
+			ReadTimeout:       10 * time.Second,
+			WriteTimeout:      10 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			// docker stops containers forcefully after 10 seconds by default
+			ShutdownTimeout: 8 * time.Second,
+		},
+		Pagination: Pagination{
+			DefaultLimit: 10,
+			MaxLimit:     100,
+		},
+		GraphQL: GraphQL{
+			MaxDepth:      8,
+			MaxComplexity: 1000,
+		},
+		Storage: Storage{
+			Backend: StorageMemory,
+			DataDir: "data",
+		},
+		Interest: Interest{
+			DayCount: string(interest.Actual365),
+			Cadence:  string(interest.Monthly),
+			Places:   interest.DefaultPlaces,
+			Rounding: string(amounts.HalfEven),
+		},
+		Logging: Logging{
+			Format: string(logging.FormatJSON),
+			Level:  "info",
+		},
+		Tracing: Tracing{
+			Exporter: string(tracing.ExporterNone),
+			File:     "traces.json",
+		},
+	}
+}
+
+// Load reads the configuration from the command line arguments, the environment and the YAML file given with
+// -config or CONFIG_FILE, and validates it
+func Load(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	config := Default()
+	flags := newFlagSet(&config)
+	var file string
+	flags.StringVar(&file, "config", "", "path of the YAML configuration file")
+	if err := flags.Parse(args); err != nil {
+		return Config{}, errors.Wrap(err, "invalid command line")
+	}
+	// the flags were written to the configuration, they are applied again once the file and the environment were
+	fromCommandLine := map[string]string{}
+	flags.Visit(func(f *flag.Flag) {
+		fromCommandLine[f.Name] = f.Value.String()
+	})
+
+	config = Default()
+	if envFile, exists := lookupEnv(fileEnv); exists && file == "" {
+		file = envFile
+	}
+	if file != "" {
+		if err := readFile(file, &config); err != nil {
+			return Config{}, err
+		}
+	}
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		value, exists := lookupEnv(envName(f.Name))
+		if !exists || err != nil || f.Name == "config" {
+			return
+		}
+		if setErr := f.Value.Set(value); setErr != nil {
+			err = errors.Wrapf(setErr, "invalid %v", envName(f.Name))
+		}
+	})
+	if err != nil {
+		return Config{}, err
+	}
+	for name, value := range fromCommandLine {
+		if err := flags.Set(name, value); err != nil {
+			return Config{}, errors.Wrapf(err, "invalid -%v", name)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, errors.Wrap(err, "invalid configuration")
+	}
+	return config, nil
+}
+
+func newFlagSet(config *Config) *flag.FlagSet {
+	flags := flag.NewFlagSet("webserver", flag.ContinueOnError)
+	flags.StringVar(&config.Server.ListenAddress, "listen-address", config.Server.ListenAddress,
+		"address the API listens on")
+	flags.StringVar(&config.Server.GRPCListenAddress, "grpc-listen-address", config.Server.GRPCListenAddress,
+		"address the gRPC API listens on, the gRPC API is disabled when empty")
+	flags.DurationVar(&config.Server.ReadTimeout, "read-timeout", config.Server.ReadTimeout,
+		"maximum duration to read a request")
+	flags.DurationVar(&config.Server.WriteTimeout, "write-timeout", config.Server.WriteTimeout,
+		"maximum duration to write a response")
+	flags.DurationVar(&config.Server.IdleTimeout, "idle-timeout", config.Server.IdleTimeout,
+		"maximum duration a keep-alive connection is idle")
+	flags.DurationVar(&config.Server.ShutdownTimeout, "shutdown-timeout", config.Server.ShutdownTimeout,
+		"maximum duration to complete the requests in flight when shutting down")
+	flags.IntVar(&config.Pagination.DefaultLimit, "pagination-default-limit", config.Pagination.DefaultLimit,
+		"page size when none is requested")
+	flags.IntVar(&config.Pagination.MaxLimit, "pagination-max-limit", config.Pagination.MaxLimit,
+		"largest page size that can be requested")
+	flags.IntVar(&config.GraphQL.MaxDepth, "graphql-max-depth", config.GraphQL.MaxDepth,
+		"deepest nesting of fields of a GraphQL query")
+	flags.IntVar(&config.GraphQL.MaxComplexity, "graphql-max-complexity", config.GraphQL.MaxComplexity,
+		"largest number of fields a GraphQL query may resolve")
+	flags.StringVar(&config.Storage.Backend, "storage-backend", config.Storage.Backend,
+		"storage of the ledger: memory or file")
+	flags.StringVar(&config.Storage.DataDir, "storage-data-dir", config.Storage.DataDir,
+		"directory of the journal of the file storage")
+	flags.Var(&config.Fees.Schedules, "fee-schedules",
+		"fee schedules charged on the transactions, as a YAML or JSON list")
+	flags.TextVar(&config.Interest.OverdraftRate, "interest-overdraft-rate", config.Interest.OverdraftRate,
+		"annual interest rate charged on overdrafts, 0.18 is 18%")
+	flags.TextVar(&config.Interest.CreditRate, "interest-credit-rate", config.Interest.CreditRate,
+		"annual interest rate paid on credit balances, 0.02 is 2%")
+	flags.StringVar(&config.Interest.DayCount, "interest-day-count", config.Interest.DayCount,
+		"day count convention of the rates: ACT/360, ACT/365 or ACT/ACT")
+	flags.StringVar(&config.Interest.Cadence, "interest-cadence", config.Interest.Cadence,
+		"how often interest is posted: daily or monthly")
+	flags.TextVar(&config.Interest.StartAt, "interest-start-at", config.Interest.StartAt,
+		"first day interest accrues on, as an RFC 3339 time")
+	flags.IntVar(&config.Interest.Places, "interest-places", config.Interest.Places,
+		"decimal places posted interest is rounded to")
+	flags.StringVar(&config.Interest.Rounding, "interest-rounding", config.Interest.Rounding,
+		"rounding of posted interest: half_up or half_even")
+	flags.StringVar(&config.Logging.Format, "log-format", config.Logging.Format, "format of the logs: json or text")
+	flags.StringVar(&config.Logging.Level, "log-level", config.Logging.Level,
+		"level of the logs: debug, info, warn or error")
+	flags.StringVar(&config.Tracing.Exporter, "trace-exporter", config.Tracing.Exporter,
+		"exporter of the spans: none, stdout, file or otlp")
+	flags.StringVar(&config.Tracing.File, "trace-file", config.Tracing.File,
+		"file the spans are appended to by the file exporter")
+	flags.StringVar(&config.Tracing.OTLPEndpoint, "trace-otlp-endpoint", config.Tracing.OTLPEndpoint,
+		"URL of the OTLP/HTTP collector")
+	flags.StringVar(&config.Tracing.OTLPHeaders, "trace-otlp-headers", config.Tracing.OTLPHeaders,
+		"headers sent to the collector, as key=value pairs")
+	return flags
+}
+
+// envName returns the environment variable of a flag, e.g. LISTEN_ADDRESS for listen-address
+func envName(flagName string) string {
+	return strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+func readFile(path string, config *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to read configuration file")
+	}
+	defer file.Close()
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return errors.Wrapf(err, "invalid configuration file %v", path)
+	}
+	return nil
+}
+
+// Validate checks every setting, so a misconfigured webserver fails to start rather than when the setting is used
+func (c Config) Validate() error {
+	if c.Server.ListenAddress == "" {
+		return errors.New("listen address is required")
+	}
+	if c.Server.GRPCListenAddress == c.Server.ListenAddress {
+		return errors.New("gRPC listen address must differ from the listen address")
+	}
+	for name, timeout := range map[string]time.Duration{
+		"read timeout":     c.Server.ReadTimeout,
+		"write timeout":    c.Server.WriteTimeout,
+		"idle timeout":     c.Server.IdleTimeout,
+		"shutdown timeout": c.Server.ShutdownTimeout,
+	} {
+		if timeout <= 0 {
+			return errors.Errorf("%v must be positive", name)
+		}
+	}
+	if c.Pagination.MaxLimit <= 0 {
+		return errors.New("pagination max limit must be positive")
+	}
+	if c.Pagination.DefaultLimit <= 0 || c.Pagination.DefaultLimit > c.Pagination.MaxLimit {
+		return errors.Errorf("pagination default limit must be between 1 and %v", c.Pagination.MaxLimit)
+	}
+	if c.GraphQL.MaxDepth <= 0 || c.GraphQL.MaxComplexity <= 0 {
+		return errors.New("GraphQL max depth and max complexity must be positive")
+	}
+	switch c.Storage.Backend {
+	case StorageMemory:
+	case StorageFile:
+		if c.Storage.DataDir == "" {
+			return errors.New("data directory is required by the file storage")
+		}
+	default:
+		return errors.Errorf("unknown storage backend %q", c.Storage.Backend)
+	}
+	if _, err := c.Fees.Parse(); err != nil {
+		return err
+	}
+	if _, err := c.Interest.Parse(); err != nil {
+		return err
+	}
+	if _, err := c.Logging.Parse(); err != nil {
+		return err
+	}
+	if _, err := c.Tracing.Parse(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// String returns the schedules as YAML, which Set reads back
+func (f *FeeSchedules) String() string {
+	if f == nil || len(*f) == 0 {
+		return ""
+	}
+	content, err := yaml.Marshal(*f)
+	if err != nil {
+		return ""
+	}
+	return string(content)
+}
+
+// Set replaces the schedules with the YAML or JSON list
+func (f *FeeSchedules) Set(value string) error {
+	var schedules FeeSchedules
+	if err := yaml.Unmarshal([]byte(value), &schedules); err != nil {
+		return errors.Wrap(err, "invalid fee schedules")
+	}
+	*f = schedules
+	return nil
+}
+
+// Parse returns the fee schedules, validated
+func (f Fees) Parse() ([]fees.Schedule, error) {
+	schedules := make([]fees.Schedule, 0, len(f.Schedules))
+	for _, configured := range f.Schedules {
+		schedule := fees.Schedule{
+			Name:       configured.Name,
+			Currency:   configured.Currency,
+			AppliesTo:  fees.AppliesTo(configured.AppliesTo),
+			Percentage: configured.Percentage,
+			Fixed:      configured.Fixed,
+			Min:        configured.Min,
+			Max:        configured.Max,
+			Rounding:   amounts.RoundingMode(configured.Rounding),
+		}
+		for _, tier := range configured.Tiers {
+			schedule.Tiers = append(schedule.Tiers,
+				fees.Tier{UpTo: tier.UpTo, Percentage: tier.Percentage, Fixed: tier.Fixed})
+		}
+		if err := schedule.Validate(); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+// Enabled tells whether interest is posted, that is whether a rate is set
+func (i Interest) Enabled() bool {
+	return !i.OverdraftRate.IsZero() || !i.CreditRate.IsZero()
+}
+
+// Parse returns the interest configuration, validated
+func (i Interest) Parse() (interest.Config, error) {
+	config := interest.Config{
+		OverdraftRate: i.OverdraftRate,
+		CreditRate:    i.CreditRate,
+		DayCount:      interest.DayCount(i.DayCount),
+		Cadence:       interest.Cadence(i.Cadence),
+		StartAt:       i.StartAt,
+		Places:        int32(i.Places),
+		Rounding:      amounts.RoundingMode(i.Rounding),
+	}
+	if err := config.Validate(); err != nil {
+		return interest.Config{}, errors.Wrap(err, "invalid interest")
+	}
+	return config, nil
+}
+
+// Parse returns the logging configuration
+func (l Logging) Parse() (logging.Config, error) {
+	return logging.ParseConfig(l.Format, l.Level)
+}
+
+// Parse returns the tracing configuration
+func (t Tracing) Parse() (tracing.Config, error) {
+	exporter, err := tracing.ParseExporter(t.Exporter)
+	if err != nil {
+		return tracing.Config{}, err
+	}
+	headers, err := parseHeaders(t.OTLPHeaders)
+	if err != nil {
+		return tracing.Config{}, err
+	}
+	if exporter == tracing.ExporterFile && t.File == "" {
+		return tracing.Config{}, errors.New("trace file is required by the file exporter")
+	}
+	return tracing.Config{
+		Exporter:     exporter,
+		File:         t.File,
+		OTLPEndpoint: t.OTLPEndpoint,
+		OTLPHeaders:  headers,
+	}, nil
+}
+
+func parseHeaders(value string) (map[string]string, error) {
+	headers := map[string]string{}
+	if value == "" {
+		return headers, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		key, val, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			return nil, errors.Errorf("invalid OTLP header %q: must be key=value", pair)
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return headers, nil
+}
+
+func (f FeeSchedules) names() []string {
+	names := make([]string, 0, len(f))
+	for _, schedule := range f {
+		names = append(names, schedule.Name)
+	}
+	return names
+}
+
+// Masked returns a copy of the configuration safe to print, with the secrets masked
+func (c Config) Masked() Config {
+	if c.Tracing.OTLPHeaders != "" {
+		c.Tracing.OTLPHeaders = masked
+	}
+	return c
+}
+
+// LogValue logs the configuration with the secrets masked, under the names of the YAML file
+func (c Config) LogValue() slog.Value {
+	c = c.Masked()
+	return slog.GroupValue(
+		slog.Group("server",
+			"listen_address", c.Server.ListenAddress,
+			"grpc_listen_address", c.Server.GRPCListenAddress,
+			"read_timeout", c.Server.ReadTimeout.String(),
+			"write_timeout", c.Server.WriteTimeout.String(),
+			"idle_timeout", c.Server.IdleTimeout.String(),
+			"shutdown_timeout", c.Server.ShutdownTimeout.String(),
+		),
+		slog.Group("pagination",
+			"default_limit", c.Pagination.DefaultLimit,
+			"max_limit", c.Pagination.MaxLimit,
+		),
+		slog.Group("graphql",
+			"max_depth", c.GraphQL.MaxDepth,
+			"max_complexity", c.GraphQL.MaxComplexity,
+		),
+		slog.Group("storage",
+			"backend", c.Storage.Backend,
+			"data_dir", c.Storage.DataDir,
+		),
+		slog.Group("fees",
+			"schedules", c.Fees.Schedules.names(),
+		),
+		slog.Group("interest",
+			"overdraft_rate", c.Interest.OverdraftRate.String(),
+			"credit_rate", c.Interest.CreditRate.String(),
+			"day_count", c.Interest.DayCount,
+			"cadence", c.Interest.Cadence,
+			"start_at", c.Interest.StartAt,
+			"places", c.Interest.Places,
+			"rounding", c.Interest.Rounding,
+		),
+		slog.Group("logging",
+			"format", c.Logging.Format,
+			"level", c.Logging.Level,
+		),
+		slog.Group("tracing",
+			"exporter", c.Tracing.Exporter,
+			"file", c.Tracing.File,
+			"otlp_endpoint", c.Tracing.OTLPEndpoint,
+			"otlp_headers", c.Tracing.OTLPHeaders,
+		),
+	)
+}
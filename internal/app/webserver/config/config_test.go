@@ -0,0 +1,212 @@
+package config_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"teya_home_assignment/internal/app/webserver/config"
+	"teya_home_assignment/internal/pkg/amounts"
+	"teya_home_assignment/internal/pkg/fees"
+	"teya_home_assignment/internal/pkg/interest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, exists := values[key]
+		return value, exists
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad__DefaultsWithoutSettings(t *testing.T) {
+	cfg, err := config.Load(nil, env(nil))
+
+	require.NoError(t, err)
+	assert.Equal(t, config.Default(), cfg)
+}
+
+func TestLoad__AppliesFileThenEnvironmentThenFlags(t *testing.T) {
+	// Arrange
+	file := writeFile(t, `
+server:
+  listen_address: ":9000"
+  read_timeout: 3s
+pagination:
+  default_limit: 20
+  max_limit: 50
+logging:
+  level: warn
+`)
+
+	// Act
+	cfg, err := config.Load(
+		[]string{"-config", file, "-pagination-default-limit", "30"},
+		env(map[string]string{"PAGINATION_DEFAULT_LIMIT": "25", "LOG_LEVEL": "debug"}),
+	)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, ":9000", cfg.Server.ListenAddress)
+	assert.Equal(t, 3*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, 50, cfg.Pagination.MaxLimit)
+	assert.Equal(t, 30, cfg.Pagination.DefaultLimit)
+	assert.Equal(t, "debug", cfg.Logging.Level)
+	assert.Equal(t, config.Default().Server.WriteTimeout, cfg.Server.WriteTimeout)
+}
+
+func TestLoad__ReadsFileFromEnvironment(t *testing.T) {
+	file := writeFile(t, "storage:\n  backend: file\n  data_dir: /var/lib/ledger\n")
+
+	cfg, err := config.Load(nil, env(map[string]string{"CONFIG_FILE": file}))
+
+	require.NoError(t, err)
+	assert.Equal(t, config.Storage{Backend: config.StorageFile, DataDir: "/var/lib/ledger"}, cfg.Storage)
+}
+
+func TestLoad__ReadsFeeSchedules(t *testing.T) {
+	// Arrange
+	file := writeFile(t, `
+fees:
+  schedules:
+    - name: Merchant service charge
+      currency: EUR
+      applies_to: credits
+      percentage: 0.015
+      fixed: "0.20"
+      min: 0.25
+      rounding: half_up
+`)
+	fromEnv := `[{"name": "Card fee", "currency": "EUR", "applies_to": "debits", "fixed": "0.10", "rounding": "half_even",
+		"tiers": [{"up_to": "100", "percentage": "0.01"}, {"percentage": "0.005"}]}]`
+
+	// Act
+	fromFile, errFile := config.Load([]string{"-config", file}, env(nil))
+	overridden, errOverridden := config.Load([]string{"-config", file}, env(map[string]string{"FEE_SCHEDULES": fromEnv}))
+
+	// Assert
+	require.NoError(t, errFile)
+	schedules, err := fromFile.Fees.Parse()
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+	assert.Equal(t, "Merchant service charge", schedules[0].Name)
+	assert.Equal(t, fees.Credits, schedules[0].AppliesTo)
+	assert.Equal(t, "0.015", schedules[0].Percentage.String())
+	assert.Equal(t, "0.2", schedules[0].Fixed.String())
+	assert.Equal(t, amounts.HalfUp, schedules[0].Rounding)
+	require.NoError(t, errOverridden)
+	schedules, err = overridden.Fees.Parse()
+	require.NoError(t, err)
+	require.Len(t, schedules, 1)
+	assert.Equal(t, "Card fee", schedules[0].Name)
+	require.Len(t, schedules[0].Tiers, 2)
+	assert.Equal(t, "100", schedules[0].Tiers[0].UpTo.String())
+}
+
+func TestLoad__ReadsInterest(t *testing.T) {
+	// Arrange
+	file := writeFile(t, `
+interest:
+  overdraft_rate: 0.18
+  cadence: daily
+  start_at: 2025-01-01T00:00:00Z
+`)
+
+	// Act
+	cfg, err := config.Load([]string{"-config", file, "-interest-places", "4"},
+		env(map[string]string{"INTEREST_CREDIT_RATE": "0.02"}))
+
+	// Assert
+	require.NoError(t, err)
+	assert.False(t, config.Default().Interest.Enabled())
+	assert.True(t, cfg.Interest.Enabled())
+	interestConfig, err := cfg.Interest.Parse()
+	require.NoError(t, err)
+	assert.Equal(t, "0.18", interestConfig.OverdraftRate.String())
+	assert.Equal(t, "0.02", interestConfig.CreditRate.String())
+	assert.Equal(t, interest.Actual365, interestConfig.DayCount)
+	assert.Equal(t, interest.Daily, interestConfig.Cadence)
+	assert.Equal(t, time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), interestConfig.StartAt)
+	assert.Equal(t, int32(4), interestConfig.Places)
+	assert.Equal(t, amounts.HalfEven, interestConfig.Rounding)
+}
+
+func TestLoad__RejectsInvalidConfiguration(t *testing.T) {
+	testCases := map[string]struct {
+		args []string
+		env  map[string]string
+		file string
+	}{
+		"unknown flag":           {args: []string{"-port", "80"}},
+		"same listen addresses":  {env: map[string]string{"GRPC_LISTEN_ADDRESS": ":8000"}},
+		"default above maximum":  {args: []string{"-pagination-default-limit", "500"}},
+		"zero GraphQL depth":     {args: []string{"-graphql-max-depth", "0"}},
+		"unknown backend":        {env: map[string]string{"STORAGE_BACKEND": "postgres"}},
+		"invalid duration":       {env: map[string]string{"READ_TIMEOUT": "soon"}},
+		"negative timeout":       {args: []string{"-idle-timeout", "-1s"}},
+		"unknown log level":      {args: []string{"-log-level", "verbose"}},
+		"unknown exporter":       {env: map[string]string{"TRACE_EXPORTER": "jaeger"}},
+		"invalid OTLP headers":   {env: map[string]string{"TRACE_OTLP_HEADERS": "api-key"}},
+		"negative interest rate": {args: []string{"-interest-overdraft-rate", "-0.18"}},
+		"invalid interest rate":  {env: map[string]string{"INTEREST_CREDIT_RATE": "2%"}},
+		"unknown day count":      {env: map[string]string{"INTEREST_DAY_COUNT": "30/360"}},
+		"unknown cadence":        {file: "interest:\n  cadence: weekly\n"},
+		"unknown file setting":   {file: "server:\n  port: 80\n"},
+		"invalid fee schedules":  {env: map[string]string{"FEE_SCHEDULES": "name: fee"}},
+		"invalid fee schedule":   {args: []string{"-fee-schedules", `[{name: fee, currency: EUR, applies_to: refunds}]`}},
+		"missing file":           {args: []string{"-config", "/does/not/exist.yaml"}},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			args := testCase.args
+			if testCase.file != "" {
+				args = append(args, "-config", writeFile(t, testCase.file))
+			}
+
+			_, err := config.Load(args, env(testCase.env))
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestConfig_Masked__MasksSecrets(t *testing.T) {
+	cfg := config.Default()
+	cfg.Tracing.OTLPHeaders = "api-key=secret"
+
+	masked := cfg.Masked()
+
+	assert.Equal(t, "****", masked.Tracing.OTLPHeaders)
+	assert.Equal(t, "api-key=secret", cfg.Tracing.OTLPHeaders)
+}
+
+func TestConfig_LogValue__LogsEverySection(t *testing.T) {
+	// Arrange
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	// Act
+	logger.Info("configuration", "config", config.Default())
+
+	// Assert
+	var logged struct {
+		Config map[string]any `json:"config"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &logged))
+	for _, section := range []string{"server", "pagination", "graphql", "storage", "fees", "interest", "logging", "tracing"} {
+		assert.Contains(t, logged.Config, section)
+	}
+	assert.Equal(t, map[string]any{"max_depth": float64(8), "max_complexity": float64(1000)}, logged.Config["graphql"])
+}
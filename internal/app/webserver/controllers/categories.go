@@ -0,0 +1,252 @@
+package controllers
+
+import (
+	"log/slog"
+	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/pkg/categories"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/openapi"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CategoriesController struct {
+	categoriesService *categories.Categorizer
+	ledgerService     *ledger.Ledger
+}
+
+func NewCategoriesController(categoriesService *categories.Categorizer, ledgerService *ledger.Ledger) *CategoriesController {
+	return &CategoriesController{categoriesService: categoriesService, ledgerService: ledgerService}
+}
+
+func (c *CategoriesController) RegisterRoutes(router fiber.Router) error {
+	router.Post(CategoryRuleRoute, c.createRule)
+	router.Get(CategoryRuleRoute, c.getAllRules)
+	router.Get(CategoryRuleRoute+"/:id", c.getRule)
+	router.Put(CategoryRuleRoute+"/:id", c.updateRule)
+	router.Delete(CategoryRuleRoute+"/:id", c.deleteRule)
+	router.Get(TransactionCategoryRoute, c.getTransactionCategory)
+	router.Put(TransactionCategoryRoute, c.annotateTransaction)
+	router.Delete(TransactionCategoryRoute, c.removeAnnotation)
+	return nil
+}
+
+func (c *CategoriesController) Operations() []openapi.Operation {
+	ruleResponses := func(status int, description string) map[int]openapi.Response {
+		return map[int]openapi.Response{
+			status:                 {Description: description, Content: openapi.JSON(api.CategoryRule{})},
+			fiber.StatusBadRequest: errorResponse("Invalid category rule"),
+			fiber.StatusNotFound:   errorResponse("Category rule not found"),
+		}
+	}
+	transactionResponses := func(status int, response openapi.Response) map[int]openapi.Response {
+		return map[int]openapi.Response{
+			status:                 response,
+			fiber.StatusBadRequest: errorResponse("Invalid transaction id or category"),
+			fiber.StatusNotFound:   errorResponse("Transaction or category annotation not found"),
+		}
+	}
+	return []openapi.Operation{
+		{
+			Method:  fiber.MethodPost,
+			Path:    CategoryRuleRoute,
+			Tag:     "Categories",
+			Summary: "Create a rule categorizing the matching transactions",
+			Request: openapi.JSON(api.CategoryRuleReqBody{}),
+			Responses: map[int]openapi.Response{
+				fiber.StatusCreated: {
+					Description: "The category rule was created",
+					Content:     openapi.JSON(api.CategoryRule{}),
+				},
+				fiber.StatusBadRequest: errorResponse("Invalid category rule"),
+			},
+		},
+		{
+			Method:  fiber.MethodGet,
+			Path:    CategoryRuleRoute,
+			Tag:     "Categories",
+			Summary: "List the category rules",
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK: {Content: openapi.JSON(api.CategoryRulesRespBody{})},
+			},
+		},
+		{
+			Method:    fiber.MethodGet,
+			Path:      CategoryRuleRoute + "/:id",
+			Tag:       "Categories",
+			Summary:   "Get a category rule",
+			Responses: ruleResponses(fiber.StatusOK, ""),
+		},
+		{
+			Method:    fiber.MethodPut,
+			Path:      CategoryRuleRoute + "/:id",
+			Tag:       "Categories",
+			Summary:   "Update a category rule",
+			Request:   openapi.JSON(api.CategoryRuleReqBody{}),
+			Responses: ruleResponses(fiber.StatusOK, "The category rule was updated"),
+		},
+		{
+			Method:  fiber.MethodDelete,
+			Path:    CategoryRuleRoute + "/:id",
+			Tag:     "Categories",
+			Summary: "Delete a category rule",
+			Responses: map[int]openapi.Response{
+				fiber.StatusNoContent:  {Description: "The category rule was deleted"},
+				fiber.StatusBadRequest: errorResponse("Invalid category rule id"),
+				fiber.StatusNotFound:   errorResponse("Category rule not found"),
+			},
+		},
+		{
+			Method:  fiber.MethodGet,
+			Path:    TransactionCategoryRoute,
+			Tag:     "Categories",
+			Summary: "Get the category of a transaction, annotated or matched by a rule",
+			Responses: transactionResponses(fiber.StatusOK, openapi.Response{
+				Content: openapi.JSON(api.TransactionCategory{}),
+			}),
+		},
+		{
+			Method:  fiber.MethodPut,
+			Path:    TransactionCategoryRoute,
+			Tag:     "Categories",
+			Summary: "Annotate a transaction with a category, overriding the rules",
+			Request: openapi.JSON(api.CategoryReqBody{}),
+			Responses: transactionResponses(fiber.StatusOK, openapi.Response{
+				Description: "The transaction was annotated",
+				Content:     openapi.JSON(api.TransactionCategory{}),
+			}),
+		},
+		{
+			Method:  fiber.MethodDelete,
+			Path:    TransactionCategoryRoute,
+			Tag:     "Categories",
+			Summary: "Remove the category annotation of a transaction",
+			Responses: transactionResponses(fiber.StatusNoContent, openapi.Response{
+				Description: "The annotation was removed",
+			}),
+		},
+	}
+}
+
+func (c *CategoriesController) createRule(ctx *fiber.Ctx) error {
+	rule, err := parseCategoryRuleReqBody(ctx)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	rule, err = c.categoriesService.Create(rule)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully created category rule", "id", rule.ID)
+	return ctx.Status(fiber.StatusCreated).JSON(api.FromCategoryRuleModel(rule))
+}
+
+func (c *CategoriesController) getAllRules(ctx *fiber.Ctx) error {
+	ruleSet := c.categoriesService.List()
+	resp := api.CategoryRulesRespBody{Rules: make([]api.CategoryRule, len(ruleSet))}
+	for i, rule := range ruleSet {
+		resp.Rules[i] = api.FromCategoryRuleModel(rule)
+	}
+	slog.DebugContext(ctx.UserContext(), "successfully returned category rules", "count", len(ruleSet))
+	return ctx.Status(fiber.StatusOK).JSON(resp)
+}
+
+func (c *CategoriesController) getRule(ctx *fiber.Ctx) error {
+	id, err := paramID(ctx)
+	if err != nil {
+		return err
+	}
+	rule, err := c.categoriesService.Get(id)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(api.FromCategoryRuleModel(rule))
+}
+
+func (c *CategoriesController) updateRule(ctx *fiber.Ctx) error {
+	id, err := paramID(ctx)
+	if err != nil {
+		return err
+	}
+	rule, err := parseCategoryRuleReqBody(ctx)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	rule, err = c.categoriesService.Update(id, rule)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully updated category rule", "id", id)
+	return ctx.Status(fiber.StatusOK).JSON(api.FromCategoryRuleModel(rule))
+}
+
+func (c *CategoriesController) deleteRule(ctx *fiber.Ctx) error {
+	id, err := paramID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := c.categoriesService.Delete(id); err != nil {
+		return invalidRequest(err)
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully deleted category rule", "id", id)
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+func (c *CategoriesController) getTransactionCategory(ctx *fiber.Ctx) error {
+	transaction, err := c.getTransaction(ctx)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	category := c.categoriesService.Categorize(transaction)
+	return ctx.Status(fiber.StatusOK).JSON(api.FromCategoryModel(transaction.ExternalID, category))
+}
+
+func (c *CategoriesController) annotateTransaction(ctx *fiber.Ctx) error {
+	transaction, err := c.getTransaction(ctx)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	reqBody := api.CategoryReqBody{}
+	if err := parseBody(ctx, &reqBody); err != nil {
+		return err
+	}
+	if _, err := c.categoriesService.Annotate(transaction.ExternalID, reqBody.Category); err != nil {
+		return invalidRequest(err)
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully annotated transaction category",
+		"id", transaction.ExternalID, "category", reqBody.Category)
+	category := c.categoriesService.Categorize(transaction)
+	return ctx.Status(fiber.StatusOK).JSON(api.FromCategoryModel(transaction.ExternalID, category))
+}
+
+func (c *CategoriesController) removeAnnotation(ctx *fiber.Ctx) error {
+	transaction, err := c.getTransaction(ctx)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	if err := c.categoriesService.RemoveAnnotation(transaction.ExternalID); err != nil {
+		return invalidRequest(err)
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully removed transaction category annotation", "id", transaction.ExternalID)
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+func (c *CategoriesController) getTransaction(ctx *fiber.Ctx) (ledger.Transaction, error) {
+	id, err := paramID(ctx)
+	if err != nil {
+		return ledger.Transaction{}, err
+	}
+	transaction, exists := c.ledgerService.GetTransaction(id)
+	if !exists {
+		return ledger.Transaction{}, api.ErrTransactionNotFound
+	}
+	return transaction, nil
+}
+
+func parseCategoryRuleReqBody(ctx *fiber.Ctx) (categories.Rule, error) {
+	reqBody := api.CategoryRuleReqBody{}
+	if err := parseBody(ctx, &reqBody); err != nil {
+		return categories.Rule{}, err
+	}
+	return reqBody.ToModel()
+}
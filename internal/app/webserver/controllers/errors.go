@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"fmt"
+	"log/slog"
+	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/pkg/logging"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrorHandler sends the error a request failed with as a problem details response, see RFC 7807.
+// It is the error handler of the fiber app, the handlers return their errors rather than sending them.
+func ErrorHandler(ctx *fiber.Ctx, err error) error {
+	problem := api.FromError(err)
+	problem.Instance = ctx.Path()
+	problem.RequestID = logging.RequestID(ctx.UserContext())
+	if problem.Status >= fiber.StatusInternalServerError {
+		slog.ErrorContext(ctx.UserContext(), "request failed", "route", ctx.Route().Path, "error", err)
+	} else {
+		slog.WarnContext(ctx.UserContext(), "request rejected", "route", ctx.Route().Path, "code", problem.Code,
+			"error", err)
+	}
+	return ctx.Status(problem.Status).JSON(problem, api.MIMEApplicationProblemJSON)
+}
+
+// parseBody parses the JSON request body and validates it
+func parseBody(ctx *fiber.Ctx, reqBody any) error {
+	if err := decodeBody(ctx, reqBody); err != nil {
+		return err
+	}
+	return api.Validate(reqBody)
+}
+
+// decodeBody parses the JSON request body, for the request bodies validated along with the services
+func decodeBody(ctx *fiber.Ctx, reqBody any) error {
+	if err := ctx.BodyParser(reqBody); err != nil {
+		return &api.ProblemError{
+			Status: fiber.StatusBadRequest,
+			Code:   api.CodeMalformedBody,
+			Detail: "invalid request body",
+			Err:    err,
+		}
+	}
+	return nil
+}
+
+// invalidRequest fails a request whose content the services rejected, like an amount which is not a decimal.
+// The problem errors, like the ones of parseBody, are returned as is.
+func invalidRequest(err error) error {
+	var problemErr *api.ProblemError
+	if errors.As(err, &problemErr) {
+		return err
+	}
+	return &api.ProblemError{
+		Status: fiber.StatusBadRequest,
+		Code:   api.CodeInvalidRequest,
+		Detail: err.Error(),
+		Err:    err,
+	}
+}
+
+// invalidParameter fails a request with an invalid query or path parameter
+func invalidParameter(name, message string) error {
+	return &api.ProblemError{
+		Status: fiber.StatusBadRequest,
+		Code:   api.CodeInvalidParameter,
+		Detail: fmt.Sprintf("invalid %v parameter", name),
+		Errors: []api.FieldError{{Field: name, Code: string(api.CodeInvalidParameter), Message: message}},
+	}
+}
+
+// paramID parses the id path parameter of the resource routes
+func paramID(ctx *fiber.Ctx) (uuid.UUID, error) {
+	id, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return uuid.Nil, invalidParameter("id", "must be a UUID")
+	}
+	return id, nil
+}
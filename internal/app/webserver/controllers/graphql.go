@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"log/slog"
+	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/app/webserver/graphqlapi"
+	"teya_home_assignment/internal/pkg/openapi"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GraphQLController serves the GraphQL API, answering the balance, the transactions and their totals by category in
+// a single request
+type GraphQLController struct {
+	server *graphqlapi.Server
+}
+
+func NewGraphQLController(server *graphqlapi.Server) *GraphQLController {
+	return &GraphQLController{server: server}
+}
+
+func (c *GraphQLController) RegisterRoutes(router fiber.Router) error {
+	router.Post(GraphQLRoute, c.execute)
+	return nil
+}
+
+func (c *GraphQLController) Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Method: fiber.MethodPost,
+			Path:   GraphQLRoute,
+			Tag:    "GraphQL",
+			Summary: "Execute a GraphQL query or mutation over the account, its transactions and balance. " +
+				"The schema is answered by introspection.",
+			Request: openapi.JSON(api.GraphQLReqBody{}),
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK: {
+					Description: "The result of the query, along with the errors it failed with if any",
+					Content:     openapi.JSON(api.GraphQLRespBody{}),
+				},
+				fiber.StatusBadRequest: errorResponse("Invalid request body"),
+			},
+		},
+	}
+}
+
+// execute answers the results with the 200 status, even those of the invalid queries or of the failed fields, as
+// required by the GraphQL over HTTP specification for the application/json media type
+func (c *GraphQLController) execute(ctx *fiber.Ctx) error {
+	reqBody := api.GraphQLReqBody{}
+	if err := parseBody(ctx, &reqBody); err != nil {
+		return err
+	}
+	result := c.server.Execute(ctx.UserContext(), reqBody.Query, reqBody.OperationName, reqBody.Variables)
+	slog.DebugContext(ctx.UserContext(), "executed GraphQL query",
+		"operation", reqBody.OperationName, "errors", len(result.Errors))
+	return ctx.Status(fiber.StatusOK).JSON(result)
+}
@@ -1,18 +1,76 @@
 package controllers
 
 import (
+	"log/slog"
+	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/pkg/health"
+	"teya_home_assignment/internal/pkg/openapi"
+
 	"github.com/gofiber/fiber/v2"
 )
 
-type HealthController struct{}
+type HealthController struct {
+	checker *health.Checker
+}
 
-func NewHealthController() *HealthController {
-	return &HealthController{}
+func NewHealthController(checker *health.Checker) *HealthController {
+	return &HealthController{checker: checker}
 }
 
 func (c *HealthController) RegisterRoutes(router fiber.Router) error {
-	router.Get(HealthRoute, func(c *fiber.Ctx) error {
-		return c.SendStatus(200)
-	})
+	// HealthRoute predates the probes, it is kept as the liveness probe
+	router.Get(HealthRoute, c.live)
+	router.Get(LivenessRoute, c.live)
+	router.Get(ReadinessRoute, c.ready)
 	return nil
 }
+
+func (c *HealthController) Operations() []openapi.Operation {
+	liveResponses := map[int]openapi.Response{
+		fiber.StatusOK: {Description: "The process is live", Content: openapi.JSON(api.HealthRespBody{})},
+	}
+	return []openapi.Operation{
+		{
+			Method:    fiber.MethodGet,
+			Path:      HealthRoute,
+			Tag:       "Health",
+			Summary:   "Liveness probe, kept for compatibility",
+			Responses: liveResponses,
+		},
+		{
+			Method:    fiber.MethodGet,
+			Path:      LivenessRoute,
+			Tag:       "Health",
+			Summary:   "Liveness probe",
+			Responses: liveResponses,
+		},
+		{
+			Method:  fiber.MethodGet,
+			Path:    ReadinessRoute,
+			Tag:     "Health",
+			Summary: "Readiness probe, with the status of the components",
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK: {Description: "Ready to serve requests", Content: openapi.JSON(api.HealthRespBody{})},
+				fiber.StatusServiceUnavailable: {
+					Description: "A component is down",
+					Content:     openapi.JSON(api.HealthRespBody{}),
+				},
+			},
+		},
+	}
+}
+
+// live tells the process is responsive. It does not check the components, restarting the process would not fix them.
+func (c *HealthController) live(ctx *fiber.Ctx) error {
+	return ctx.Status(fiber.StatusOK).JSON(api.HealthRespBody{Status: string(health.StatusUp)})
+}
+
+// ready tells whether the webserver can serve requests, with the status of the components it depends on
+func (c *HealthController) ready(ctx *fiber.Ctx) error {
+	report := c.checker.Check()
+	if report.Status != health.StatusUp {
+		slog.WarnContext(ctx.UserContext(), "webserver is not ready", "components", report.Components)
+		return ctx.Status(fiber.StatusServiceUnavailable).JSON(api.FromHealthReport(report))
+	}
+	return ctx.Status(fiber.StatusOK).JSON(api.FromHealthReport(report))
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"log/slog"
+	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/pkg/interest"
+	"teya_home_assignment/internal/pkg/openapi"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type InterestController struct {
+	interestService *interest.Engine
+}
+
+func NewInterestController(interestService *interest.Engine) *InterestController {
+	return &InterestController{interestService: interestService}
+}
+
+func (c *InterestController) RegisterRoutes(router fiber.Router) error {
+	router.Get(InterestAccrualRoute, c.getAccrual)
+	return nil
+}
+
+func (c *InterestController) Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Method:  fiber.MethodGet,
+			Path:    InterestAccrualRoute,
+			Tag:     "Interest",
+			Summary: "Get the interest accrued over a period, with the interest transaction if posted",
+			Query:   periodQuery,
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK:         {Content: openapi.JSON(api.InterestAccrualRespBody{})},
+				fiber.StatusBadRequest: errorResponse("Invalid period"),
+			},
+		},
+	}
+}
+
+// getAccrual recomputes the interest accrued within a past period from the ledger history,
+// along with the interest transaction posted for that exact period if any
+func (c *InterestController) getAccrual(ctx *fiber.Ctx) error {
+	from, to, err := parsePeriod(ctx)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	accrual, err := c.interestService.Accrue(from, to)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	resp := api.FromAccrualModel(accrual)
+	if posted, exists := c.interestService.GetPosted(accrual.From, accrual.To); exists {
+		transaction := api.FromTransactionModel(posted)
+		resp.Posted = &transaction
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully accrued interest",
+		"from", accrual.From, "to", accrual.To, "amount", accrual.Total)
+	return ctx.Status(fiber.StatusOK).JSON(resp)
+}
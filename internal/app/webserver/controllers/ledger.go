@@ -2,26 +2,38 @@ package controllers
 
 import (
 	"fmt"
+	"log/slog"
+	"math"
+	"net/url"
+	"regexp"
 	"strconv"
+	"strings"
 	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/app/webserver/config"
+	"teya_home_assignment/internal/pkg/categories"
 	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/openapi"
+	"teya_home_assignment/internal/pkg/rules"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/pkg/errors"
-	"github.com/shopspring/decimal"
 )
 
+// versionETagPattern matches the entity tags of the versions of the ledger, the version being quoted
+var versionETagPattern = regexp.MustCompile(`^"([0-9]{1,20})"$`)
+
 type LedgerController struct {
-	ledgerService *ledger.Ledger
+	ledgerService     *ledger.Ledger
+	categoriesService *categories.Categorizer
+	pagination        config.Pagination
 }
 
-func NewLedgerController() (*LedgerController, error) {
-	service, err := ledger.NewLedger()
-	if err != nil {
-		return nil, errors.Wrap(err, "could not create ledger controller")
-	}
-	return &LedgerController{ledgerService: service}, nil
+func NewLedgerController(
+	ledgerService *ledger.Ledger,
+	categoriesService *categories.Categorizer,
+	pagination config.Pagination,
+) *LedgerController {
+	return &LedgerController{ledgerService: ledgerService, categoriesService: categoriesService, pagination: pagination}
 }
 
 func (c *LedgerController) RegisterRoutes(router fiber.Router) error {
@@ -31,85 +43,247 @@ func (c *LedgerController) RegisterRoutes(router fiber.Router) error {
 	return nil
 }
 
+func (c *LedgerController) Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Method:  fiber.MethodPost,
+			Path:    TransactionRoute,
+			Tag:     "Ledger",
+			Summary: "Post a transaction, a positive amount credits the account and a negative one debits it",
+			Headers: []openapi.Parameter{
+				{
+					Name: api.IdempotencyKeyHeader,
+					Description: "Makes the request safe to retry: the transaction posted with the key is answered " +
+						"instead of posting it again, with the Idempotent-Replayed header set",
+				},
+				{
+					Name: fiber.HeaderIfMatch,
+					Description: "Posts the transaction only if the ledger is still at the version of the ETag " +
+						"answered by the account, so it is not posted against a balance which has changed since",
+				},
+			},
+			Request: openapi.JSON(api.NewTransactionReqBody{}),
+			Responses: map[int]openapi.Response{
+				fiber.StatusCreated: {
+					Description: "The transaction was posted with its fees, the ETag header tells the version of " +
+						"the ledger it moved on to",
+					Content: openapi.JSON(api.NewTransactionRespBody{}),
+				},
+				fiber.StatusAccepted: {
+					Description: "The transaction is held for review",
+					Content:     openapi.JSON(api.Review{}),
+				},
+				fiber.StatusBadRequest: errorResponse("Invalid request body, idempotency key or If-Match header"),
+				fiber.StatusPreconditionFailed: errorResponse("The ledger moved on from the version of the " +
+					"If-Match header"),
+				fiber.StatusUnprocessableEntity: errorResponse("The transaction exceeds a limit or violates a rule, " +
+					"or the idempotency key was used for another transaction"),
+				fiber.StatusInternalServerError: errorResponse("The transaction could not be posted"),
+			},
+		},
+		{
+			Method:  fiber.MethodGet,
+			Path:    TransactionRoute,
+			Tag:     "Ledger",
+			Summary: "List the transactions in the order they were posted",
+			Query: []openapi.Parameter{
+				{Name: "offset", Required: true, Schema: intRange(0, math.MaxInt32)},
+				{
+					Name:        "limit",
+					Description: fmt.Sprintf("Defaults to %d", c.pagination.DefaultLimit),
+					Schema:      intRange(1, c.pagination.MaxLimit),
+				},
+				{Name: "category", Description: "Lists the transactions of the category only"},
+			},
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK: {
+					Description: "A page of the transactions, the Link header links to the next and previous pages",
+					Content:     openapi.JSON(api.PaginatedTransactionsResponse{}),
+				},
+				fiber.StatusBadRequest:          errorResponse("Invalid pagination"),
+				fiber.StatusInternalServerError: errorResponse("The transactions could not be listed"),
+			},
+		},
+		{
+			Method:  fiber.MethodGet,
+			Path:    AccountRoute,
+			Tag:     "Ledger",
+			Summary: "Get the balance of the account",
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK: {
+					Description: "The balance, the ETag header tells the version of the ledger it was " +
+						"calculated at",
+					Content: openapi.JSON(api.GetBalanceRespBody{}),
+				},
+				fiber.StatusInternalServerError: errorResponse("The balance could not be calculated"),
+			},
+		},
+	}
+}
+
 func (c *LedgerController) createTransaction(ctx *fiber.Ctx) error {
 	reqBody := api.NewTransactionReqBody{}
-	if err := ctx.BodyParser(&reqBody); err != nil {
-		fmt.Println("invalid request body on transaction create")
-		return ctx.Status(fiber.StatusBadRequest).SendString("invalid request body")
+	if err := decodeBody(ctx, &reqBody); err != nil {
+		return err
+	}
+	// fiber reuses the request buffers, the key is kept by the ledger
+	transaction, err := api.ParseNewTransaction(reqBody, strings.Clone(ctx.Get(api.IdempotencyKeyHeader)))
+	if err != nil {
+		return err
+	}
+	opts := transaction.Options
+	if ifMatch := ctx.Get(fiber.HeaderIfMatch); ifMatch != "" && ifMatch != "*" {
+		version, err := parseVersionETag(ifMatch)
+		if err != nil {
+			return invalidParameter(fiber.HeaderIfMatch, "must be the ETag answered by the account")
+		}
+		opts = append(opts, ledger.WithExpectedVersion(version))
+	}
+	posted, err := c.ledgerService.PostTransactionContext(ctx.UserContext(), transaction.Amount, opts...)
+	var reviewErr *rules.ReviewRequiredError
+	if errors.As(err, &reviewErr) {
+		slog.InfoContext(ctx.UserContext(), "transaction held", "error", err)
+		return ctx.Status(fiber.StatusAccepted).JSON(api.FromReviewModel(reviewErr.Review))
 	}
-	if err := validator.New().Struct(reqBody); err != nil {
-		fmt.Printf("invalid request on transaction create: %v\n", err)
-		return ctx.Status(fiber.StatusBadRequest).SendString(err.Error())
+	if errors.Is(err, ledger.ErrDuplicateTransaction) && transaction.IdempotencyKey != "" {
+		return c.replayTransaction(ctx, transaction, reqBody)
 	}
-	transactionAmount, err := decimal.NewFromString(reqBody.Amount)
 	if err != nil {
-		fmt.Printf("invalid request on transaction create: %v\n", err)
-		return ctx.Status(fiber.StatusBadRequest).SendString("invalid transaction amount")
+		return errors.Wrap(err, "could not add transaction")
 	}
-	if err := c.ledgerService.AddTransaction(transactionAmount); err != nil {
-		fmt.Printf("failed to add transaction: %v\n", err)
-		return ctx.Status(fiber.StatusInternalServerError).SendString("could not add transaction")
+	slog.InfoContext(ctx.UserContext(), "successfully added transaction",
+		"id", posted[0].ExternalID, "amount", transaction.Amount, "fees", len(posted)-1)
+	ctx.Set(fiber.HeaderETag, versionETag(posted[len(posted)-1].ID))
+	return ctx.Status(fiber.StatusCreated).JSON(c.postedResponse(posted))
+}
+
+// replayTransaction answers a transaction submitted again with the idempotency key of a posted one with the
+// response it was posted with, provided it is the same transaction
+func (c *LedgerController) replayTransaction(
+	ctx *fiber.Ctx,
+	transaction api.NewTransaction,
+	reqBody api.NewTransactionReqBody,
+) error {
+	posted, exists := c.ledgerService.GetPostedByIdempotencyKey(transaction.IdempotencyKey)
+	if !exists {
+		return errors.Errorf("could not find transaction of idempotency key %v", transaction.IdempotencyKey)
 	}
-	fmt.Printf("successfully add transaction: %v\n", transactionAmount)
-	return ctx.SendStatus(fiber.StatusCreated)
+	if err := api.CheckReplay(posted[0], transaction, reqBody); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx.UserContext(), "replayed transaction", "id", posted[0].ExternalID)
+	ctx.Set(api.IdempotentReplayedHeader, "true")
+	return ctx.Status(fiber.StatusCreated).JSON(c.postedResponse(posted))
+}
+
+// postedResponse returns the posted transaction and its fees, along with their category
+func (c *LedgerController) postedResponse(posted []ledger.Transaction) api.NewTransactionRespBody {
+	resp := api.FromPostedTransactionsModel(posted)
+	resp.Transaction.Category = c.categoriesService.Categorize(posted[0]).Name
+	for i := range resp.Fees {
+		resp.Fees[i].Category = c.categoriesService.Categorize(posted[i+1]).Name
+	}
+	return resp
 }
 
 func (c *LedgerController) getAllTransaction(ctx *fiber.Ctx) error {
 	// Default limit. It is optional
-	limit := 10
+	limit := c.pagination.DefaultLimit
 
 	offsetParam := ctx.Query("offset")
 	if offsetParam == "" {
-		fmt.Println("invalid request on transaction getAllTransaction - missing offset parameter")
-		return ctx.Status(fiber.StatusBadRequest).SendString("missing offset query parameter")
+		return invalidParameter("offset", "is required")
 	}
 	offset, err := strconv.Atoi(offsetParam)
 	if err != nil || offset < 0 {
-		fmt.Printf("invalid request on transaction getAllTransaction - invalid offset parameter: %v(error: %v)\n",
-			offsetParam, err)
-		return ctx.Status(fiber.StatusBadRequest).SendString("invalid offset parameter")
+		return invalidParameter("offset", "must be an integer greater than or equal to 0")
 	}
 
 	if limitParam := ctx.Query("limit"); limitParam != "" {
 		limit, err = strconv.Atoi(limitParam)
-		if err != nil || limit <= 0 || limit > 100 {
-			fmt.Printf("invalid request on transaction getAllTransaction - invalid limit parameter: %v(error: %v)\n",
-				limitParam, err)
-			return ctx.Status(fiber.StatusBadRequest).SendString("invalid limit query parameter: must be between 1 and 100")
+		if err != nil || limit <= 0 || limit > c.pagination.MaxLimit {
+			return invalidParameter("limit", fmt.Sprintf("must be between 1 and %v", c.pagination.MaxLimit))
 		}
 	}
-	transactionsHistory, err := c.ledgerService.GetTransactionHistory(offset, limit)
+	var page ledger.Page
+	if category := ctx.Query("category"); category != "" {
+		page, err = c.ledgerService.FindTransactionPage(offset, limit, func(transaction ledger.Transaction) bool {
+			return c.categoriesService.Categorize(transaction).Name == category
+		})
+	} else {
+		page, err = c.ledgerService.GetTransactionPage(offset, limit)
+	}
 	if err != nil {
-		fmt.Printf("failed to get transaction history: %v\n", err)
-		return ctx.Status(fiber.StatusInternalServerError).SendString("could not get transactions")
+		return errors.Wrap(err, "could not get transactions")
 	}
 
-	transactions := make([]api.Transaction, len(transactionsHistory))
-	for i, transaction := range transactionsHistory {
+	transactions := make([]api.Transaction, len(page.Transactions))
+	for i, transaction := range page.Transactions {
 		transactions[i] = api.FromTransactionModel(transaction)
+		transactions[i].Category = c.categoriesService.Categorize(transaction).Name
 	}
 
 	response := api.PaginatedTransactionsResponse{
 		Transactions: transactions,
 		Pagination: api.Pagination{
-			Offset: offset,
-			Limit:  limit,
+			Offset:  offset,
+			Limit:   limit,
+			Total:   page.Total,
+			HasMore: page.HasMore(),
 		},
 	}
+	var links []string
+	if page.HasMore() {
+		response.Pagination.Next = pageURL(ctx, offset+len(page.Transactions), limit)
+		links = append(links, response.Pagination.Next, "next")
+	}
+	if offset > 0 {
+		response.Pagination.Prev = pageURL(ctx, max(offset-limit, 0), limit)
+		links = append(links, response.Pagination.Prev, "prev")
+	}
+	ctx.Links(links...)
 
-	fmt.Printf("successfully returned transactions: %v\n", transactions)
+	slog.DebugContext(ctx.UserContext(), "successfully returned transactions", "count", len(transactions))
 	return ctx.Status(fiber.StatusOK).JSON(response)
 }
 
 func (c *LedgerController) getBalance(ctx *fiber.Ctx) error {
-	balance, err := c.ledgerService.GetBalance()
+	balance, version, err := c.ledgerService.GetBalanceAndVersion(ctx.UserContext())
 	if err != nil {
-		fmt.Printf("failed to get balance: %v\n", err)
-		return ctx.Status(fiber.StatusInternalServerError).SendString("could not get balance")
+		return errors.Wrap(err, "could not get balance")
 	}
 	resp := api.GetBalanceRespBody{
 		Balance: balance.String(),
 	}
-	fmt.Printf("successfully calculated balance: %v\n", balance)
+	slog.DebugContext(ctx.UserContext(), "successfully calculated balance", "balance", balance, "version", version)
+	ctx.Set(fiber.HeaderETag, versionETag(version))
 	return ctx.Status(fiber.StatusOK).JSON(resp)
 }
+
+// pageURL returns the URL of the page of the listing requested at the given offset, keeping the other parameters.
+// The URL is relative to the host, so it holds behind a proxy.
+func pageURL(ctx *fiber.Ctx, offset, limit int) string {
+	query := url.Values{}
+	for key, value := range ctx.Queries() {
+		query.Set(key, value)
+	}
+	query.Set("offset", strconv.Itoa(offset))
+	query.Set("limit", strconv.Itoa(limit))
+	return ctx.Path() + "?" + query.Encode()
+}
+
+// versionETag returns the entity tag of a version of the ledger, which is strong as the balance at a version never
+// changes
+func versionETag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// parseVersionETag returns the version of the ledger of an entity tag. Weak tags and lists of tags are rejected, a
+// transaction is posted against a single version.
+func parseVersionETag(etag string) (uint64, error) {
+	match := versionETagPattern.FindStringSubmatch(etag)
+	if match == nil {
+		return 0, errors.Errorf("invalid entity tag %q", etag)
+	}
+	return strconv.ParseUint(match[1], 10, 64)
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"log/slog"
+	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/pkg/limits"
+	"teya_home_assignment/internal/pkg/openapi"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type LimitsController struct {
+	limitsService *limits.Limiter
+}
+
+func NewLimitsController(limitsService *limits.Limiter) *LimitsController {
+	return &LimitsController{limitsService: limitsService}
+}
+
+func (c *LimitsController) RegisterRoutes(router fiber.Router) error {
+	router.Get(LimitsRoute, c.getLimits)
+	router.Put(LimitsRoute, c.setLimits)
+	return nil
+}
+
+func (c *LimitsController) Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Method:  fiber.MethodGet,
+			Path:    LimitsRoute,
+			Tag:     "Limits",
+			Summary: "Get the transaction limits",
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK: {Content: openapi.JSON(api.LimitsBody{})},
+			},
+		},
+		{
+			Method:      fiber.MethodPut,
+			Path:        LimitsRoute,
+			Tag:         "Limits",
+			Summary:     "Set the transaction limits",
+			Description: "An empty or zero limit disables it",
+			Request:     openapi.JSON(api.LimitsBody{}),
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK:         {Content: openapi.JSON(api.LimitsBody{})},
+				fiber.StatusBadRequest: errorResponse("Invalid limits"),
+			},
+		},
+	}
+}
+
+func (c *LimitsController) getLimits(ctx *fiber.Ctx) error {
+	return ctx.Status(fiber.StatusOK).JSON(api.FromLimitsModel(c.limitsService.GetRules()))
+}
+
+func (c *LimitsController) setLimits(ctx *fiber.Ctx) error {
+	reqBody := api.LimitsBody{}
+	if err := parseBody(ctx, &reqBody); err != nil {
+		return err
+	}
+	rules, err := reqBody.ToModel()
+	if err != nil {
+		return invalidRequest(err)
+	}
+	if err := c.limitsService.SetRules(rules); err != nil {
+		return invalidRequest(err)
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully set limits", "limits", reqBody)
+	return ctx.Status(fiber.StatusOK).JSON(api.FromLimitsModel(rules))
+}
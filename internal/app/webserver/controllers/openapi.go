@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"log/slog"
+	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/pkg/openapi"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	OpenAPIRoute = "/openapi.json"
+	DocsRoute    = "/docs"
+)
+
+var apiInfo = openapi.Info{
+	Title:       "Ledger API",
+	Description: "Single account ledger of " + LedgerCurrency + " transactions",
+	Version:     "1.0.0",
+}
+
+// docsPage renders the OpenAPI document served next to it with Swagger UI
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Ledger API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({url: "openapi.json", dom_id: "#swagger-ui"});
+    };
+  </script>
+</body>
+</html>
+`
+
+// OpenAPIController serves the OpenAPI document of the routes registered by the controllers, built once on creation
+type OpenAPIController struct {
+	document *openapi.Document
+}
+
+// NewOpenAPIController documents the operations of the controllers and its own
+func NewOpenAPIController(controllers []Controller) (*OpenAPIController, error) {
+	c := &OpenAPIController{}
+	var operations []openapi.Operation
+	for _, controller := range append(controllers, c) {
+		operations = append(operations, controller.Operations()...)
+	}
+	document, err := openapi.Build(apiInfo, []openapi.Server{{URL: APIRouteBasePath}}, operations)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build OpenAPI document")
+	}
+	c.document = document
+	return c, nil
+}
+
+func (c *OpenAPIController) RegisterRoutes(router fiber.Router) error {
+	router.Get(OpenAPIRoute, c.getDocument)
+	router.Get(DocsRoute, c.getDocsPage)
+	return nil
+}
+
+func (c *OpenAPIController) Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Method:  fiber.MethodGet,
+			Path:    OpenAPIRoute,
+			Tag:     "Documentation",
+			Summary: "Get the OpenAPI document of the API",
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK: {Content: openapi.JSON(map[string]any{})},
+			},
+		},
+		{
+			Method:  fiber.MethodGet,
+			Path:    DocsRoute,
+			Tag:     "Documentation",
+			Summary: "Browse the API documentation",
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK: {Content: openapi.Text(fiber.MIMETextHTML)},
+			},
+		},
+	}
+}
+
+func (c *OpenAPIController) getDocument(ctx *fiber.Ctx) error {
+	return ctx.Status(fiber.StatusOK).JSON(c.document)
+}
+
+func (c *OpenAPIController) getDocsPage(ctx *fiber.Ctx) error {
+	slog.DebugContext(ctx.UserContext(), "serving API documentation page")
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return ctx.Status(fiber.StatusOK).SendString(docsPage)
+}
+
+// errorResponse documents the problem details the requests fail with, see ErrorHandler
+func errorResponse(description string) openapi.Response {
+	return openapi.Response{
+		Description: description,
+		Content:     &openapi.Content{Schema: api.Problem{}, Types: []string{api.MIMEApplicationProblemJSON}},
+	}
+}
+
+// periodQuery documents the from/to query parameters read by parsePeriod
+var periodQuery = []openapi.Parameter{
+	{
+		Name:        "from",
+		Description: "Start of the period, a date (YYYY-MM-DD) or an RFC 3339 timestamp",
+		Required:    true,
+	},
+	{
+		Name:        "to",
+		Description: "End of the period, a date (YYYY-MM-DD) included in the period or an RFC 3339 timestamp",
+		Required:    true,
+	},
+}
+
+func intRange(min, max int) *openapi.Schema {
+	return &openapi.Schema{Type: "integer", Minimum: &min, Maximum: &max}
+}
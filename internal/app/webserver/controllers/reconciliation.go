@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"bytes"
+	"log/slog"
+	"strconv"
+	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/openapi"
+	"teya_home_assignment/internal/pkg/reconciliation"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+type ReconciliationController struct {
+	ledgerService *ledger.Ledger
+}
+
+func NewReconciliationController(ledgerService *ledger.Ledger) *ReconciliationController {
+	return &ReconciliationController{ledgerService: ledgerService}
+}
+
+func (c *ReconciliationController) RegisterRoutes(router fiber.Router) error {
+	router.Post(ReconciliationRoute, c.reconcile)
+	return nil
+}
+
+func (c *ReconciliationController) Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Method:  fiber.MethodPost,
+			Path:    ReconciliationRoute,
+			Tag:     "Reconciliation",
+			Summary: "Reconcile the ledger with a settlement CSV file",
+			Query: []openapi.Parameter{
+				{
+					Name:        "date_tolerance_days",
+					Description: "Days a settlement may be apart from the transaction it matches",
+					Schema:      &openapi.Schema{Type: "integer", Minimum: new(int)},
+				},
+			},
+			Request: openapi.Text("text/csv"),
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK:                  {Content: openapi.JSON(api.ReconciliationReportRespBody{})},
+				fiber.StatusBadRequest:          errorResponse("Invalid settlement file or date tolerance"),
+				fiber.StatusInternalServerError: errorResponse("The settlement file could not be reconciled"),
+			},
+		},
+	}
+}
+
+// reconcile expects the settlement CSV file as the raw request body
+func (c *ReconciliationController) reconcile(ctx *fiber.Ctx) error {
+	dateTolerance := reconciliation.DefaultDateTolerance
+	if toleranceParam := ctx.Query("date_tolerance_days"); toleranceParam != "" {
+		days, err := strconv.Atoi(toleranceParam)
+		if err != nil || days < 0 {
+			return invalidParameter("date_tolerance_days", "must be an integer greater than or equal to 0")
+		}
+		dateTolerance = time.Duration(days) * 24 * time.Hour
+	}
+
+	lines, err := reconciliation.ParseSettlementFile(bytes.NewReader(ctx.Body()))
+	if err != nil {
+		return invalidRequest(err)
+	}
+	report, err := reconciliation.Reconcile(c.ledgerService, lines, dateTolerance)
+	if err != nil {
+		return errors.Wrap(err, "could not reconcile settlement file")
+	}
+
+	slog.InfoContext(ctx.UserContext(), "successfully reconciled settlement file",
+		"matched", len(report.Matched),
+		"amount_mismatches", len(report.AmountMismatches),
+		"unmatched_in_ledger", len(report.UnmatchedInLedger),
+		"unmatched_in_file", len(report.UnmatchedInFile),
+	)
+	return ctx.Status(fiber.StatusOK).JSON(api.FromReconciliationReportModel(report))
+}
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"log/slog"
+	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/pkg/openapi"
+	"teya_home_assignment/internal/pkg/report"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReportController struct {
+	reportService *report.Rollup
+}
+
+func NewReportController(reportService *report.Rollup) *ReportController {
+	return &ReportController{reportService: reportService}
+}
+
+func (c *ReportController) RegisterRoutes(router fiber.Router) error {
+	router.Get(AggregateReportRoute, c.getAggregateReport)
+	return nil
+}
+
+func (c *ReportController) Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Method:  fiber.MethodGet,
+			Path:    AggregateReportRoute,
+			Tag:     "Reports",
+			Summary: "Aggregate the transactions by interval",
+			Query: []openapi.Parameter{
+				{
+					Name:        "interval",
+					Description: "Defaults to day",
+					Schema: &openapi.Schema{
+						Type: "string",
+						Enum: []string{string(report.Day), string(report.Week), string(report.Month)},
+					},
+				},
+				{Name: "tz", Description: "IANA time zone the intervals are bucketed in, UTC by default"},
+				{Name: "from", Description: "First day of the report (YYYY-MM-DD)", Required: true},
+				{Name: "to", Description: "Last day of the report (YYYY-MM-DD)", Required: true},
+			},
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK:         {Content: openapi.JSON(api.AggregateReportRespBody{})},
+				fiber.StatusBadRequest: errorResponse("Invalid report parameters"),
+			},
+		},
+	}
+}
+
+// getAggregateReport aggregates the transactions by day, week or month. The period is given as dates,
+// both inclusive, in the requested time zone.
+func (c *ReportController) getAggregateReport(ctx *fiber.Ctx) error {
+	interval := report.Interval(ctx.Query("interval", string(report.Day)))
+	location, err := time.LoadLocation(ctx.Query("tz", "UTC"))
+	if err != nil {
+		return invalidParameter("tz", "must be an IANA time zone")
+	}
+	from, err := time.ParseInLocation(periodDateFormat, ctx.Query("from"), location)
+	if err != nil {
+		return invalidParameter("from", "must be a date formatted as YYYY-MM-DD")
+	}
+	to, err := time.ParseInLocation(periodDateFormat, ctx.Query("to"), location)
+	if err != nil {
+		return invalidParameter("to", "must be a date formatted as YYYY-MM-DD")
+	}
+	// to is inclusive
+	to = time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, location)
+	buckets, err := c.reportService.Aggregate(from, to, interval, location)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	slog.DebugContext(ctx.UserContext(), "successfully aggregated report", "interval", interval, "count", len(buckets))
+	return ctx.Status(fiber.StatusOK).JSON(api.FromBucketsModel(interval, location, from, to, buckets))
+}
@@ -1,38 +1,222 @@
 package controllers
 
 import (
-	"fmt"
+	"log/slog"
+	"teya_home_assignment/internal/app/webserver/config"
+	"teya_home_assignment/internal/app/webserver/graphqlapi"
+	"teya_home_assignment/internal/pkg/amounts"
+	"teya_home_assignment/internal/pkg/categories"
+	"teya_home_assignment/internal/pkg/feed"
+	"teya_home_assignment/internal/pkg/fees"
+	"teya_home_assignment/internal/pkg/health"
+	"teya_home_assignment/internal/pkg/interest"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/limits"
+	"teya_home_assignment/internal/pkg/metrics"
+	"teya_home_assignment/internal/pkg/openapi"
+	"teya_home_assignment/internal/pkg/report"
+	"teya_home_assignment/internal/pkg/rules"
+	"teya_home_assignment/internal/pkg/scheduler"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
 )
 
 const (
 	APIRouteBasePath = "/api/v1"
 
-	TransactionRoute = "/transaction"
-	AccountRoute     = "/account"
+	TransactionRoute         = "/transaction"
+	AccountRoute             = "/account"
+	StatementRoute           = "/account/statement"
+	StatementExportRoute     = "/account/statement/export"
+	ReconciliationRoute      = "/reconciliation"
+	ScheduleRoute            = "/schedule"
+	InterestAccrualRoute     = "/interest/accrual"
+	LimitsRoute              = "/admin/limits"
+	RulesRoute               = "/rules"
+	ReviewRoute              = "/review"
+	CategoryRuleRoute        = "/category/rule"
+	AggregateReportRoute     = "/report/aggregate"
+	TransactionCategoryRoute = "/transaction/:id/category"
+	GraphQLRoute             = "/graphql"
 
-	HealthRoute = "/health"
+	HealthRoute    = "/health"
+	LivenessRoute  = "/health/live"
+	ReadinessRoute = "/health/ready"
+
+	// LedgerCurrency is the currency of the single account served by the ledger
+	LedgerCurrency = "EUR"
+
+	// feedBuffer is the number of posted entries a watcher of the ledger may lag behind before being dropped
+	feedBuffer = 256
 )
 
+// maxAmountMagnitude is the largest amount of a single transaction, credited or debited
+var maxAmountMagnitude = decimal.RequireFromString("1000000000")
+
 type Controller interface {
 	RegisterRoutes(router fiber.Router) error
+	// Operations documents the routes registered by the controller in the OpenAPI document
+	Operations() []openapi.Operation
+}
+
+// Worker is a service running in the background, started once the ledger was restored and stopped when the
+// webserver shuts down
+type Worker interface {
+	Start()
+	Stop()
+	Running() bool
+}
+
+// Restorer is a worker whose state is restored once the ledger was, before it is started
+type Restorer interface {
+	Restore() error
+}
+
+// Store persists the ledger and the schedules, so they survive restarts
+type Store interface {
+	ledger.Store
+	scheduler.Store
+}
+
+// Services are the services behind the controllers whose lifecycle is managed by the webserver
+type Services struct {
+	Ledger     *ledger.Ledger
+	Categories *categories.Categorizer
+	// Feed broadcasts the entries posted to the ledger, it is closed when the webserver shuts down
+	Feed *feed.Feed
+	// Workers are the background workers by name
+	Workers map[string]Worker
+}
+
+// Restore restores the ledger from the storage, then the workers whose state depends on it
+func (s Services) Restore() error {
+	if err := s.Ledger.Restore(); err != nil {
+		return err
+	}
+	for name, worker := range s.Workers {
+		if restorer, ok := worker.(Restorer); ok {
+			if err := restorer.Restore(); err != nil {
+				return errors.Wrapf(err, "failed to restore %v", name)
+			}
+		}
+	}
+	return nil
 }
 
-func InitControllers() (controllers []Controller, err error) {
-	fmt.Println("initializing controllers")
-	controllers = append(controllers, NewHealthController())
-	ledgerController, err := NewLedgerController()
+// InitControllers creates the services and their controllers. The ledger and the schedules are persisted in the
+// store if any, or kept in memory only otherwise. The services are neither restored nor are the workers started.
+func InitControllers(
+	cfg config.Config,
+	metricsService *metrics.Metrics,
+	store Store,
+	checker *health.Checker,
+) (controllers []Controller, services Services, err error) {
+	slog.Info("initializing controllers")
+	controllers = append(controllers, NewHealthController(checker))
+	feeSchedules, err := cfg.Fees.Parse()
+	if err != nil {
+		return nil, Services{}, errors.Wrap(err, "failed to init fee engine")
+	}
+	for _, schedule := range feeSchedules {
+		if schedule.Currency != LedgerCurrency {
+			return nil, Services{}, errors.Errorf("fee schedule %v is in %v, the ledger is in %v",
+				schedule.Name, schedule.Currency, LedgerCurrency)
+		}
+	}
+	feeService, err := fees.NewEngine(LedgerCurrency, feeSchedules...)
+	if err != nil {
+		return nil, Services{}, errors.Wrap(err, "failed to init fee engine")
+	}
+	// limits start disabled until configured through the admin API
+	limitsService, err := limits.NewLimiter(limits.Rules{})
+	if err != nil {
+		return nil, Services{}, errors.Wrap(err, "failed to init limiter")
+	}
+	amountPolicy, err := amounts.NewPolicy(LedgerCurrency, maxAmountMagnitude)
+	if err != nil {
+		return nil, Services{}, errors.Wrap(err, "failed to init amount policy")
+	}
+	rulesService := rules.NewEngine()
+	reportService := report.NewRollup()
+	feedService := feed.NewFeed(feedBuffer)
+	// limits are checked first so transactions over a limit are never held for review
+	ledgerOpts := []ledger.Option{
+		ledger.WithAmountPolicy(amountPolicy.Normalize),
+		ledger.WithPostingCheck(limitsService.Check),
+		ledger.WithPostingCheck(rulesService.Check),
+		ledger.WithPostingHook(feeService.PostingHook),
+		ledger.WithPostedObserver(limitsService.Observe),
+		ledger.WithPostedObserver(rulesService.Observe),
+		ledger.WithPostedObserver(reportService.Observe),
+		ledger.WithPostedObserver(metricsService.ObservePosted),
+		ledger.WithPostedObserver(feedService.Observe),
+		// the metrics and the feed are about the entries posted since the start, not the restored ones
+		ledger.WithRestoredObserver(limitsService.Observe),
+		ledger.WithRestoredObserver(rulesService.Observe),
+		ledger.WithRestoredObserver(reportService.Observe),
+		ledger.WithRejectedObserver(metricsService.ObserveRejected),
+	}
+	if store != nil {
+		ledgerOpts = append(ledgerOpts, ledger.WithStore(metricsService.InstrumentStore(store)))
+	}
+	ledgerService, err := ledger.NewLedger(ledgerOpts...)
+	if err != nil {
+		return nil, Services{}, errors.Wrap(err, "failed to init ledger")
+	}
+	metricsService.RegisterLedger(ledgerService)
+	controllers = append(controllers, NewLimitsController(limitsService))
+	controllers = append(controllers, NewRulesController(rulesService, ledgerService))
+	categoriesService := categories.NewCategorizer()
+	controllers = append(controllers, NewCategoriesController(categoriesService, ledgerService))
+	controllers = append(controllers, NewLedgerController(ledgerService, categoriesService, cfg.Pagination))
+	graphQLServer, err := graphqlapi.NewServer(ledgerService, categoriesService, cfg, LedgerCurrency)
+	if err != nil {
+		return nil, Services{}, errors.Wrap(err, "failed to init GraphQL server")
+	}
+	controllers = append(controllers, NewGraphQLController(graphQLServer))
+	controllers = append(controllers, NewStatementController(ledgerService))
+	controllers = append(controllers, NewReconciliationController(ledgerService))
+	controllers = append(controllers, NewReportController(reportService))
+
+	var schedulerOpts []scheduler.Option
+	if store != nil {
+		schedulerOpts = append(schedulerOpts, scheduler.WithStore(store))
+	}
+	schedulerService := scheduler.NewScheduler(ledgerService, schedulerOpts...)
+	controllers = append(controllers, NewScheduleController(schedulerService, amountPolicy.Normalize))
+
+	workers := map[string]Worker{"scheduler": schedulerService}
+	// interest is neither posted nor audited unless configured
+	if cfg.Interest.Enabled() {
+		interestConfig, err := cfg.Interest.Parse()
+		if err != nil {
+			return nil, Services{}, errors.Wrap(err, "failed to init interest engine")
+		}
+		interestService, err := interest.NewEngine(ledgerService, interestConfig)
+		if err != nil {
+			return nil, Services{}, errors.Wrap(err, "failed to init interest engine")
+		}
+		controllers = append(controllers, NewInterestController(interestService))
+		workers["interest_engine"] = interestService
+	}
+	openAPIController, err := NewOpenAPIController(controllers)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to init ledger controller")
+		return nil, Services{}, errors.Wrap(err, "failed to init OpenAPI controller")
+	}
+	controllers = append(controllers, openAPIController)
+	services = Services{
+		Ledger:     ledgerService,
+		Categories: categoriesService,
+		Feed:       feedService,
+		Workers:    workers,
 	}
-	controllers = append(controllers, ledgerController)
-	return controllers, nil
+	return controllers, services, nil
 }
 
 func SetupRoutes(router fiber.Router, controllers []Controller) error {
-	fmt.Println("setup API routes")
+	slog.Info("setup API routes")
 	for _, controller := range controllers {
 		if err := controller.RegisterRoutes(router); err != nil {
 			return errors.Wrap(err, "failed to register routes")
@@ -0,0 +1,327 @@
+package controllers
+
+import (
+	"log/slog"
+	"maps"
+	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/openapi"
+	"teya_home_assignment/internal/pkg/rules"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type RulesController struct {
+	rulesService  *rules.Engine
+	ledgerService *ledger.Ledger
+}
+
+func NewRulesController(rulesService *rules.Engine, ledgerService *ledger.Ledger) *RulesController {
+	return &RulesController{rulesService: rulesService, ledgerService: ledgerService}
+}
+
+func (c *RulesController) RegisterRoutes(router fiber.Router) error {
+	router.Post(RulesRoute, c.createRule)
+	router.Get(RulesRoute, c.getAllRules)
+	router.Post(RulesRoute+"/test", c.testRules)
+	router.Get(RulesRoute+"/flagged", c.getFlaggedTransactions)
+	router.Get(RulesRoute+"/:id", c.getRule)
+	router.Put(RulesRoute+"/:id", c.updateRule)
+	router.Delete(RulesRoute+"/:id", c.deleteRule)
+	router.Get(ReviewRoute, c.getAllReviews)
+	router.Get(ReviewRoute+"/:id", c.getReview)
+	router.Post(ReviewRoute+"/:id/approve", c.approveReview)
+	router.Post(ReviewRoute+"/:id/decline", c.declineReview)
+	return nil
+}
+
+func (c *RulesController) Operations() []openapi.Operation {
+	ruleResponses := func(status int, description string) map[int]openapi.Response {
+		return map[int]openapi.Response{
+			status:                 {Description: description, Content: openapi.JSON(api.Rule{})},
+			fiber.StatusBadRequest: errorResponse("Invalid rule"),
+			fiber.StatusNotFound:   errorResponse("Rule not found"),
+		}
+	}
+	reviewResponses := map[int]openapi.Response{
+		fiber.StatusOK:         {Content: openapi.JSON(api.Review{})},
+		fiber.StatusBadRequest: errorResponse("Invalid review id"),
+		fiber.StatusNotFound:   errorResponse("Review not found"),
+		fiber.StatusConflict:   errorResponse("The review is not pending anymore"),
+	}
+	approveResponses := maps.Clone(reviewResponses)
+	approveResponses[fiber.StatusUnprocessableEntity] = errorResponse("The reviewed transaction exceeds a limit")
+	return []openapi.Operation{
+		{
+			Method:  fiber.MethodPost,
+			Path:    RulesRoute,
+			Tag:     "Rules",
+			Summary: "Create a rule evaluated on each posted transaction",
+			Request: openapi.JSON(api.RuleReqBody{}),
+			Responses: map[int]openapi.Response{
+				fiber.StatusCreated:    {Description: "The rule was created", Content: openapi.JSON(api.Rule{})},
+				fiber.StatusBadRequest: errorResponse("Invalid rule"),
+			},
+		},
+		{
+			Method:  fiber.MethodGet,
+			Path:    RulesRoute,
+			Tag:     "Rules",
+			Summary: "List the rules",
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK: {Content: openapi.JSON(api.RulesRespBody{})},
+			},
+		},
+		{
+			Method:      fiber.MethodPost,
+			Path:        RulesRoute + "/test",
+			Tag:         "Rules",
+			Summary:     "Evaluate a sample transaction against a rule without posting it",
+			Description: "The registered rules are evaluated if no rule is given, by default on the current account",
+			Request:     openapi.JSON(api.RuleTestReqBody{}),
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK:         {Content: openapi.JSON(api.RuleTestRespBody{})},
+				fiber.StatusBadRequest: errorResponse("Invalid rule or transaction"),
+			},
+		},
+		{
+			Method:  fiber.MethodGet,
+			Path:    RulesRoute + "/flagged",
+			Tag:     "Rules",
+			Summary: "List the transactions flagged by the rules",
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK: {Content: openapi.JSON(api.FlaggedTransactionsRespBody{})},
+			},
+		},
+		{
+			Method:    fiber.MethodGet,
+			Path:      RulesRoute + "/:id",
+			Tag:       "Rules",
+			Summary:   "Get a rule",
+			Responses: ruleResponses(fiber.StatusOK, ""),
+		},
+		{
+			Method:    fiber.MethodPut,
+			Path:      RulesRoute + "/:id",
+			Tag:       "Rules",
+			Summary:   "Update a rule",
+			Request:   openapi.JSON(api.RuleReqBody{}),
+			Responses: ruleResponses(fiber.StatusOK, "The rule was updated"),
+		},
+		{
+			Method:  fiber.MethodDelete,
+			Path:    RulesRoute + "/:id",
+			Tag:     "Rules",
+			Summary: "Delete a rule",
+			Responses: map[int]openapi.Response{
+				fiber.StatusNoContent:  {Description: "The rule was deleted"},
+				fiber.StatusBadRequest: errorResponse("Invalid rule id"),
+				fiber.StatusNotFound:   errorResponse("Rule not found"),
+			},
+		},
+		{
+			Method:  fiber.MethodGet,
+			Path:    ReviewRoute,
+			Tag:     "Reviews",
+			Summary: "List the transactions held for review",
+			Query: []openapi.Parameter{
+				{
+					Name: "status",
+					Schema: &openapi.Schema{
+						Type: "string",
+						Enum: []string{
+							string(rules.ReviewPending), string(rules.ReviewApproved), string(rules.ReviewDeclined),
+						},
+					},
+				},
+			},
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK:         {Content: openapi.JSON(api.ReviewsRespBody{})},
+				fiber.StatusBadRequest: errorResponse("Invalid status"),
+			},
+		},
+		{
+			Method:  fiber.MethodGet,
+			Path:    ReviewRoute + "/:id",
+			Tag:     "Reviews",
+			Summary: "Get a review",
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK:         {Content: openapi.JSON(api.Review{})},
+				fiber.StatusBadRequest: errorResponse("Invalid review id"),
+				fiber.StatusNotFound:   errorResponse("Review not found"),
+			},
+		},
+		{
+			Method:    fiber.MethodPost,
+			Path:      ReviewRoute + "/:id/approve",
+			Tag:       "Reviews",
+			Summary:   "Approve a review, posting the transaction",
+			Responses: approveResponses,
+		},
+		{
+			Method:    fiber.MethodPost,
+			Path:      ReviewRoute + "/:id/decline",
+			Tag:       "Reviews",
+			Summary:   "Decline a review, the transaction is never posted",
+			Responses: reviewResponses,
+		},
+	}
+}
+
+func (c *RulesController) createRule(ctx *fiber.Ctx) error {
+	rule, err := parseRuleReqBody(ctx)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	rule, err = c.rulesService.Create(rule)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully created rule", "id", rule.ID)
+	return ctx.Status(fiber.StatusCreated).JSON(api.FromRuleModel(rule))
+}
+
+func (c *RulesController) getAllRules(ctx *fiber.Ctx) error {
+	ruleSet := c.rulesService.List()
+	resp := api.RulesRespBody{Rules: make([]api.Rule, len(ruleSet))}
+	for i, rule := range ruleSet {
+		resp.Rules[i] = api.FromRuleModel(rule)
+	}
+	slog.DebugContext(ctx.UserContext(), "successfully returned rules", "count", len(ruleSet))
+	return ctx.Status(fiber.StatusOK).JSON(resp)
+}
+
+func (c *RulesController) getRule(ctx *fiber.Ctx) error {
+	id, err := paramID(ctx)
+	if err != nil {
+		return err
+	}
+	rule, err := c.rulesService.Get(id)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(api.FromRuleModel(rule))
+}
+
+func (c *RulesController) updateRule(ctx *fiber.Ctx) error {
+	id, err := paramID(ctx)
+	if err != nil {
+		return err
+	}
+	rule, err := parseRuleReqBody(ctx)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	rule, err = c.rulesService.Update(id, rule)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully updated rule", "id", id)
+	return ctx.Status(fiber.StatusOK).JSON(api.FromRuleModel(rule))
+}
+
+func (c *RulesController) deleteRule(ctx *fiber.Ctx) error {
+	id, err := paramID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := c.rulesService.Delete(id); err != nil {
+		return invalidRequest(err)
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully deleted rule", "id", id)
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+func (c *RulesController) testRules(ctx *fiber.Ctx) error {
+	reqBody := api.RuleTestReqBody{}
+	if err := parseBody(ctx, &reqBody); err != nil {
+		return err
+	}
+	transaction, account, err := reqBody.ToModel(c.rulesService.GetAccount())
+	if err != nil {
+		return invalidRequest(err)
+	}
+	var candidate *rules.Rule
+	if reqBody.Rule != nil {
+		rule := reqBody.Rule.ToModel()
+		candidate = &rule
+	}
+	result, err := c.rulesService.Test(candidate, transaction, account)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully tested rules", "decision", result.Decision)
+	return ctx.Status(fiber.StatusOK).JSON(api.FromRuleResultModel(result))
+}
+
+func (c *RulesController) getFlaggedTransactions(ctx *fiber.Ctx) error {
+	flags := c.rulesService.GetFlags()
+	resp := api.FlaggedTransactionsRespBody{Flagged: make([]api.FlaggedTransaction, len(flags))}
+	for i, flag := range flags {
+		resp.Flagged[i] = api.FromFlagModel(flag)
+	}
+	slog.DebugContext(ctx.UserContext(), "successfully returned flagged transactions", "count", len(flags))
+	return ctx.Status(fiber.StatusOK).JSON(resp)
+}
+
+func (c *RulesController) getAllReviews(ctx *fiber.Ctx) error {
+	status := rules.ReviewStatus(ctx.Query("status"))
+	switch status {
+	case "", rules.ReviewPending, rules.ReviewApproved, rules.ReviewDeclined:
+	default:
+		return invalidParameter("status", "must be pending, approved or declined")
+	}
+	reviews := c.rulesService.ListReviews(status)
+	resp := api.ReviewsRespBody{Reviews: make([]api.Review, len(reviews))}
+	for i, review := range reviews {
+		resp.Reviews[i] = api.FromReviewModel(review)
+	}
+	slog.DebugContext(ctx.UserContext(), "successfully returned reviews", "count", len(reviews))
+	return ctx.Status(fiber.StatusOK).JSON(resp)
+}
+
+func (c *RulesController) getReview(ctx *fiber.Ctx) error {
+	id, err := paramID(ctx)
+	if err != nil {
+		return err
+	}
+	review, err := c.rulesService.GetReview(id)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(api.FromReviewModel(review))
+}
+
+func (c *RulesController) approveReview(ctx *fiber.Ctx) error {
+	id, err := paramID(ctx)
+	if err != nil {
+		return err
+	}
+	review, _, err := c.rulesService.Approve(c.ledgerService, id)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully approved review", "id", id, "transaction_id", review.Posted)
+	return ctx.Status(fiber.StatusOK).JSON(api.FromReviewModel(review))
+}
+
+func (c *RulesController) declineReview(ctx *fiber.Ctx) error {
+	id, err := paramID(ctx)
+	if err != nil {
+		return err
+	}
+	review, err := c.rulesService.Decline(id)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully declined review", "id", id)
+	return ctx.Status(fiber.StatusOK).JSON(api.FromReviewModel(review))
+}
+
+func parseRuleReqBody(ctx *fiber.Ctx) (rules.Rule, error) {
+	reqBody := api.RuleReqBody{}
+	if err := parseBody(ctx, &reqBody); err != nil {
+		return rules.Rule{}, err
+	}
+	return reqBody.ToModel(), nil
+}
@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"log/slog"
+	"strconv"
+	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/openapi"
+	"teya_home_assignment/internal/pkg/scheduler"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+type ScheduleController struct {
+	schedulerService *scheduler.Scheduler
+	// amountPolicy is the policy of the ledger, applied when the schedule is saved rather than failing every run
+	amountPolicy ledger.AmountPolicy
+}
+
+func NewScheduleController(
+	schedulerService *scheduler.Scheduler,
+	amountPolicy ledger.AmountPolicy,
+) *ScheduleController {
+	return &ScheduleController{schedulerService: schedulerService, amountPolicy: amountPolicy}
+}
+
+func (c *ScheduleController) RegisterRoutes(router fiber.Router) error {
+	router.Post(ScheduleRoute, c.createSchedule)
+	router.Get(ScheduleRoute, c.getAllSchedules)
+	router.Get(ScheduleRoute+"/:id", c.getSchedule)
+	router.Put(ScheduleRoute+"/:id", c.updateSchedule)
+	router.Delete(ScheduleRoute+"/:id", c.deleteSchedule)
+	router.Get(ScheduleRoute+"/:id/next", c.getNextRuns)
+	return nil
+}
+
+func (c *ScheduleController) Operations() []openapi.Operation {
+	scheduleResponses := func(status int, description string) map[int]openapi.Response {
+		return map[int]openapi.Response{
+			status:                 {Description: description, Content: openapi.JSON(api.Schedule{})},
+			fiber.StatusBadRequest: errorResponse("Invalid schedule"),
+			fiber.StatusNotFound:   errorResponse("Schedule not found"),
+		}
+	}
+	return []openapi.Operation{
+		{
+			Method:      fiber.MethodPost,
+			Path:        ScheduleRoute,
+			Tag:         "Schedules",
+			Summary:     "Create a schedule posting a recurring transaction",
+			Description: "The schedule recurs either on a cron expression or on an interval, like 24h",
+			Request:     openapi.JSON(api.ScheduleReqBody{}),
+			Responses: map[int]openapi.Response{
+				fiber.StatusCreated: {
+					Description: "The schedule was created",
+					Content:     openapi.JSON(api.Schedule{}),
+				},
+				fiber.StatusBadRequest: errorResponse("Invalid schedule"),
+				fiber.StatusConflict:   errorResponse("The schedule already exists"),
+			},
+		},
+		{
+			Method:  fiber.MethodGet,
+			Path:    ScheduleRoute,
+			Tag:     "Schedules",
+			Summary: "List the schedules",
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK: {Content: openapi.JSON(api.SchedulesRespBody{})},
+			},
+		},
+		{
+			Method:    fiber.MethodGet,
+			Path:      ScheduleRoute + "/:id",
+			Tag:       "Schedules",
+			Summary:   "Get a schedule",
+			Responses: scheduleResponses(fiber.StatusOK, ""),
+		},
+		{
+			Method:    fiber.MethodPut,
+			Path:      ScheduleRoute + "/:id",
+			Tag:       "Schedules",
+			Summary:   "Update a schedule",
+			Request:   openapi.JSON(api.ScheduleReqBody{}),
+			Responses: scheduleResponses(fiber.StatusOK, "The schedule was updated"),
+		},
+		{
+			Method:  fiber.MethodDelete,
+			Path:    ScheduleRoute + "/:id",
+			Tag:     "Schedules",
+			Summary: "Delete a schedule",
+			Responses: map[int]openapi.Response{
+				fiber.StatusNoContent:  {Description: "The schedule was deleted"},
+				fiber.StatusBadRequest: errorResponse("Invalid schedule id"),
+				fiber.StatusNotFound:   errorResponse("Schedule not found"),
+			},
+		},
+		{
+			Method:  fiber.MethodGet,
+			Path:    ScheduleRoute + "/:id/next",
+			Tag:     "Schedules",
+			Summary: "Get the next runs of a schedule",
+			Query: []openapi.Parameter{
+				{Name: "count", Description: "Defaults to 5", Schema: intRange(1, 100)},
+			},
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK:         {Content: openapi.JSON(api.ScheduleNextRunsRespBody{})},
+				fiber.StatusBadRequest: errorResponse("Invalid schedule id or count"),
+				fiber.StatusNotFound:   errorResponse("Schedule not found"),
+			},
+		},
+	}
+}
+
+func (c *ScheduleController) createSchedule(ctx *fiber.Ctx) error {
+	schedule, err := parseScheduleReqBody(ctx)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	if schedule.Amount, err = c.amountPolicy(schedule.Amount); err != nil {
+		return err
+	}
+	schedule, err = c.schedulerService.Create(schedule)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully created schedule", "id", schedule.ID)
+	return ctx.Status(fiber.StatusCreated).JSON(api.FromScheduleModel(schedule, time.Now()))
+}
+
+func (c *ScheduleController) getAllSchedules(ctx *fiber.Ctx) error {
+	now := time.Now()
+	schedules := c.schedulerService.List()
+	resp := api.SchedulesRespBody{Schedules: make([]api.Schedule, len(schedules))}
+	for i, schedule := range schedules {
+		resp.Schedules[i] = api.FromScheduleModel(schedule, now)
+	}
+	slog.DebugContext(ctx.UserContext(), "successfully returned schedules", "count", len(schedules))
+	return ctx.Status(fiber.StatusOK).JSON(resp)
+}
+
+func (c *ScheduleController) getSchedule(ctx *fiber.Ctx) error {
+	id, err := paramID(ctx)
+	if err != nil {
+		return err
+	}
+	schedule, err := c.schedulerService.Get(id)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(api.FromScheduleModel(schedule, time.Now()))
+}
+
+func (c *ScheduleController) updateSchedule(ctx *fiber.Ctx) error {
+	id, err := paramID(ctx)
+	if err != nil {
+		return err
+	}
+	schedule, err := parseScheduleReqBody(ctx)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	if schedule.Amount, err = c.amountPolicy(schedule.Amount); err != nil {
+		return err
+	}
+	schedule, err = c.schedulerService.Update(id, schedule)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully updated schedule", "id", id)
+	return ctx.Status(fiber.StatusOK).JSON(api.FromScheduleModel(schedule, time.Now()))
+}
+
+func (c *ScheduleController) deleteSchedule(ctx *fiber.Ctx) error {
+	id, err := paramID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := c.schedulerService.Delete(id); err != nil {
+		return invalidRequest(err)
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully deleted schedule", "id", id)
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+func (c *ScheduleController) getNextRuns(ctx *fiber.Ctx) error {
+	// Default count. It is optional
+	count := 5
+
+	id, err := paramID(ctx)
+	if err != nil {
+		return err
+	}
+	if countParam := ctx.Query("count"); countParam != "" {
+		count, err = strconv.Atoi(countParam)
+		if err != nil || count <= 0 || count > 100 {
+			return invalidParameter("count", "must be between 1 and 100")
+		}
+	}
+	nextRuns, err := c.schedulerService.NextRuns(id, count)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(api.ScheduleNextRunsRespBody{NextRuns: nextRuns})
+}
+
+func parseScheduleReqBody(ctx *fiber.Ctx) (scheduler.Schedule, error) {
+	reqBody := api.ScheduleReqBody{}
+	if err := parseBody(ctx, &reqBody); err != nil {
+		return scheduler.Schedule{}, err
+	}
+	amount, err := decimal.NewFromString(reqBody.Amount)
+	if err != nil {
+		return scheduler.Schedule{}, errors.New("invalid schedule amount")
+	}
+	schedule := scheduler.Schedule{
+		Cron:        reqBody.Cron,
+		StartAt:     reqBody.StartAt.UTC(),
+		Amount:      amount,
+		Description: reqBody.Description,
+	}
+	if reqBody.Interval != "" {
+		if schedule.Interval, err = time.ParseDuration(reqBody.Interval); err != nil {
+			return scheduler.Schedule{}, errors.New("invalid schedule interval")
+		}
+	}
+	if reqBody.EndAt != nil {
+		schedule.EndAt = reqBody.EndAt.UTC()
+	}
+	return schedule, nil
+}
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"slices"
+	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/openapi"
+	"teya_home_assignment/internal/pkg/statement"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	StatementFormatJSON    = "json"
+	StatementFormatText    = "text"
+	StatementFormatHTML    = "html"
+	StatementFormatCAMT053 = "camt053"
+	StatementFormatOFX     = "ofx"
+
+	periodDateFormat = "2006-01-02"
+)
+
+// statementAccount identifies the single account served by this ledger in exported statements
+var statementAccount = statement.Account{
+	ID:       "LEDGER-0001",
+	BankID:   "TEYA",
+	Currency: LedgerCurrency,
+}
+
+type StatementController struct {
+	ledgerService *ledger.Ledger
+}
+
+func NewStatementController(ledgerService *ledger.Ledger) *StatementController {
+	return &StatementController{ledgerService: ledgerService}
+}
+
+func (c *StatementController) RegisterRoutes(router fiber.Router) error {
+	router.Get(StatementRoute, c.getStatement)
+	router.Get(StatementExportRoute, c.exportStatement)
+	return nil
+}
+
+func (c *StatementController) Operations() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Method:  fiber.MethodGet,
+			Path:    StatementRoute,
+			Tag:     "Statements",
+			Summary: "Get the statement of the account over a period",
+			Query: append(slices.Clone(periodQuery), openapi.Parameter{
+				Name:        "format",
+				Description: "Defaults to json",
+				Schema: &openapi.Schema{
+					Type: "string",
+					Enum: []string{StatementFormatJSON, StatementFormatText, StatementFormatHTML},
+				},
+			}),
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK: {Content: &openapi.Content{
+					Schema: api.StatementRespBody{},
+					Types:  []string{fiber.MIMEApplicationJSON, fiber.MIMETextPlain, fiber.MIMETextHTML},
+				}},
+				fiber.StatusBadRequest:          errorResponse("Invalid period or format"),
+				fiber.StatusInternalServerError: errorResponse("The statement could not be generated"),
+			},
+		},
+		{
+			Method:  fiber.MethodGet,
+			Path:    StatementExportRoute,
+			Tag:     "Statements",
+			Summary: "Export the statement of the account over a period as an attachment",
+			Query: append(slices.Clone(periodQuery), openapi.Parameter{
+				Name:        "format",
+				Description: "Defaults to camt053",
+				Schema: &openapi.Schema{
+					Type: "string",
+					Enum: []string{StatementFormatCAMT053, StatementFormatOFX},
+				},
+			}),
+			Responses: map[int]openapi.Response{
+				fiber.StatusOK:                  {Content: openapi.Text(fiber.MIMEApplicationXML, "application/x-ofx")},
+				fiber.StatusBadRequest:          errorResponse("Invalid period or format"),
+				fiber.StatusInternalServerError: errorResponse("The statement could not be exported"),
+			},
+		},
+	}
+}
+
+func (c *StatementController) getStatement(ctx *fiber.Ctx) error {
+	from, to, err := parsePeriod(ctx)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	format := ctx.Query("format", StatementFormatJSON)
+	if format != StatementFormatJSON && format != StatementFormatText && format != StatementFormatHTML {
+		return invalidParameter("format", "must be json, text or html")
+	}
+
+	s, err := statement.New(c.ledgerService, statementAccount, from, to)
+	if err != nil {
+		return errors.Wrap(err, "could not generate statement")
+	}
+	slog.InfoContext(ctx.UserContext(), "successfully generated statement", "id", s.ID())
+	if format == StatementFormatJSON {
+		return ctx.Status(fiber.StatusOK).JSON(api.FromStatementModel(s))
+	}
+
+	var buf bytes.Buffer
+	contentType := fiber.MIMETextPlainCharsetUTF8
+	if format == StatementFormatHTML {
+		contentType = fiber.MIMETextHTMLCharsetUTF8
+		err = statement.RenderHTML(&buf, s)
+	} else {
+		err = statement.RenderText(&buf, s)
+	}
+	if err != nil {
+		return errors.Wrap(err, "could not render statement")
+	}
+	ctx.Set(fiber.HeaderContentType, contentType)
+	return ctx.Status(fiber.StatusOK).Send(buf.Bytes())
+}
+
+func (c *StatementController) exportStatement(ctx *fiber.Ctx) error {
+	from, to, err := parsePeriod(ctx)
+	if err != nil {
+		return invalidRequest(err)
+	}
+	format := ctx.Query("format", StatementFormatCAMT053)
+	if format != StatementFormatCAMT053 && format != StatementFormatOFX {
+		return invalidParameter("format", "must be camt053 or ofx")
+	}
+
+	s, err := statement.New(c.ledgerService, statementAccount, from, to)
+	if err != nil {
+		return errors.Wrap(err, "could not generate statement")
+	}
+	var buf bytes.Buffer
+	contentType, extension := fiber.MIMEApplicationXMLCharsetUTF8, "xml"
+	if format == StatementFormatOFX {
+		contentType, extension = "application/x-ofx", "ofx"
+		err = statement.EncodeOFX(&buf, s)
+	} else {
+		err = statement.EncodeCAMT053(&buf, s)
+	}
+	if err != nil {
+		return errors.Wrap(err, "could not export statement")
+	}
+
+	slog.InfoContext(ctx.UserContext(), "successfully exported statement", "id", s.ID(), "format", format)
+	ctx.Attachment(s.ID() + "." + extension)
+	ctx.Set(fiber.HeaderContentType, contentType)
+	return ctx.Status(fiber.StatusOK).Send(buf.Bytes())
+}
+
+// parsePeriod reads the mandatory from/to query parameters, given either as dates or RFC 3339 timestamps.
+// A date passed as "to" is inclusive, so the period ends at the start of the following day.
+func parsePeriod(ctx *fiber.Ctx) (time.Time, time.Time, error) {
+	from, _, err := parsePeriodTime(ctx.Query("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, invalidParameter("from", err.Error())
+	}
+	to, isDate, err := parsePeriodTime(ctx.Query("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, invalidParameter("to", err.Error())
+	}
+	if isDate {
+		to = to.AddDate(0, 0, 1)
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, invalidParameter("to", "must be after from")
+	}
+	return from, to, nil
+}
+
+func parsePeriodTime(value string) (time.Time, bool, error) {
+	if value == "" {
+		return time.Time{}, false, fmt.Errorf("is required")
+	}
+	if date, err := time.Parse(periodDateFormat, value); err == nil {
+		return date, true, nil
+	}
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("must be a date formatted as YYYY-MM-DD or an RFC 3339 timestamp, got %q", value)
+	}
+	return timestamp, false, nil
+}
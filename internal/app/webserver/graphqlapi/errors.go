@@ -0,0 +1,62 @@
+package graphqlapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"teya_home_assignment/internal/app/webserver/api"
+)
+
+// Error is a GraphQL error carrying the error code of the HTTP API, e.g. limit_exceeded, in its extensions
+type Error struct {
+	Message string
+	Problem api.Problem
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Extensions holds the code and status of the error, along with the invalid fields, the rule or the limit if any
+func (e *Error) Extensions() map[string]any {
+	extensions := map[string]any{"code": e.Problem.Code, "status": e.Problem.Status}
+	if len(e.Problem.Errors) > 0 {
+		extensions["errors"] = e.Problem.Errors
+	}
+	if e.Problem.Rule != "" {
+		extensions["rule"] = e.Problem.Rule
+	}
+	if e.Problem.Limit != "" {
+		extensions["limit"] = e.Problem.Limit
+	}
+	if e.Problem.RuleID != nil {
+		extensions["rule_id"] = e.Problem.RuleID
+	}
+	return extensions
+}
+
+// resolverError converts the error a field failed to resolve with, classified as by the HTTP API. The details of the
+// internal errors are logged but not disclosed.
+func resolverError(ctx context.Context, err error) error {
+	problem := api.FromError(err)
+	if problem.Status >= http.StatusInternalServerError {
+		slog.ErrorContext(ctx, "field failed to resolve", "error", err)
+	} else {
+		slog.WarnContext(ctx, "field rejected", "code", problem.Code, "error", err)
+	}
+	message := problem.Detail
+	if message == "" {
+		message = problem.Title
+	}
+	return &Error{Message: message, Problem: problem}
+}
+
+// invalidArgument fails the resolution of a field given an invalid argument
+func invalidArgument(ctx context.Context, name, message string) error {
+	return resolverError(ctx, &api.ProblemError{
+		Status: http.StatusBadRequest,
+		Code:   api.CodeInvalidParameter,
+		Detail: "invalid " + name + " argument",
+		Errors: []api.FieldError{{Field: name, Code: string(api.CodeInvalidParameter), Message: message}},
+	})
+}
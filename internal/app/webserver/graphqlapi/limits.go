@@ -0,0 +1,138 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"teya_home_assignment/internal/app/webserver/api"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// connectionFields are the fields answering a page of results, whose edges are resolved once per result
+var connectionFields = map[string]bool{"transactions": true}
+
+// edgesField is the field of a connection listing the results of its page
+const edgesField = "edges"
+
+// Limits bounds the queries, so a single query cannot make the server resolve an unbounded number of fields
+type Limits struct {
+	// MaxDepth is the deepest nesting of fields
+	MaxDepth int
+	// MaxComplexity is the largest number of fields resolved, the fields of the edges of a connection being
+	// counted once per result of the page it requests
+	MaxComplexity int
+	// DefaultPageSize is the size of the pages of the connections requested without first nor last
+	DefaultPageSize int
+}
+
+// check rejects the operation to be executed when it exceeds a limit. The document must be valid, so its fragments
+// do not spread into each other in cycles. The fields of the introspection are counted, but not walked.
+func (l Limits) check(document *ast.Document, operationName string, variables map[string]any) error {
+	w := &walker{limits: l, fragments: map[string]*ast.FragmentDefinition{}, variables: variables}
+	var operations []*ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operations = append(operations, definition)
+			}
+		case *ast.FragmentDefinition:
+			w.fragments[definition.Name.Value] = definition
+		}
+	}
+	if len(operations) != 1 {
+		// the operation to execute is ambiguous or unknown, which the execution reports
+		return nil
+	}
+	return w.walk(operations[0].SelectionSet, 1, 1, 0)
+}
+
+type walker struct {
+	limits    Limits
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+	// complexity is the complexity of the fields walked so far
+	complexity int
+}
+
+// walk walks the fields of the selection set, at the given depth. The weight of the fields is the number of times
+// they are resolved, the product of the page sizes of the edges they are in. The page size is the one of the
+// connection whose fields are walked, 0 for the other fields.
+func (w *walker) walk(selectionSet *ast.SelectionSet, depth, weight, pageSize int) error {
+	if selectionSet == nil {
+		return nil
+	}
+	for _, selection := range selectionSet.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if depth > w.limits.MaxDepth {
+				return limitError(api.CodeQueryTooDeep, selection,
+					fmt.Sprintf("query is deeper than %d fields", w.limits.MaxDepth))
+			}
+			w.complexity += weight
+			if w.complexity > w.limits.MaxComplexity {
+				return limitError(api.CodeQueryTooComplex, selection,
+					fmt.Sprintf("query resolves more than %d fields", w.limits.MaxComplexity))
+			}
+			if strings.HasPrefix(selection.Name.Value, "__") {
+				continue
+			}
+			fieldWeight, fieldPageSize := weight, 0
+			if selection.Name.Value == edgesField && pageSize > 0 {
+				// the weight is bounded, the complexity exceeding its limit as soon as the weight does
+				fieldWeight = min(weight*pageSize, w.limits.MaxComplexity+1)
+			}
+			if connectionFields[selection.Name.Value] {
+				fieldPageSize = w.pageSize(selection)
+			}
+			if err := w.walk(selection.SelectionSet, depth+1, fieldWeight, fieldPageSize); err != nil {
+				return err
+			}
+		case *ast.FragmentSpread:
+			if fragment, exists := w.fragments[selection.Name.Value]; exists {
+				if err := w.walk(fragment.SelectionSet, depth, weight, pageSize); err != nil {
+					return err
+				}
+			}
+		case *ast.InlineFragment:
+			if err := w.walk(selection.SelectionSet, depth, weight, pageSize); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pageSize returns the number of results of a connection field. A page of no result weighs as much as a page of
+// one, so the fields it selects still count, and the sizes over the complexity limit as much as the limit.
+func (w *walker) pageSize(field *ast.Field) int {
+	bound := w.limits.MaxComplexity + 1
+	size := w.limits.DefaultPageSize
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" && argument.Name.Value != "last" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil {
+				size = min(n, bound)
+			}
+		case *ast.Variable:
+			// the variables are decoded from JSON, whose numbers are floats
+			if n, ok := w.variables[value.Name.Value].(float64); ok {
+				size = int(min(n, float64(bound)))
+			}
+		}
+	}
+	return min(max(size, 1), bound)
+}
+
+func limitError(code api.ErrorCode, field *ast.Field, detail string) error {
+	return gqlerrors.NewLocatedError(&Error{
+		Message: detail,
+		Problem: api.Problem{Status: http.StatusBadRequest, Code: code, Detail: detail},
+	}, []ast.Node{field})
+}
@@ -0,0 +1,633 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/app/webserver/config"
+	"teya_home_assignment/internal/pkg/categories"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/rules"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// cursorPrefix prefixes the offset of a transaction in the history, or in the transactions of a category, in the
+// cursors of the connections. The cursors are opaque to the clients.
+const cursorPrefix = "transaction:"
+
+// Decimal is an amount, serialized as a string so no precision is lost
+var Decimal = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Decimal",
+	Description: "A decimal number serialized as a string, e.g. \"-12.5\"",
+	Serialize: func(value any) any {
+		switch value := value.(type) {
+		case decimal.Decimal:
+			return value.String()
+		case *decimal.Decimal:
+			return value.String()
+		}
+		return nil
+	},
+	// the input amounts are kept as given, to be validated as the amounts of the HTTP API
+	ParseValue: func(value any) any {
+		switch value := value.(type) {
+		case string:
+			return value
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		}
+		return nil
+	},
+	ParseLiteral: func(value ast.Value) any {
+		switch value := value.(type) {
+		case *ast.StringValue:
+			return value.Value
+		case *ast.IntValue:
+			return value.Value
+		case *ast.FloatValue:
+			return value.Value
+		}
+		return nil
+	},
+})
+
+// DateTime is a time serialized as an RFC 3339 timestamp
+var DateTime = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "DateTime",
+	Description: "A time serialized as an RFC 3339 timestamp, e.g. \"2024-05-01T12:00:00Z\"",
+	Serialize: func(value any) any {
+		if value, ok := value.(time.Time); ok {
+			return value.Format(time.RFC3339Nano)
+		}
+		return nil
+	},
+	ParseValue: func(value any) any {
+		if value, ok := value.(string); ok {
+			return parseDateTime(value)
+		}
+		return nil
+	},
+	ParseLiteral: func(value ast.Value) any {
+		if value, ok := value.(*ast.StringValue); ok {
+			return parseDateTime(value.Value)
+		}
+		return nil
+	},
+})
+
+func parseDateTime(value string) any {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil
+	}
+	return t
+}
+
+// account is the single account served by the ledger
+type account struct{}
+
+type connection struct {
+	Edges      []edge
+	PageInfo   pageInfo
+	TotalCount int
+}
+
+type edge struct {
+	Cursor string
+	Node   ledger.Transaction
+}
+
+type pageInfo struct {
+	HasNextPage     bool
+	HasPreviousPage bool
+	StartCursor     *string
+	EndCursor       *string
+}
+
+type categoryTotal struct {
+	Category string
+	Count    int
+	Credits  decimal.Decimal
+	Debits   decimal.Decimal
+	Net      decimal.Decimal
+}
+
+// createTransactionPayload is the outcome of the createTransaction mutation, either the posted transaction and its
+// fees or the review the transaction is held for
+type createTransactionPayload struct {
+	posted []ledger.Transaction
+	review *rules.Review
+}
+
+// resolver resolves the fields of the schema against the services of the HTTP API
+type resolver struct {
+	ledgerService     *ledger.Ledger
+	categoriesService *categories.Categorizer
+	pagination        config.Pagination
+	currency          string
+}
+
+// NewSchema returns the schema of the GraphQL API over the account of the ledger, in the given currency
+func NewSchema(
+	ledgerService *ledger.Ledger,
+	categoriesService *categories.Categorizer,
+	pagination config.Pagination,
+	currency string,
+) (graphql.Schema, error) {
+	r := &resolver{
+		ledgerService:     ledgerService,
+		categoriesService: categoriesService,
+		pagination:        pagination,
+		currency:          currency,
+	}
+	metadataEntryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "MetadataEntry",
+		Fields: graphql.Fields{
+			"key":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"value": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+	transactionType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Transaction",
+		Description: "An entry of the ledger, a positive amount credits the account and a negative one debits it",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return p.Source.(ledger.Transaction).ExternalID.String(), nil
+				},
+			},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(DateTime)},
+			"amount":    &graphql.Field{Type: graphql.NewNonNull(Decimal)},
+			"kind": &graphql.Field{
+				Type:        graphql.String,
+				Description: "The kind of the entries posted by the ledger itself, e.g. fee or interest",
+				Resolve: optional(func(transaction ledger.Transaction) string {
+					return transaction.Kind
+				}),
+			},
+			"category": &graphql.Field{
+				Type: graphql.String,
+				Resolve: optional(func(transaction ledger.Transaction) string {
+					return r.categoriesService.Categorize(transaction).Name
+				}),
+			},
+			"reference": &graphql.Field{
+				Type: graphql.String,
+				Resolve: optional(func(transaction ledger.Transaction) string {
+					return transaction.Reference
+				}),
+			},
+			"description": &graphql.Field{
+				Type: graphql.String,
+				Resolve: optional(func(transaction ledger.Transaction) string {
+					return transaction.Description
+				}),
+			},
+			"metadata": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(metadataEntryType))),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					metadata := p.Source.(ledger.Transaction).Metadata
+					entries := make([]map[string]any, 0, len(metadata))
+					for _, key := range slices.Sorted(maps.Keys(metadata)) {
+						entries = append(entries, map[string]any{"key": key, "value": metadata[key]})
+					}
+					return entries, nil
+				},
+			},
+		},
+	})
+	transactionType.AddFieldConfig("linkedTo", &graphql.Field{
+		Type:        transactionType,
+		Description: "The transaction a fee was charged for",
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			return r.transaction(p.Source.(ledger.Transaction).LinkedTo), nil
+		},
+	})
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"hasPreviousPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"startCursor":     &graphql.Field{Type: graphql.String},
+			"endCursor":       &graphql.Field{Type: graphql.String},
+		},
+	})
+	connectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "TransactionConnection",
+		Description: "A page of transactions, from the oldest",
+		Fields: graphql.Fields{
+			"edges": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.NewObject(graphql.ObjectConfig{
+					Name: "TransactionEdge",
+					Fields: graphql.Fields{
+						"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+						"node":   &graphql.Field{Type: graphql.NewNonNull(transactionType)},
+					},
+				})))),
+			},
+			"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+			"totalCount": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "The number of transactions of the connection, over all its pages",
+			},
+		},
+	})
+	categoryTotalType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "CategoryTotal",
+		Description: "The totals of the transactions of a category",
+		Fields: graphql.Fields{
+			"category": &graphql.Field{
+				Type:        graphql.String,
+				Description: "The category, null for the transactions which are not categorized",
+				Resolve: optional(func(total categoryTotal) string {
+					return total.Category
+				}),
+			},
+			"count":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"credits": &graphql.Field{Type: graphql.NewNonNull(Decimal), Description: "The sum of the credits"},
+			"debits": &graphql.Field{
+				Type:        graphql.NewNonNull(Decimal),
+				Description: "The sum of the debits, negative",
+			},
+			"net": &graphql.Field{Type: graphql.NewNonNull(Decimal)},
+		},
+	})
+	accountType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Account",
+		Fields: graphql.Fields{
+			"currency": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(graphql.ResolveParams) (any, error) {
+					return r.currency, nil
+				},
+			},
+			"balance": &graphql.Field{
+				Type: graphql.NewNonNull(Decimal),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					balance, err := r.ledgerService.GetBalanceContext(p.Context)
+					if err != nil {
+						return nil, resolverError(p.Context, errors.Wrap(err, "could not get balance"))
+					}
+					return balance, nil
+				},
+			},
+			"transactions": &graphql.Field{
+				Type: graphql.NewNonNull(connectionType),
+				Description: fmt.Sprintf("Pages through the transactions, from the oldest. The first ones are "+
+					"answered unless last is given, %d of them by default and up to %d.",
+					pagination.DefaultLimit, pagination.MaxLimit),
+				Args: graphql.FieldConfigArgument{
+					"first":  &graphql.ArgumentConfig{Type: graphql.Int},
+					"after":  &graphql.ArgumentConfig{Type: graphql.String},
+					"last":   &graphql.ArgumentConfig{Type: graphql.Int},
+					"before": &graphql.ArgumentConfig{Type: graphql.String},
+					"category": &graphql.ArgumentConfig{
+						Type:        graphql.String,
+						Description: "Pages through the transactions of the category only",
+					},
+				},
+				Resolve: r.transactions,
+			},
+			"categoryTotals": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(categoryTotalType))),
+				Description: "The totals of the transactions by category, created within [from, to) when given, " +
+					"by decreasing number of transactions",
+				Args: graphql.FieldConfigArgument{
+					"from": &graphql.ArgumentConfig{Type: DateTime},
+					"to":   &graphql.ArgumentConfig{Type: DateTime},
+				},
+				Resolve: r.categoryTotals,
+			},
+		},
+	})
+	reviewType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Review",
+		Description: "A transaction held for review by the rules it matched",
+		Fields: graphql.Fields{
+			"id": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return p.Source.(*rules.Review).ID.String(), nil
+				},
+			},
+			"status": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return string(p.Source.(*rules.Review).Status), nil
+				},
+			},
+			"transaction": &graphql.Field{Type: graphql.NewNonNull(transactionType)},
+			"rules": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+				Description: "The names of the rules the transaction matched",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					matches := p.Source.(*rules.Review).Matches
+					names := make([]string, 0, len(matches))
+					for _, match := range matches {
+						names = append(names, match.Name)
+					}
+					return names, nil
+				},
+			},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"account": &graphql.Field{
+				Type: graphql.NewNonNull(accountType),
+				Resolve: func(graphql.ResolveParams) (any, error) {
+					return account{}, nil
+				},
+			},
+			"transaction": &graphql.Field{
+				Type:        transactionType,
+				Description: "The transaction of the ID, null if unknown",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, err := uuid.Parse(p.Args["id"].(string))
+					if err != nil {
+						return nil, invalidArgument(p.Context, "id", "must be a UUID")
+					}
+					return r.transaction(id), nil
+				},
+			},
+		},
+	})
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createTransaction": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewObject(graphql.ObjectConfig{
+					Name: "CreateTransactionPayload",
+					Description: "The posted transaction along with its fees, or the review the transaction is " +
+						"held for",
+					Fields: graphql.Fields{
+						"transaction": &graphql.Field{
+							Type: transactionType,
+							Resolve: func(p graphql.ResolveParams) (any, error) {
+								if posted := p.Source.(createTransactionPayload).posted; len(posted) > 0 {
+									return posted[0], nil
+								}
+								return nil, nil
+							},
+						},
+						"fees": &graphql.Field{
+							Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(transactionType))),
+							Resolve: func(p graphql.ResolveParams) (any, error) {
+								if posted := p.Source.(createTransactionPayload).posted; len(posted) > 0 {
+									return posted[1:], nil
+								}
+								return []ledger.Transaction{}, nil
+							},
+						},
+						"review": &graphql.Field{
+							Type: reviewType,
+							Resolve: func(p graphql.ResolveParams) (any, error) {
+								if review := p.Source.(createTransactionPayload).review; review != nil {
+									return review, nil
+								}
+								return nil, nil
+							},
+						},
+					},
+				})),
+				Description: "Posts a transaction, validated as by the HTTP API",
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.NewInputObject(graphql.InputObjectConfig{
+							Name: "CreateTransactionInput",
+							Fields: graphql.InputObjectConfigFieldMap{
+								"amount":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(Decimal)},
+								"reference":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+								"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+								"metadata": &graphql.InputObjectFieldConfig{
+									Type: graphql.NewList(graphql.NewNonNull(graphql.NewInputObject(
+										graphql.InputObjectConfig{
+											Name: "MetadataEntryInput",
+											Fields: graphql.InputObjectConfigFieldMap{
+												"key": &graphql.InputObjectFieldConfig{
+													Type: graphql.NewNonNull(graphql.String),
+												},
+												"value": &graphql.InputObjectFieldConfig{
+													Type: graphql.NewNonNull(graphql.String),
+												},
+											},
+										},
+									))),
+								},
+							},
+						})),
+					},
+				},
+				Resolve: r.createTransaction,
+			},
+		},
+	})
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+	return schema, errors.Wrap(err, "failed to build GraphQL schema")
+}
+
+// optional resolves a string field of the source to null when empty
+func optional[T any](field func(T) string) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		if value := field(p.Source.(T)); value != "" {
+			return value, nil
+		}
+		return nil, nil
+	}
+}
+
+// transaction returns the transaction of the ID, or nil when unknown
+func (r *resolver) transaction(id uuid.UUID) any {
+	if id == uuid.Nil {
+		return nil
+	}
+	transaction, exists := r.ledgerService.GetTransaction(id)
+	if !exists {
+		return nil
+	}
+	return transaction
+}
+
+// transactions answers a page of the history, or of the transactions of a category. The cursors are the offsets of
+// the transactions, which are stable as the history is only appended to.
+func (r *resolver) transactions(p graphql.ResolveParams) (any, error) {
+	first, hasFirst := p.Args["first"].(int)
+	last, hasLast := p.Args["last"].(int)
+	if hasFirst && hasLast {
+		return nil, invalidArgument(p.Context, "last", "must not be given along with first")
+	}
+	if !hasFirst && !hasLast {
+		first, hasFirst = r.pagination.DefaultLimit, true
+	}
+	for name, size := range map[string]int{"first": first, "last": last} {
+		if size < 0 || size > r.pagination.MaxLimit {
+			return nil, invalidArgument(p.Context, name, fmt.Sprintf("must be between 0 and %v", r.pagination.MaxLimit))
+		}
+	}
+
+	category, _ := p.Args["category"].(string)
+	var filter func(ledger.Transaction) bool
+	total := r.ledgerService.GetStats().TransactionCount
+	if category != "" {
+		filter = func(transaction ledger.Transaction) bool {
+			return r.categoriesService.Categorize(transaction).Name == category
+		}
+		// an empty page counts the matching transactions without collecting them
+		matching, err := r.ledgerService.FindTransactionPage(0, 0, filter)
+		if err != nil {
+			return nil, resolverError(p.Context, errors.Wrap(err, "could not get transactions"))
+		}
+		total = matching.Total
+	}
+
+	start, end := 0, total
+	if after, ok := p.Args["after"].(string); ok {
+		offset, err := decodeCursor(p.Context, "after", after)
+		if err != nil {
+			return nil, err
+		}
+		start = min(offset+1, total)
+	}
+	if before, ok := p.Args["before"].(string); ok {
+		offset, err := decodeCursor(p.Context, "before", before)
+		if err != nil {
+			return nil, err
+		}
+		end = max(min(offset, end), start)
+	}
+	if hasFirst {
+		end = min(end, start+first)
+	} else {
+		start = max(start, end-last)
+	}
+
+	var page []ledger.Transaction
+	var err error
+	if filter != nil {
+		page, err = r.ledgerService.FindTransactions(start, end-start, filter)
+	} else {
+		page, err = r.ledgerService.GetTransactionHistory(start, end-start)
+	}
+	if err != nil {
+		return nil, resolverError(p.Context, errors.Wrap(err, "could not get transactions"))
+	}
+	result := connection{
+		Edges:      make([]edge, len(page)),
+		PageInfo:   pageInfo{HasNextPage: start+len(page) < total, HasPreviousPage: start > 0},
+		TotalCount: total,
+	}
+	for i, transaction := range page {
+		result.Edges[i] = edge{Cursor: encodeCursor(start + i), Node: transaction}
+	}
+	if len(result.Edges) > 0 {
+		result.PageInfo.StartCursor = &result.Edges[0].Cursor
+		result.PageInfo.EndCursor = &result.Edges[len(result.Edges)-1].Cursor
+	}
+	slog.DebugContext(p.Context, "successfully resolved transactions", "count", len(page))
+	return result, nil
+}
+
+func encodeCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(ctx context.Context, name, cursor string) (int, error) {
+	decoded, err := base64.StdEncoding.DecodeString(cursor)
+	if err == nil && strings.HasPrefix(string(decoded), cursorPrefix) {
+		offset, err := strconv.Atoi(strings.TrimPrefix(string(decoded), cursorPrefix))
+		if err == nil && offset >= 0 {
+			return offset, nil
+		}
+	}
+	return 0, invalidArgument(ctx, name, "must be a cursor of a transaction")
+}
+
+func (r *resolver) categoryTotals(p graphql.ResolveParams) (any, error) {
+	// a missing bound leaves the period open
+	from, hasFrom := p.Args["from"].(time.Time)
+	to, hasTo := p.Args["to"].(time.Time)
+	if hasFrom && hasTo && to.Before(from) {
+		return nil, invalidArgument(p.Context, "to", "must not be before from")
+	}
+	// the totals are summed in a single pass over the history, without copying it
+	totals := map[string]*categoryTotal{}
+	err := r.ledgerService.VisitTransactionsBetween(from, to, func(transaction ledger.Transaction) {
+		name := r.categoriesService.Categorize(transaction).Name
+		total, exists := totals[name]
+		if !exists {
+			total = &categoryTotal{Category: name}
+			totals[name] = total
+		}
+		total.Count++
+		if transaction.Amount.IsNegative() {
+			total.Debits = total.Debits.Add(transaction.Amount)
+		} else {
+			total.Credits = total.Credits.Add(transaction.Amount)
+		}
+		total.Net = total.Net.Add(transaction.Amount)
+	})
+	if err != nil {
+		return nil, resolverError(p.Context, errors.Wrap(err, "could not get transactions"))
+	}
+	result := make([]categoryTotal, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, *total)
+	}
+	slices.SortFunc(result, func(a, b categoryTotal) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Category, b.Category)
+	})
+	return result, nil
+}
+
+func (r *resolver) createTransaction(p graphql.ResolveParams) (any, error) {
+	input := p.Args["input"].(map[string]any)
+	// the transactions are validated against the same rules as the HTTP API
+	reqBody := api.NewTransactionReqBody{}
+	reqBody.Amount, _ = input["amount"].(string)
+	reqBody.Reference, _ = input["reference"].(string)
+	reqBody.Description, _ = input["description"].(string)
+	if entries, ok := input["metadata"].([]any); ok && len(entries) > 0 {
+		reqBody.Metadata = make(map[string]string, len(entries))
+		for _, entry := range entries {
+			entry := entry.(map[string]any)
+			reqBody.Metadata[entry["key"].(string)] = entry["value"].(string)
+		}
+	}
+	transaction, err := api.ParseNewTransaction(reqBody, "")
+	if err != nil {
+		return nil, resolverError(p.Context, err)
+	}
+	posted, err := r.ledgerService.PostTransactionContext(p.Context, transaction.Amount, transaction.Options...)
+	var reviewErr *rules.ReviewRequiredError
+	if errors.As(err, &reviewErr) {
+		slog.InfoContext(p.Context, "transaction held", "error", err)
+		return createTransactionPayload{review: &reviewErr.Review}, nil
+	}
+	if err != nil {
+		return nil, resolverError(p.Context, errors.Wrap(err, "could not add transaction"))
+	}
+	slog.InfoContext(p.Context, "successfully added transaction",
+		"id", posted[0].ExternalID, "amount", transaction.Amount, "fees", len(posted)-1)
+	return createTransactionPayload{posted: posted}, nil
+}
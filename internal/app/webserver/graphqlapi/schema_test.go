@@ -0,0 +1,274 @@
+package graphqlapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"teya_home_assignment/internal/app/webserver/config"
+	"teya_home_assignment/internal/app/webserver/controllers"
+	"teya_home_assignment/internal/app/webserver/graphqlapi"
+	"teya_home_assignment/internal/pkg/categories"
+	"teya_home_assignment/internal/pkg/health"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/metrics"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withMerchantFees charges 1.5% + 0.20, at least 0.25, on the credits
+func withMerchantFees(t *testing.T, cfg config.Config) config.Config {
+	t.Helper()
+	require.NoError(t, cfg.Fees.Schedules.Set(`[{name: Merchant service charge, currency: EUR, applies_to: credits,
+  percentage: 0.015, fixed: 0.20, min: 0.25, rounding: half_up}]`))
+	return cfg
+}
+
+// newServer returns a GraphQL server over the services of the webserver, along with the services
+func newServer(t *testing.T, cfg config.Config) (*graphqlapi.Server, controllers.Services) {
+	t.Helper()
+	_, services, err := controllers.InitControllers(cfg, metrics.New(), nil, health.NewChecker())
+	require.NoError(t, err)
+	server, err := graphqlapi.NewServer(services.Ledger, services.Categories, cfg, controllers.LedgerCurrency)
+	require.NoError(t, err)
+	return server, services
+}
+
+// gqlResult is the result of a query, decoded as sent to the clients
+type gqlResult struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func execute(t *testing.T, server *graphqlapi.Server, query string, variables map[string]any) gqlResult {
+	t.Helper()
+	encoded, err := json.Marshal(server.Execute(context.Background(), query, "", variables))
+	require.NoError(t, err)
+	var result gqlResult
+	require.NoError(t, json.Unmarshal(encoded, &result))
+	return result
+}
+
+type transactionsPage struct {
+	Edges []struct {
+		Cursor string `json:"cursor"`
+		Node   struct {
+			Amount   string  `json:"amount"`
+			Kind     *string `json:"kind"`
+			Category *string `json:"category"`
+		} `json:"node"`
+	} `json:"edges"`
+	PageInfo struct {
+		HasNextPage     bool   `json:"hasNextPage"`
+		HasPreviousPage bool   `json:"hasPreviousPage"`
+		EndCursor       string `json:"endCursor"`
+	} `json:"pageInfo"`
+	TotalCount int `json:"totalCount"`
+}
+
+func (p transactionsPage) amounts() []string {
+	amounts := make([]string, 0, len(p.Edges))
+	for _, edge := range p.Edges {
+		amounts = append(amounts, edge.Node.Amount)
+	}
+	return amounts
+}
+
+func TestServer_Execute__AnswersAccountInOneQuery(t *testing.T) {
+	// Arrange
+	server, services := newServer(t, withMerchantFees(t, config.Default()))
+	_, err := services.Categories.Create(categories.Rule{
+		Category:  "sales",
+		MinAmount: decimal.NewNullDecimal(decimal.NewFromInt(100)),
+	})
+	require.NoError(t, err)
+	for _, amount := range []int64{100, 200} {
+		require.NoError(t, services.Ledger.AddTransaction(decimal.NewFromInt(amount)))
+	}
+
+	// Act
+	result := execute(t, server, `{
+		account {
+			currency
+			balance
+			transactions(last: 2) {
+				edges { node { amount kind category } } pageInfo { hasNextPage hasPreviousPage } totalCount
+			}
+			categoryTotals { category count credits debits net }
+		}
+	}`, nil)
+
+	// Assert
+	require.Empty(t, result.Errors)
+	var data struct {
+		Account struct {
+			Currency       string           `json:"currency"`
+			Balance        string           `json:"balance"`
+			Transactions   transactionsPage `json:"transactions"`
+			CategoryTotals []map[string]any `json:"categoryTotals"`
+		} `json:"account"`
+	}
+	require.NoError(t, json.Unmarshal(result.Data, &data))
+	assert.Equal(t, "EUR", data.Account.Currency)
+	assert.Equal(t, "295.1", data.Account.Balance)
+	assert.Equal(t, []string{"200", "-3.2"}, data.Account.Transactions.amounts())
+	assert.Equal(t, ledger.KindFee, *data.Account.Transactions.Edges[1].Node.Kind)
+	assert.Equal(t, "sales", *data.Account.Transactions.Edges[0].Node.Category)
+	assert.Nil(t, data.Account.Transactions.Edges[1].Node.Category)
+	assert.False(t, data.Account.Transactions.PageInfo.HasNextPage)
+	assert.True(t, data.Account.Transactions.PageInfo.HasPreviousPage)
+	assert.Equal(t, 4, data.Account.Transactions.TotalCount)
+	assert.Equal(t, []map[string]any{
+		{"category": nil, "count": float64(2), "credits": "0", "debits": "-4.9", "net": "-4.9"},
+		{"category": "sales", "count": float64(2), "credits": "300", "debits": "0", "net": "300"},
+	}, data.Account.CategoryTotals)
+}
+
+func TestServer_Execute__PagesThroughTransactions(t *testing.T) {
+	// Arrange
+	server, services := newServer(t, config.Default())
+	_, err := services.Categories.Create(categories.Rule{
+		Category:  "sales",
+		MinAmount: decimal.NewNullDecimal(decimal.NewFromInt(1)),
+	})
+	require.NoError(t, err)
+	for _, amount := range []int64{10, 20, 30} {
+		require.NoError(t, services.Ledger.AddTransaction(decimal.NewFromInt(amount)))
+	}
+	query := `query($after: String) {
+		account { transactions(first: 2, after: $after, category: "sales") {
+			edges { cursor node { amount } } pageInfo { hasNextPage endCursor } totalCount
+		} }
+	}`
+	page := func(variables map[string]any) transactionsPage {
+		result := execute(t, server, query, variables)
+		require.Empty(t, result.Errors)
+		var data struct {
+			Account struct {
+				Transactions transactionsPage `json:"transactions"`
+			} `json:"account"`
+		}
+		require.NoError(t, json.Unmarshal(result.Data, &data))
+		return data.Account.Transactions
+	}
+	first := page(nil)
+
+	// Act
+	second := page(map[string]any{"after": first.PageInfo.EndCursor})
+
+	// Assert
+	assert.Equal(t, []string{"10", "20"}, first.amounts())
+	assert.True(t, first.PageInfo.HasNextPage)
+	assert.Equal(t, 3, first.TotalCount)
+	assert.Equal(t, []string{"30"}, second.amounts())
+	assert.False(t, second.PageInfo.HasNextPage)
+}
+
+func TestServer_Execute__CreatesTransaction(t *testing.T) {
+	// Arrange
+	server, services := newServer(t, withMerchantFees(t, config.Default()))
+
+	// Act
+	result := execute(t, server, `mutation($amount: Decimal!) {
+		createTransaction(input: {
+			amount: $amount, reference: "INV-1", metadata: [{key: "channel", value: "pos"}]
+		}) {
+			transaction { id reference metadata { key value } }
+			fees { amount linkedTo { reference } }
+			review { id }
+		}
+	}`, map[string]any{"amount": "100"})
+
+	// Assert
+	require.Empty(t, result.Errors)
+	var data struct {
+		CreateTransaction struct {
+			Transaction struct {
+				ID        string              `json:"id"`
+				Reference string              `json:"reference"`
+				Metadata  []map[string]string `json:"metadata"`
+			} `json:"transaction"`
+			Fees []struct {
+				Amount   string            `json:"amount"`
+				LinkedTo map[string]string `json:"linkedTo"`
+			} `json:"fees"`
+			Review *struct{} `json:"review"`
+		} `json:"createTransaction"`
+	}
+	require.NoError(t, json.Unmarshal(result.Data, &data))
+	assert.Equal(t, "INV-1", data.CreateTransaction.Transaction.Reference)
+	assert.Equal(t, []map[string]string{{"key": "channel", "value": "pos"}},
+		data.CreateTransaction.Transaction.Metadata)
+	require.Len(t, data.CreateTransaction.Fees, 1)
+	assert.Equal(t, "-1.7", data.CreateTransaction.Fees[0].Amount)
+	assert.Equal(t, map[string]string{"reference": "INV-1"}, data.CreateTransaction.Fees[0].LinkedTo)
+	assert.Nil(t, data.CreateTransaction.Review)
+	balance, err := services.Ledger.GetBalance()
+	require.NoError(t, err)
+	assert.Equal(t, "98.3", balance.String())
+}
+
+func TestServer_Execute__ReportsErrorCodes(t *testing.T) {
+	cfg := config.Default()
+	cfg.GraphQL = config.GraphQL{MaxDepth: 5, MaxComplexity: 50}
+	testCases := map[string]struct {
+		query string
+		code  string
+	}{
+		"invalid amount": {
+			query: `mutation { createTransaction(input: {amount: "lots"}) { transaction { id } } }`,
+			code:  "validation_failed",
+		},
+		"invalid cursor": {
+			query: `{ account { transactions(after: "offset") { totalCount } } }`,
+			code:  "invalid_parameter",
+		},
+		"page too large": {
+			query: `{ account { transactions(first: 1000) { totalCount } } }`,
+			code:  "invalid_parameter",
+		},
+		"too deep": {
+			query: `{ account { transactions { edges { node { linkedTo { id } } } } } }`,
+			code:  "query_too_deep",
+		},
+		"too complex": {
+			query: `{ account { transactions(first: 20) { edges { cursor node { id amount } } } } }`,
+			code:  "query_too_complex",
+		},
+		"too complex through fragments": {
+			query: `{ account { transactions(last: 10) { edges { ...edge } } } }
+				fragment edge on TransactionEdge { cursor node { id amount createdAt } }`,
+			code: "query_too_complex",
+		},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			server, _ := newServer(t, cfg)
+
+			// Act
+			result := execute(t, server, testCase.query, nil)
+
+			// Assert
+			require.Len(t, result.Errors, 1)
+			assert.Equal(t, testCase.code, result.Errors[0].Extensions["code"], result.Errors[0].Message)
+		})
+	}
+}
+
+func TestServer_Execute__ReportsInvalidQueries(t *testing.T) {
+	// Arrange
+	server, _ := newServer(t, config.Default())
+
+	// Act
+	result := execute(t, server, `{ account { overdraft } }`, nil)
+
+	// Assert
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0].Message, `Cannot query field "overdraft"`)
+	assert.Equal(t, "null", string(result.Data))
+}
@@ -0,0 +1,69 @@
+package graphqlapi
+
+import (
+	"context"
+	"teya_home_assignment/internal/app/webserver/config"
+	"teya_home_assignment/internal/pkg/categories"
+	"teya_home_assignment/internal/pkg/ledger"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Server executes the GraphQL queries against the ledger, backed by the same services as the HTTP API
+type Server struct {
+	schema graphql.Schema
+	limits Limits
+}
+
+func NewServer(
+	ledgerService *ledger.Ledger,
+	categoriesService *categories.Categorizer,
+	cfg config.Config,
+	currency string,
+) (*Server, error) {
+	schema, err := NewSchema(ledgerService, categoriesService, cfg.Pagination, currency)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		schema: schema,
+		limits: Limits{
+			MaxDepth:        cfg.GraphQL.MaxDepth,
+			MaxComplexity:   cfg.GraphQL.MaxComplexity,
+			DefaultPageSize: cfg.Pagination.DefaultLimit,
+		},
+	}, nil
+}
+
+// Execute parses and validates the query, then executes the operation of the name, which may be empty when the
+// query holds a single operation. The operations over a limit are rejected without being executed.
+func (s *Server) Execute(
+	ctx context.Context,
+	query string,
+	operationName string,
+	variables map[string]any,
+) *graphql.Result {
+	document, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	validation := graphql.ValidateDocument(&s.schema, document, nil)
+	if !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+	if err := s.limits.check(document, operationName, variables); err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           document,
+		OperationName: operationName,
+		Args:          variables,
+		Context:       ctx,
+	})
+}
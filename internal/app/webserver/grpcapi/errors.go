@@ -0,0 +1,81 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"teya_home_assignment/internal/app/webserver/api"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// ErrorDomain is the domain of the ErrorInfo detail of the failed calls, whose reason is the error code of the HTTP
+// API, e.g. limit_exceeded
+const ErrorDomain = "ledger"
+
+// statusError converts the error a call failed with to a status, classified as by the HTTP API. The status carries
+// an ErrorInfo detail, along with a BadRequest detail listing the invalid fields if any.
+func statusError(ctx context.Context, err error) error {
+	problem := api.FromError(err)
+	code := statusCode(problem.Status)
+	if code == codes.Internal {
+		slog.ErrorContext(ctx, "call failed", "error", err)
+	} else {
+		slog.WarnContext(ctx, "call rejected", "code", problem.Code, "error", err)
+	}
+	message := problem.Detail
+	if message == "" {
+		message = problem.Title
+	}
+	info := &errdetails.ErrorInfo{Reason: string(problem.Code), Domain: ErrorDomain, Metadata: map[string]string{}}
+	if problem.Rule != "" {
+		info.Metadata["rule"] = problem.Rule
+	}
+	if problem.Limit != "" {
+		info.Metadata["limit"] = problem.Limit
+	}
+	if problem.RuleID != nil {
+		info.Metadata["rule_id"] = problem.RuleID.String()
+	}
+	details := []protoadapt.MessageV1{info}
+	if len(problem.Errors) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, fieldErr := range problem.Errors {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       fieldErr.Field,
+				Description: fieldErr.Message,
+			})
+		}
+		details = append(details, badRequest)
+	}
+	callStatus, detailsErr := status.New(code, message).WithDetails(details...)
+	if detailsErr != nil {
+		return status.Error(code, message)
+	}
+	return callStatus.Err()
+}
+
+// statusCode returns the status code matching the HTTP status of an error
+func statusCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusUnprocessableEntity, http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusRequestEntityTooLarge:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+	if httpStatus >= http.StatusInternalServerError {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
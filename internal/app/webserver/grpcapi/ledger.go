@@ -0,0 +1,226 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/app/webserver/config"
+	"teya_home_assignment/internal/app/webserver/controllers"
+	ledgerv1 "teya_home_assignment/internal/app/webserver/grpcapi/proto/ledger/v1"
+	"teya_home_assignment/internal/pkg/categories"
+	"teya_home_assignment/internal/pkg/feed"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/rules"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// LedgerServer serves the ledger over gRPC, backed by the same services as the HTTP API
+type LedgerServer struct {
+	ledgerv1.UnimplementedLedgerServiceServer
+	ledgerService     *ledger.Ledger
+	categoriesService *categories.Categorizer
+	feed              *feed.Feed
+	// pageSize bounds the number of transactions read from the ledger at once when listing them
+	pageSize int
+}
+
+func NewLedgerServer(services controllers.Services, pagination config.Pagination) *LedgerServer {
+	return &LedgerServer{
+		ledgerService:     services.Ledger,
+		categoriesService: services.Categories,
+		feed:              services.Feed,
+		pageSize:          pagination.MaxLimit,
+	}
+}
+
+func (s *LedgerServer) CreateTransaction(
+	ctx context.Context,
+	req *ledgerv1.CreateTransactionRequest,
+) (*ledgerv1.CreateTransactionResponse, error) {
+	// the requests are validated against the same rules as the HTTP API
+	reqBody := api.NewTransactionReqBody{
+		Amount:      req.GetAmount(),
+		Reference:   req.GetReference(),
+		Description: req.GetDescription(),
+		Metadata:    req.GetMetadata(),
+	}
+	var idempotencyKey string
+	if values := metadata.ValueFromIncomingContext(ctx, IdempotencyKeyMetadata); len(values) > 0 {
+		idempotencyKey = values[0]
+	}
+	transaction, err := api.ParseNewTransaction(reqBody, idempotencyKey)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	posted, err := s.ledgerService.PostTransactionContext(ctx, transaction.Amount, transaction.Options...)
+	var reviewErr *rules.ReviewRequiredError
+	if errors.As(err, &reviewErr) {
+		slog.InfoContext(ctx, "transaction held", "error", err)
+		return &ledgerv1.CreateTransactionResponse{
+			Result: &ledgerv1.CreateTransactionResponse_Review{Review: s.toReview(reviewErr.Review)},
+		}, nil
+	}
+	if errors.Is(err, ledger.ErrDuplicateTransaction) && transaction.IdempotencyKey != "" {
+		return s.replayTransaction(ctx, transaction, reqBody)
+	}
+	if err != nil {
+		return nil, statusError(ctx, errors.Wrap(err, "could not add transaction"))
+	}
+	slog.InfoContext(ctx, "successfully added transaction",
+		"id", posted[0].ExternalID, "amount", transaction.Amount, "fees", len(posted)-1)
+	return s.postedResponse(posted), nil
+}
+
+// replayTransaction answers a transaction submitted again with the idempotency key of a posted one with the
+// response it was posted with, provided it is the same transaction
+func (s *LedgerServer) replayTransaction(
+	ctx context.Context,
+	transaction api.NewTransaction,
+	reqBody api.NewTransactionReqBody,
+) (*ledgerv1.CreateTransactionResponse, error) {
+	posted, exists := s.ledgerService.GetPostedByIdempotencyKey(transaction.IdempotencyKey)
+	if !exists {
+		return nil, statusError(ctx,
+			errors.Errorf("could not find transaction of idempotency key %v", transaction.IdempotencyKey))
+	}
+	if err := api.CheckReplay(posted[0], transaction, reqBody); err != nil {
+		return nil, statusError(ctx, err)
+	}
+	slog.InfoContext(ctx, "replayed transaction", "id", posted[0].ExternalID)
+	if err := grpc.SetHeader(ctx, metadata.Pairs(IdempotentReplayedMetadata, "true")); err != nil {
+		return nil, err
+	}
+	return s.postedResponse(posted), nil
+}
+
+// postedResponse returns the posted transaction and its fees
+func (s *LedgerServer) postedResponse(posted []ledger.Transaction) *ledgerv1.CreateTransactionResponse {
+	result := &ledgerv1.PostedTransaction{Transaction: s.toTransaction(posted[0])}
+	for _, fee := range posted[1:] {
+		result.Fees = append(result.Fees, s.toTransaction(fee))
+	}
+	return &ledgerv1.CreateTransactionResponse{
+		Result: &ledgerv1.CreateTransactionResponse_Posted{Posted: result},
+	}
+}
+
+func (s *LedgerServer) ListTransactions(
+	req *ledgerv1.ListTransactionsRequest,
+	stream grpc.ServerStreamingServer[ledgerv1.Transaction],
+) error {
+	offset, remaining := int(req.GetOffset()), int(req.GetLimit())
+	// the transactions are read page by page, so the ledger is not locked for the whole stream
+	for {
+		size := s.pageSize
+		if req.GetLimit() > 0 {
+			size = min(size, remaining)
+		}
+		page, err := s.page(offset, size, req.GetCategory())
+		if err != nil {
+			return statusError(stream.Context(), errors.Wrap(err, "could not get transactions"))
+		}
+		for _, transaction := range page {
+			if err := stream.Send(s.toTransaction(transaction)); err != nil {
+				return err
+			}
+		}
+		offset += len(page)
+		remaining -= len(page)
+		if len(page) < size || (req.GetLimit() > 0 && remaining == 0) {
+			return nil
+		}
+	}
+}
+
+func (s *LedgerServer) page(offset, limit int, category string) ([]ledger.Transaction, error) {
+	if category == "" {
+		return s.ledgerService.GetTransactionHistory(offset, limit)
+	}
+	return s.ledgerService.FindTransactions(offset, limit, func(transaction ledger.Transaction) bool {
+		return s.categoriesService.Categorize(transaction).Name == category
+	})
+}
+
+func (s *LedgerServer) GetBalance(
+	ctx context.Context,
+	_ *ledgerv1.GetBalanceRequest,
+) (*ledgerv1.GetBalanceResponse, error) {
+	balance, err := s.ledgerService.GetBalanceContext(ctx)
+	if err != nil {
+		return nil, statusError(ctx, errors.Wrap(err, "could not get balance"))
+	}
+	slog.DebugContext(ctx, "successfully calculated balance", "balance", balance)
+	return &ledgerv1.GetBalanceResponse{Balance: balance.String(), Currency: controllers.LedgerCurrency}, nil
+}
+
+func (s *LedgerServer) WatchTransactions(
+	_ *ledgerv1.WatchTransactionsRequest,
+	stream grpc.ServerStreamingServer[ledgerv1.Transaction],
+) error {
+	subscription, err := s.feed.Subscribe()
+	if err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	defer subscription.Close()
+	// the headers tell the client the entries posted from now on are streamed
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case transaction, open := <-subscription.Entries():
+			if !open {
+				return watchError(subscription.Err())
+			}
+			if err := stream.Send(s.toTransaction(transaction)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watchError tells why a watch ended once its subscription was ended by the feed
+func watchError(err error) error {
+	if errors.Is(err, feed.ErrSlowSubscriber) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return status.Error(codes.Unavailable, "service is shutting down")
+}
+
+func (s *LedgerServer) toTransaction(transaction ledger.Transaction) *ledgerv1.Transaction {
+	resp := &ledgerv1.Transaction{
+		Id:          transaction.ExternalID.String(),
+		Amount:      transaction.Amount.String(),
+		CreatedAt:   timestamppb.New(transaction.CreatedAt),
+		Reference:   transaction.Reference,
+		Description: transaction.Description,
+		Metadata:    transaction.Metadata,
+		Category:    s.categoriesService.Categorize(transaction).Name,
+		Kind:        transaction.Kind,
+	}
+	if transaction.LinkedTo != uuid.Nil {
+		resp.LinkedTo = transaction.LinkedTo.String()
+	}
+	return resp
+}
+
+func (s *LedgerServer) toReview(review rules.Review) *ledgerv1.Review {
+	resp := &ledgerv1.Review{Id: review.ID.String(), Transaction: s.toTransaction(review.Transaction)}
+	for _, match := range review.Matches {
+		resp.Matches = append(resp.Matches, &ledgerv1.RuleMatch{
+			RuleId: match.RuleID.String(),
+			Name:   match.Name,
+			Action: string(match.Action),
+		})
+	}
+	return resp
+}
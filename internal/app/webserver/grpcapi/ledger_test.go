@@ -0,0 +1,261 @@
+package grpcapi_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"teya_home_assignment/internal/app/webserver/config"
+	"teya_home_assignment/internal/app/webserver/controllers"
+	"teya_home_assignment/internal/app/webserver/grpcapi"
+	ledgerv1 "teya_home_assignment/internal/app/webserver/grpcapi/proto/ledger/v1"
+	"teya_home_assignment/internal/pkg/health"
+	"teya_home_assignment/internal/pkg/metrics"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// withMerchantFees charges 1.5% + 0.20, at least 0.25, on the credits
+func withMerchantFees(t *testing.T, cfg config.Config) config.Config {
+	t.Helper()
+	require.NoError(t, cfg.Fees.Schedules.Set(`[{name: Merchant service charge, currency: EUR, applies_to: credits,
+  percentage: 0.015, fixed: 0.20, min: 0.25, rounding: half_up}]`))
+	return cfg
+}
+
+// newClient serves the ledger on an in-process listener and returns a client of it, along with the services
+// behind the server
+func newClient(t *testing.T, cfg config.Config, ready func() bool) (ledgerv1.LedgerServiceClient, controllers.Services) {
+	t.Helper()
+	_, services, err := controllers.InitControllers(cfg, metrics.New(), nil, health.NewChecker())
+	require.NoError(t, err)
+	listener := bufconn.Listen(1 << 20)
+	server := grpcapi.NewServer(ready, grpcapi.NewLedgerServer(services, cfg.Pagination))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return ledgerv1.NewLedgerServiceClient(conn), services
+}
+
+func ready() bool {
+	return true
+}
+
+func post(t *testing.T, services controllers.Services, amounts ...string) {
+	t.Helper()
+	for _, amount := range amounts {
+		require.NoError(t, services.Ledger.AddTransaction(decimal.RequireFromString(amount)))
+	}
+}
+
+// amounts receives the transactions of a stream until it ends
+func amounts(t *testing.T, stream grpc.ServerStreamingClient[ledgerv1.Transaction]) []string {
+	t.Helper()
+	var received []string
+	for {
+		transaction, err := stream.Recv()
+		if err == io.EOF {
+			return received
+		}
+		require.NoError(t, err)
+		received = append(received, transaction.GetAmount())
+	}
+}
+
+func TestLedgerServer_CreateTransaction__PostsToTheSharedLedger(t *testing.T) {
+	// Arrange
+	client, services := newClient(t, withMerchantFees(t, config.Default()), ready)
+
+	// Act
+	resp, err := client.CreateTransaction(context.Background(), &ledgerv1.CreateTransactionRequest{
+		Amount:    "100",
+		Reference: "INV-1",
+		Metadata:  map[string]string{"order": "42"},
+	})
+
+	// Assert
+	require.NoError(t, err)
+	posted := resp.GetPosted()
+	require.NotNil(t, posted)
+	assert.Equal(t, "100", posted.GetTransaction().GetAmount())
+	assert.Equal(t, "INV-1", posted.GetTransaction().GetReference())
+	assert.Equal(t, map[string]string{"order": "42"}, posted.GetTransaction().GetMetadata())
+	require.Len(t, posted.GetFees(), 1)
+	assert.Equal(t, "-1.7", posted.GetFees()[0].GetAmount())
+	assert.Equal(t, posted.GetTransaction().GetId(), posted.GetFees()[0].GetLinkedTo())
+	balance, err := client.GetBalance(context.Background(), &ledgerv1.GetBalanceRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "98.3", balance.GetBalance())
+	assert.Equal(t, controllers.LedgerCurrency, balance.GetCurrency())
+	assert.Equal(t, 2, services.Ledger.GetStats().TransactionCount)
+}
+
+func TestLedgerServer_CreateTransaction__ReplaysSameIdempotencyKey(t *testing.T) {
+	// Arrange
+	client, services := newClient(t, withMerchantFees(t, config.Default()), ready)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), grpcapi.IdempotencyKeyMetadata, "order-1")
+	req := &ledgerv1.CreateTransactionRequest{Amount: "100", Reference: "INV-1"}
+	first, err := client.CreateTransaction(ctx, req)
+	require.NoError(t, err)
+	var header metadata.MD
+
+	// Act
+	replayed, err := client.CreateTransaction(ctx, req, grpc.Header(&header))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, first.GetPosted().GetTransaction().GetId(), replayed.GetPosted().GetTransaction().GetId())
+	require.Len(t, replayed.GetPosted().GetFees(), 1)
+	assert.Equal(t, first.GetPosted().GetFees()[0].GetId(), replayed.GetPosted().GetFees()[0].GetId())
+	assert.Equal(t, []string{"true"}, header.Get(grpcapi.IdempotentReplayedMetadata))
+	assert.Equal(t, 2, services.Ledger.GetStats().TransactionCount)
+}
+
+func TestLedgerServer_CreateTransaction__RejectsReusedIdempotencyKey(t *testing.T) {
+	testCases := map[string]struct {
+		key  string
+		code codes.Code
+	}{
+		"reused key":  {key: "order-1", code: codes.FailedPrecondition},
+		"invalid key": {key: "order 1", code: codes.InvalidArgument},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			client, services := newClient(t, config.Default(), ready)
+			ctx := metadata.AppendToOutgoingContext(context.Background(), grpcapi.IdempotencyKeyMetadata, "order-1")
+			_, err := client.CreateTransaction(ctx, &ledgerv1.CreateTransactionRequest{Amount: "100"})
+			require.NoError(t, err)
+			ctx = metadata.AppendToOutgoingContext(context.Background(), grpcapi.IdempotencyKeyMetadata, testCase.key)
+
+			// Act
+			_, err = client.CreateTransaction(ctx, &ledgerv1.CreateTransactionRequest{Amount: "200"})
+
+			// Assert
+			assert.Equal(t, testCase.code, status.Code(err))
+			assert.Equal(t, 1, services.Ledger.GetStats().TransactionCount)
+		})
+	}
+}
+
+func TestLedgerServer_CreateTransaction__ReportsInvalidFields(t *testing.T) {
+	// Arrange
+	client, _ := newClient(t, config.Default(), ready)
+
+	// Act
+	_, err := client.CreateTransaction(context.Background(), &ledgerv1.CreateTransactionRequest{
+		Reference: strings.Repeat("r", 36),
+	})
+
+	// Assert
+	callStatus := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, callStatus.Code())
+	var info *errdetails.ErrorInfo
+	var badRequest *errdetails.BadRequest
+	for _, detail := range callStatus.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.ErrorInfo:
+			info = detail
+		case *errdetails.BadRequest:
+			badRequest = detail
+		}
+	}
+	require.NotNil(t, info)
+	assert.Equal(t, "validation_failed", info.GetReason())
+	assert.Equal(t, grpcapi.ErrorDomain, info.GetDomain())
+	require.NotNil(t, badRequest)
+	var fields []string
+	for _, violation := range badRequest.GetFieldViolations() {
+		fields = append(fields, violation.GetField())
+	}
+	assert.Equal(t, []string{"amount", "reference"}, fields)
+}
+
+func TestLedgerServer_ListTransactions__StreamsEveryPage(t *testing.T) {
+	// Arrange
+	cfg := config.Default()
+	cfg.Pagination = config.Pagination{DefaultLimit: 1, MaxLimit: 2}
+	client, services := newClient(t, cfg, ready)
+	post(t, services, "-1", "-2", "-3", "-4", "-5")
+
+	// Act
+	limited, err := client.ListTransactions(context.Background(), &ledgerv1.ListTransactionsRequest{Offset: 1, Limit: 3})
+	require.NoError(t, err)
+	unlimited, err := client.ListTransactions(context.Background(), &ledgerv1.ListTransactionsRequest{Offset: 1})
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, []string{"-2", "-3", "-4"}, amounts(t, limited))
+	assert.Equal(t, []string{"-2", "-3", "-4", "-5"}, amounts(t, unlimited))
+}
+
+func TestLedgerServer_WatchTransactions__StreamsPostedEntries(t *testing.T) {
+	// Arrange
+	client, services := newClient(t, withMerchantFees(t, config.Default()), ready)
+	post(t, services, "-1")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.WatchTransactions(ctx, &ledgerv1.WatchTransactionsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	// Act
+	post(t, services, "-2", "10")
+
+	// Assert
+	var received []string
+	for range 3 {
+		transaction, err := stream.Recv()
+		require.NoError(t, err)
+		received = append(received, transaction.GetAmount())
+	}
+	assert.Equal(t, []string{"-2", "10", "-0.35"}, received)
+}
+
+func TestLedgerServer_WatchTransactions__EndsWhenFeedCloses(t *testing.T) {
+	// Arrange
+	client, services := newClient(t, config.Default(), ready)
+	stream, err := client.WatchTransactions(context.Background(), &ledgerv1.WatchTransactionsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	// Act
+	services.Feed.Close()
+	_, err = stream.Recv()
+
+	// Assert
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestNewServer__RejectsCallsUntilReady(t *testing.T) {
+	// Arrange
+	client, _ := newClient(t, config.Default(), func() bool { return false })
+	ctx := metadata.AppendToOutgoingContext(context.Background(), grpcapi.RequestIDMetadata, "request-1")
+	var header metadata.MD
+
+	// Act
+	_, err := client.GetBalance(ctx, &ledgerv1.GetBalanceRequest{}, grpc.Header(&header))
+
+	// Assert
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, []string{"request-1"}, header.Get(grpcapi.RequestIDMetadata))
+}
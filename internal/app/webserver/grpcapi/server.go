@@ -0,0 +1,105 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	ledgerv1 "teya_home_assignment/internal/app/webserver/grpcapi/proto/ledger/v1"
+	"teya_home_assignment/internal/pkg/logging"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDMetadata carries the ID of the call, both in the request and in the response header, like the
+// X-Request-ID header of the HTTP API
+const RequestIDMetadata = "x-request-id"
+
+const (
+	// IdempotencyKeyMetadata carries the key making CreateTransaction safe to retry, like the Idempotency-Key header
+	// of the HTTP API
+	IdempotencyKeyMetadata = "idempotency-key"
+	// IdempotentReplayedMetadata is set in the response header of the calls replayed for a known idempotency key
+	IdempotentReplayedMetadata = "idempotent-replayed"
+)
+
+// NewServer creates the gRPC server of the ledger. Like the HTTP API, it rejects the calls with UNAVAILABLE until
+// ready returns true. The services are listed by the reflection service, so generic clients can call them.
+func NewServer(ready func() bool, ledgerServer ledgerv1.LedgerServiceServer) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(func(
+			ctx context.Context,
+			req any,
+			info *grpc.UnaryServerInfo,
+			handler grpc.UnaryHandler,
+		) (resp any, err error) {
+			err = intercept(ctx, info.FullMethod, ready, func(header metadata.MD) error {
+				return grpc.SetHeader(ctx, header)
+			}, func(ctx context.Context) error {
+				resp, err = handler(ctx, req)
+				return err
+			})
+			return resp, err
+		}),
+		grpc.ChainStreamInterceptor(func(
+			srv any,
+			stream grpc.ServerStream,
+			info *grpc.StreamServerInfo,
+			handler grpc.StreamHandler,
+		) error {
+			handle := func(ctx context.Context) error {
+				return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+			}
+			return intercept(stream.Context(), info.FullMethod, ready, stream.SetHeader, handle)
+		}),
+	)
+	ledgerv1.RegisterLedgerServiceServer(server, ledgerServer)
+	reflection.Register(server)
+	return server
+}
+
+// intercept tags the call with a request ID, rejects it until the server is ready, then logs it once handled
+func intercept(
+	ctx context.Context,
+	method string,
+	ready func() bool,
+	setHeader func(metadata.MD) error,
+	handle func(ctx context.Context) error,
+) error {
+	start := time.Now()
+	var requestID string
+	if values := metadata.ValueFromIncomingContext(ctx, RequestIDMetadata); len(values) > 0 {
+		requestID = values[0]
+	}
+	if !logging.IsValidRequestID(requestID) {
+		requestID = uuid.NewString()
+	}
+	ctx = logging.WithRequestID(ctx, requestID)
+	if err := setHeader(metadata.Pairs(RequestIDMetadata, requestID)); err != nil {
+		slog.WarnContext(ctx, "failed to send request ID", "error", err)
+	}
+	err := status.Error(codes.Unavailable, "service is starting")
+	if ready() {
+		err = handle(ctx)
+	}
+	slog.InfoContext(ctx, "handled call",
+		"method", method,
+		"code", status.Code(err).String(),
+		"duration", time.Since(start),
+	)
+	return err
+}
+
+// contextStream is a server stream whose context carries the request ID of the call
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
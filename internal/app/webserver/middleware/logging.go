@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"log/slog"
+	"strings"
+	"teya_home_assignment/internal/pkg/logging"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the ID of the request, both in the request and in the response
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID tags every request with an ID, returned in the response header and attached to every log line
+// written for the request. The ID sent by the client is kept if valid, so requests can be traced across services.
+func RequestID() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		// fiber reuses the request buffers once the handler returned, the ID may outlive it
+		requestID := strings.Clone(ctx.Get(RequestIDHeader))
+		if !logging.IsValidRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		ctx.Set(RequestIDHeader, requestID)
+		ctx.SetUserContext(logging.WithRequestID(ctx.UserContext(), requestID))
+		return ctx.Next()
+	}
+}
+
+// AccessLog logs every request once handled, along with its status and duration
+func AccessLog() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		start := time.Now()
+		err := ctx.Next()
+		slog.InfoContext(ctx.UserContext(), "handled request",
+			"method", ctx.Method(),
+			"path", ctx.Path(),
+			"status", responseStatus(ctx, err),
+			"duration", time.Since(start),
+		)
+		return err
+	}
+}
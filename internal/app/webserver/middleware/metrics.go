@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"strings"
+	"teya_home_assignment/internal/pkg/metrics"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Metrics records the count and latency of the requests, by the route pattern they matched
+func Metrics(metricsService *metrics.Metrics) fiber.Handler {
+	routes := &routeSet{}
+	return func(ctx *fiber.Ctx) error {
+		start := time.Now()
+		err := ctx.Next()
+		// fiber reuses the request buffers, the labels outlive the request
+		metricsService.ObserveRequest(strings.Clone(ctx.Method()), routes.pattern(ctx),
+			responseStatus(ctx, err), time.Since(start))
+		return err
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Ready rejects the requests with 503 Service Unavailable until the webserver is ready to serve them.
+// The requests to the exempted path prefixes, like the probes, are always served.
+func Ready(ready func() bool, exempt ...string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if ready() {
+			return ctx.Next()
+		}
+		for _, prefix := range exempt {
+			if strings.HasPrefix(ctx.Path(), prefix) {
+				return ctx.Next()
+			}
+		}
+		return fiber.NewError(fiber.StatusServiceUnavailable, "service is starting")
+	}
+}
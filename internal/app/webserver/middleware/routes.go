@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"sync"
+	"teya_home_assignment/internal/app/webserver/api"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// unmatchedRoute labels the requests matching no route, so unknown paths do not create new series
+const unmatchedRoute = "unmatched"
+
+// routeSet tells the route pattern a request matched, once handled. When no route matched, fiber reports the
+// last middleware the request went through instead, so the pattern is checked against the registered routes.
+type routeSet struct {
+	once   sync.Once
+	routes map[string]bool
+}
+
+func (s *routeSet) pattern(ctx *fiber.Ctx) string {
+	// the routes are all registered before the app starts serving
+	s.once.Do(func() {
+		s.routes = make(map[string]bool)
+		for _, route := range ctx.App().GetRoutes(true) {
+			s.routes[route.Method+" "+route.Path] = true
+		}
+	})
+	route := ctx.Route()
+	if !s.routes[route.Method+" "+route.Path] {
+		return unmatchedRoute
+	}
+	return route.Path
+}
+
+// responseStatus returns the status the response is sent with, once the error handler ran
+func responseStatus(ctx *fiber.Ctx, err error) int {
+	if err == nil {
+		return ctx.Response().StatusCode()
+	}
+	return api.FromError(err).Status
+}
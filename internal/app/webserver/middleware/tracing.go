@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("teya_home_assignment/internal/app/webserver")
+
+// Tracing starts a server span for every request, continuing the trace of the client when the request carries
+// a W3C trace context. The span is attached to the user context, so the layers called by the handlers are part
+// of the trace.
+func Tracing() fiber.Handler {
+	routes := &routeSet{}
+	return func(ctx *fiber.Ctx) error {
+		headers := propagation.MapCarrier{}
+		ctx.Request().Header.VisitAll(func(key, value []byte) {
+			headers[strings.ToLower(string(key))] = string(value)
+		})
+		parent := otel.GetTextMapPropagator().Extract(ctx.UserContext(), headers)
+		method := strings.Clone(ctx.Method())
+		spanCtx, span := tracer.Start(parent, method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", method),
+				attribute.String("url.path", strings.Clone(ctx.Path())),
+			),
+		)
+		defer span.End()
+		ctx.SetUserContext(spanCtx)
+
+		err := ctx.Next()
+		route := routes.pattern(ctx)
+		status := responseStatus(ctx, err)
+		span.SetName(method + " " + route)
+		span.SetAttributes(
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", status),
+		)
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+		}
+		if err != nil {
+			span.RecordError(err)
+		}
+		return err
+	}
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"teya_home_assignment/internal/app/webserver/config"
+	"teya_home_assignment/internal/app/webserver/controllers"
+	"teya_home_assignment/internal/app/webserver/grpcapi"
+	"teya_home_assignment/internal/app/webserver/middleware"
+	"teya_home_assignment/internal/pkg/health"
+	"teya_home_assignment/internal/pkg/journal"
+	"teya_home_assignment/internal/pkg/metrics"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// MetricsRoute serves the metrics to Prometheus, outside of the versioned API
+const MetricsRoute = "/metrics"
+
+// ErrShutdownTimeout is returned when requests were still in flight once the shutdown timeout elapsed
+var ErrShutdownTimeout = errors.New("requests still in flight after shutdown timeout")
+
+// errRestoring is the status of the ledger until it was restored
+var errRestoring = errors.New("ledger is being restored")
+
+// Server is the webserver along with the services behind its HTTP and gRPC APIs
+type Server struct {
+	config   config.Config
+	app      *fiber.App
+	grpc     *grpc.Server
+	journal  *journal.Journal
+	services controllers.Services
+	checker  *health.Checker
+	// startMu is held while starting, so the workers are not stopped while being started
+	startMu sync.Mutex
+	started atomic.Bool
+}
+
+// New opens the storage and creates the services and the APIs. The APIs serve once the server was started.
+func New(cfg config.Config) (*Server, error) {
+	s := &Server{config: cfg, checker: health.NewChecker()}
+	var store controllers.Store
+	if cfg.Storage.Backend == config.StorageFile {
+		ledgerJournal, err := journal.Open(cfg.Storage.DataDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open storage")
+		}
+		s.journal = ledgerJournal
+		store = ledgerJournal
+	}
+
+	s.app = fiber.New(fiber.Config{
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		ErrorHandler: controllers.ErrorHandler,
+	})
+	metricsService := metrics.New()
+	s.app.Use(
+		middleware.Tracing(),
+		middleware.RequestID(),
+		middleware.AccessLog(),
+		middleware.Metrics(metricsService),
+		middleware.Ready(s.started.Load, MetricsRoute, controllers.APIRouteBasePath+controllers.HealthRoute),
+	)
+	s.app.Get(MetricsRoute, adaptor.HTTPHandler(metricsService.Handler()))
+	apiControllers, services, err := controllers.InitControllers(cfg, metricsService, store, s.checker)
+	if err != nil {
+		s.closeStorage()
+		return nil, errors.Wrap(err, "failed to set up controllers")
+	}
+	s.services = services
+	if err := controllers.SetupRoutes(s.app.Group(controllers.APIRouteBasePath), apiControllers); err != nil {
+		s.closeStorage()
+		return nil, errors.Wrap(err, "failed to set up routes")
+	}
+	s.grpc = grpcapi.NewServer(s.started.Load, grpcapi.NewLedgerServer(services, cfg.Pagination))
+	s.registerChecks()
+	return s, nil
+}
+
+// registerChecks registers the components the readiness of the webserver depends on
+func (s *Server) registerChecks() {
+	s.checker.Register("ledger", func() error {
+		if !s.started.Load() {
+			return errRestoring
+		}
+		return nil
+	})
+	if ledgerJournal := s.journal; ledgerJournal != nil {
+		s.checker.Register("storage", ledgerJournal.Check)
+	}
+	for name, worker := range s.services.Workers {
+		s.checker.Register(name, func() error {
+			if !worker.Running() {
+				return errors.Errorf("%v is not running", name)
+			}
+			return nil
+		})
+	}
+}
+
+// Start restores the ledger from the storage and starts the background workers, then the APIs serve
+func (s *Server) Start() error {
+	s.startMu.Lock()
+	defer s.startMu.Unlock()
+	if s.started.Load() {
+		return nil
+	}
+	slog.Info("restoring ledger")
+	if err := s.services.Restore(); err != nil {
+		return errors.Wrap(err, "failed to start")
+	}
+	slog.Info("restored ledger", "transactions", s.services.Ledger.GetStats().TransactionCount)
+	for name, worker := range s.services.Workers {
+		slog.Info("starting worker", "worker", name)
+		worker.Start()
+	}
+	s.started.Store(true)
+	return nil
+}
+
+// App returns the API, mostly useful to test it
+func (s *Server) App() *fiber.App {
+	return s.app
+}
+
+// Run serves the APIs on the configured addresses until the context is done, then shuts down. The gRPC API is
+// only served when its address is configured.
+func (s *Server) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.config.Server.ListenAddress)
+	if err != nil {
+		s.stopWorkers()
+		s.closeStorage()
+		return errors.Wrap(err, "failed to listen")
+	}
+	var grpcListener net.Listener
+	if s.config.Server.GRPCListenAddress != "" {
+		grpcListener, err = net.Listen("tcp", s.config.Server.GRPCListenAddress)
+		if err != nil {
+			listener.Close()
+			s.stopWorkers()
+			s.closeStorage()
+			return errors.Wrap(err, "failed to listen for gRPC")
+		}
+	}
+	return s.Serve(ctx, listener, grpcListener)
+}
+
+// Serve starts the server and serves the HTTP API on the listener, and the gRPC API on grpcListener unless nil,
+// until the context is done, then shuts down. The probes are served while the server starts.
+func (s *Server) Serve(ctx context.Context, listener, grpcListener net.Listener) error {
+	served := make(chan error, 2)
+	servers := 1
+	go func() {
+		served <- errors.Wrap(s.app.Listener(listener), "failed to serve")
+	}()
+	if grpcListener != nil {
+		servers++
+		slog.Info("serving gRPC API", "address", grpcListener.Addr().String())
+		go func() {
+			served <- errors.Wrap(s.grpc.Serve(grpcListener), "failed to serve gRPC")
+		}()
+	}
+	started := make(chan error, 1)
+	go func() {
+		started <- s.Start()
+	}()
+	var startErr, serveErr error
+	select {
+	case serveErr = <-served:
+		servers--
+	case startErr = <-started:
+		if startErr == nil {
+			select {
+			case serveErr = <-served:
+				servers--
+			case <-ctx.Done():
+			}
+		}
+	case <-ctx.Done():
+	}
+	slog.Info("shutting down", "timeout", s.config.Server.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.Server.ShutdownTimeout)
+	defer cancel()
+	err := s.Shutdown(shutdownCtx)
+	// the listeners may not be served yet when the context is done early, closing them makes sure they never are
+	listener.Close()
+	if grpcListener != nil {
+		grpcListener.Close()
+	}
+	for ; servers > 0; servers-- {
+		<-served
+	}
+	if serveErr != nil {
+		return serveErr
+	}
+	if startErr != nil {
+		return startErr
+	}
+	return err
+}
+
+// Shutdown stops accepting connections and waits for the requests and calls in flight to complete until the
+// context is done. The watches of the ledger are ended first, as they never complete. The background workers are
+// then stopped and the storage flushed and closed, even if requests are still in flight: they fail rather than
+// being acknowledged without being persisted.
+func (s *Server) Shutdown(ctx context.Context) error {
+	var shutdownErr error
+	s.services.Feed.Close()
+	if err := s.app.ShutdownWithContext(ctx); err != nil {
+		shutdownErr = errors.Wrap(err, "failed to shut down webserver")
+		if errors.Is(err, context.DeadlineExceeded) {
+			shutdownErr = ErrShutdownTimeout
+		}
+	}
+	if err := s.stopGRPC(ctx); err != nil && shutdownErr == nil {
+		shutdownErr = err
+	}
+	s.stopWorkers()
+	if err := s.closeStorage(); err != nil && shutdownErr == nil {
+		shutdownErr = err
+	}
+	return shutdownErr
+}
+
+// stopGRPC waits for the calls in flight to complete until the context is done, then cancels them
+func (s *Server) stopGRPC(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.grpc.Stop()
+		<-stopped
+		return ErrShutdownTimeout
+	}
+}
+
+func (s *Server) stopWorkers() {
+	s.startMu.Lock()
+	defer s.startMu.Unlock()
+	for _, worker := range s.services.Workers {
+		worker.Stop()
+	}
+}
+
+func (s *Server) closeStorage() error {
+	if s.journal == nil {
+		return nil
+	}
+	err := s.journal.Close()
+	s.journal = nil
+	if err != nil {
+		return errors.Wrap(err, "failed to close storage")
+	}
+	return nil
+}
@@ -0,0 +1,523 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"teya_home_assignment/internal/app/webserver/api"
+	"teya_home_assignment/internal/app/webserver/config"
+	"teya_home_assignment/internal/app/webserver/controllers"
+	ledgerv1 "teya_home_assignment/internal/app/webserver/grpcapi/proto/ledger/v1"
+	"teya_home_assignment/internal/app/webserver/server"
+	"teya_home_assignment/internal/pkg/openapi"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// withMerchantFees charges 1.5% + 0.20, at least 0.25, on the credits
+func withMerchantFees(t *testing.T, cfg config.Config) config.Config {
+	t.Helper()
+	require.NoError(t, cfg.Fees.Schedules.Set(`[{name: Merchant service charge, currency: EUR, applies_to: credits,
+  percentage: 0.015, fixed: 0.20, min: 0.25, rounding: half_up}]`))
+	return cfg
+}
+
+func fileConfig(t *testing.T, dataDir string) config.Config {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Storage = config.Storage{Backend: config.StorageFile, DataDir: dataDir}
+	require.NoError(t, cfg.Validate())
+	return cfg
+}
+
+func TestServer_Shutdown__KeepsAcceptedTransactions(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	webserver, err := server.New(fileConfig(t, dataDir))
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, shutdown := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- webserver.Serve(ctx, listener, nil)
+	}()
+	resp, err := http.Post(fmt.Sprintf("http://%v/api/v1/transaction", listener.Addr()), "application/json",
+		strings.NewReader(`{"amount": "100", "reference": "before shutdown"}`))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// Act
+	shutdown()
+	require.NoError(t, <-served)
+	restarted, err := server.New(fileConfig(t, dataDir))
+	require.NoError(t, err)
+	require.NoError(t, restarted.Start())
+	defer restarted.Shutdown(context.Background())
+
+	// Assert
+	resp, err = restarted.App().Test(httptest.NewRequest(http.MethodGet, "/api/v1/transaction?offset=0", nil))
+	require.NoError(t, err)
+	var page struct {
+		Transactions []struct {
+			Amount    string `json:"amount"`
+			Reference string `json:"reference"`
+		} `json:"transactions"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	require.NotEmpty(t, page.Transactions)
+	assert.Equal(t, "100", page.Transactions[0].Amount)
+	assert.Equal(t, "before shutdown", page.Transactions[0].Reference)
+}
+
+func TestServer_Shutdown__StopsAcceptingConnections(t *testing.T) {
+	// Arrange
+	webserver, err := server.New(config.Default())
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, shutdown := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- webserver.Serve(ctx, listener, nil)
+	}()
+
+	// Act
+	shutdown()
+	err = <-served
+
+	// Assert
+	require.NoError(t, err)
+	_, err = http.Get(fmt.Sprintf("http://%v/api/v1/health", listener.Addr()))
+	assert.Error(t, err)
+}
+
+func TestServer_Shutdown__EndsGRPCWatches(t *testing.T) {
+	// Arrange
+	webserver, err := server.New(config.Default())
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	grpcListener := bufconn.Listen(1 << 20)
+	ctx, shutdown := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- webserver.Serve(ctx, listener, grpcListener)
+	}()
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return grpcListener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := ledgerv1.NewLedgerServiceClient(conn)
+	// the calls are rejected until the server started
+	require.Eventually(t, func() bool {
+		_, err := client.GetBalance(context.Background(), &ledgerv1.GetBalanceRequest{})
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	stream, err := client.WatchTransactions(context.Background(), &ledgerv1.WatchTransactionsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	// Act
+	shutdown()
+	_, watchErr := stream.Recv()
+
+	// Assert
+	require.NoError(t, <-served)
+	assert.Equal(t, codes.Unavailable, status.Code(watchErr))
+}
+
+func readiness(t *testing.T, webserver *server.Server) (int, api.HealthRespBody) {
+	t.Helper()
+	resp, err := webserver.App().Test(httptest.NewRequest(http.MethodGet, "/api/v1/health/ready", nil))
+	require.NoError(t, err)
+	var body api.HealthRespBody
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return resp.StatusCode, body
+}
+
+func TestServer_Readiness__UnavailableUntilStarted(t *testing.T) {
+	// Arrange
+	webserver, err := server.New(fileConfig(t, t.TempDir()))
+	require.NoError(t, err)
+	defer webserver.Shutdown(context.Background())
+
+	// Act
+	statusBefore, bodyBefore := readiness(t, webserver)
+	apiResp, err := webserver.App().Test(httptest.NewRequest(http.MethodGet, "/api/v1/account", nil))
+	require.NoError(t, err)
+	liveResp, err := webserver.App().Test(httptest.NewRequest(http.MethodGet, "/api/v1/health/live", nil))
+	require.NoError(t, err)
+	require.NoError(t, webserver.Start())
+	statusAfter, bodyAfter := readiness(t, webserver)
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, statusBefore)
+	assert.Equal(t, "down", bodyBefore.Status)
+	assert.Equal(t, "down", bodyBefore.Components["ledger"].Status)
+	assert.Equal(t, "down", bodyBefore.Components["scheduler"].Status)
+	assert.Equal(t, "up", bodyBefore.Components["storage"].Status)
+	assert.Equal(t, http.StatusServiceUnavailable, apiResp.StatusCode)
+	assert.Equal(t, api.CodeServiceUnavailable, problem(t, apiResp).Code)
+	assert.Equal(t, http.StatusOK, liveResp.StatusCode)
+	assert.Equal(t, http.StatusOK, statusAfter)
+	assert.Equal(t, "up", bodyAfter.Status)
+	for name, component := range bodyAfter.Components {
+		assert.Equal(t, "up", component.Status, name)
+	}
+}
+
+func TestServer_Start__RestoresEntriesWithoutCountingThemAsPosted(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	webserver, err := server.New(withMerchantFees(t, fileConfig(t, dataDir)))
+	require.NoError(t, err)
+	require.NoError(t, webserver.Start())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transaction", strings.NewReader(`{"amount": "100"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := webserver.App().Test(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.NoError(t, webserver.Shutdown(context.Background()))
+
+	// Act
+	restarted, err := server.New(withMerchantFees(t, fileConfig(t, dataDir)))
+	require.NoError(t, err)
+	require.NoError(t, restarted.Start())
+	defer restarted.Shutdown(context.Background())
+
+	// Assert
+	resp, err = restarted.App().Test(httptest.NewRequest(http.MethodGet, server.MetricsRoute, nil))
+	require.NoError(t, err)
+	scraped, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.NotContains(t, string(scraped), "ledger_transactions_posted_total{")
+	// the report is rebuilt from the restored payment and its fee
+	today := time.Now().UTC()
+	resp, err = restarted.App().Test(httptest.NewRequest(http.MethodGet, fmt.Sprintf(
+		"/api/v1/report/aggregate?interval=day&from=%v&to=%v",
+		today.AddDate(0, 0, -1).Format(time.DateOnly), today.AddDate(0, 0, 1).Format(time.DateOnly)), nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var aggregate api.AggregateReportRespBody
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&aggregate))
+	count := 0
+	for _, bucket := range aggregate.Buckets {
+		count += bucket.Count
+	}
+	assert.Equal(t, 2, count)
+}
+
+func TestServer_Readiness__ReportsUnwritableStorage(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	webserver, err := server.New(fileConfig(t, dataDir))
+	require.NoError(t, err)
+	require.NoError(t, webserver.Start())
+	defer webserver.Shutdown(context.Background())
+
+	// Act
+	require.NoError(t, os.RemoveAll(dataDir))
+	status, body := readiness(t, webserver)
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "down", body.Components["storage"].Status)
+	assert.NotEmpty(t, body.Components["storage"].Error)
+}
+
+func TestServer_Start__PostsInterestOnlyWhenConfigured(t *testing.T) {
+	// Arrange
+	withInterest := config.Default()
+	withInterest.Interest.OverdraftRate = decimal.RequireFromString("0.18")
+	testCases := map[string]struct {
+		cfg     config.Config
+		enabled bool
+	}{
+		"default":        {cfg: config.Default()},
+		"overdraft rate": {cfg: withInterest, enabled: true},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			webserver, err := server.New(testCase.cfg)
+			require.NoError(t, err)
+			require.NoError(t, webserver.Start())
+			defer webserver.Shutdown(context.Background())
+
+			// Act
+			_, body := readiness(t, webserver)
+			resp, err := webserver.App().Test(httptest.NewRequest(http.MethodGet,
+				"/api/v1/interest/accrual?from=2025-01-01&to=2025-01-31", nil))
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, testCase.enabled, body.Components["interest_engine"].Status == "up")
+			if testCase.enabled {
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+			} else {
+				assert.NotContains(t, body.Components, "interest_engine")
+				assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestServer_OpenAPI__DocumentsEveryRoute(t *testing.T) {
+	// Arrange
+	cfg := config.Default()
+	cfg.Interest.OverdraftRate = decimal.RequireFromString("0.18")
+	webserver, err := server.New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, webserver.Start())
+	defer webserver.Shutdown(context.Background())
+	var routes []string
+	for _, route := range webserver.App().GetRoutes(true) {
+		path, isAPIRoute := strings.CutPrefix(route.Path, controllers.APIRouteBasePath)
+		// fiber registers a HEAD route along each GET route
+		if !isAPIRoute || route.Method == http.MethodHead {
+			continue
+		}
+		routes = append(routes, route.Method+" "+openapi.PathOf(path))
+	}
+	slices.Sort(routes)
+
+	// Act
+	resp, err := webserver.App().Test(httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var document openapi.Document
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&document))
+
+	// Assert
+	assert.Equal(t, routes, document.Operations())
+}
+
+func problem(t *testing.T, resp *http.Response) api.Problem {
+	t.Helper()
+	assert.Equal(t, api.MIMEApplicationProblemJSON, resp.Header.Get(fiber.HeaderContentType))
+	var body api.Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, resp.StatusCode, body.Status)
+	return body
+}
+
+func TestServer_Transactions__LinksPages(t *testing.T) {
+	// Arrange
+	webserver, err := server.New(withMerchantFees(t, config.Default()))
+	require.NoError(t, err)
+	require.NoError(t, webserver.Start())
+	defer webserver.Shutdown(context.Background())
+	for range 3 {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/transaction", strings.NewReader(`{"amount": "100"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := webserver.App().Test(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+
+	// Act
+	resp, err := webserver.App().Test(httptest.NewRequest(http.MethodGet, "/api/v1/transaction?offset=2&limit=2", nil))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var body api.PaginatedTransactionsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Len(t, body.Transactions, 2)
+	assert.Equal(t, api.Pagination{
+		Offset:  2,
+		Limit:   2,
+		Total:   6,
+		HasMore: true,
+		Next:    "/api/v1/transaction?limit=2&offset=4",
+		Prev:    "/api/v1/transaction?limit=2&offset=0",
+	}, body.Pagination)
+	assert.Equal(t,
+		`</api/v1/transaction?limit=2&offset=4>; rel="next",</api/v1/transaction?limit=2&offset=0>; rel="prev"`,
+		resp.Header.Get(fiber.HeaderLink))
+}
+
+func TestServer_Errors__ReportsInvalidFieldsByJSONName(t *testing.T) {
+	// Arrange
+	webserver, err := server.New(config.Default())
+	require.NoError(t, err)
+	require.NoError(t, webserver.Start())
+	defer webserver.Shutdown(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transaction",
+		strings.NewReader(`{"reference": "`+strings.Repeat("r", 36)+`"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.Header.Set(fiber.HeaderXRequestID, "request-1")
+
+	// Act
+	resp, err := webserver.App().Test(req)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	body := problem(t, resp)
+	assert.Equal(t, api.CodeValidationFailed, body.Code)
+	assert.Equal(t, "urn:problem-type:ledger:validation_failed", body.Type)
+	assert.Equal(t, "/api/v1/transaction", body.Instance)
+	assert.Equal(t, "request-1", body.RequestID)
+	assert.Equal(t, []api.FieldError{
+		{Field: "amount", Code: "required", Message: "is required"},
+		{Field: "reference", Code: "max", Message: "must be at most 35 characters long"},
+	}, body.Errors)
+}
+
+func TestServer_Transactions__NormalizesAmounts(t *testing.T) {
+	// Arrange
+	webserver, err := server.New(config.Default())
+	require.NoError(t, err)
+	require.NoError(t, webserver.Start())
+	defer webserver.Shutdown(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transaction", strings.NewReader(`{"amount": "-10.250"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	// Act
+	resp, err := webserver.App().Test(req)
+
+	// Assert
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var body api.NewTransactionRespBody
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "-10.25", body.Transaction.Amount.String())
+	assert.Empty(t, body.Fees, "debits are not charged")
+}
+
+func TestServer_Errors__ReportsInvalidAmounts(t *testing.T) {
+	testCases := map[string]string{
+		"0.00":          "nonzero",
+		"1000000000.01": "max_magnitude",
+		"0.001":         "max_places",
+		"1e6":           "numeric",
+	}
+	for amount, code := range testCases {
+		t.Run(amount, func(t *testing.T) {
+			// Arrange
+			webserver, err := server.New(config.Default())
+			require.NoError(t, err)
+			require.NoError(t, webserver.Start())
+			defer webserver.Shutdown(context.Background())
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/transaction",
+				strings.NewReader(`{"amount": "`+amount+`"}`))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+			// Act
+			resp, err := webserver.App().Test(req)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			body := problem(t, resp)
+			assert.Equal(t, api.CodeValidationFailed, body.Code)
+			require.Len(t, body.Errors, 1)
+			assert.Equal(t, "amount", body.Errors[0].Field)
+			assert.Equal(t, code, body.Errors[0].Code)
+		})
+	}
+}
+
+func TestServer_Errors__MapsSentinelErrors(t *testing.T) {
+	// Arrange
+	webserver, err := server.New(config.Default())
+	require.NoError(t, err)
+	require.NoError(t, webserver.Start())
+	defer webserver.Shutdown(context.Background())
+
+	// Act
+	resp, err := webserver.App().Test(httptest.NewRequest(http.MethodGet,
+		"/api/v1/rules/3fa85f64-5717-4562-b3fc-2c963f66afa6", nil))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	body := problem(t, resp)
+	assert.Equal(t, api.CodeRuleNotFound, body.Code)
+	assert.Equal(t, "rule not found", body.Detail)
+}
+
+func TestServer_Errors__ReportsInvalidParameters(t *testing.T) {
+	// Arrange
+	webserver, err := server.New(config.Default())
+	require.NoError(t, err)
+	require.NoError(t, webserver.Start())
+	defer webserver.Shutdown(context.Background())
+
+	// Act
+	resp, err := webserver.App().Test(httptest.NewRequest(http.MethodGet, "/api/v1/transaction?offset=-1", nil))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	body := problem(t, resp)
+	assert.Equal(t, api.CodeInvalidParameter, body.Code)
+	require.Len(t, body.Errors, 1)
+	assert.Equal(t, "offset", body.Errors[0].Field)
+}
+
+func TestServer_Errors__ReportsInvalidIfMatch(t *testing.T) {
+	for _, ifMatch := range []string{`W/"0"`, `"0", "1"`, "0", `"version"`} {
+		t.Run(ifMatch, func(t *testing.T) {
+			// Arrange
+			webserver, err := server.New(config.Default())
+			require.NoError(t, err)
+			require.NoError(t, webserver.Start())
+			defer webserver.Shutdown(context.Background())
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/transaction", strings.NewReader(`{"amount": "100"}`))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			req.Header.Set(fiber.HeaderIfMatch, ifMatch)
+
+			// Act
+			resp, err := webserver.App().Test(req)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			body := problem(t, resp)
+			assert.Equal(t, api.CodeInvalidParameter, body.Code)
+			require.Len(t, body.Errors, 1)
+			assert.Equal(t, fiber.HeaderIfMatch, body.Errors[0].Field)
+		})
+	}
+}
+
+func TestServer_Errors__ReportsUnknownRoutes(t *testing.T) {
+	// Arrange
+	webserver, err := server.New(config.Default())
+	require.NoError(t, err)
+	require.NoError(t, webserver.Start())
+	defer webserver.Shutdown(context.Background())
+
+	// Act
+	resp, err := webserver.App().Test(httptest.NewRequest(http.MethodGet, "/api/v1/unknown", nil))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, api.CodeNotFound, problem(t, resp).Code)
+}
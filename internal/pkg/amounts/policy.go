@@ -0,0 +1,93 @@
+package amounts
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Reasons an amount is rejected for by the policy
+const (
+	ReasonZero         = "nonzero"
+	ReasonMaxMagnitude = "max_magnitude"
+	ReasonMaxPlaces    = "max_places"
+)
+
+// InvalidAmountError is returned for the amounts which do not comply with the policy
+type InvalidAmountError struct {
+	Amount decimal.Decimal
+	// Reason is one of the Reason constants
+	Reason string
+	// Limit is the magnitude or number of places exceeded, empty for the zero amounts
+	Limit string
+}
+
+func (e *InvalidAmountError) Error() string {
+	return fmt.Sprintf("invalid amount %v: %v", e.Amount, e.Message())
+}
+
+// Message tells why the amount was rejected, as reported to the clients
+func (e *InvalidAmountError) Message() string {
+	switch e.Reason {
+	case ReasonZero:
+		return "must not be zero"
+	case ReasonMaxMagnitude:
+		return fmt.Sprintf("must be between -%v and %v", e.Limit, e.Limit)
+	default:
+		return fmt.Sprintf("must have at most %v decimal places", e.Limit)
+	}
+}
+
+// Policy is what the amounts of the transactions posted to the ledger must comply with
+type Policy struct {
+	// MaxMagnitude is the largest absolute amount
+	MaxMagnitude decimal.Decimal
+	// Places is the largest number of decimal places, usually the minor unit of the currency, see Places
+	Places int32
+}
+
+// NewPolicy returns the policy of the amounts in the currency, up to the given magnitude and down to its minor unit
+func NewPolicy(currency string, maxMagnitude decimal.Decimal) (Policy, error) {
+	policy := Policy{MaxMagnitude: maxMagnitude, Places: Places(currency)}
+	if err := policy.Validate(); err != nil {
+		return Policy{}, err
+	}
+	return policy, nil
+}
+
+func (p Policy) Validate() error {
+	if !p.MaxMagnitude.IsPositive() {
+		return errors.New("max amount magnitude must be positive")
+	}
+	if p.Places < 0 {
+		return errors.New("amount decimal places must not be negative")
+	}
+	return nil
+}
+
+// Normalize returns the amount with exactly Places decimal places, so an amount is represented alike whatever the
+// representation it was requested in, e.g. 10.5, 10.50 or 1.05e1. The amounts which are zero, larger than
+// MaxMagnitude in absolute value or more precise than Places are rejected with an *InvalidAmountError, they are
+// never rounded.
+func (p Policy) Normalize(amount decimal.Decimal) (decimal.Decimal, error) {
+	if amount.IsZero() {
+		return decimal.Decimal{}, &InvalidAmountError{Amount: amount, Reason: ReasonZero}
+	}
+	if amount.Abs().GreaterThan(p.MaxMagnitude) {
+		return decimal.Decimal{}, &InvalidAmountError{
+			Amount: amount,
+			Reason: ReasonMaxMagnitude,
+			Limit:  p.MaxMagnitude.String(),
+		}
+	}
+	if !amount.Truncate(p.Places).Equal(amount) {
+		return decimal.Decimal{}, &InvalidAmountError{
+			Amount: amount,
+			Reason: ReasonMaxPlaces,
+			Limit:  fmt.Sprint(p.Places),
+		}
+	}
+	// the amount has no more places, rounding only rescales it
+	return amount.Round(p.Places), nil
+}
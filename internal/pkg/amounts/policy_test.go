@@ -0,0 +1,101 @@
+package amounts_test
+
+import (
+	"testing"
+	"teya_home_assignment/internal/pkg/amounts"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPolicy(t *testing.T, currency string) amounts.Policy {
+	t.Helper()
+	policy, err := amounts.NewPolicy(currency, decimal.NewFromInt(1000000))
+	require.NoError(t, err)
+	return policy
+}
+
+func TestPolicy_Normalize__RepresentsEqualAmountsAlike(t *testing.T) {
+	// Arrange
+	policy := newPolicy(t, "EUR")
+
+	// Act
+	normalized := make([]decimal.Decimal, 0, 3)
+	for _, amount := range []string{"10.5", "10.500", "1.05e1"} {
+		amount, err := policy.Normalize(decimal.RequireFromString(amount))
+		require.NoError(t, err)
+		normalized = append(normalized, amount)
+	}
+
+	// Assert
+	for _, amount := range normalized {
+		assert.Equal(t, decimal.New(1050, -2), amount)
+		assert.Equal(t, "10.5", amount.String())
+	}
+}
+
+func TestPolicy_Normalize__RejectsInvalidAmounts(t *testing.T) {
+	testCases := map[string]struct {
+		currency string
+		amount   string
+		reason   string
+	}{
+		"zero":                      {currency: "EUR", amount: "0.00", reason: amounts.ReasonZero},
+		"over max magnitude":        {currency: "EUR", amount: "1000000.01", reason: amounts.ReasonMaxMagnitude},
+		"over max debit magnitude":  {currency: "EUR", amount: "-1e7", reason: amounts.ReasonMaxMagnitude},
+		"below minor unit":          {currency: "EUR", amount: "0.0000000001", reason: amounts.ReasonMaxPlaces},
+		"below currency minor unit": {currency: "JPY", amount: "100.5", reason: amounts.ReasonMaxPlaces},
+	}
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			policy := newPolicy(t, testCase.currency)
+
+			// Act
+			_, err := policy.Normalize(decimal.RequireFromString(testCase.amount))
+
+			// Assert
+			var amountErr *amounts.InvalidAmountError
+			require.ErrorAs(t, err, &amountErr)
+			assert.Equal(t, testCase.reason, amountErr.Reason)
+		})
+	}
+}
+
+func TestNewPolicy__RejectsNonPositiveMagnitude(t *testing.T) {
+	// Act
+	_, err := amounts.NewPolicy("EUR", decimal.Zero)
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestRoundingMode_Round(t *testing.T) {
+	testCases := map[amounts.RoundingMode][]string{
+		// amounts 0.125, -0.125 and 0.135 rounded to 2 places
+		amounts.HalfUp:   {"0.13", "-0.13", "0.14"},
+		amounts.HalfEven: {"0.12", "-0.12", "0.14"},
+		amounts.Down:     {"0.12", "-0.12", "0.13"},
+	}
+	for mode, expected := range testCases {
+		t.Run(string(mode), func(t *testing.T) {
+			// Act
+			rounded := make([]string, 0, 3)
+			for _, amount := range []string{"0.125", "-0.125", "0.135"} {
+				rounded = append(rounded, mode.Round(decimal.RequireFromString(amount), 2).String())
+			}
+
+			// Assert
+			assert.Equal(t, expected, rounded)
+		})
+	}
+}
+
+func TestRoundingMode_Validate__RejectsUnknownModes(t *testing.T) {
+	// Act
+	err := amounts.RoundingMode("").Validate()
+
+	// Assert
+	assert.Error(t, err)
+}
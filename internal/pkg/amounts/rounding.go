@@ -0,0 +1,57 @@
+// Package amounts holds the rules the amounts posted to the ledger follow: the policy the amounts requested by the
+// clients must comply with, and the rounding of the amounts the services derive from others, like fees and interest.
+package amounts
+
+import (
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// RoundingMode tells how an amount is rounded to a number of decimal places
+type RoundingMode string
+
+const (
+	// HalfUp rounds half away from zero, 0.125 is rounded to 0.13 and -0.125 to -0.13
+	HalfUp RoundingMode = "half_up"
+	// HalfEven rounds half to the even neighbour, aka banker's rounding, 0.125 is rounded to 0.12
+	HalfEven RoundingMode = "half_even"
+	// Down rounds towards zero, 0.129 is rounded to 0.12 and -0.129 to -0.12
+	Down RoundingMode = "down"
+)
+
+func (m RoundingMode) Validate() error {
+	switch m {
+	case HalfUp, HalfEven, Down:
+		return nil
+	}
+	return errors.Errorf("unsupported rounding mode %q", m)
+}
+
+// Round rounds the amount to the given number of decimal places. The mode must be valid.
+func (m RoundingMode) Round(amount decimal.Decimal, places int32) decimal.Decimal {
+	switch m {
+	case HalfEven:
+		return amount.RoundBank(places)
+	case Down:
+		return amount.Truncate(places)
+	default:
+		return amount.Round(places)
+	}
+}
+
+// currencyPlaces holds the minor units of the currencies which do not use 2 decimal places
+var currencyPlaces = map[string]int32{
+	"ISK": 0,
+	"JPY": 0,
+	"KRW": 0,
+	"BHD": 3,
+	"KWD": 3,
+}
+
+// Places returns the number of decimal places of the minor unit of the currency, see ISO 4217
+func Places(currency string) int32 {
+	if places, exists := currencyPlaces[currency]; exists {
+		return places
+	}
+	return 2
+}
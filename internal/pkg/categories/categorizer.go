@@ -0,0 +1,184 @@
+package categories
+
+import (
+	"sort"
+	"sync"
+	"teya_home_assignment/internal/pkg/ledger"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrRuleNotFound       = errors.New("category rule not found")
+	ErrAnnotationNotFound = errors.New("category annotation not found")
+)
+
+// Source tells how the category of a transaction was assigned
+type Source string
+
+const (
+	SourceRule   Source = "rule"
+	SourceManual Source = "manual"
+)
+
+// Category is the category of a transaction, an empty name means the transaction is not categorized
+type Category struct {
+	Name   string
+	Source Source
+	// RuleID is the rule which assigned the category, if assigned by a rule
+	RuleID uuid.UUID
+}
+
+// Annotation overrides the category of a transaction. Transactions are immutable, so the override is kept
+// aside the ledger and takes precedence over the rules.
+type Annotation struct {
+	TransactionID uuid.UUID
+	Category      string
+	CreatedAt     time.Time
+}
+
+// Categorizer assigns categories to transactions from the category rules, unless overridden by an annotation.
+// Categories are derived when read, so rule changes apply to past transactions as well.
+type Categorizer struct {
+	mu          sync.Mutex
+	rules       map[uuid.UUID]*Rule
+	ruleSeq     uint64
+	annotations map[uuid.UUID]Annotation
+	// ordered holds the rules in evaluation order, rebuilt when the rules change
+	ordered []Rule
+	// assigned memoizes the category assigned by the rules to each transaction, cleared when the rules change
+	assigned map[uuid.UUID]Category
+}
+
+func NewCategorizer() *Categorizer {
+	return &Categorizer{
+		rules:       make(map[uuid.UUID]*Rule),
+		annotations: make(map[uuid.UUID]Annotation),
+		assigned:    make(map[uuid.UUID]Category),
+	}
+}
+
+// Create compiles and registers a rule
+func (c *Categorizer) Create(rule Rule) (Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return Rule{}, errors.Wrap(err, "invalid category rule")
+	}
+	rule.ID = uuid.New()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ruleSeq++
+	rule.seq = c.ruleSeq
+	c.rules[rule.ID] = &rule
+	c.rulesChanged()
+	return rule, nil
+}
+
+func (c *Categorizer) Get(id uuid.UUID) (Rule, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rule, exists := c.rules[id]
+	if !exists {
+		return Rule{}, ErrRuleNotFound
+	}
+	return *rule, nil
+}
+
+// List returns the rules in evaluation order
+func (c *Categorizer) List() []Rule {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Rule{}, c.ordered...)
+}
+
+// Update replaces a rule, keeping its position among the rules of the same priority
+func (c *Categorizer) Update(id uuid.UUID, rule Rule) (Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return Rule{}, errors.Wrap(err, "invalid category rule")
+	}
+	rule.ID = id
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	existing, exists := c.rules[id]
+	if !exists {
+		return Rule{}, ErrRuleNotFound
+	}
+	rule.seq = existing.seq
+	c.rules[id] = &rule
+	c.rulesChanged()
+	return rule, nil
+}
+
+func (c *Categorizer) Delete(id uuid.UUID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.rules[id]; !exists {
+		return ErrRuleNotFound
+	}
+	delete(c.rules, id)
+	c.rulesChanged()
+	return nil
+}
+
+// Annotate overrides the category of a transaction
+func (c *Categorizer) Annotate(transactionID uuid.UUID, category string) (Annotation, error) {
+	if category == "" {
+		return Annotation{}, errors.New("category is required")
+	}
+	annotation := Annotation{
+		TransactionID: transactionID,
+		Category:      category,
+		CreatedAt:     time.Now().UTC(),
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.annotations[transactionID] = annotation
+	return annotation, nil
+}
+
+// RemoveAnnotation removes the override, so the category is assigned by the rules again
+func (c *Categorizer) RemoveAnnotation(transactionID uuid.UUID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.annotations[transactionID]; !exists {
+		return ErrAnnotationNotFound
+	}
+	delete(c.annotations, transactionID)
+	return nil
+}
+
+// Categorize returns the category of a transaction: the annotation if any, otherwise the first matching rule
+func (c *Categorizer) Categorize(transaction ledger.Transaction) Category {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if annotation, exists := c.annotations[transaction.ExternalID]; exists {
+		return Category{Name: annotation.Category, Source: SourceManual}
+	}
+	if category, exists := c.assigned[transaction.ExternalID]; exists {
+		return category
+	}
+	category := Category{}
+	for _, rule := range c.ordered {
+		if rule.Matches(transaction) {
+			category = Category{Name: rule.Category, Source: SourceRule, RuleID: rule.ID}
+			break
+		}
+	}
+	c.assigned[transaction.ExternalID] = category
+	return category
+}
+
+func (c *Categorizer) rulesChanged() {
+	c.ordered = make([]Rule, 0, len(c.rules))
+	for _, rule := range c.rules {
+		c.ordered = append(c.ordered, *rule)
+	}
+	sort.Slice(c.ordered, func(i, j int) bool {
+		if c.ordered[i].Priority != c.ordered[j].Priority {
+			return c.ordered[i].Priority < c.ordered[j].Priority
+		}
+		return c.ordered[i].seq < c.ordered[j].seq
+	})
+	clear(c.assigned)
+}
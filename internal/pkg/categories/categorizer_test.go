@@ -0,0 +1,124 @@
+package categories_test
+
+import (
+	"testing"
+	"teya_home_assignment/internal/pkg/categories"
+	"teya_home_assignment/internal/pkg/ledger"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTransaction(amount int64, description, counterparty string) ledger.Transaction {
+	transaction := ledger.Transaction{
+		Amount:      decimal.NewFromInt(amount),
+		ExternalID:  uuid.New(),
+		Description: description,
+	}
+	if counterparty != "" {
+		transaction.Metadata = map[string]string{categories.CounterpartyMetadataKey: counterparty}
+	}
+	return transaction
+}
+
+func amount(value int64) decimal.NullDecimal {
+	return decimal.NewNullDecimal(decimal.NewFromInt(value))
+}
+
+func TestCategorizer_Create__RejectsInvalidRules(t *testing.T) {
+	categorizer := categories.NewCategorizer()
+	for name, rule := range map[string]categories.Rule{
+		"missing category": {DescriptionPattern: "coffee"},
+		"no criteria":      {Category: "groceries"},
+		"invalid pattern":  {Category: "groceries", DescriptionPattern: "(coffee"},
+		"empty range":      {Category: "groceries", MinAmount: amount(10), MaxAmount: amount(5)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := categorizer.Create(rule)
+
+			assert.ErrorContains(t, err, "invalid category rule")
+		})
+	}
+}
+
+func TestCategorizer_Categorize__MatchesAllCriteria(t *testing.T) {
+	// Arrange
+	categorizer := categories.NewCategorizer()
+	rule, err := categorizer.Create(categories.Rule{
+		Category:           "groceries",
+		DescriptionPattern: "^card payment",
+		Counterparty:       "Tesco",
+		MinAmount:          amount(-200),
+		MaxAmount:          amount(0),
+	})
+	require.NoError(t, err)
+
+	// Act
+	matching := categorizer.Categorize(newTransaction(-50, "Card payment 1234", "TESCO"))
+	otherCounterparty := categorizer.Categorize(newTransaction(-50, "Card payment 1234", "Aldi"))
+	outOfRange := categorizer.Categorize(newTransaction(-250, "Card payment 1234", "Tesco"))
+	otherDescription := categorizer.Categorize(newTransaction(-50, "Transfer", "Tesco"))
+
+	// Assert
+	assert.Equal(t, categories.Category{Name: "groceries", Source: categories.SourceRule, RuleID: rule.ID}, matching)
+	assert.Empty(t, otherCounterparty.Name)
+	assert.Empty(t, outOfRange.Name)
+	assert.Empty(t, otherDescription.Name)
+}
+
+func TestCategorizer_Categorize__LowestPriorityWins(t *testing.T) {
+	// Arrange
+	categorizer := categories.NewCategorizer()
+	_, err := categorizer.Create(categories.Rule{Category: "shopping", Priority: 10, DescriptionPattern: "amazon"})
+	require.NoError(t, err)
+	_, err = categorizer.Create(categories.Rule{Category: "subscriptions", Priority: 1, DescriptionPattern: "prime"})
+	require.NoError(t, err)
+	_, err = categorizer.Create(categories.Rule{Category: "entertainment", Priority: 1, DescriptionPattern: "amazon"})
+	require.NoError(t, err)
+
+	// Act
+	category := categorizer.Categorize(newTransaction(-9, "Amazon Prime", ""))
+
+	// Assert
+	assert.Equal(t, "subscriptions", category.Name)
+}
+
+func TestCategorizer_Categorize__AppliesRuleChangesToPastTransactions(t *testing.T) {
+	// Arrange
+	categorizer := categories.NewCategorizer()
+	transaction := newTransaction(-4, "Coffee shop", "")
+	require.Empty(t, categorizer.Categorize(transaction).Name)
+	rule, err := categorizer.Create(categories.Rule{Category: "eating out", DescriptionPattern: "coffee"})
+	require.NoError(t, err)
+	require.Equal(t, "eating out", categorizer.Categorize(transaction).Name)
+
+	// Act
+	_, err = categorizer.Update(rule.ID, categories.Rule{Category: "coffee", DescriptionPattern: "coffee"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "coffee", categorizer.Categorize(transaction).Name)
+}
+
+func TestCategorizer_Annotate__OverridesRulesUntilRemoved(t *testing.T) {
+	// Arrange
+	categorizer := categories.NewCategorizer()
+	_, err := categorizer.Create(categories.Rule{Category: "eating out", DescriptionPattern: "coffee"})
+	require.NoError(t, err)
+	transaction := newTransaction(-4, "Coffee beans", "")
+
+	// Act
+	_, err = categorizer.Annotate(transaction.ExternalID, "groceries")
+	overridden := categorizer.Categorize(transaction)
+	errRemove := categorizer.RemoveAnnotation(transaction.ExternalID)
+	restored := categorizer.Categorize(transaction)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, categories.Category{Name: "groceries", Source: categories.SourceManual}, overridden)
+	require.NoError(t, errRemove)
+	assert.Equal(t, "eating out", restored.Name)
+	assert.ErrorIs(t, categorizer.RemoveAnnotation(transaction.ExternalID), categories.ErrAnnotationNotFound)
+}
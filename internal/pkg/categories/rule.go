@@ -0,0 +1,71 @@
+package categories
+
+import (
+	"regexp"
+	"strings"
+	"teya_home_assignment/internal/pkg/ledger"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// CounterpartyMetadataKey is the transaction metadata key holding the counterparty, e.g. the merchant
+const CounterpartyMetadataKey = "counterparty"
+
+// Rule assigns a category to the transactions matching all of its criteria, at least one criterion is required.
+// When several rules match, the one with the lowest priority wins, ties are broken by creation order.
+type Rule struct {
+	ID       uuid.UUID
+	Category string
+	Priority int
+	// DescriptionPattern is a case insensitive regular expression searched in the description
+	DescriptionPattern string
+	// Counterparty is compared case insensitively to the counterparty metadata of the transaction
+	Counterparty string
+	// MinAmount and MaxAmount bound the signed amount, both inclusive
+	MinAmount decimal.NullDecimal
+	MaxAmount decimal.NullDecimal
+
+	descriptionRegexp *regexp.Regexp
+	seq               uint64
+}
+
+// Validate checks the rule and compiles its description pattern
+func (r *Rule) Validate() error {
+	if r.Category == "" {
+		return errors.New("category is required")
+	}
+	if r.DescriptionPattern == "" && r.Counterparty == "" && !r.MinAmount.Valid && !r.MaxAmount.Valid {
+		return errors.New("at least one of description pattern, counterparty or amount range is required")
+	}
+	if r.MinAmount.Valid && r.MaxAmount.Valid && r.MinAmount.Decimal.GreaterThan(r.MaxAmount.Decimal) {
+		return errors.New("min amount must not be greater than max amount")
+	}
+	r.descriptionRegexp = nil
+	if r.DescriptionPattern != "" {
+		descriptionRegexp, err := regexp.Compile("(?i)" + r.DescriptionPattern)
+		if err != nil {
+			return errors.Wrap(err, "invalid description pattern")
+		}
+		r.descriptionRegexp = descriptionRegexp
+	}
+	return nil
+}
+
+// Matches reports whether the transaction meets all the criteria of the rule
+func (r *Rule) Matches(transaction ledger.Transaction) bool {
+	if r.descriptionRegexp != nil && !r.descriptionRegexp.MatchString(transaction.Description) {
+		return false
+	}
+	if r.Counterparty != "" && !strings.EqualFold(r.Counterparty, transaction.Metadata[CounterpartyMetadataKey]) {
+		return false
+	}
+	if r.MinAmount.Valid && transaction.Amount.LessThan(r.MinAmount.Decimal) {
+		return false
+	}
+	if r.MaxAmount.Valid && transaction.Amount.GreaterThan(r.MaxAmount.Decimal) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package feed
+
+import (
+	"sync"
+	"teya_home_assignment/internal/pkg/ledger"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrSlowSubscriber ends a subscription whose buffer could not hold the entries being posted
+	ErrSlowSubscriber = errors.New("subscriber did not keep up with the posted entries")
+	// ErrClosed ends the subscriptions when the feed is closed, e.g. when the webserver shuts down
+	ErrClosed = errors.New("feed closed")
+)
+
+// Feed broadcasts the entries posted to the ledger to its subscribers. It is registered on the ledger as a posted
+// observer, which runs while the ledger is locked, so it never waits for the subscribers: the subscriptions which
+// cannot buffer the entries are ended rather than slowing down the ledger or missing entries silently.
+type Feed struct {
+	mu            sync.Mutex
+	buffer        int
+	subscriptions map[*Subscription]struct{}
+	closed        bool
+}
+
+// NewFeed creates a feed whose subscriptions buffer up to the given number of entries
+func NewFeed(buffer int) *Feed {
+	return &Feed{buffer: buffer, subscriptions: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the entries posted since it was created, in the order they were posted
+type Subscription struct {
+	feed    *Feed
+	entries chan ledger.Transaction
+	err     error
+}
+
+// Subscribe starts receiving the posted entries
+func (f *Feed) Subscribe() (*Subscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil, ErrClosed
+	}
+	subscription := &Subscription{feed: f, entries: make(chan ledger.Transaction, f.buffer)}
+	f.subscriptions[subscription] = struct{}{}
+	return subscription, nil
+}
+
+// Observe is a ledger.PostedObserver broadcasting the posted entries. The entries posted together are delivered
+// together, or not at all.
+func (f *Feed) Observe(posted []ledger.Transaction) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for subscription := range f.subscriptions {
+		if cap(subscription.entries)-len(subscription.entries) < len(posted) {
+			f.end(subscription, ErrSlowSubscriber)
+			continue
+		}
+		for _, entry := range posted {
+			subscription.entries <- entry
+		}
+	}
+}
+
+// Close ends every subscription with ErrClosed, and refuses the new ones
+func (f *Feed) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	for subscription := range f.subscriptions {
+		f.end(subscription, ErrClosed)
+	}
+}
+
+// end closes the entries of the subscription, it must be called with the feed locked
+func (f *Feed) end(subscription *Subscription, err error) {
+	subscription.err = err
+	close(subscription.entries)
+	delete(f.subscriptions, subscription)
+}
+
+// Entries receives the posted entries. It is closed once the subscription ended, Err then tells why.
+func (s *Subscription) Entries() <-chan ledger.Transaction {
+	return s.entries
+}
+
+// Err returns why the subscription ended, nil while it goes on or when it was closed by the subscriber
+func (s *Subscription) Err() error {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	return s.err
+}
+
+// Close stops receiving the posted entries
+func (s *Subscription) Close() {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	if _, subscribed := s.feed.subscriptions[s]; subscribed {
+		s.feed.end(s, nil)
+	}
+}
@@ -0,0 +1,94 @@
+package feed_test
+
+import (
+	"testing"
+	"teya_home_assignment/internal/pkg/feed"
+	"teya_home_assignment/internal/pkg/ledger"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFedLedger(t *testing.T, buffer int) (*ledger.Ledger, *feed.Feed) {
+	t.Helper()
+	ledgerFeed := feed.NewFeed(buffer)
+	ledgerInstance, err := ledger.NewLedger(ledger.WithPostedObserver(ledgerFeed.Observe))
+	require.NoError(t, err)
+	return ledgerInstance, ledgerFeed
+}
+
+// received drains the entries buffered by the subscription
+func received(subscription *feed.Subscription) []decimal.Decimal {
+	var amounts []decimal.Decimal
+	for {
+		select {
+		case entry, open := <-subscription.Entries():
+			if !open {
+				return amounts
+			}
+			amounts = append(amounts, entry.Amount)
+		default:
+			return amounts
+		}
+	}
+}
+
+func TestFeed_Observe__BroadcastsPostedEntries(t *testing.T) {
+	// Arrange
+	ledgerInstance, ledgerFeed := newFedLedger(t, 10)
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(1)))
+	first, err := ledgerFeed.Subscribe()
+	require.NoError(t, err)
+	second, err := ledgerFeed.Subscribe()
+	require.NoError(t, err)
+
+	// Act
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(2)))
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(3)))
+
+	// Assert
+	expected := []decimal.Decimal{decimal.NewFromInt(2), decimal.NewFromInt(3)}
+	assert.Equal(t, expected, received(first))
+	assert.Equal(t, expected, received(second))
+	assert.NoError(t, first.Err())
+}
+
+func TestFeed_Observe__EndsSlowSubscriptions(t *testing.T) {
+	// Arrange
+	ledgerInstance, ledgerFeed := newFedLedger(t, 1)
+	slow, err := ledgerFeed.Subscribe()
+	require.NoError(t, err)
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(1)))
+
+	// Act
+	err = ledgerInstance.AddTransaction(decimal.NewFromInt(2))
+
+	// Assert
+	require.NoError(t, err, "the ledger does not wait for the subscribers")
+	assert.Equal(t, []decimal.Decimal{decimal.NewFromInt(1)}, received(slow))
+	_, open := <-slow.Entries()
+	assert.False(t, open)
+	assert.ErrorIs(t, slow.Err(), feed.ErrSlowSubscriber)
+}
+
+func TestFeed_Close__EndsSubscriptions(t *testing.T) {
+	// Arrange
+	_, ledgerFeed := newFedLedger(t, 10)
+	subscription, err := ledgerFeed.Subscribe()
+	require.NoError(t, err)
+	unsubscribed, err := ledgerFeed.Subscribe()
+	require.NoError(t, err)
+	unsubscribed.Close()
+
+	// Act
+	ledgerFeed.Close()
+	_, errSubscribe := ledgerFeed.Subscribe()
+
+	// Assert
+	_, open := <-subscription.Entries()
+	assert.False(t, open)
+	assert.ErrorIs(t, subscription.Err(), feed.ErrClosed)
+	assert.NoError(t, unsubscribed.Err())
+	assert.ErrorIs(t, errSubscribe, feed.ErrClosed)
+}
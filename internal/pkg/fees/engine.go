@@ -0,0 +1,92 @@
+package fees
+
+import (
+	"fmt"
+	"teya_home_assignment/internal/pkg/amounts"
+	"teya_home_assignment/internal/pkg/ledger"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Fee is the outcome of evaluating a fee schedule against a transaction. Amount is the positive fee charged.
+type Fee struct {
+	Schedule   string
+	Percentage decimal.Decimal
+	Fixed      decimal.Decimal
+	Amount     decimal.Decimal
+	Capped     bool
+}
+
+// Description summarizes how the fee was computed, it is stored on the posted fee entry
+func (f Fee) Description() string {
+	description := fmt.Sprintf("%v: %v%% + %v", f.Schedule, f.Percentage.Shift(2).String(), f.Fixed.String())
+	if f.Capped {
+		description += " (capped)"
+	}
+	return description
+}
+
+// Engine evaluates the fee schedules of the ledger's currency against the transactions being posted
+type Engine struct {
+	currency  string
+	schedules []Schedule
+}
+
+func NewEngine(currency string, schedules ...Schedule) (*Engine, error) {
+	e := &Engine{currency: currency}
+	for _, schedule := range schedules {
+		if err := schedule.Validate(); err != nil {
+			return nil, errors.Wrap(err, "invalid fee schedule")
+		}
+		if schedule.Currency == currency {
+			e.schedules = append(e.schedules, schedule)
+		}
+	}
+	return e, nil
+}
+
+// Evaluate returns the fees charged for a transaction of the given amount
+func (e *Engine) Evaluate(amount decimal.Decimal) []Fee {
+	var fees []Fee
+	for _, schedule := range e.schedules {
+		if !schedule.appliesTo(amount) {
+			continue
+		}
+		percentage, fixed, ok := schedule.pricing(amount.Abs())
+		if !ok {
+			continue
+		}
+		fee := Fee{Schedule: schedule.Name, Percentage: percentage, Fixed: fixed}
+		fee.Amount = amount.Abs().Mul(percentage).Add(fixed)
+		if fee.Amount.LessThan(schedule.Min) {
+			fee.Amount, fee.Capped = schedule.Min, true
+		}
+		if !schedule.Max.IsZero() && fee.Amount.GreaterThan(schedule.Max) {
+			fee.Amount, fee.Capped = schedule.Max, true
+		}
+		// fees are rounded to the minor unit of the currency
+		fee.Amount = schedule.Rounding.Round(fee.Amount, amounts.Places(e.currency))
+		if fee.Amount.IsPositive() {
+			fees = append(fees, fee)
+		}
+	}
+	return fees
+}
+
+// PostingHook charges the fees of every client transaction posted to the ledger as linked debit entries.
+// Entries posted by the ledger itself, like fees and interest, are not charged.
+func (e *Engine) PostingHook(transaction ledger.Transaction) ([]ledger.LinkedEntry, error) {
+	if transaction.Kind != "" {
+		return nil, nil
+	}
+	var entries []ledger.LinkedEntry
+	for _, fee := range e.Evaluate(transaction.Amount) {
+		entries = append(entries, ledger.LinkedEntry{
+			Amount:      fee.Amount.Neg(),
+			Description: fee.Description(),
+			Kind:        ledger.KindFee,
+		})
+	}
+	return entries, nil
+}
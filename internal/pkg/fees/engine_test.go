@@ -0,0 +1,164 @@
+package fees_test
+
+import (
+	"testing"
+	"teya_home_assignment/internal/pkg/amounts"
+	"teya_home_assignment/internal/pkg/fees"
+	"teya_home_assignment/internal/pkg/ledger"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var merchantServiceCharge = fees.Schedule{
+	Name:       "MSC",
+	Currency:   "EUR",
+	AppliesTo:  fees.Credits,
+	Percentage: decimal.RequireFromString("0.015"),
+	Fixed:      decimal.RequireFromString("0.20"),
+	Min:        decimal.RequireFromString("0.25"),
+	Max:        decimal.RequireFromString("5"),
+	Rounding:   amounts.HalfUp,
+}
+
+func TestEngine_Evaluate__ChargesPercentageAndFixed(t *testing.T) {
+	// Arrange
+	engine, err := fees.NewEngine("EUR", merchantServiceCharge)
+	require.NoError(t, err)
+
+	// Act
+	charged := engine.Evaluate(decimal.RequireFromString("100"))
+
+	// Assert
+	require.Len(t, charged, 1)
+	assert.True(t, decimal.RequireFromString("1.7").Equal(charged[0].Amount), charged[0].Amount.String())
+	assert.False(t, charged[0].Capped)
+	assert.Equal(t, "MSC: 1.5% + 0.2", charged[0].Description())
+}
+
+func TestEngine_Evaluate__AppliesCaps(t *testing.T) {
+	testCases := map[string]string{
+		"1":     "0.25",
+		"10000": "5",
+	}
+	for amount, expected := range testCases {
+		t.Run(amount, func(t *testing.T) {
+			// Arrange
+			engine, err := fees.NewEngine("EUR", merchantServiceCharge)
+			require.NoError(t, err)
+
+			// Act
+			charged := engine.Evaluate(decimal.RequireFromString(amount))
+
+			// Assert
+			require.Len(t, charged, 1)
+			assert.True(t, decimal.RequireFromString(expected).Equal(charged[0].Amount), charged[0].Amount.String())
+			assert.True(t, charged[0].Capped)
+		})
+	}
+}
+
+func TestEngine_Evaluate__PicksTierByAmount(t *testing.T) {
+	// Arrange
+	tiered := fees.Schedule{
+		Name:      "tiered",
+		Currency:  "EUR",
+		AppliesTo: fees.Credits,
+		Tiers: []fees.Tier{
+			{UpTo: decimal.NewFromInt(50), Percentage: decimal.RequireFromString("0.02")},
+			{UpTo: decimal.Zero, Percentage: decimal.RequireFromString("0.01")},
+		},
+		Rounding: amounts.HalfUp,
+	}
+	engine, err := fees.NewEngine("EUR", tiered)
+	require.NoError(t, err)
+
+	// Act
+	small := engine.Evaluate(decimal.NewFromInt(40))
+	large := engine.Evaluate(decimal.NewFromInt(200))
+
+	// Assert
+	require.Len(t, small, 1)
+	assert.True(t, decimal.RequireFromString("0.8").Equal(small[0].Amount), small[0].Amount.String())
+	require.Len(t, large, 1)
+	assert.True(t, decimal.NewFromInt(2).Equal(large[0].Amount), large[0].Amount.String())
+}
+
+func TestEngine_Evaluate__IgnoresOtherCurrenciesAndDirections(t *testing.T) {
+	// Arrange
+	gbp := merchantServiceCharge
+	gbp.Currency = "GBP"
+	engine, err := fees.NewEngine("EUR", merchantServiceCharge, gbp)
+	require.NoError(t, err)
+
+	// Act
+	credit := engine.Evaluate(decimal.NewFromInt(100))
+	debit := engine.Evaluate(decimal.NewFromInt(-100))
+
+	// Assert
+	assert.Len(t, credit, 1)
+	assert.Empty(t, debit)
+}
+
+func TestEngine_Evaluate__RoundsToCurrencyMinorUnit(t *testing.T) {
+	// Arrange
+	isk := fees.Schedule{
+		Name:       "ISK",
+		Currency:   "ISK",
+		AppliesTo:  fees.All,
+		Percentage: decimal.RequireFromString("0.015"),
+		Rounding:   amounts.HalfUp,
+	}
+	engine, err := fees.NewEngine("ISK", isk)
+	require.NoError(t, err)
+
+	// Act
+	charged := engine.Evaluate(decimal.NewFromInt(1030))
+
+	// Assert
+	require.Len(t, charged, 1)
+	assert.True(t, decimal.NewFromInt(15).Equal(charged[0].Amount), charged[0].Amount.String())
+}
+
+func TestSchedule_Validate__RejectsUnsortedTiers(t *testing.T) {
+	// Arrange
+	schedule := fees.Schedule{
+		Name:      "tiered",
+		Currency:  "EUR",
+		AppliesTo: fees.Credits,
+		Tiers: []fees.Tier{
+			{UpTo: decimal.NewFromInt(100)},
+			{UpTo: decimal.NewFromInt(50)},
+		},
+		Rounding: amounts.HalfUp,
+	}
+
+	// Act
+	err := schedule.Validate()
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestEngine_PostingHook__PostsLinkedFeeWithTransaction(t *testing.T) {
+	// Arrange
+	engine, err := fees.NewEngine("EUR", merchantServiceCharge)
+	require.NoError(t, err)
+	ledgerInstance, err := ledger.NewLedger(ledger.WithPostingHook(engine.PostingHook))
+	require.NoError(t, err)
+
+	// Act
+	posted, err := ledgerInstance.PostTransaction(decimal.NewFromInt(100))
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, posted, 2)
+	assert.Equal(t, ledger.KindFee, posted[1].Kind)
+	assert.Equal(t, posted[0].ExternalID, posted[1].LinkedTo)
+	assert.True(t, decimal.RequireFromString("-1.7").Equal(posted[1].Amount), posted[1].Amount.String())
+	assert.Len(t, ledgerInstance.TransactionHistory, 2)
+	balance, err := ledgerInstance.GetBalance()
+	require.NoError(t, err)
+	assert.True(t, decimal.RequireFromString("98.3").Equal(balance), balance.String())
+}
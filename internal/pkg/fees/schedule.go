@@ -0,0 +1,103 @@
+package fees
+
+import (
+	"teya_home_assignment/internal/pkg/amounts"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// AppliesTo selects the transactions a fee schedule is charged on
+type AppliesTo string
+
+const (
+	// Credits are incoming payments, e.g. the card transactions of a merchant
+	Credits AppliesTo = "credits"
+	Debits  AppliesTo = "debits"
+	All     AppliesTo = "all"
+)
+
+// Tier is the pricing applied to transactions whose absolute amount is below UpTo.
+// A zero UpTo means the tier is unbounded and must come last.
+type Tier struct {
+	UpTo       decimal.Decimal
+	Percentage decimal.Decimal
+	Fixed      decimal.Decimal
+}
+
+// Schedule describes a fee charged on every matching transaction in its currency.
+// The fee is the percentage of the absolute transaction amount plus the fixed amount, taken from the matching
+// tier when tiers are set, then capped within [Min, Max]. A zero Max means the fee is not capped.
+// Percentages are expressed as fractions, 0.015 is 1.5%.
+type Schedule struct {
+	Name       string
+	Currency   string
+	AppliesTo  AppliesTo
+	Percentage decimal.Decimal
+	Fixed      decimal.Decimal
+	Tiers      []Tier
+	Min        decimal.Decimal
+	Max        decimal.Decimal
+	// Rounding rounds the fee to the minor unit of the currency, once capped
+	Rounding amounts.RoundingMode
+}
+
+func (s Schedule) Validate() error {
+	if s.Name == "" {
+		return errors.New("fee schedule name is required")
+	}
+	if s.Currency == "" {
+		return errors.Errorf("fee schedule %v: currency is required", s.Name)
+	}
+	switch s.AppliesTo {
+	case Credits, Debits, All:
+	default:
+		return errors.Errorf("fee schedule %v: unsupported applies to %q", s.Name, s.AppliesTo)
+	}
+	if err := s.Rounding.Validate(); err != nil {
+		return errors.Wrapf(err, "fee schedule %v", s.Name)
+	}
+	if s.Percentage.IsNegative() || s.Fixed.IsNegative() || s.Min.IsNegative() || s.Max.IsNegative() {
+		return errors.Errorf("fee schedule %v: fees must not be negative", s.Name)
+	}
+	if !s.Max.IsZero() && s.Max.LessThan(s.Min) {
+		return errors.Errorf("fee schedule %v: max must not be lower than min", s.Name)
+	}
+	for i, tier := range s.Tiers {
+		if tier.Percentage.IsNegative() || tier.Fixed.IsNegative() {
+			return errors.Errorf("fee schedule %v: tier %d fees must not be negative", s.Name, i)
+		}
+		if tier.UpTo.IsZero() && i != len(s.Tiers)-1 {
+			return errors.Errorf("fee schedule %v: only the last tier may be unbounded", s.Name)
+		}
+		if i > 0 && !tier.UpTo.IsZero() && !tier.UpTo.GreaterThan(s.Tiers[i-1].UpTo) {
+			return errors.Errorf("fee schedule %v: tiers must be sorted by their upper bound", s.Name)
+		}
+	}
+	return nil
+}
+
+func (s Schedule) appliesTo(amount decimal.Decimal) bool {
+	switch s.AppliesTo {
+	case Credits:
+		return amount.IsPositive()
+	case Debits:
+		return amount.IsNegative()
+	default:
+		return !amount.IsZero()
+	}
+}
+
+// pricing returns the percentage and fixed fee for the given absolute amount.
+// Amounts above the last bounded tier are not charged when there is no unbounded tier.
+func (s Schedule) pricing(amount decimal.Decimal) (decimal.Decimal, decimal.Decimal, bool) {
+	if len(s.Tiers) == 0 {
+		return s.Percentage, s.Fixed, true
+	}
+	for _, tier := range s.Tiers {
+		if tier.UpTo.IsZero() || amount.LessThan(tier.UpTo) {
+			return tier.Percentage, tier.Fixed, true
+		}
+	}
+	return decimal.Zero, decimal.Zero, false
+}
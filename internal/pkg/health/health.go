@@ -0,0 +1,65 @@
+package health
+
+import (
+	"maps"
+	"sync"
+)
+
+// Status is the status of a component, or of the service as a whole
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// Check returns an error when a component cannot serve
+type Check func() error
+
+// Component is the result of the check of a component
+type Component struct {
+	Status Status
+	Error  string
+}
+
+// Report is the status of every component. The service is up when all of its components are.
+type Report struct {
+	Status     Status
+	Components map[string]Component
+}
+
+// Checker checks the components the service depends on
+type Checker struct {
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+func NewChecker() *Checker {
+	return &Checker{checks: make(map[string]Check)}
+}
+
+// Register adds a component to check, replacing any component registered with the same name
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// Check runs the checks of all the components
+func (c *Checker) Check() Report {
+	// the checks may be slow, they run unlocked
+	c.mu.RLock()
+	checks := maps.Clone(c.checks)
+	c.mu.RUnlock()
+
+	report := Report{Status: StatusUp, Components: make(map[string]Component, len(checks))}
+	for name, check := range checks {
+		if err := check(); err != nil {
+			report.Status = StatusDown
+			report.Components[name] = Component{Status: StatusDown, Error: err.Error()}
+			continue
+		}
+		report.Components[name] = Component{Status: StatusUp}
+	}
+	return report
+}
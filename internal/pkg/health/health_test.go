@@ -0,0 +1,33 @@
+package health_test
+
+import (
+	"errors"
+	"testing"
+	"teya_home_assignment/internal/pkg/health"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker_Check__UpWhenAllComponentsAre(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Register("storage", func() error { return nil })
+	checker.Register("scheduler", func() error { return nil })
+
+	report := checker.Check()
+
+	assert.Equal(t, health.StatusUp, report.Status)
+	assert.Equal(t, health.Component{Status: health.StatusUp}, report.Components["storage"])
+	assert.Len(t, report.Components, 2)
+}
+
+func TestChecker_Check__DownWhenAnyComponentIs(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Register("storage", func() error { return errors.New("disk full") })
+	checker.Register("scheduler", func() error { return nil })
+
+	report := checker.Check()
+
+	assert.Equal(t, health.StatusDown, report.Status)
+	assert.Equal(t, health.Component{Status: health.StatusDown, Error: "disk full"}, report.Components["storage"])
+	assert.Equal(t, health.StatusUp, report.Components["scheduler"].Status)
+}
@@ -0,0 +1,92 @@
+package interest
+
+import (
+	"teya_home_assignment/internal/pkg/amounts"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// DayCount is the convention used to turn an annual rate into a daily one
+type DayCount string
+
+const (
+	// Actual360 divides the annual rate by 360
+	Actual360 DayCount = "ACT/360"
+	// Actual365 divides the annual rate by 365, regardless of leap years
+	Actual365 DayCount = "ACT/365"
+	// ActualActual divides the annual rate by the number of days of the accrual day's year
+	ActualActual DayCount = "ACT/ACT"
+)
+
+// Cadence is how often accrued interest is posted to the ledger
+type Cadence string
+
+const (
+	Daily   Cadence = "daily"
+	Monthly Cadence = "monthly"
+)
+
+// DefaultPlaces is the number of decimal places interest is rounded to when posted
+const DefaultPlaces = 2
+
+// Config holds the interest rates and conventions. Rates are annual and expressed as fractions (0.18 is 18%).
+// A zero rate disables accrual for balances of that sign.
+type Config struct {
+	OverdraftRate decimal.Decimal
+	CreditRate    decimal.Decimal
+	DayCount      DayCount
+	Cadence       Cadence
+	// StartAt is the first day interest accrues on, it is truncated to midnight UTC. The engine resumes after the
+	// last period posted to the ledger, and from the day of its first run when neither is set.
+	StartAt time.Time
+	// Places is the number of decimal places posted interest is rounded to
+	Places int32
+	// Rounding rounds the interest accrued over a period to Places when posted, the daily accruals are not rounded
+	Rounding amounts.RoundingMode
+}
+
+func (c Config) Validate() error {
+	if c.OverdraftRate.IsNegative() || c.CreditRate.IsNegative() {
+		return errors.New("interest rates must not be negative")
+	}
+	switch c.DayCount {
+	case Actual360, Actual365, ActualActual:
+	default:
+		return errors.Errorf("unsupported day count convention %q", c.DayCount)
+	}
+	switch c.Cadence {
+	case Daily, Monthly:
+	default:
+		return errors.Errorf("unsupported posting cadence %q", c.Cadence)
+	}
+	if c.Places < 0 {
+		return errors.New("decimal places must not be negative")
+	}
+	if err := c.Rounding.Validate(); err != nil {
+		return errors.Wrap(err, "invalid interest rounding")
+	}
+	return nil
+}
+
+// yearDays returns the day count denominator for the given day
+func (c Config) yearDays(day time.Time) int64 {
+	switch c.DayCount {
+	case Actual360:
+		return 360
+	case ActualActual:
+		return int64(time.Date(day.Year()+1, time.January, 1, 0, 0, 0, 0, time.UTC).
+			Sub(time.Date(day.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+	default:
+		return 365
+	}
+}
+
+// periodEnd returns the end of the posting period starting at the given day
+func (c Config) periodEnd(periodStart time.Time) time.Time {
+	if c.Cadence == Monthly {
+		return time.Date(periodStart.Year(), periodStart.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return periodStart.AddDate(0, 0, 1)
+}
@@ -0,0 +1,246 @@
+package interest
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"teya_home_assignment/internal/pkg/ledger"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// DefaultTickInterval is how often the engine checks whether a posting period ended
+const DefaultTickInterval = time.Minute
+
+// DailyAccrual is the interest accrued over a single day on the balance at the end of that day
+type DailyAccrual struct {
+	Date     time.Time
+	Balance  decimal.Decimal
+	Rate     decimal.Decimal
+	Interest decimal.Decimal
+}
+
+// Accrual is the interest accrued within [From, To). Total is the unrounded sum of the daily accruals,
+// Posted is the amount posted to the ledger for the period once rounded.
+type Accrual struct {
+	From   time.Time
+	To     time.Time
+	Days   []DailyAccrual
+	Total  decimal.Decimal
+	Posted decimal.Decimal
+}
+
+// Engine accrues interest on the ledger balance and posts it at the configured cadence.
+// Accruals are derived from the ledger history only, so any past period can be recomputed for audit.
+type Engine struct {
+	ledgerService *ledger.Ledger
+	config        Config
+	clock         func() time.Time
+	tickInterval  time.Duration
+
+	mu sync.Mutex
+	// cursor is the start of the first period that was not posted yet
+	cursor time.Time
+	// restored tells whether the cursor was moved past the periods posted to the ledger, which is done by the first
+	// run, once the ledger was restored
+	restored bool
+
+	stop    chan struct{}
+	done    chan struct{}
+	running atomic.Bool
+}
+
+type Option func(e *Engine)
+
+// WithClock overrides the time source used to decide which periods ended
+func WithClock(clock func() time.Time) Option {
+	return func(e *Engine) {
+		e.clock = clock
+	}
+}
+
+func NewEngine(ledgerService *ledger.Ledger, config Config, opts ...Option) (*Engine, error) {
+	if err := config.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid interest config")
+	}
+	e := &Engine{
+		ledgerService: ledgerService,
+		config:        config,
+		clock:         time.Now,
+		tickInterval:  DefaultTickInterval,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if !e.config.StartAt.IsZero() {
+		e.cursor = truncateToDay(e.config.StartAt)
+	}
+	return e, nil
+}
+
+// Accrue computes the daily interest accrued within [from, to), both truncated to midnight UTC. The end of day
+// balances are computed in a single pass over the ledger history.
+func (e *Engine) Accrue(from, to time.Time) (*Accrual, error) {
+	from, to = truncateToDay(from), truncateToDay(to)
+	if !from.Before(to) {
+		return nil, errors.Errorf("invalid accrual period: from(%v) must be before to(%v)", from, to)
+	}
+	var days, dayEnds []time.Time
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+		dayEnds = append(dayEnds, day.AddDate(0, 0, 1))
+	}
+	balances, err := e.ledgerService.GetBalancesAt(dayEnds)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get the end of day balances")
+	}
+	accrual := &Accrual{From: from, To: to, Days: make([]DailyAccrual, 0, len(days)), Total: decimal.Zero}
+	for i, day := range days {
+		balance := balances[i]
+		rate := e.config.CreditRate
+		if balance.IsNegative() {
+			rate = e.config.OverdraftRate
+		}
+		interest := balance.Mul(rate).Div(decimal.NewFromInt(e.config.yearDays(day)))
+		accrual.Days = append(accrual.Days, DailyAccrual{Date: day, Balance: balance, Rate: rate, Interest: interest})
+		accrual.Total = accrual.Total.Add(interest)
+	}
+	accrual.Posted = e.config.Rounding.Round(accrual.Total, e.config.Places)
+	return accrual, nil
+}
+
+// RunDue posts the interest of every posting period that ended by the given time
+func (e *Engine) RunDue(now time.Time) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.restored {
+		if err := e.restoreCursor(now); err != nil {
+			return err
+		}
+		e.restored = true
+	}
+	for {
+		periodEnd := e.config.periodEnd(e.cursor)
+		if periodEnd.After(now) {
+			return nil
+		}
+		if err := e.post(e.cursor, periodEnd); err != nil {
+			return err
+		}
+		e.cursor = periodEnd
+	}
+}
+
+// restoreCursor moves the cursor to the end of the last period whose interest was posted to the ledger, so a
+// restarted engine neither skips a period nor posts one twice. Without a start configured and any interest posted,
+// interest accrues from the day of the first run.
+func (e *Engine) restoreCursor(now time.Time) error {
+	last, exists := e.ledgerService.FindLastTransaction(func(transaction ledger.Transaction) bool {
+		return transaction.Kind == ledger.KindInterest
+	})
+	if exists {
+		periodEnd, err := parsePeriodEnd(last.IdempotencyKey)
+		if err != nil {
+			return errors.Wrap(err, "could not restore the last posted period")
+		}
+		if periodEnd.After(e.cursor) {
+			e.cursor = periodEnd
+		}
+	}
+	if e.cursor.IsZero() {
+		e.cursor = truncateToDay(now)
+	}
+	return nil
+}
+
+func (e *Engine) post(from, to time.Time) error {
+	accrual, err := e.Accrue(from, to)
+	if err != nil {
+		return errors.Wrap(err, "could not accrue interest")
+	}
+	if accrual.Posted.IsZero() {
+		return nil
+	}
+	description := fmt.Sprintf("Interest %v - %v", from.Format(time.DateOnly), to.AddDate(0, 0, -1).Format(time.DateOnly))
+	err = e.ledgerService.AddTransaction(accrual.Posted,
+		ledger.WithDescription(description),
+		ledger.WithKind(ledger.KindInterest),
+		ledger.WithIdempotencyKey(idempotencyKey(from, to)))
+	if errors.Is(err, ledger.ErrDuplicateTransaction) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "could not post interest for %v", description)
+	}
+	slog.Info("posted interest", "amount", accrual.Posted, "from", from, "to", to)
+	return nil
+}
+
+// GetPosted returns the interest transaction posted for the period, if any
+func (e *Engine) GetPosted(from, to time.Time) (ledger.Transaction, bool) {
+	return e.ledgerService.GetTransactionByIdempotencyKey(idempotencyKey(truncateToDay(from), truncateToDay(to)))
+}
+
+// Start runs the engine in the background until Stop is called
+func (e *Engine) Start() {
+	e.stop = make(chan struct{})
+	e.done = make(chan struct{})
+	e.running.Store(true)
+	go func() {
+		defer close(e.done)
+		defer e.running.Store(false)
+		ticker := time.NewTicker(e.tickInterval)
+		defer ticker.Stop()
+		for {
+			if err := e.RunDue(e.clock()); err != nil {
+				slog.Error("failed to post interest", "error", err)
+			}
+			select {
+			case <-e.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop signals the background loop to exit and waits for the interest being posted to complete
+func (e *Engine) Stop() {
+	if e.stop == nil {
+		return
+	}
+	close(e.stop)
+	<-e.done
+	e.stop = nil
+}
+
+// Running tells whether the background loop is running
+func (e *Engine) Running() bool {
+	return e.running.Load()
+}
+
+func idempotencyKey(from, to time.Time) string {
+	return "interest:" + from.Format(time.DateOnly) + ":" + to.Format(time.DateOnly)
+}
+
+// parsePeriodEnd returns the end of the period the interest posted with the idempotency key was accrued over
+func parsePeriodEnd(key string) (time.Time, error) {
+	parts := strings.Split(key, ":")
+	if len(parts) != 3 || parts[0] != "interest" {
+		return time.Time{}, errors.Errorf("invalid interest idempotency key %q", key)
+	}
+	periodEnd, err := time.Parse(time.DateOnly, parts[2])
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "invalid interest idempotency key %q", key)
+	}
+	return periodEnd, nil
+}
+
+func truncateToDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
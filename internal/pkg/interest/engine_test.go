@@ -0,0 +1,190 @@
+package interest_test
+
+import (
+	"testing"
+	"teya_home_assignment/internal/pkg/amounts"
+	"teya_home_assignment/internal/pkg/interest"
+	"teya_home_assignment/internal/pkg/journal"
+	"teya_home_assignment/internal/pkg/ledger"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var jan1 = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func newConfig(dayCount interest.DayCount, cadence interest.Cadence) interest.Config {
+	return interest.Config{
+		OverdraftRate: decimal.RequireFromString("0.365"),
+		CreditRate:    decimal.Zero,
+		DayCount:      dayCount,
+		Cadence:       cadence,
+		StartAt:       jan1,
+		Places:        interest.DefaultPlaces,
+		Rounding:      amounts.HalfEven,
+	}
+}
+
+// newTestLedger creates a ledger whose clock is controlled by the returned pointer
+func newTestLedger(t *testing.T) (*ledger.Ledger, *time.Time) {
+	t.Helper()
+	now := jan1
+	ledgerInstance, err := ledger.NewLedger(ledger.WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+	return ledgerInstance, &now
+}
+
+func TestConfig_Validate__RejectsUnknownConventions(t *testing.T) {
+	// Arrange
+	config := newConfig("30/360", interest.Daily)
+
+	// Act
+	err := config.Validate()
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestEngine_Accrue__AccruesOverdraftDaily(t *testing.T) {
+	// Arrange
+	ledgerInstance, now := newTestLedger(t)
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(-1000)))
+	*now = jan1.AddDate(0, 0, 2).Add(12 * time.Hour)
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(1500)))
+	engine, err := interest.NewEngine(ledgerInstance, newConfig(interest.Actual365, interest.Daily))
+	require.NoError(t, err)
+
+	// Act
+	accrual, err := engine.Accrue(jan1, jan1.AddDate(0, 0, 4))
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, accrual.Days, 4)
+	// 1000 * 36.5% / 365 = 1 per day overdrawn, the account is in credit from the third day
+	assert.True(t, decimal.NewFromInt(-1).Equal(accrual.Days[0].Interest), accrual.Days[0].Interest.String())
+	assert.True(t, decimal.NewFromInt(-1).Equal(accrual.Days[1].Interest), accrual.Days[1].Interest.String())
+	assert.True(t, decimal.Zero.Equal(accrual.Days[2].Interest), accrual.Days[2].Interest.String())
+	assert.True(t, decimal.NewFromInt(-2).Equal(accrual.Total), accrual.Total.String())
+}
+
+func TestEngine_Accrue__UsesDayCountConvention(t *testing.T) {
+	testCases := map[interest.DayCount]string{
+		interest.Actual360:    "-1.0138888888888889",
+		interest.Actual365:    "-1",
+		interest.ActualActual: "-0.9972677595628415",
+	}
+	for dayCount, expected := range testCases {
+		t.Run(string(dayCount), func(t *testing.T) {
+			// Arrange
+			leapDay := time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)
+			now := leapDay
+			ledgerInstance, err := ledger.NewLedger(ledger.WithClock(func() time.Time { return now }))
+			require.NoError(t, err)
+			require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(-1000)))
+			config := newConfig(dayCount, interest.Daily)
+			config.StartAt = leapDay
+			engine, err := interest.NewEngine(ledgerInstance, config)
+			require.NoError(t, err)
+
+			// Act
+			accrual, err := engine.Accrue(leapDay, leapDay.AddDate(0, 0, 1))
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, expected, accrual.Total.String())
+		})
+	}
+}
+
+func TestEngine_Accrue__AccruesCreditInterestWhenEnabled(t *testing.T) {
+	// Arrange
+	ledgerInstance, _ := newTestLedger(t)
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(3650)))
+	config := newConfig(interest.Actual365, interest.Daily)
+	config.CreditRate = decimal.RequireFromString("0.01")
+	engine, err := interest.NewEngine(ledgerInstance, config)
+	require.NoError(t, err)
+
+	// Act
+	accrual, err := engine.Accrue(jan1, jan1.AddDate(0, 0, 1))
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, decimal.RequireFromString("0.1").Equal(accrual.Total), accrual.Total.String())
+}
+
+func TestEngine_RunDue__PostsRoundedInterestOncePerPeriod(t *testing.T) {
+	// Arrange
+	ledgerInstance, now := newTestLedger(t)
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.RequireFromString("-100")))
+	engine, err := interest.NewEngine(ledgerInstance, newConfig(interest.Actual365, interest.Monthly))
+	require.NoError(t, err)
+	*now = time.Date(2025, time.February, 1, 0, 5, 0, 0, time.UTC)
+
+	// Act
+	require.NoError(t, engine.RunDue(*now))
+	require.NoError(t, engine.RunDue(*now))
+
+	// Assert
+	require.Len(t, ledgerInstance.TransactionHistory, 2)
+	posted := ledgerInstance.TransactionHistory[1]
+	// 100 * 36.5% / 365 * 31 days
+	assert.True(t, decimal.RequireFromString("-3.1").Equal(posted.Amount), posted.Amount.String())
+	assert.Equal(t, "Interest 2025-01-01 - 2025-01-31", posted.Description)
+	audited, exists := engine.GetPosted(jan1, time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC))
+	assert.True(t, exists)
+	assert.Equal(t, posted.ID, audited.ID)
+}
+
+func TestEngine_RunDue__ResumesAfterLastPostedPeriodOnRestart(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	now := jan1
+	clock := ledger.WithClock(func() time.Time { return now })
+	// the engine starts from its first run, as configured by the webserver
+	config := newConfig(interest.Actual365, interest.Monthly)
+	config.StartAt = time.Time{}
+	ledgerJournal, err := journal.Open(dataDir)
+	require.NoError(t, err)
+	ledgerInstance, err := ledger.NewLedger(clock, ledger.WithStore(ledgerJournal))
+	require.NoError(t, err)
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.RequireFromString("-100")))
+	engine, err := interest.NewEngine(ledgerInstance, config)
+	require.NoError(t, err)
+	require.NoError(t, engine.RunDue(now))
+	now = time.Date(2025, time.February, 1, 0, 5, 0, 0, time.UTC)
+	require.NoError(t, engine.RunDue(now))
+	require.NoError(t, ledgerJournal.Close())
+	// the webserver is down from the end of January to mid March
+	now = time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC)
+	restoredJournal, err := journal.Open(dataDir)
+	require.NoError(t, err)
+	defer restoredJournal.Close()
+	restored, err := ledger.NewLedger(clock, ledger.WithStore(restoredJournal))
+	require.NoError(t, err)
+	require.NoError(t, restored.Restore())
+	restarted, err := interest.NewEngine(restored, config)
+	require.NoError(t, err)
+
+	// Act
+	require.NoError(t, restarted.RunDue(now))
+	now = time.Date(2025, time.April, 1, 0, 5, 0, 0, time.UTC)
+	require.NoError(t, restarted.RunDue(now))
+
+	// Assert
+	posted, err := restored.FindTransactions(0, 10, func(transaction ledger.Transaction) bool {
+		return transaction.Kind == ledger.KindInterest
+	})
+	require.NoError(t, err)
+	descriptions := make([]string, 0, len(posted))
+	for _, transaction := range posted {
+		descriptions = append(descriptions, transaction.Description)
+	}
+	assert.Equal(t, []string{
+		"Interest 2025-01-01 - 2025-01-31",
+		"Interest 2025-02-01 - 2025-02-28",
+		"Interest 2025-03-01 - 2025-03-31",
+	}, descriptions)
+}
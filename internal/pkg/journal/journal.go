@@ -0,0 +1,225 @@
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/scheduler"
+
+	"github.com/pkg/errors"
+)
+
+// FileName is the name of the journal in the data directory
+const FileName = "ledger.journal"
+
+// SchedulesFileName is the name of the file the schedules are saved to in the data directory
+const SchedulesFileName = "schedules.json"
+
+// Journal is an append only file persisting the entries added to the ledger, one group of entries posted together
+// per line. Every group is synced to disk before the ledger adds it, so an accepted transaction survives a crash.
+// The schedules of the scheduler are saved next to it, in the same data directory.
+type Journal struct {
+	mu      sync.Mutex
+	dataDir string
+	file    *os.File
+	// size is the length of the complete entries, the journal is rolled back to it when a write fails
+	size int64
+}
+
+// ErrLocked is returned when opening a journal another process opened, e.g. the webserver
+var ErrLocked = errors.New("journal is used by another process")
+
+// Open opens the journal of the data directory, creating both if they do not exist. The journal is locked until
+// closed, so a single process appends to it.
+func Open(dataDir string) (*Journal, error) {
+	if err := os.MkdirAll(dataDir, 0o750); err != nil {
+		return nil, errors.Wrap(err, "failed to create data directory")
+	}
+	file, err := os.OpenFile(filepath.Join(dataDir, FileName), os.O_CREATE|os.O_RDWR, 0o640)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open journal")
+	}
+	if err := lock(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "failed to open journal")
+	}
+	return &Journal{dataDir: dataDir, file: file, size: size}, nil
+}
+
+// Load returns the groups of entries in the journal. A last line left incomplete by a crash while it was written
+// was never acknowledged, it is discarded.
+func (j *Journal) Load() ([][]ledger.Transaction, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil, ErrClosed
+	}
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.Wrap(err, "failed to read journal")
+	}
+	var groups [][]ledger.Transaction
+	var offset int64
+	reader := bufio.NewReader(j.file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(line) > 0 {
+				slog.Warn("discarding incomplete journal entry", "line", lineNumber, "bytes", len(line))
+				if err := j.file.Truncate(offset); err != nil {
+					return nil, errors.Wrap(err, "failed to discard incomplete journal entry")
+				}
+			}
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read journal")
+		}
+		var posted []ledger.Transaction
+		if err := json.Unmarshal(bytes.TrimSpace(line), &posted); err != nil {
+			return nil, errors.Wrapf(err, "corrupted journal entry on line %v", lineNumber)
+		}
+		groups = append(groups, posted)
+		offset += int64(len(line))
+	}
+	if _, err := j.file.Seek(offset, io.SeekStart); err != nil {
+		return nil, errors.Wrap(err, "failed to read journal")
+	}
+	j.size = offset
+	return groups, nil
+}
+
+// Append writes a group of entries and syncs it to disk
+func (j *Journal) Append(posted []ledger.Transaction) error {
+	line, err := json.Marshal(posted)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode entries")
+	}
+	line = append(line, '\n')
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return ErrClosed
+	}
+	if _, err := j.file.Write(line); err != nil {
+		return j.rollback(errors.Wrap(err, "failed to write journal"))
+	}
+	if err := j.file.Sync(); err != nil {
+		return j.rollback(errors.Wrap(err, "failed to sync journal"))
+	}
+	j.size += int64(len(line))
+	return nil
+}
+
+// rollback discards what was written of an entry that failed to persist, so it is never restored
+func (j *Journal) rollback(err error) error {
+	if truncateErr := j.file.Truncate(j.size); truncateErr != nil {
+		slog.Error("failed to roll back journal", "error", truncateErr)
+	} else if _, seekErr := j.file.Seek(j.size, io.SeekStart); seekErr != nil {
+		slog.Error("failed to roll back journal", "error", seekErr)
+	}
+	return err
+}
+
+// LoadSchedules returns the schedules saved along with the journal, none if they were never saved
+func (j *Journal) LoadSchedules() ([]scheduler.Schedule, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return nil, ErrClosed
+	}
+	content, err := os.ReadFile(filepath.Join(j.dataDir, SchedulesFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read schedules")
+	}
+	var schedules []scheduler.Schedule
+	if err := json.Unmarshal(content, &schedules); err != nil {
+		return nil, errors.Wrap(err, "corrupted schedules")
+	}
+	return schedules, nil
+}
+
+// SaveSchedules replaces the schedules saved along with the journal. They are written to a temporary file renamed
+// over the previous one once synced, so a crash leaves either the previous or the new schedules.
+func (j *Journal) SaveSchedules(schedules []scheduler.Schedule) error {
+	content, err := json.Marshal(schedules)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode schedules")
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return ErrClosed
+	}
+	file, err := os.CreateTemp(j.dataDir, "."+SchedulesFileName+"-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to write schedules")
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return errors.Wrap(err, "failed to write schedules")
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return errors.Wrap(err, "failed to sync schedules")
+	}
+	if err := file.Close(); err != nil {
+		return errors.Wrap(err, "failed to write schedules")
+	}
+	if err := os.Rename(file.Name(), filepath.Join(j.dataDir, SchedulesFileName)); err != nil {
+		return errors.Wrap(err, "failed to write schedules")
+	}
+	return syncDir(j.dataDir)
+}
+
+// ErrClosed is returned when using a closed journal
+var ErrClosed = errors.New("journal is closed")
+
+// Check tells whether entries can be appended, by writing a probe file to the data directory
+func (j *Journal) Check() error {
+	j.mu.Lock()
+	closed := j.file == nil
+	j.mu.Unlock()
+	if closed {
+		return ErrClosed
+	}
+	probe, err := os.CreateTemp(j.dataDir, ".probe-*")
+	if err != nil {
+		return errors.Wrap(err, "data directory is not writable")
+	}
+	probe.Close()
+	if err := os.Remove(probe.Name()); err != nil {
+		return errors.Wrap(err, "data directory is not writable")
+	}
+	return nil
+}
+
+// Close syncs and closes the journal
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return ErrClosed
+	}
+	file := j.file
+	j.file = nil
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return errors.Wrap(err, "failed to sync journal")
+	}
+	return file.Close()
+}
@@ -0,0 +1,174 @@
+package journal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"teya_home_assignment/internal/pkg/journal"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/scheduler"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newJournaledLedger(t *testing.T, dataDir string, opts ...ledger.Option) (*ledger.Ledger, *journal.Journal) {
+	t.Helper()
+	ledgerJournal, err := journal.Open(dataDir)
+	require.NoError(t, err)
+	ledgerInstance, err := ledger.NewLedger(append(opts, ledger.WithStore(ledgerJournal))...)
+	require.NoError(t, err)
+	require.NoError(t, ledgerInstance.Restore())
+	return ledgerInstance, ledgerJournal
+}
+
+func TestJournal__RestoresLedgerAfterRestart(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	ledgerInstance, ledgerJournal := newJournaledLedger(t, dataDir)
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(100), ledger.WithIdempotencyKey("key")))
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.RequireFromString("-20.5"),
+		ledger.WithMetadata(map[string]string{"channel": "online"})))
+	require.NoError(t, ledgerJournal.Close())
+	var observed, observedRestored []ledger.Transaction
+
+	// Act
+	observer := func(posted []ledger.Transaction) {
+		observed = append(observed, posted...)
+	}
+	restoredObserver := func(restored []ledger.Transaction) {
+		observedRestored = append(observedRestored, restored...)
+	}
+	restored, restoredJournal := newJournaledLedger(t, dataDir,
+		ledger.WithPostedObserver(observer), ledger.WithRestoredObserver(restoredObserver))
+	defer restoredJournal.Close()
+
+	// Assert
+	original, err := ledgerInstance.GetTransactionHistory(0, 10)
+	require.NoError(t, err)
+	history, err := restored.GetTransactionHistory(0, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	for i := range history {
+		assert.Equal(t, original[i].ExternalID, history[i].ExternalID)
+		assert.Equal(t, original[i].ID, history[i].ID)
+		assert.True(t, original[i].CreatedAt.Equal(history[i].CreatedAt))
+		assert.True(t, original[i].Amount.Equal(history[i].Amount))
+	}
+	assert.Equal(t, "online", history[1].Metadata["channel"])
+	assert.Len(t, observedRestored, 2)
+	assert.Empty(t, observed)
+	balance, err := restored.GetBalance()
+	require.NoError(t, err)
+	assert.True(t, decimal.RequireFromString("79.5").Equal(balance))
+	assert.ErrorIs(t, restored.AddTransaction(decimal.NewFromInt(1), ledger.WithIdempotencyKey("key")),
+		ledger.ErrDuplicateTransaction)
+	posted, err := restored.PostTransaction(decimal.NewFromInt(1))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), posted[0].ID)
+}
+
+func TestJournal__DiscardsIncompleteLastEntry(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	ledgerInstance, ledgerJournal := newJournaledLedger(t, dataDir)
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(100)))
+	require.NoError(t, ledgerJournal.Close())
+	file, err := os.OpenFile(filepath.Join(dataDir, journal.FileName), os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = file.WriteString(`[{"id":2,"amount":"5`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	// Act
+	restored, restoredJournal := newJournaledLedger(t, dataDir)
+	require.NoError(t, restored.AddTransaction(decimal.NewFromInt(1)))
+	require.NoError(t, restoredJournal.Close())
+	reopened, reopenedJournal := newJournaledLedger(t, dataDir)
+	defer reopenedJournal.Close()
+
+	// Assert
+	history, err := reopened.GetTransactionHistory(0, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.True(t, decimal.NewFromInt(1).Equal(history[1].Amount))
+}
+
+func TestJournal__RejectsCorruptedEntry(t *testing.T) {
+	dataDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, journal.FileName), []byte("not json\n"), 0o600))
+	ledgerJournal, err := journal.Open(dataDir)
+	require.NoError(t, err)
+	defer ledgerJournal.Close()
+
+	ledgerInstance, err := ledger.NewLedger(ledger.WithStore(ledgerJournal))
+	require.NoError(t, err)
+
+	assert.Error(t, ledgerInstance.Restore())
+}
+
+func TestJournal_Check__FailsOnceClosed(t *testing.T) {
+	ledgerJournal, err := journal.Open(t.TempDir())
+	require.NoError(t, err)
+
+	errOpen := ledgerJournal.Check()
+	require.NoError(t, ledgerJournal.Close())
+	errClosed := ledgerJournal.Check()
+
+	assert.NoError(t, errOpen)
+	assert.ErrorIs(t, errClosed, journal.ErrClosed)
+	assert.ErrorIs(t, ledgerJournal.Append(nil), journal.ErrClosed)
+}
+
+func TestJournal_Open__LockedUntilClosed(t *testing.T) {
+	dataDir := t.TempDir()
+	ledgerJournal, err := journal.Open(dataDir)
+	require.NoError(t, err)
+
+	_, errLocked := journal.Open(dataDir)
+	require.NoError(t, ledgerJournal.Close())
+	reopened, errReopened := journal.Open(dataDir)
+
+	assert.ErrorIs(t, errLocked, journal.ErrLocked)
+	require.NoError(t, errReopened)
+	assert.NoError(t, reopened.Close())
+}
+
+func TestJournal__RestoresSchedulesAfterRestart(t *testing.T) {
+	// Arrange
+	dataDir := t.TempDir()
+	start := time.Date(2025, time.January, 1, 9, 0, 0, 0, time.UTC)
+	ledgerInstance, ledgerJournal := newJournaledLedger(t, dataDir)
+	schedulerInstance := scheduler.NewScheduler(ledgerInstance, scheduler.WithStore(ledgerJournal))
+	require.NoError(t, schedulerInstance.Restore())
+	schedule, err := schedulerInstance.Create(scheduler.Schedule{
+		StartAt:     start,
+		Interval:    time.Hour,
+		Amount:      decimal.NewFromInt(-5),
+		Description: "subscription",
+	})
+	require.NoError(t, err)
+	require.NoError(t, schedulerInstance.RunDue(start.Add(30*time.Minute)))
+	require.NoError(t, ledgerJournal.Close())
+
+	// Act
+	restored, restoredJournal := newJournaledLedger(t, dataDir)
+	defer restoredJournal.Close()
+	restoredScheduler := scheduler.NewScheduler(restored, scheduler.WithStore(restoredJournal))
+	err = restoredScheduler.Restore()
+
+	// Assert
+	require.NoError(t, err)
+	schedules := restoredScheduler.List()
+	require.Len(t, schedules, 1)
+	assert.Equal(t, schedule.ID, schedules[0].ID)
+	assert.True(t, schedule.StartAt.Equal(schedules[0].StartAt))
+	assert.True(t, schedule.Amount.Equal(schedules[0].Amount))
+	assert.Equal(t, schedule.Description, schedules[0].Description)
+	require.NoError(t, restoredScheduler.RunDue(start.Add(150*time.Minute)))
+	history, err := restored.GetTransactionHistory(0, 10)
+	require.NoError(t, err)
+	assert.Len(t, history, 3)
+}
@@ -0,0 +1,31 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package journal
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// lock locks the journal exclusively until its file is closed, another process opening it fails with ErrLocked
+func lock(file *os.File) error {
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return ErrLocked
+		}
+		return errors.Wrap(err, "failed to lock journal")
+	}
+	return nil
+}
+
+// syncDir syncs the entries of the directory, so a file renamed into it survives a crash
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to sync data directory")
+	}
+	defer file.Close()
+	return errors.Wrap(file.Sync(), "failed to sync data directory")
+}
@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package journal
+
+import "os"
+
+// lock does not lock the journal where flock is not available, the data directory must not be shared
+func lock(*os.File) error {
+	return nil
+}
+
+// syncDir does nothing where directories can not be synced, the file renamed into it is synced already
+func syncDir(string) error {
+	return nil
+}
@@ -1,43 +1,310 @@
 package ledger
 
 import (
-	"fmt"
+	"context"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracerName is the instrumentation name of the spans of the ledger
+const tracerName = "teya_home_assignment/internal/pkg/ledger"
+
+// ErrDuplicateTransaction is returned when adding a transaction with an idempotency key that was already used
+var ErrDuplicateTransaction = errors.New("duplicate transaction")
+
+// ErrVersionMismatch is returned when adding a transaction expecting a version of the ledger it moved on from
+var ErrVersionMismatch = errors.New("ledger version mismatch")
+
 type Ledger struct {
 	TransactionHistory   []Transaction
 	transactionIdSeq     atomic.Uint64
 	cachedBalance        decimal.Decimal
 	cachedBalanceTillIdx int64
+	clock                func() time.Time
+	// idempotencyKeys maps the idempotency keys used so far to the index of their transaction
+	idempotencyKeys map[string]int
+	// externalIDs maps the external IDs of the transactions to their index
+	externalIDs       map[uuid.UUID]int
+	amountPolicy      AmountPolicy
+	postingChecks     []PostingCheck
+	postingHooks      []PostingHook
+	postedObservers   []PostedObserver
+	restoredObservers []PostedObserver
+	rejectedObservers []RejectedObserver
+	store             Store
+	tracerProvider    trace.TracerProvider
+	tracer            trace.Tracer
+	mu                sync.RWMutex
 }
 
-func NewLedger() (*Ledger, error) {
+func NewLedger(opts ...Option) (*Ledger, error) {
 	l := &Ledger{
 		TransactionHistory: make([]Transaction, 0),
 		//cachedBalanceTillIdx is inclusive and at this point we did not cache the first transaction
 		cachedBalanceTillIdx: -1,
+		clock:                time.Now,
+		idempotencyKeys:      make(map[string]int),
+		externalIDs:          make(map[uuid.UUID]int),
+		tracerProvider:       otel.GetTracerProvider(),
+	}
+	for _, opt := range opts {
+		opt(l)
 	}
+	l.tracer = l.tracerProvider.Tracer(tracerName)
 	l.transactionIdSeq.Store(0)
 	return l, nil
 }
 
-func (l *Ledger) AddTransaction(amount decimal.Decimal) error {
+// Restore adds the entries persisted in the store, notifying the restored observers of them so they rebuild their
+// state. The posted observers are not notified, the entries are not new, and the checks and hooks already ran when
+// they were posted. It must be called once, before any transaction is posted.
+func (l *Ledger) Restore() error {
+	if l.store == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.TransactionHistory) > 0 {
+		return errors.New("failed to restore ledger: transactions were already posted")
+	}
+	groups, err := l.store.Load()
+	if err != nil {
+		return errors.Wrap(err, "failed to restore ledger")
+	}
+	for _, posted := range groups {
+		if len(posted) == 0 {
+			continue
+		}
+		if key := posted[0].IdempotencyKey; key != "" {
+			l.idempotencyKeys[key] = len(l.TransactionHistory)
+		}
+		for i, transaction := range posted {
+			if transaction.ID <= l.transactionIdSeq.Load() {
+				return errors.Errorf("failed to restore ledger: entry %v is out of order", transaction.ID)
+			}
+			l.transactionIdSeq.Store(transaction.ID)
+			l.externalIDs[transaction.ExternalID] = len(l.TransactionHistory) + i
+		}
+		l.TransactionHistory = append(l.TransactionHistory, posted...)
+		for _, observer := range l.restoredObservers {
+			observer(posted)
+		}
+	}
+	return nil
+}
+
+func (l *Ledger) AddTransaction(amount decimal.Decimal, opts ...TransactionOption) error {
+	_, err := l.PostTransaction(amount, opts...)
+	return err
+}
+
+// PostTransaction adds a transaction along with the entries derived from it by the posting hooks, atomically.
+// It returns the added transaction followed by its linked entries.
+func (l *Ledger) PostTransaction(amount decimal.Decimal, opts ...TransactionOption) ([]Transaction, error) {
+	return l.PostTransactionContext(context.Background(), amount, opts...)
+}
+
+// PostTransactionContext is PostTransaction, traced as part of the operation carried by the context
+func (l *Ledger) PostTransactionContext(
+	ctx context.Context,
+	amount decimal.Decimal,
+	opts ...TransactionOption,
+) ([]Transaction, error) {
+	ctx, span := l.tracer.Start(ctx, "ledger.PostTransaction")
+	defer span.End()
 	newTransaction := Transaction{
-		ID:         l.getNewID(),
 		Amount:     amount,
 		ExternalID: uuid.New(),
 	}
-	l.TransactionHistory = append(l.TransactionHistory, newTransaction)
+	for _, opt := range opts {
+		opt(&newTransaction)
+	}
+	span.SetAttributes(attribute.String("ledger.transaction.external_id", newTransaction.ExternalID.String()))
+
+	posted, err := l.post(ctx, newTransaction)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "transaction rejected")
+		for _, observer := range l.rejectedObservers {
+			observer(newTransaction, err)
+		}
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("ledger.entries", len(posted)))
+	return posted, nil
+}
+
+func (l *Ledger) post(ctx context.Context, newTransaction Transaction) ([]Transaction, error) {
+	// the amount depends on the policy only, it is normalized before the ledger is locked
+	if l.amountPolicy != nil {
+		amount, err := l.amountPolicy(newTransaction.Amount)
+		if err != nil {
+			return nil, errors.Wrap(err, "transaction rejected")
+		}
+		newTransaction.Amount = amount
+	}
+	_, lockSpan := l.tracer.Start(ctx, "ledger.lock")
+	l.mu.Lock()
+	lockSpan.End()
+	defer l.mu.Unlock()
+	if newTransaction.IdempotencyKey != "" {
+		if _, exists := l.idempotencyKeys[newTransaction.IdempotencyKey]; exists {
+			return nil, errors.Wrapf(ErrDuplicateTransaction, "idempotency key %v", newTransaction.IdempotencyKey)
+		}
+	}
+	// the version is checked after the idempotency key, so a transaction submitted again after it was posted is
+	// reported as a duplicate rather than as expecting the version it moved the ledger on from
+	if expected := newTransaction.expectedVersion; expected != nil && *expected != l.version() {
+		return nil, errors.Wrapf(ErrVersionMismatch, "expected version %v, ledger is at version %v",
+			*expected, l.version())
+	}
+	newTransaction.expectedVersion = nil
+	newTransaction.CreatedAt = l.clock().UTC()
+	// keep the history sorted by creation time even if the wall clock goes backwards
+	if last := len(l.TransactionHistory) - 1; last >= 0 && newTransaction.CreatedAt.Before(l.TransactionHistory[last].CreatedAt) {
+		newTransaction.CreatedAt = l.TransactionHistory[last].CreatedAt
+	}
+
+	if err := l.runPostingChecks(ctx, newTransaction); err != nil {
+		return nil, err
+	}
+
+	posted, err := l.runPostingHooks(ctx, newTransaction)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range posted {
+		posted[i].ID = l.getNewID()
+	}
+	// like a database sequence, the IDs of entries that failed to persist are not reused
+	if l.store != nil {
+		if err := l.store.Append(posted); err != nil {
+			return nil, errors.Wrap(err, "failed to persist transaction")
+		}
+	}
+	if newTransaction.IdempotencyKey != "" {
+		l.idempotencyKeys[newTransaction.IdempotencyKey] = len(l.TransactionHistory)
+	}
+	for i := range posted {
+		l.externalIDs[posted[i].ExternalID] = len(l.TransactionHistory) + i
+	}
+	l.TransactionHistory = append(l.TransactionHistory, posted...)
+	_, observersSpan := l.tracer.Start(ctx, "ledger.postedObservers")
+	for _, observer := range l.postedObservers {
+		observer(posted)
+	}
+	observersSpan.End()
+	return posted, nil
+}
+
+// runPostingHooks returns the transaction followed by the entries derived from it
+func (l *Ledger) runPostingHooks(ctx context.Context, newTransaction Transaction) ([]Transaction, error) {
+	_, span := l.tracer.Start(ctx, "ledger.postingHooks")
+	defer span.End()
+	posted := []Transaction{newTransaction}
+	for _, hook := range l.postingHooks {
+		entries, err := hook(newTransaction)
+		if err != nil {
+			return nil, errors.Wrap(err, "posting hook failed")
+		}
+		for _, entry := range entries {
+			posted = append(posted, Transaction{
+				Amount:      entry.Amount,
+				ExternalID:  uuid.New(),
+				CreatedAt:   newTransaction.CreatedAt,
+				Description: entry.Description,
+				Kind:        entry.Kind,
+				LinkedTo:    newTransaction.ExternalID,
+			})
+		}
+	}
+	return posted, nil
+}
+
+func (l *Ledger) runPostingChecks(ctx context.Context, newTransaction Transaction) error {
+	_, span := l.tracer.Start(ctx, "ledger.postingChecks")
+	defer span.End()
+	for _, check := range l.postingChecks {
+		if err := check(newTransaction); err != nil {
+			return errors.Wrap(err, "transaction rejected")
+		}
+	}
 	return nil
 }
 
+// GetTransactionByIdempotencyKey returns the transaction added with the given idempotency key
+func (l *Ledger) GetTransactionByIdempotencyKey(key string) (Transaction, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	idx, exists := l.idempotencyKeys[key]
+	if !exists {
+		return Transaction{}, false
+	}
+	return l.TransactionHistory[idx], true
+}
+
+// GetPostedByIdempotencyKey returns the transaction added with the given idempotency key followed by its linked
+// entries, as returned by PostTransaction when it was added
+func (l *Ledger) GetPostedByIdempotencyKey(key string) ([]Transaction, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	idx, exists := l.idempotencyKeys[key]
+	if !exists {
+		return nil, false
+	}
+	transaction := l.TransactionHistory[idx]
+	// the entries posted together are contiguous in the history
+	end := idx + 1
+	for end < len(l.TransactionHistory) && l.TransactionHistory[end].LinkedTo == transaction.ExternalID {
+		end++
+	}
+	return slices.Clone(l.TransactionHistory[idx:end]), true
+}
+
+// GetTransaction returns the transaction with the given external ID
+func (l *Ledger) GetTransaction(externalID uuid.UUID) (Transaction, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	idx, exists := l.externalIDs[externalID]
+	if !exists {
+		return Transaction{}, false
+	}
+	return l.TransactionHistory[idx], true
+}
+
 func (l *Ledger) GetBalance() (decimal.Decimal, error) {
-	fmt.Printf("GetBalance called with current cache:\n cachedBalance: %v\n cachedBalanceTillIdx: %v\n",
-		l.cachedBalance, l.cachedBalanceTillIdx)
+	return l.GetBalanceContext(context.Background())
+}
+
+// GetBalanceContext is GetBalance, traced as part of the operation carried by the context
+func (l *Ledger) GetBalanceContext(ctx context.Context) (decimal.Decimal, error) {
+	balance, _, err := l.GetBalanceAndVersion(ctx)
+	return balance, err
+}
+
+// GetBalanceAndVersion returns the balance along with the version of the ledger it was calculated at
+func (l *Ledger) GetBalanceAndVersion(ctx context.Context) (decimal.Decimal, uint64, error) {
+	_, span := l.tracer.Start(ctx, "ledger.GetBalance")
+	defer span.End()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slog.Debug("GetBalance called with current cache",
+		"balance", l.cachedBalance, "cached_balance_till_idx", l.cachedBalanceTillIdx)
+	span.SetAttributes(attribute.Int64("ledger.uncached_transactions",
+		int64(len(l.TransactionHistory))-1-l.cachedBalanceTillIdx))
 	balance := l.cachedBalance
 	for i := l.cachedBalanceTillIdx + 1; i < int64(len(l.TransactionHistory)); i++ {
 		balance = balance.Add(l.TransactionHistory[i].Amount)
@@ -45,12 +312,103 @@ func (l *Ledger) GetBalance() (decimal.Decimal, error) {
 		l.cachedBalanceTillIdx = i
 		l.cachedBalance = balance
 	}
+	return balance, l.version(), nil
+}
+
+// GetVersion returns the version of the ledger, which increases whenever entries are added: the ID of the last entry,
+// or 0 while the ledger is empty. A transaction added WithExpectedVersion is rejected unless the ledger is still at
+// that version.
+func (l *Ledger) GetVersion() uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.version()
+}
+
+func (l *Ledger) version() uint64 {
+	if len(l.TransactionHistory) == 0 {
+		return 0
+	}
+	return l.TransactionHistory[len(l.TransactionHistory)-1].ID
+}
+
+// Stats describes the size of the ledger
+type Stats struct {
+	TransactionCount int
+	// UncachedTransactions is the number of transactions added since the balance was last cached
+	UncachedTransactions int
+}
+
+func (l *Ledger) GetStats() Stats {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return Stats{
+		TransactionCount: len(l.TransactionHistory),
+		// cachedBalanceTillIdx is inclusive
+		UncachedTransactions: len(l.TransactionHistory) - int(l.cachedBalanceTillIdx) - 1,
+	}
+}
+
+// GetBalanceAt returns the balance made of all transactions created strictly before the given time
+func (l *Ledger) GetBalanceAt(at time.Time) (decimal.Decimal, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	balance := decimal.Zero
+	for _, transaction := range l.TransactionHistory[:l.indexOf(at)] {
+		balance = balance.Add(transaction.Amount)
+	}
 	return balance, nil
 }
 
+// GetBalancesAt returns the balance at each of the given times as GetBalanceAt does, the times being in ascending
+// order. The history is summed in a single pass whatever the number of times.
+func (l *Ledger) GetBalancesAt(ats []time.Time) ([]decimal.Decimal, error) {
+	for i := 1; i < len(ats); i++ {
+		if ats[i].Before(ats[i-1]) {
+			return nil, errors.Errorf("times are not in ascending order: %v is before %v", ats[i], ats[i-1])
+		}
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	balances := make([]decimal.Decimal, 0, len(ats))
+	balance := decimal.Zero
+	summedTill := 0
+	for _, at := range ats {
+		idx := l.indexOf(at)
+		for _, transaction := range l.TransactionHistory[summedTill:idx] {
+			balance = balance.Add(transaction.Amount)
+		}
+		summedTill = idx
+		balances = append(balances, balance)
+	}
+	return balances, nil
+}
+
 func (l *Ledger) GetTransactionHistory(offset, limit int) ([]Transaction, error) {
+	page, err := l.GetTransactionPage(offset, limit)
+	return page.Transactions, err
+}
+
+// Page is a page of transactions, along with the number of transactions over all the pages
+type Page struct {
+	Offset       int
+	Transactions []Transaction
+	// Total is counted along with the page, so the page and its total are consistent even while transactions are
+	// added
+	Total int
+}
+
+// HasMore tells whether transactions follow the page
+func (p Page) HasMore() bool {
+	return p.Offset+len(p.Transactions) < p.Total
+}
+
+// GetTransactionPage returns a page of the transaction history, the total being the length of the history
+func (l *Ledger) GetTransactionPage(offset, limit int) (Page, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	page := Page{Offset: offset, Transactions: []Transaction{}, Total: len(l.TransactionHistory)}
 	if offset > len(l.TransactionHistory) {
-		return []Transaction{}, nil
+		return page, nil
 	}
 
 	endIndex := offset + limit
@@ -58,7 +416,96 @@ func (l *Ledger) GetTransactionHistory(offset, limit int) ([]Transaction, error)
 		endIndex = len(l.TransactionHistory)
 	}
 
-	return l.TransactionHistory[offset:endIndex], nil
+	page.Transactions = l.TransactionHistory[offset:endIndex]
+	return page, nil
+}
+
+// FindTransactionPage paginates over the transactions matching the filter like FindTransactions. The whole history
+// is filtered to count the matching transactions, in the same pass as the page is collected.
+func (l *Ledger) FindTransactionPage(offset, limit int, filter func(Transaction) bool) (Page, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	page := Page{Offset: offset, Transactions: make([]Transaction, 0, limit)}
+	for _, transaction := range l.TransactionHistory {
+		if !filter(transaction) {
+			continue
+		}
+		if page.Total >= offset && len(page.Transactions) < limit {
+			page.Transactions = append(page.Transactions, transaction)
+		}
+		page.Total++
+	}
+	return page, nil
+}
+
+// FindTransactions paginates over the transactions matching the filter, in the order they were added
+func (l *Ledger) FindTransactions(offset, limit int, filter func(Transaction) bool) ([]Transaction, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	found := make([]Transaction, 0, limit)
+	for _, transaction := range l.TransactionHistory {
+		if len(found) == limit {
+			break
+		}
+		if !filter(transaction) {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		found = append(found, transaction)
+	}
+	return found, nil
+}
+
+// FindLastTransaction returns the last transaction added which matches the filter, if any
+func (l *Ledger) FindLastTransaction(filter func(Transaction) bool) (Transaction, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for i := len(l.TransactionHistory) - 1; i >= 0; i-- {
+		if filter(l.TransactionHistory[i]) {
+			return l.TransactionHistory[i], true
+		}
+	}
+	return Transaction{}, false
+}
+
+// GetTransactionsBetween returns the transactions created within [from, to)
+func (l *Ledger) GetTransactionsBetween(from, to time.Time) ([]Transaction, error) {
+	if to.Before(from) {
+		return nil, errors.Errorf("invalid time range: %v is before %v", to, from)
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.TransactionHistory[l.indexOf(from):l.indexOf(to)], nil
+}
+
+// VisitTransactionsBetween calls visit with every transaction created within [from, to), in the order they were
+// added, a zero to meaning no end. The transactions are visited under the read lock rather than copied, so visit
+// must not call the ledger.
+func (l *Ledger) VisitTransactionsBetween(from, to time.Time, visit func(Transaction)) error {
+	if !to.IsZero() && to.Before(from) {
+		return errors.Errorf("invalid time range: %v is before %v", to, from)
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	end := len(l.TransactionHistory)
+	if !to.IsZero() {
+		end = l.indexOf(to)
+	}
+	for _, transaction := range l.TransactionHistory[l.indexOf(from):end] {
+		visit(transaction)
+	}
+	return nil
+}
+
+// indexOf returns the index of the first transaction created at or after the given time.
+// Transactions are appended in creation order, so the history is sorted by CreatedAt.
+func (l *Ledger) indexOf(at time.Time) int {
+	return sort.Search(len(l.TransactionHistory), func(i int) bool {
+		return !l.TransactionHistory[i].CreatedAt.Before(at)
+	})
 }
 
 func (l *Ledger) getNewID() uint64 {
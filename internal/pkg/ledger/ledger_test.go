@@ -1,13 +1,18 @@
 package ledger_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"teya_home_assignment/internal/pkg/ledger"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestLedger_NewLedger__CreatesEmptyLedger(t *testing.T) {
@@ -424,3 +429,456 @@ func TestCachingLedger_GetBalance__CacheWorksWithMultipleCallsAndTransactions(t
 	assert.True(t, decimal.NewFromFloat32(60.00).Equal(thirdBalanceSecondCall),
 		fmt.Sprintf("%+v != 60.00", thirdBalanceSecondCall))
 }
+
+func TestLedger_AddTransaction__StampsCreationTime(t *testing.T) {
+	// Arrange
+	now := time.Date(2025, time.March, 1, 10, 0, 0, 0, time.UTC)
+	ledgerInstance, err := ledger.NewLedger(ledger.WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+
+	// Act
+	err = ledgerInstance.AddTransaction(decimal.NewFromFloat32(10))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, now, ledgerInstance.TransactionHistory[0].CreatedAt)
+}
+
+func TestLedger_GetTransactionsBetween__ReturnsTransactionsWithinRange(t *testing.T) {
+	// Arrange
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	ledgerInstance, err := ledger.NewLedger(ledger.WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		now = start.AddDate(0, 0, i)
+		require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(int64(i+1))))
+	}
+
+	// Act
+	transactions, err := ledgerInstance.GetTransactionsBetween(start.AddDate(0, 0, 1), start.AddDate(0, 0, 3))
+
+	// Assert
+	assert.NoError(t, err)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, uint64(2), transactions[0].ID)
+	assert.Equal(t, uint64(3), transactions[1].ID)
+}
+
+func TestLedger_VisitTransactionsBetween__VisitsTransactionsWithinRange(t *testing.T) {
+	// Arrange
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	ledgerInstance, err := ledger.NewLedger(ledger.WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+	for i := range 4 {
+		now = start.AddDate(0, 0, i)
+		require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(int64(i+1))))
+	}
+	var within, unbounded []uint64
+
+	// Act
+	err = ledgerInstance.VisitTransactionsBetween(start.AddDate(0, 0, 1), start.AddDate(0, 0, 3),
+		func(transaction ledger.Transaction) { within = append(within, transaction.ID) })
+	require.NoError(t, err)
+	err = ledgerInstance.VisitTransactionsBetween(start.AddDate(0, 0, 2), time.Time{},
+		func(transaction ledger.Transaction) { unbounded = append(unbounded, transaction.ID) })
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, []uint64{2, 3}, within)
+	assert.Equal(t, []uint64{3, 4}, unbounded)
+}
+
+func TestLedger_GetBalanceAt__SumsTransactionsBeforeTime(t *testing.T) {
+	// Arrange
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	ledgerInstance, err := ledger.NewLedger(ledger.WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+	for i, amount := range []int64{100, -30, 5} {
+		now = start.AddDate(0, 0, i)
+		require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(amount)))
+	}
+
+	// Act
+	balance, err := ledgerInstance.GetBalanceAt(start.AddDate(0, 0, 2))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(70).Equal(balance), fmt.Sprintf("%+v != 70", balance))
+}
+
+func TestLedger_GetBalancesAt__SumsTransactionsBeforeEachTime(t *testing.T) {
+	// Arrange
+	start := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	ledgerInstance, err := ledger.NewLedger(ledger.WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+	for i, amount := range []int64{100, -30, 5} {
+		now = start.AddDate(0, 0, i)
+		require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(amount)))
+	}
+
+	// Act
+	balances, err := ledgerInstance.GetBalancesAt([]time.Time{start, start.AddDate(0, 0, 2), start.AddDate(0, 0, 5)})
+	_, errUnordered := ledgerInstance.GetBalancesAt([]time.Time{start.AddDate(0, 0, 1), start})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0", "70", "75"}, []string{balances[0].String(), balances[1].String(), balances[2].String()})
+	assert.Error(t, errUnordered)
+}
+
+func TestLedger_AddTransaction__RejectsDuplicateIdempotencyKey(t *testing.T) {
+	// Arrange
+	ledgerInstance, err := ledger.NewLedger()
+	require.NoError(t, err)
+	err = ledgerInstance.AddTransaction(decimal.NewFromFloat32(10), ledger.WithIdempotencyKey("key-1"))
+	require.NoError(t, err)
+
+	// Act
+	err = ledgerInstance.AddTransaction(decimal.NewFromFloat32(10), ledger.WithIdempotencyKey("key-1"))
+
+	// Assert
+	assert.ErrorIs(t, err, ledger.ErrDuplicateTransaction)
+	assert.Len(t, ledgerInstance.TransactionHistory, 1)
+	transaction, exists := ledgerInstance.GetTransactionByIdempotencyKey("key-1")
+	assert.True(t, exists)
+	assert.Equal(t, uint64(1), transaction.ID)
+}
+
+func TestLedger_AddTransaction__RejectsStaleExpectedVersion(t *testing.T) {
+	// Arrange
+	fee := func(transaction ledger.Transaction) ([]ledger.LinkedEntry, error) {
+		return []ledger.LinkedEntry{{Amount: decimal.NewFromInt(-1), Kind: ledger.KindFee}}, nil
+	}
+	ledgerInstance, err := ledger.NewLedger(ledger.WithPostingHook(fee))
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), ledgerInstance.GetVersion())
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(10), ledger.WithExpectedVersion(0)))
+	version := ledgerInstance.GetVersion()
+
+	// Act
+	results := make(chan error, 2)
+	for range 2 {
+		go func() {
+			results <- ledgerInstance.AddTransaction(decimal.NewFromInt(10), ledger.WithExpectedVersion(version))
+		}()
+	}
+	errs := []error{<-results, <-results}
+
+	// Assert
+	assert.Equal(t, uint64(2), version)
+	assert.Equal(t, uint64(4), ledgerInstance.GetVersion())
+	assert.Len(t, ledgerInstance.TransactionHistory, 4)
+	if errs[0] == nil {
+		errs[0], errs[1] = errs[1], errs[0]
+	}
+	assert.ErrorIs(t, errs[0], ledger.ErrVersionMismatch)
+	assert.NoError(t, errs[1])
+	balance, balanceVersion, err := ledgerInstance.GetBalanceAndVersion(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "18", balance.String())
+	assert.Equal(t, uint64(4), balanceVersion)
+}
+
+func TestLedger_AddTransaction__ReportsDuplicateBeforeVersionMismatch(t *testing.T) {
+	// Arrange
+	ledgerInstance, err := ledger.NewLedger()
+	require.NoError(t, err)
+	opts := []ledger.TransactionOption{ledger.WithIdempotencyKey("key-1"), ledger.WithExpectedVersion(0)}
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(10), opts...))
+
+	// Act
+	err = ledgerInstance.AddTransaction(decimal.NewFromInt(10), opts...)
+
+	// Assert
+	assert.ErrorIs(t, err, ledger.ErrDuplicateTransaction)
+}
+
+func TestLedger_PostTransaction__AddsLinkedEntriesAtomically(t *testing.T) {
+	// Arrange
+	fee := func(transaction ledger.Transaction) ([]ledger.LinkedEntry, error) {
+		return []ledger.LinkedEntry{{Amount: transaction.Amount.Div(decimal.NewFromInt(-100)), Kind: ledger.KindFee}}, nil
+	}
+	ledgerInstance, err := ledger.NewLedger(ledger.WithPostingHook(fee))
+	require.NoError(t, err)
+
+	// Act
+	posted, err := ledgerInstance.PostTransaction(decimal.NewFromInt(200))
+
+	// Assert
+	assert.NoError(t, err)
+	require.Len(t, posted, 2)
+	assert.Equal(t, ledgerInstance.TransactionHistory, posted)
+	assert.Equal(t, uint64(2), posted[1].ID)
+	assert.Equal(t, posted[0].ExternalID, posted[1].LinkedTo)
+	assert.True(t, decimal.NewFromInt(-2).Equal(posted[1].Amount), posted[1].Amount.String())
+}
+
+func TestLedger_PostTransaction__AddsNothingWhenHookFails(t *testing.T) {
+	// Arrange
+	failing := func(transaction ledger.Transaction) ([]ledger.LinkedEntry, error) {
+		return nil, fmt.Errorf("no fee schedule")
+	}
+	ledgerInstance, err := ledger.NewLedger(ledger.WithPostingHook(failing))
+	require.NoError(t, err)
+
+	// Act
+	_, err = ledgerInstance.PostTransaction(decimal.NewFromInt(200), ledger.WithIdempotencyKey("key-1"))
+
+	// Assert
+	assert.Error(t, err)
+	assert.Empty(t, ledgerInstance.TransactionHistory)
+	_, exists := ledgerInstance.GetTransactionByIdempotencyKey("key-1")
+	assert.False(t, exists)
+}
+
+func TestLedger_GetPostedByIdempotencyKey__ReturnsLinkedEntries(t *testing.T) {
+	// Arrange
+	fee := func(transaction ledger.Transaction) ([]ledger.LinkedEntry, error) {
+		return []ledger.LinkedEntry{{Amount: decimal.NewFromInt(-1), Kind: ledger.KindFee}}, nil
+	}
+	ledgerInstance, err := ledger.NewLedger(ledger.WithPostingHook(fee))
+	require.NoError(t, err)
+	posted, err := ledgerInstance.PostTransaction(decimal.NewFromInt(200), ledger.WithIdempotencyKey("key-1"))
+	require.NoError(t, err)
+	_, err = ledgerInstance.PostTransaction(decimal.NewFromInt(300))
+	require.NoError(t, err)
+
+	// Act
+	found, exists := ledgerInstance.GetPostedByIdempotencyKey("key-1")
+	_, unknownExists := ledgerInstance.GetPostedByIdempotencyKey("key-2")
+
+	// Assert
+	assert.True(t, exists)
+	assert.Equal(t, posted, found)
+	assert.False(t, unknownExists)
+}
+
+func TestLedger_GetTransaction__FindsTransactionByExternalID(t *testing.T) {
+	// Arrange
+	ledgerInstance, err := ledger.NewLedger()
+	require.NoError(t, err)
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(100)))
+	posted, err := ledgerInstance.PostTransaction(decimal.NewFromInt(200))
+	require.NoError(t, err)
+
+	// Act
+	found, exists := ledgerInstance.GetTransaction(posted[0].ExternalID)
+	_, unknownExists := ledgerInstance.GetTransaction(uuid.New())
+
+	// Assert
+	assert.True(t, exists)
+	assert.Equal(t, posted[0], found)
+	assert.False(t, unknownExists)
+}
+
+func TestLedger_FindTransactions__PaginatesOverMatchingTransactions(t *testing.T) {
+	// Arrange
+	ledgerInstance, err := ledger.NewLedger()
+	require.NoError(t, err)
+	for i := 1; i <= 10; i++ {
+		require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(int64(i))))
+	}
+	even := func(transaction ledger.Transaction) bool {
+		return transaction.Amount.IntPart()%2 == 0
+	}
+
+	// Act
+	found, err := ledgerInstance.FindTransactions(1, 3, even)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, found, 3)
+	assert.True(t, decimal.NewFromInt(4).Equal(found[0].Amount))
+	assert.True(t, decimal.NewFromInt(6).Equal(found[1].Amount))
+	assert.True(t, decimal.NewFromInt(8).Equal(found[2].Amount))
+}
+
+func TestLedger_FindTransactionPage__CountsMatchingTransactions(t *testing.T) {
+	// Arrange
+	ledgerInstance, err := ledger.NewLedger()
+	require.NoError(t, err)
+	for i := 1; i <= 10; i++ {
+		require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(int64(i))))
+	}
+	even := func(transaction ledger.Transaction) bool {
+		return transaction.Amount.IntPart()%2 == 0
+	}
+
+	// Act
+	first, err := ledgerInstance.FindTransactionPage(0, 3, even)
+	require.NoError(t, err)
+	last, err := ledgerInstance.FindTransactionPage(3, 3, even)
+	require.NoError(t, err)
+
+	// Assert
+	assert.Len(t, first.Transactions, 3)
+	assert.Equal(t, 5, first.Total)
+	assert.True(t, first.HasMore())
+	require.Len(t, last.Transactions, 2)
+	assert.True(t, decimal.NewFromInt(8).Equal(last.Transactions[0].Amount))
+	assert.Equal(t, 5, last.Total)
+	assert.False(t, last.HasMore())
+}
+
+func TestLedger_PostTransaction__AppliesAmountPolicy(t *testing.T) {
+	// Arrange
+	var rejected int
+	ledgerInstance, err := ledger.NewLedger(
+		ledger.WithAmountPolicy(func(amount decimal.Decimal) (decimal.Decimal, error) {
+			if amount.IsZero() {
+				return decimal.Decimal{}, assert.AnError
+			}
+			return amount.Round(2), nil
+		}),
+		ledger.WithRejectedObserver(func(ledger.Transaction, error) {
+			rejected++
+		}),
+	)
+	require.NoError(t, err)
+
+	// Act
+	posted, postErr := ledgerInstance.PostTransaction(decimal.RequireFromString("1.5"))
+	_, zeroErr := ledgerInstance.PostTransaction(decimal.Zero)
+
+	// Assert
+	require.NoError(t, postErr)
+	assert.Equal(t, decimal.New(150, -2), posted[0].Amount)
+	assert.ErrorIs(t, zeroErr, assert.AnError)
+	assert.Equal(t, 1, rejected)
+	assert.Equal(t, 1, ledgerInstance.GetStats().TransactionCount)
+}
+
+func TestLedger_PostTransaction__NotifiesRejectedObservers(t *testing.T) {
+	// Arrange
+	var rejected []error
+	ledgerInstance, err := ledger.NewLedger(
+		ledger.WithRejectedObserver(func(_ ledger.Transaction, err error) {
+			rejected = append(rejected, err)
+		}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(100), ledger.WithIdempotencyKey("key")))
+
+	// Act
+	err = ledgerInstance.AddTransaction(decimal.NewFromInt(100), ledger.WithIdempotencyKey("key"))
+
+	// Assert
+	require.Error(t, err)
+	require.Len(t, rejected, 1)
+	assert.ErrorIs(t, rejected[0], ledger.ErrDuplicateTransaction)
+}
+
+func TestLedger_GetStats__CountsUncachedTransactions(t *testing.T) {
+	// Arrange
+	ledgerInstance, err := ledger.NewLedger()
+	require.NoError(t, err)
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(100)))
+	_, err = ledgerInstance.GetBalance()
+	require.NoError(t, err)
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(50)))
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(25)))
+
+	// Act
+	stats := ledgerInstance.GetStats()
+
+	// Assert
+	assert.Equal(t, ledger.Stats{TransactionCount: 3, UncachedTransactions: 2}, stats)
+}
+
+func TestLedger_PostTransactionContext__TracesPostingPhases(t *testing.T) {
+	// Arrange
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ledgerInstance, err := ledger.NewLedger(ledger.WithTracerProvider(provider))
+	require.NoError(t, err)
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+
+	// Act
+	_, err = ledgerInstance.PostTransactionContext(ctx, decimal.NewFromInt(100))
+	parent.End()
+
+	// Assert
+	require.NoError(t, err)
+	names := map[string]bool{}
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+		names[span.Name()] = true
+	}
+	for _, name := range []string{"ledger.PostTransaction", "ledger.lock", "ledger.postingChecks",
+		"ledger.postingHooks", "ledger.postedObservers"} {
+		assert.True(t, names[name], "missing span %v", name)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Load() ([][]ledger.Transaction, error) { return nil, nil }
+
+func (failingStore) Append([]ledger.Transaction) error { return fmt.Errorf("disk full") }
+
+func TestLedger_PostTransaction__AddsNothingWhenStoreFails(t *testing.T) {
+	// Arrange
+	ledgerInstance, err := ledger.NewLedger(ledger.WithStore(failingStore{}))
+	require.NoError(t, err)
+
+	// Act
+	err = ledgerInstance.AddTransaction(decimal.NewFromInt(100), ledger.WithIdempotencyKey("key"))
+
+	// Assert
+	require.Error(t, err)
+	assert.Empty(t, ledgerInstance.TransactionHistory)
+	_, exists := ledgerInstance.GetTransactionByIdempotencyKey("key")
+	assert.False(t, exists)
+}
+
+func TestVerifyHistory__ValidHistory(t *testing.T) {
+	// Arrange
+	fee := func(transaction ledger.Transaction) ([]ledger.LinkedEntry, error) {
+		return []ledger.LinkedEntry{{Amount: decimal.NewFromInt(-1), Kind: ledger.KindFee}}, nil
+	}
+	ledgerInstance, err := ledger.NewLedger(ledger.WithPostingHook(fee))
+	require.NoError(t, err)
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(200), ledger.WithIdempotencyKey("key-1")))
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(300)))
+
+	history, err := ledgerInstance.GetTransactionHistory(0, 10)
+	require.NoError(t, err)
+
+	// Act
+	violations := ledger.VerifyHistory(history)
+
+	// Assert
+	assert.Empty(t, violations)
+}
+
+func TestVerifyHistory__ReportsViolations(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	first, second, third := uuid.New(), uuid.New(), uuid.New()
+	history := []ledger.Transaction{
+		{ID: 1, ExternalID: first, CreatedAt: now, IdempotencyKey: "key-1"},
+		{ID: 2, ExternalID: second, CreatedAt: now, LinkedTo: first},
+		{ID: 2, ExternalID: third, CreatedAt: now.Add(-time.Second), IdempotencyKey: "key-1"},
+		{ID: 4, ExternalID: second, CreatedAt: now, LinkedTo: first},
+	}
+
+	// Act
+	violations := ledger.VerifyHistory(history)
+
+	// Assert
+	var problems []string
+	for _, violation := range violations {
+		problems = append(problems, violation.String())
+	}
+	assert.Equal(t, []string{
+		fmt.Sprintf(`entry 2 (%v): idempotency key "key-1" already used by entry 0`, third),
+		fmt.Sprintf("entry 2 (%v): ID 2 is not greater than the ID 2 of the previous entry", third),
+		fmt.Sprintf("entry 2 (%v): created at %v, before the previous entry", third, now.Add(-time.Second)),
+		fmt.Sprintf("entry 3 (%v): external ID already used by entry 1", second),
+		fmt.Sprintf("entry 3 (%v): linked to %v, which is not the transaction it was posted with", second, first),
+	}, problems)
+}
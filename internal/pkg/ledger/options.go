@@ -0,0 +1,125 @@
+package ledger
+
+import (
+	"maps"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option customizes a Ledger created by NewLedger
+type Option func(l *Ledger)
+
+// WithClock overrides the time source used to stamp new transactions.
+// Mostly useful for tests that require deterministic timestamps.
+func WithClock(clock func() time.Time) Option {
+	return func(l *Ledger) {
+		l.clock = clock
+	}
+}
+
+// WithPostingHook registers a hook deriving linked entries for every transaction posted to the ledger
+func WithPostingHook(hook PostingHook) Option {
+	return func(l *Ledger) {
+		l.postingHooks = append(l.postingHooks, hook)
+	}
+}
+
+// WithAmountPolicy applies the policy to the amount of every transaction added to the ledger, before the checks
+func WithAmountPolicy(policy AmountPolicy) Option {
+	return func(l *Ledger) {
+		l.amountPolicy = policy
+	}
+}
+
+// WithPostingCheck registers a check every transaction must pass before it is added to the ledger
+func WithPostingCheck(check PostingCheck) Option {
+	return func(l *Ledger) {
+		l.postingChecks = append(l.postingChecks, check)
+	}
+}
+
+// WithPostedObserver registers an observer notified of every group of entries added to the ledger
+func WithPostedObserver(observer PostedObserver) Option {
+	return func(l *Ledger) {
+		l.postedObservers = append(l.postedObservers, observer)
+	}
+}
+
+// WithRestoredObserver registers an observer notified of every group of entries restored from the store by
+// Ledger.Restore. An observer deriving its state from the whole ledger is registered as a posted observer too.
+func WithRestoredObserver(observer PostedObserver) Option {
+	return func(l *Ledger) {
+		l.restoredObservers = append(l.restoredObservers, observer)
+	}
+}
+
+// WithRejectedObserver registers an observer notified of every transaction the ledger refused to add
+func WithRejectedObserver(observer RejectedObserver) Option {
+	return func(l *Ledger) {
+		l.rejectedObservers = append(l.rejectedObservers, observer)
+	}
+}
+
+// WithStore persists the entries added to the ledger in the store. The entries already persisted are restored by
+// Ledger.Restore.
+func WithStore(store Store) Option {
+	return func(l *Ledger) {
+		l.store = store
+	}
+}
+
+// WithTracerProvider traces the ledger operations with the provider instead of the global one
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(l *Ledger) {
+		l.tracerProvider = provider
+	}
+}
+
+// TransactionOption sets optional properties of a transaction added with Ledger.AddTransaction
+type TransactionOption func(t *Transaction)
+
+// WithReference attaches an external reference (e.g. the acquirer's) to the transaction
+func WithReference(reference string) TransactionOption {
+	return func(t *Transaction) {
+		t.Reference = reference
+	}
+}
+
+// WithDescription attaches a free text description to the transaction
+func WithDescription(description string) TransactionOption {
+	return func(t *Transaction) {
+		t.Description = description
+	}
+}
+
+// WithMetadata attaches client defined key/value pairs to the transaction, e.g. the payment channel.
+// The map is copied so the transaction cannot be changed through it.
+func WithMetadata(metadata map[string]string) TransactionOption {
+	return func(t *Transaction) {
+		t.Metadata = maps.Clone(metadata)
+	}
+}
+
+// WithIdempotencyKey makes Ledger.AddTransaction reject the transaction with ErrDuplicateTransaction
+// if a transaction with the same key was already added
+func WithIdempotencyKey(key string) TransactionOption {
+	return func(t *Transaction) {
+		t.IdempotencyKey = key
+	}
+}
+
+// WithKind marks a transaction posted by the ledger itself rather than requested by a client, e.g. interest
+func WithKind(kind string) TransactionOption {
+	return func(t *Transaction) {
+		t.Kind = kind
+	}
+}
+
+// WithExpectedVersion makes Ledger.AddTransaction reject the transaction with ErrVersionMismatch unless the ledger is
+// still at the given version, e.g. the one the balance the client checked was calculated at, see Ledger.GetVersion
+func WithExpectedVersion(version uint64) TransactionOption {
+	return func(t *Transaction) {
+		t.expectedVersion = &version
+	}
+}
@@ -1,13 +1,70 @@
 package ledger
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
 // Transaction represents an internal model for transaction entity
 type Transaction struct {
-	ID         uint64          `json:"id"`
-	Amount     decimal.Decimal `json:"amount"`
-	ExternalID uuid.UUID       `json:"external_id"`
+	ID             uint64            `json:"id"`
+	Amount         decimal.Decimal   `json:"amount"`
+	ExternalID     uuid.UUID         `json:"external_id"`
+	CreatedAt      time.Time         `json:"created_at"`
+	Reference      string            `json:"reference,omitempty"`
+	Description    string            `json:"description,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	IdempotencyKey string            `json:"idempotency_key,omitempty"`
+	Kind           string            `json:"kind,omitempty"`
+	LinkedTo       uuid.UUID         `json:"linked_to,omitempty"`
+
+	// expectedVersion is the version of the ledger the transaction must be added at, if any. It is a condition of
+	// the posting rather than a property of the transaction, so it is cleared once checked.
+	expectedVersion *uint64
+}
+
+const (
+	// KindFee marks the fee entries posted along with the transaction they were charged for
+	KindFee = "fee"
+	// KindInterest marks the interest posted by the ledger on the account balance
+	KindInterest = "interest"
+)
+
+// LinkedEntry is an entry derived from a transaction being posted, e.g. the fee charged for it.
+// It is added to the ledger along with that transaction and linked to it.
+type LinkedEntry struct {
+	Amount      decimal.Decimal
+	Description string
+	Kind        string
+}
+
+// PostingHook derives the entries to post along with a transaction. It runs while the ledger is locked,
+// so it must not call the ledger.
+type PostingHook func(transaction Transaction) ([]LinkedEntry, error)
+
+// AmountPolicy returns the amount of a transaction as it is posted, e.g. in a canonical representation, or an error
+// rejecting the transaction. It does not apply to the entries derived by the posting hooks.
+type AmountPolicy func(amount decimal.Decimal) (decimal.Decimal, error)
+
+// PostingCheck validates a transaction before it is added, an error rejects the transaction.
+// It runs while the ledger is locked, so it must not call the ledger.
+type PostingCheck func(transaction Transaction) error
+
+// PostedObserver is notified of the entries added to the ledger, while the ledger is still locked.
+// Along with PostingCheck it allows keeping state that is always consistent with the ledger.
+type PostedObserver func(posted []Transaction)
+
+// RejectedObserver is notified of the transactions the ledger refused to add, along with the reason.
+// It runs once the ledger is unlocked.
+type RejectedObserver func(transaction Transaction, err error)
+
+// Store persists the entries added to the ledger, so the ledger survives restarts
+type Store interface {
+	// Load returns the groups of entries persisted so far, in the order they were added
+	Load() ([][]Transaction, error)
+	// Append persists a group of entries posted together. It is called while the ledger is locked, before the
+	// entries are added, an error rejects them.
+	Append(posted []Transaction) error
 }
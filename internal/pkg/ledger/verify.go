@@ -0,0 +1,69 @@
+package ledger
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Violation is an inconsistency found in the history of the ledger, at the given index
+type Violation struct {
+	Index      int
+	ExternalID uuid.UUID
+	Problem    string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("entry %v (%v): %v", v.Index, v.ExternalID, v.Problem)
+}
+
+// VerifyHistory checks the invariants of a history of entries, in the order they were added:
+//   - the IDs are increasing and the external IDs unique
+//   - the entries are sorted by creation time, which the lookups by time rely on
+//   - an idempotency key is used once
+//   - the linked entries directly follow their transaction, which is not a linked entry itself
+//
+// The IDs and idempotency keys are only checked when set, so a history read from the API can be verified too.
+func VerifyHistory(history []Transaction) []Violation {
+	var violations []Violation
+	report := func(index int, format string, args ...any) {
+		violations = append(violations, Violation{
+			Index:      index,
+			ExternalID: history[index].ExternalID,
+			Problem:    fmt.Sprintf(format, args...),
+		})
+	}
+	externalIDs := make(map[uuid.UUID]int, len(history))
+	idempotencyKeys := make(map[string]int)
+	// group is the index of the last transaction which is not a linked entry
+	group := -1
+	for i, transaction := range history {
+		if first, exists := externalIDs[transaction.ExternalID]; exists {
+			report(i, "external ID already used by entry %v", first)
+		}
+		externalIDs[transaction.ExternalID] = i
+		if key := transaction.IdempotencyKey; key != "" {
+			if first, exists := idempotencyKeys[key]; exists {
+				report(i, "idempotency key %q already used by entry %v", key, first)
+			}
+			idempotencyKeys[key] = i
+		}
+		if i > 0 {
+			previous := history[i-1]
+			if transaction.ID != 0 && previous.ID != 0 && transaction.ID <= previous.ID {
+				report(i, "ID %v is not greater than the ID %v of the previous entry", transaction.ID, previous.ID)
+			}
+			if transaction.CreatedAt.Before(previous.CreatedAt) {
+				report(i, "created at %v, before the previous entry", transaction.CreatedAt)
+			}
+		}
+		if transaction.LinkedTo == uuid.Nil {
+			group = i
+			continue
+		}
+		if group < 0 || transaction.LinkedTo != history[group].ExternalID {
+			report(i, "linked to %v, which is not the transaction it was posted with", transaction.LinkedTo)
+		}
+	}
+	return violations
+}
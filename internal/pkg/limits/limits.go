@@ -0,0 +1,129 @@
+package limits
+
+import (
+	"fmt"
+	"sync"
+	"teya_home_assignment/internal/pkg/ledger"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	RuleMaxTransactionAmount     = "max_transaction_amount"
+	RuleMaxDailyDebits           = "max_daily_debits"
+	RuleMaxWeeklyDebits          = "max_weekly_debits"
+	RuleMaxTransactionsPerMinute = "max_transactions_per_minute"
+)
+
+// Rules are the limits enforced on the transactions posted by clients. A zero value disables a rule.
+// Debits are summed as positive amounts over sliding windows of the last 24 hours and 7 days.
+type Rules struct {
+	MaxTransactionAmount     decimal.Decimal
+	MaxDailyDebits           decimal.Decimal
+	MaxWeeklyDebits          decimal.Decimal
+	MaxTransactionsPerMinute int
+}
+
+func (r Rules) Validate() error {
+	if r.MaxTransactionAmount.IsNegative() || r.MaxDailyDebits.IsNegative() || r.MaxWeeklyDebits.IsNegative() ||
+		r.MaxTransactionsPerMinute < 0 {
+		return errors.New("limits must not be negative")
+	}
+	return nil
+}
+
+// LimitExceededError is returned when a transaction would break one of the rules
+type LimitExceededError struct {
+	Rule  string
+	Limit string
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("limit exceeded: %v of %v", e.Rule, e.Limit)
+}
+
+// Limiter enforces the rules on the transactions posted to the ledger. It is registered on the ledger both as a
+// posting check and as a posted observer, so its counters are only updated with transactions that were added
+// and the check and the update happen atomically under the ledger lock.
+// Entries posted by the ledger itself, like fees and interest, are neither limited nor counted.
+type Limiter struct {
+	mu           sync.Mutex
+	rules        Rules
+	perMinute    *slidingWindow
+	dailyDebits  *slidingWindow
+	weeklyDebits *slidingWindow
+}
+
+func NewLimiter(rules Rules) (*Limiter, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid limits")
+	}
+	return &Limiter{
+		rules:        rules,
+		perMinute:    newSlidingWindow(time.Minute),
+		dailyDebits:  newSlidingWindow(24 * time.Hour),
+		weeklyDebits: newSlidingWindow(7 * 24 * time.Hour),
+	}, nil
+}
+
+func (l *Limiter) GetRules() Rules {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rules
+}
+
+// SetRules replaces the rules, counters are kept so lowered limits apply to the current windows
+func (l *Limiter) SetRules(rules Rules) error {
+	if err := rules.Validate(); err != nil {
+		return errors.Wrap(err, "invalid limits")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rules = rules
+	return nil
+}
+
+// Check is a ledger.PostingCheck rejecting transactions which exceed a limit with a LimitExceededError
+func (l *Limiter) Check(transaction ledger.Transaction) error {
+	if transaction.Kind != "" {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := transaction.CreatedAt
+	if !l.rules.MaxTransactionAmount.IsZero() && transaction.Amount.Abs().GreaterThan(l.rules.MaxTransactionAmount) {
+		return &LimitExceededError{Rule: RuleMaxTransactionAmount, Limit: l.rules.MaxTransactionAmount.String()}
+	}
+	if l.rules.MaxTransactionsPerMinute > 0 && l.perMinute.count(now) >= l.rules.MaxTransactionsPerMinute {
+		return &LimitExceededError{Rule: RuleMaxTransactionsPerMinute, Limit: fmt.Sprint(l.rules.MaxTransactionsPerMinute)}
+	}
+	if !transaction.Amount.IsNegative() {
+		return nil
+	}
+	debit := transaction.Amount.Abs()
+	if !l.rules.MaxDailyDebits.IsZero() && l.dailyDebits.total(now).Add(debit).GreaterThan(l.rules.MaxDailyDebits) {
+		return &LimitExceededError{Rule: RuleMaxDailyDebits, Limit: l.rules.MaxDailyDebits.String()}
+	}
+	if !l.rules.MaxWeeklyDebits.IsZero() && l.weeklyDebits.total(now).Add(debit).GreaterThan(l.rules.MaxWeeklyDebits) {
+		return &LimitExceededError{Rule: RuleMaxWeeklyDebits, Limit: l.rules.MaxWeeklyDebits.String()}
+	}
+	return nil
+}
+
+// Observe is a ledger.PostedObserver counting the transactions that were added to the ledger
+func (l *Limiter) Observe(posted []ledger.Transaction) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, transaction := range posted {
+		if transaction.Kind != "" {
+			continue
+		}
+		l.perMinute.add(transaction.CreatedAt, decimal.Zero)
+		if transaction.Amount.IsNegative() {
+			l.dailyDebits.add(transaction.CreatedAt, transaction.Amount.Abs())
+			l.weeklyDebits.add(transaction.CreatedAt, transaction.Amount.Abs())
+		}
+	}
+}
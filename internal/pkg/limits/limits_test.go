@@ -0,0 +1,150 @@
+package limits_test
+
+import (
+	"sync"
+	"testing"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/limits"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2025, time.January, 6, 12, 0, 0, 0, time.UTC)
+
+// newLimitedLedger creates a ledger enforcing the rules, whose clock is controlled by the returned pointer
+func newLimitedLedger(t *testing.T, rules limits.Rules) (*ledger.Ledger, *limits.Limiter, *time.Time) {
+	t.Helper()
+	limiter, err := limits.NewLimiter(rules)
+	require.NoError(t, err)
+	now := start
+	ledgerInstance, err := ledger.NewLedger(
+		ledger.WithClock(func() time.Time { return now }),
+		ledger.WithPostingCheck(limiter.Check),
+		ledger.WithPostedObserver(limiter.Observe),
+	)
+	require.NoError(t, err)
+	return ledgerInstance, limiter, &now
+}
+
+func requireLimitExceeded(t *testing.T, err error, rule string) {
+	t.Helper()
+	var limitErr *limits.LimitExceededError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, rule, limitErr.Rule)
+}
+
+func TestLimiter_Check__RejectsTransactionAboveMaxAmount(t *testing.T) {
+	// Arrange
+	ledgerInstance, _, _ := newLimitedLedger(t, limits.Rules{MaxTransactionAmount: decimal.NewFromInt(100)})
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(-100)))
+
+	// Act
+	err := ledgerInstance.AddTransaction(decimal.RequireFromString("100.01"))
+
+	// Assert
+	requireLimitExceeded(t, err, limits.RuleMaxTransactionAmount)
+	assert.Len(t, ledgerInstance.TransactionHistory, 1)
+}
+
+func TestLimiter_Check__EnforcesDailyDebitsOverSlidingWindow(t *testing.T) {
+	// Arrange
+	ledgerInstance, _, now := newLimitedLedger(t, limits.Rules{MaxDailyDebits: decimal.NewFromInt(100)})
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(-60)))
+	*now = start.Add(12 * time.Hour)
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(500)))
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(-40)))
+
+	// Act
+	rejected := ledgerInstance.AddTransaction(decimal.NewFromInt(-1))
+	*now = start.Add(24*time.Hour + time.Second)
+	accepted := ledgerInstance.AddTransaction(decimal.NewFromInt(-60))
+
+	// Assert
+	requireLimitExceeded(t, rejected, limits.RuleMaxDailyDebits)
+	assert.NoError(t, accepted)
+}
+
+func TestLimiter_Check__EnforcesWeeklyDebits(t *testing.T) {
+	// Arrange
+	ledgerInstance, _, now := newLimitedLedger(t, limits.Rules{
+		MaxDailyDebits:  decimal.NewFromInt(100),
+		MaxWeeklyDebits: decimal.NewFromInt(250),
+	})
+	for day := 0; day < 3; day++ {
+		*now = start.AddDate(0, 0, day)
+		require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(-80)))
+	}
+	*now = start.AddDate(0, 0, 3)
+
+	// Act
+	err := ledgerInstance.AddTransaction(decimal.NewFromInt(-80))
+
+	// Assert
+	requireLimitExceeded(t, err, limits.RuleMaxWeeklyDebits)
+}
+
+func TestLimiter_Check__EnforcesTransactionsPerMinute(t *testing.T) {
+	// Arrange
+	ledgerInstance, _, now := newLimitedLedger(t, limits.Rules{MaxTransactionsPerMinute: 2})
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(1)))
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(1)))
+
+	// Act
+	rejected := ledgerInstance.AddTransaction(decimal.NewFromInt(1))
+	*now = start.Add(time.Minute)
+	accepted := ledgerInstance.AddTransaction(decimal.NewFromInt(1))
+
+	// Assert
+	requireLimitExceeded(t, rejected, limits.RuleMaxTransactionsPerMinute)
+	assert.NoError(t, accepted)
+}
+
+func TestLimiter_Check__IgnoresLedgerEntries(t *testing.T) {
+	// Arrange
+	ledgerInstance, _, _ := newLimitedLedger(t, limits.Rules{MaxDailyDebits: decimal.NewFromInt(10)})
+
+	// Act
+	err := ledgerInstance.AddTransaction(decimal.NewFromInt(-50), ledger.WithKind(ledger.KindInterest))
+
+	// Assert
+	assert.NoError(t, err)
+	assert.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(-10)))
+}
+
+func TestLimiter_SetRules__AppliesToCurrentWindow(t *testing.T) {
+	// Arrange
+	ledgerInstance, limiter, _ := newLimitedLedger(t, limits.Rules{})
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(-50)))
+
+	// Act
+	require.NoError(t, limiter.SetRules(limits.Rules{MaxDailyDebits: decimal.NewFromInt(60)}))
+	err := ledgerInstance.AddTransaction(decimal.NewFromInt(-20))
+
+	// Assert
+	requireLimitExceeded(t, err, limits.RuleMaxDailyDebits)
+	assert.Error(t, limiter.SetRules(limits.Rules{MaxTransactionsPerMinute: -1}))
+}
+
+func TestLimiter_Check__HoldsUnderConcurrentPosting(t *testing.T) {
+	// Arrange
+	ledgerInstance, _, _ := newLimitedLedger(t, limits.Rules{MaxDailyDebits: decimal.NewFromInt(100)})
+	var wg sync.WaitGroup
+
+	// Act
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = ledgerInstance.AddTransaction(decimal.NewFromInt(-7))
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	balance, err := ledgerInstance.GetBalance()
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(-98).Equal(balance), balance.String())
+}
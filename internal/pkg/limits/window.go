@@ -0,0 +1,50 @@
+package limits
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type event struct {
+	at     time.Time
+	amount decimal.Decimal
+}
+
+// slidingWindow keeps the events of the last window duration along with their running count and sum
+type slidingWindow struct {
+	window time.Duration
+	events []event
+	sum    decimal.Decimal
+}
+
+func newSlidingWindow(window time.Duration) *slidingWindow {
+	return &slidingWindow{window: window, sum: decimal.Zero}
+}
+
+// evict drops the events which are out of the window ending at the given time
+func (w *slidingWindow) evict(now time.Time) {
+	start := now.Add(-w.window)
+	i := 0
+	for ; i < len(w.events) && !w.events[i].at.After(start); i++ {
+		w.sum = w.sum.Sub(w.events[i].amount)
+	}
+	w.events = w.events[i:]
+}
+
+// add records an event and drops the ones out of the window, which would otherwise pile up while no rule reads it
+func (w *slidingWindow) add(at time.Time, amount decimal.Decimal) {
+	w.evict(at)
+	w.events = append(w.events, event{at: at, amount: amount})
+	w.sum = w.sum.Add(amount)
+}
+
+func (w *slidingWindow) count(now time.Time) int {
+	w.evict(now)
+	return len(w.events)
+}
+
+func (w *slidingWindow) total(now time.Time) decimal.Decimal {
+	w.evict(now)
+	return w.sum
+}
@@ -0,0 +1,123 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Format is the output format of the logs
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatText Format = "text"
+)
+
+// RequestIDKey is the attribute holding the ID of the request a log line was written for
+const RequestIDKey = "request_id"
+
+// TraceIDKey is the attribute holding the ID of the trace a log line was written in
+const TraceIDKey = "trace_id"
+
+// Redacted replaces the value of sensitive attributes
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are the attributes holding customer data, which are only logged at debug level
+var sensitiveKeys = map[string]bool{
+	"amount":   true,
+	"balance":  true,
+	"metadata": true,
+}
+
+type Config struct {
+	Format Format
+	Level  slog.Level
+}
+
+// ParseConfig parses the format and the level (debug, info, warn or error) of the logs
+func ParseConfig(format, level string) (Config, error) {
+	config := Config{Format: Format(strings.ToLower(format))}
+	switch config.Format {
+	case FormatJSON, FormatText:
+	default:
+		return Config{}, errors.Errorf("unknown log format %q", format)
+	}
+	if err := config.Level.UnmarshalText([]byte(level)); err != nil {
+		return Config{}, errors.Wrapf(err, "unknown log level %q", level)
+	}
+	return config, nil
+}
+
+// New creates a logger writing to w. Log lines written with a context carrying a request ID or a trace are
+// tagged with their ID. Unless the level is debug, the values of sensitive attributes (amounts, balances, metadata)
+// are redacted.
+func New(w io.Writer, config Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: config.Level}
+	if config.Level > slog.LevelDebug {
+		opts.ReplaceAttr = redact
+	}
+	var handler slog.Handler
+	if config.Format == FormatText {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+func redact(_ []string, attr slog.Attr) slog.Attr {
+	if sensitiveKeys[attr.Key] {
+		return slog.String(attr.Key, Redacted)
+	}
+	return attr
+}
+
+// requestIDPattern restricts the request IDs accepted from clients, so they are safe to log
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// IsValidRequestID tells whether a request ID sent by a client may be kept
+func IsValidRequestID(requestID string) bool {
+	return requestIDPattern.MatchString(requestID)
+}
+
+type requestIDContextKey struct{}
+
+// WithRequestID returns a context tagging the log lines written with it with the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by the context, if any
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// contextHandler adds the request ID and the trace ID carried by the context to the log lines
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		record.AddAttrs(slog.String(RequestIDKey, requestID))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(slog.String(TraceIDKey, spanContext.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
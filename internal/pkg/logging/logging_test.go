@@ -0,0 +1,87 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"teya_home_assignment/internal/pkg/logging"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func logLine(t *testing.T, level slog.Level, log func(logger *slog.Logger)) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	log(logging.New(&buf, logging.Config{Format: logging.FormatJSON, Level: level}))
+	line := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	return line
+}
+
+func TestNew__RedactsSensitiveAttributesAboveDebug(t *testing.T) {
+	// Act
+	line := logLine(t, slog.LevelInfo, func(logger *slog.Logger) {
+		logger.With("balance", decimal.NewFromInt(42)).Info("added transaction",
+			"id", 7, "amount", decimal.NewFromInt(10), "metadata", map[string]string{"channel": "online"})
+	})
+
+	// Assert
+	assert.Equal(t, logging.Redacted, line["amount"])
+	assert.Equal(t, logging.Redacted, line["metadata"])
+	assert.Equal(t, logging.Redacted, line["balance"])
+	assert.Equal(t, float64(7), line["id"])
+}
+
+func TestNew__KeepsSensitiveAttributesAtDebug(t *testing.T) {
+	// Act
+	line := logLine(t, slog.LevelDebug, func(logger *slog.Logger) {
+		logger.Info("added transaction", "amount", decimal.NewFromInt(10))
+	})
+
+	// Assert
+	assert.Equal(t, "10", line["amount"])
+}
+
+func TestNew__TagsLinesWithRequestID(t *testing.T) {
+	// Arrange
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+
+	// Act
+	line := logLine(t, slog.LevelInfo, func(logger *slog.Logger) {
+		logger.InfoContext(ctx, "handled request")
+	})
+
+	// Assert
+	assert.Equal(t, "req-1", line[logging.RequestIDKey])
+}
+
+func TestParseConfig__RejectsUnknownValues(t *testing.T) {
+	_, errFormat := logging.ParseConfig("xml", "info")
+	_, errLevel := logging.ParseConfig("json", "verbose")
+	config, err := logging.ParseConfig("TEXT", "debug")
+
+	assert.Error(t, errFormat)
+	assert.Error(t, errLevel)
+	require.NoError(t, err)
+	assert.Equal(t, logging.Config{Format: logging.FormatText, Level: slog.LevelDebug}, config)
+}
+
+func TestNew__TagsLinesWithTraceID(t *testing.T) {
+	// Arrange
+	traceID := trace.TraceID{1, 2, 3}
+	ctx := trace.ContextWithSpanContext(context.Background(),
+		trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: trace.SpanID{1}}))
+
+	// Act
+	line := logLine(t, slog.LevelInfo, func(logger *slog.Logger) {
+		logger.InfoContext(ctx, "handled request")
+	})
+
+	// Assert
+	assert.Equal(t, traceID.String(), line[logging.TraceIDKey])
+}
@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"teya_home_assignment/internal/pkg/amounts"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/limits"
+	"teya_home_assignment/internal/pkg/rules"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ledger"
+
+// Reasons a transaction is rejected for
+const (
+	ReasonDuplicate       = "duplicate"
+	ReasonLimitExceeded   = "limit_exceeded"
+	ReasonRuleViolation   = "rule_violation"
+	ReasonReviewRequired  = "review_required"
+	ReasonVersionMismatch = "version_mismatch"
+	ReasonInvalidAmount   = "invalid_amount"
+	ReasonOther           = "other"
+)
+
+// kindTransaction labels the transactions requested by clients, which have no kind
+const kindTransaction = "transaction"
+
+// Metrics holds the collectors of the service, exposed in the Prometheus text format by Handler
+type Metrics struct {
+	registry             *prometheus.Registry
+	httpRequests         *prometheus.CounterVec
+	httpRequestDuration  *prometheus.HistogramVec
+	postedTransactions   *prometheus.CounterVec
+	rejectedTransactions *prometheus.CounterVec
+	storageWriteDuration prometheus.Histogram
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests handled, by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of the HTTP requests, by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		postedTransactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transactions_posted_total",
+			Help:      "Number of entries added to the ledger, by kind.",
+		}, []string{"kind"}),
+		rejectedTransactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transactions_rejected_total",
+			Help:      "Number of transactions the ledger refused to add, by reason.",
+		}, []string{"reason"}),
+		storageWriteDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_write_duration_seconds",
+			Help:      "Latency of the writes to the persistent store.",
+			Buckets:   []float64{.0001, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpRequestDuration,
+		m.postedTransactions,
+		m.rejectedTransactions,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// RegisterLedger exposes the size of the ledger, read on every scrape
+func (m *Metrics) RegisterLedger(ledgerService *ledger.Ledger) {
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "transactions",
+			Help:      "Number of entries in the ledger.",
+		}, func() float64 {
+			return float64(ledgerService.GetStats().TransactionCount)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "balance_cache_lag",
+			Help:      "Number of entries added since the balance was last cached.",
+		}, func() float64 {
+			return float64(ledgerService.GetStats().UncachedTransactions)
+		}),
+	)
+}
+
+// InstrumentStore exposes the latency of the writes to the persistent store of the ledger
+func (m *Metrics) InstrumentStore(store ledger.Store) ledger.Store {
+	m.registry.MustRegister(m.storageWriteDuration)
+	return &instrumentedStore{Store: store, metrics: m}
+}
+
+type instrumentedStore struct {
+	ledger.Store
+	metrics *Metrics
+}
+
+func (s *instrumentedStore) Append(posted []ledger.Transaction) error {
+	start := time.Now()
+	err := s.Store.Append(posted)
+	s.metrics.ObserveStorageWrite(time.Since(start))
+	return err
+}
+
+// ObserveRequest records an HTTP request handled for the route pattern it matched
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	labels := prometheus.Labels{"method": method, "route": route, "status": strconv.Itoa(status)}
+	m.httpRequests.With(labels).Inc()
+	m.httpRequestDuration.With(labels).Observe(duration.Seconds())
+}
+
+// ObservePosted is a ledger.PostedObserver counting the entries added to the ledger
+func (m *Metrics) ObservePosted(posted []ledger.Transaction) {
+	for _, transaction := range posted {
+		kind := transaction.Kind
+		if kind == "" {
+			kind = kindTransaction
+		}
+		m.postedTransactions.WithLabelValues(kind).Inc()
+	}
+}
+
+// ObserveRejected is a ledger.RejectedObserver counting the rejected transactions by reason
+func (m *Metrics) ObserveRejected(_ ledger.Transaction, err error) {
+	m.rejectedTransactions.WithLabelValues(RejectionReason(err)).Inc()
+}
+
+// ObserveStorageWrite records the latency of a write to the persistent store
+func (m *Metrics) ObserveStorageWrite(duration time.Duration) {
+	m.storageWriteDuration.Observe(duration.Seconds())
+}
+
+// RejectionReason classifies the error a transaction was rejected with
+func RejectionReason(err error) string {
+	var limitErr *limits.LimitExceededError
+	var ruleErr *rules.RuleViolationError
+	var reviewErr *rules.ReviewRequiredError
+	var amountErr *amounts.InvalidAmountError
+	switch {
+	case errors.Is(err, ledger.ErrDuplicateTransaction):
+		return ReasonDuplicate
+	case errors.Is(err, ledger.ErrVersionMismatch):
+		return ReasonVersionMismatch
+	case errors.As(err, &amountErr):
+		return ReasonInvalidAmount
+	case errors.As(err, &limitErr):
+		return ReasonLimitExceeded
+	case errors.As(err, &ruleErr):
+		return ReasonRuleViolation
+	case errors.As(err, &reviewErr):
+		return ReasonReviewRequired
+	}
+	return ReasonOther
+}
@@ -0,0 +1,86 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"teya_home_assignment/internal/pkg/amounts"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/limits"
+	"teya_home_assignment/internal/pkg/metrics"
+	"teya_home_assignment/internal/pkg/rules"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, metricsService *metrics.Metrics) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	metricsService.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetrics__CountsPostedAndRejectedTransactions(t *testing.T) {
+	// Arrange
+	metricsService := metrics.New()
+	ledgerInstance, err := ledger.NewLedger(
+		ledger.WithPostingCheck(func(transaction ledger.Transaction) error {
+			if transaction.Amount.IsNegative() {
+				return &rules.RuleViolationError{}
+			}
+			return nil
+		}),
+		ledger.WithPostedObserver(metricsService.ObservePosted),
+		ledger.WithRejectedObserver(metricsService.ObserveRejected),
+	)
+	require.NoError(t, err)
+	metricsService.RegisterLedger(ledgerInstance)
+
+	// Act
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(100)))
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(10), ledger.WithKind(ledger.KindInterest)))
+	require.Error(t, ledgerInstance.AddTransaction(decimal.NewFromInt(-10)))
+	body := scrape(t, metricsService)
+
+	// Assert
+	assert.Contains(t, body, `ledger_transactions_posted_total{kind="transaction"} 1`)
+	assert.Contains(t, body, `ledger_transactions_posted_total{kind="interest"} 1`)
+	assert.Contains(t, body, `ledger_transactions_rejected_total{reason="rule_violation"} 1`)
+	assert.Contains(t, body, "ledger_transactions 2")
+	assert.Contains(t, body, "ledger_balance_cache_lag 2")
+}
+
+func TestMetrics__RecordsRequestsByRouteAndStatus(t *testing.T) {
+	// Arrange
+	metricsService := metrics.New()
+
+	// Act
+	metricsService.ObserveRequest("GET", "/api/v1/account", 200, 3*time.Millisecond)
+	metricsService.ObserveRequest("GET", "/api/v1/account", 200, 30*time.Millisecond)
+	body := scrape(t, metricsService)
+
+	// Assert
+	assert.Contains(t, body, `ledger_http_requests_total{method="GET",route="/api/v1/account",status="200"} 2`)
+	assert.Contains(t, body,
+		`ledger_http_request_duration_seconds_bucket{method="GET",route="/api/v1/account",status="200",le="0.005"} 1`)
+}
+
+func TestRejectionReason__ClassifiesLedgerErrors(t *testing.T) {
+	assert.Equal(t, metrics.ReasonDuplicate,
+		metrics.RejectionReason(errors.Wrap(ledger.ErrDuplicateTransaction, "idempotency key k")))
+	assert.Equal(t, metrics.ReasonVersionMismatch,
+		metrics.RejectionReason(errors.Wrap(ledger.ErrVersionMismatch, "expected version 1")))
+	assert.Equal(t, metrics.ReasonInvalidAmount,
+		metrics.RejectionReason(errors.Wrap(&amounts.InvalidAmountError{}, "transaction rejected")))
+	assert.Equal(t, metrics.ReasonLimitExceeded,
+		metrics.RejectionReason(errors.Wrap(&limits.LimitExceededError{}, "transaction rejected")))
+	assert.Equal(t, metrics.ReasonReviewRequired,
+		metrics.RejectionReason(errors.Wrap(&rules.ReviewRequiredError{}, "transaction rejected")))
+	assert.Equal(t, metrics.ReasonOther, metrics.RejectionReason(errors.New("posting hook failed")))
+}
@@ -0,0 +1,422 @@
+// Package openapi builds an OpenAPI 3 document from the operations served by the webserver. The schemas of the
+// request and response bodies are generated from the Go types they are encoded from.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Version is the version of the OpenAPI specification the documents follow
+const Version = "3.0.3"
+
+const (
+	// MIMEApplicationJSON is the default media type of the request and response bodies
+	MIMEApplicationJSON = "application/json"
+
+	schemasRefPrefix = "#/components/schemas/"
+)
+
+// pathParamRe matches the fiber path parameters, like :id
+var pathParamRe = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// knownSchemas are the types encoded as JSON strings
+var knownSchemas = map[reflect.Type]Schema{
+	reflect.TypeOf(time.Time{}):       {Type: "string", Format: "date-time"},
+	reflect.TypeOf(uuid.UUID{}):       {Type: "string", Format: "uuid"},
+	reflect.TypeOf(decimal.Decimal{}): {Type: "string", Format: "decimal"},
+}
+
+// Operation documents a route, Path is the fiber path relative to the server URL. The path parameters are
+// documented as required strings.
+type Operation struct {
+	Method      string
+	Path        string
+	Tag         string
+	Summary     string
+	Description string
+	Query       []Parameter
+	Headers     []Parameter
+	Request     *Content
+	// Responses are the responses by HTTP status code
+	Responses map[int]Response
+}
+
+// Content describes a request or response body. Schema is a value of the Go type the JSON media types are encoded
+// from, the other media types are documented as strings.
+type Content struct {
+	Schema any
+	// Types are the media types of the body, application/json if none
+	Types []string
+}
+
+// OneOf is a Schema of Content for bodies encoded from one of several Go types
+type OneOf []any
+
+type Response struct {
+	// Description defaults to the HTTP status text
+	Description string
+	// Content is nil for responses without a body
+	Content *Content
+}
+
+// JSON is the content of a JSON body encoded from the Go type of v
+func JSON(v any) *Content {
+	return &Content{Schema: v}
+}
+
+// Text is the content of a body which is not JSON
+func Text(types ...string) *Content {
+	return &Content{Types: types}
+}
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+// PathItem are the operations of a path by lower case HTTP method
+type PathItem map[string]*OperationObject
+
+type OperationObject struct {
+	Tags        []string                  `json:"tags,omitempty"`
+	Summary     string                    `json:"summary,omitempty"`
+	Description string                    `json:"description,omitempty"`
+	Parameters  []Parameter               `json:"parameters,omitempty"`
+	RequestBody *RequestBody              `json:"requestBody,omitempty"`
+	Responses   map[string]ResponseObject `json:"responses"`
+}
+
+// Parameter is a query or path parameter. In is set when building the document.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type ResponseObject struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+}
+
+// Build documents the operations. The Go types of the bodies are documented as component schemas named after the
+// type, so the types must have distinct names.
+func Build(info Info, servers []Server, operations []Operation) (*Document, error) {
+	doc := &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Servers:    servers,
+		Paths:      map[string]PathItem{},
+		Components: Components{Schemas: map[string]*Schema{}},
+	}
+	g := &generator{schemas: doc.Components.Schemas, types: map[string]reflect.Type{}}
+	for _, operation := range operations {
+		path := PathOf(operation.Path)
+		method := strings.ToLower(operation.Method)
+		if _, exists := doc.Paths[path][method]; exists {
+			return nil, errors.Errorf("duplicated operation %s %s", operation.Method, operation.Path)
+		}
+		object, err := g.operation(operation)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to document operation %s %s", operation.Method, operation.Path)
+		}
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = PathItem{}
+		}
+		doc.Paths[path][method] = object
+	}
+	return doc, nil
+}
+
+// parameterIn documents a parameter given in the query or the headers, as a string unless it has a schema
+func parameterIn(in string, param Parameter) Parameter {
+	param.In = in
+	if param.Schema == nil {
+		param.Schema = &Schema{Type: "string"}
+	}
+	return param
+}
+
+// PathOf converts a fiber path to an OpenAPI path, like /rules/:id to /rules/{id}
+func PathOf(path string) string {
+	return pathParamRe.ReplaceAllString(path, "{$1}")
+}
+
+type generator struct {
+	schemas map[string]*Schema
+	// types are the Go types of the component schemas by name
+	types map[string]reflect.Type
+}
+
+func (g *generator) operation(operation Operation) (*OperationObject, error) {
+	if len(operation.Responses) == 0 {
+		return nil, errors.New("no response documented")
+	}
+	object := &OperationObject{
+		Summary:     operation.Summary,
+		Description: operation.Description,
+		Responses:   map[string]ResponseObject{},
+	}
+	if operation.Tag != "" {
+		object.Tags = []string{operation.Tag}
+	}
+	for _, match := range pathParamRe.FindAllStringSubmatch(operation.Path, -1) {
+		object.Parameters = append(object.Parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	for _, param := range operation.Query {
+		object.Parameters = append(object.Parameters, parameterIn("query", param))
+	}
+	for _, param := range operation.Headers {
+		object.Parameters = append(object.Parameters, parameterIn("header", param))
+	}
+	if operation.Request != nil {
+		content, err := g.content(operation.Request)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to document request body")
+		}
+		object.RequestBody = &RequestBody{Required: true, Content: content}
+	}
+	for status, response := range operation.Responses {
+		if http.StatusText(status) == "" {
+			return nil, errors.Errorf("unknown response status %d", status)
+		}
+		responseObject := ResponseObject{Description: response.Description}
+		if responseObject.Description == "" {
+			responseObject.Description = http.StatusText(status)
+		}
+		if response.Content != nil {
+			content, err := g.content(response.Content)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to document response %d", status)
+			}
+			responseObject.Content = content
+		}
+		object.Responses[strconv.Itoa(status)] = responseObject
+	}
+	return object, nil
+}
+
+func (g *generator) content(content *Content) (map[string]MediaType, error) {
+	types := content.Types
+	if len(types) == 0 {
+		types = []string{MIMEApplicationJSON}
+	}
+	media := make(map[string]MediaType, len(types))
+	for _, mediaType := range types {
+		if content.Schema == nil || !isJSON(mediaType) {
+			media[mediaType] = MediaType{Schema: &Schema{Type: "string"}}
+			continue
+		}
+		schema, err := g.valueSchema(content.Schema)
+		if err != nil {
+			return nil, err
+		}
+		media[mediaType] = MediaType{Schema: schema}
+	}
+	return media, nil
+}
+
+func (g *generator) valueSchema(v any) (*Schema, error) {
+	oneOf, ok := v.(OneOf)
+	if !ok {
+		return g.schema(reflect.TypeOf(v))
+	}
+	schema := &Schema{}
+	for _, alternative := range oneOf {
+		alternativeSchema, err := g.schema(reflect.TypeOf(alternative))
+		if err != nil {
+			return nil, err
+		}
+		schema.OneOf = append(schema.OneOf, alternativeSchema)
+	}
+	return schema, nil
+}
+
+// schema documents the JSON encoding of the Go type, structs are referenced as component schemas
+func (g *generator) schema(t reflect.Type) (*Schema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if known, ok := knownSchemas[t]; ok {
+		return &known, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, errors.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		return g.structRef(t)
+	}
+	return nil, errors.Errorf("unsupported type %s", t)
+}
+
+func (g *generator) structRef(t reflect.Type) (*Schema, error) {
+	name := t.Name()
+	if name == "" {
+		return g.object(t)
+	}
+	ref := &Schema{Ref: schemasRefPrefix + name}
+	if existing, exists := g.types[name]; exists {
+		if existing != t {
+			return nil, errors.Errorf("types %s and %s are both documented as schema %s", existing, t, name)
+		}
+		return ref, nil
+	}
+	// the type is registered before its fields are documented, so recursive types reference themselves
+	g.types[name] = t
+	object, err := g.object(t)
+	if err != nil {
+		delete(g.types, name)
+		return nil, err
+	}
+	g.schemas[name] = object
+	return ref, nil
+}
+
+// object documents the exported fields by their JSON name. A field is required when validated as required.
+func (g *generator) object(t reflect.Type) (*Schema, error) {
+	object := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous {
+			return nil, errors.Errorf("unsupported embedded field %s in %s", field.Name, t)
+		}
+		if name == "" {
+			name = field.Name
+		}
+		property, err := g.schema(field.Type)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to document field %s of %s", field.Name, t)
+		}
+		required, enum := parseValidateTag(field.Tag.Get("validate"))
+		if required {
+			object.Required = append(object.Required, name)
+		}
+		if len(enum) > 0 && property.Type == "string" {
+			property.Enum = enum
+		}
+		object.Properties[name] = property
+	}
+	slices.Sort(object.Required)
+	return object, nil
+}
+
+// parseValidateTag reads the validator rules documented in the schema of the field, ignoring the rules applying to
+// the elements of the field
+func parseValidateTag(tag string) (required bool, enum []string) {
+	for _, rule := range strings.Split(tag, ",") {
+		if rule == "dive" {
+			break
+		}
+		if rule == "required" {
+			required = true
+		}
+		if values, ok := strings.CutPrefix(rule, "oneof="); ok {
+			enum = strings.Fields(values)
+		}
+	}
+	return required, enum
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// Operations lists the documented operations as "METHOD /path", sorted
+func (d *Document) Operations() []string {
+	var operations []string
+	for path, item := range d.Paths {
+		for method := range item {
+			operations = append(operations, strings.ToUpper(method)+" "+path)
+		}
+	}
+	slices.Sort(operations)
+	return operations
+}
@@ -0,0 +1,154 @@
+package openapi_test
+
+import (
+	"net/http"
+	"testing"
+	"teya_home_assignment/internal/pkg/openapi"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	ID        uuid.UUID         `json:"id"`
+	Amount    decimal.Decimal   `json:"amount"`
+	CreatedAt time.Time         `json:"created_at"`
+	Note      string            `json:"note,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Parent    *item             `json:"parent,omitempty"`
+	internal  string
+}
+
+type itemReqBody struct {
+	Amount  string   `json:"amount" validate:"required,number"`
+	Kind    string   `json:"kind" validate:"omitempty,oneof=credit debit"`
+	Labels  []string `json:"labels" validate:"max=5,dive,required"`
+	Ignored string   `json:"-"`
+}
+
+type itemError struct {
+	Error string `json:"error"`
+}
+
+func TestBuild__GeneratesSchemasFromTypes(t *testing.T) {
+	operations := []openapi.Operation{
+		{
+			Method:  http.MethodPost,
+			Path:    "/item",
+			Request: openapi.JSON(itemReqBody{}),
+			Responses: map[int]openapi.Response{
+				http.StatusCreated: {Content: openapi.JSON(item{})},
+			},
+		},
+	}
+
+	doc, err := openapi.Build(openapi.Info{Title: "Items", Version: "1"}, nil, operations)
+
+	require.NoError(t, err)
+	assert.Equal(t, openapi.Version, doc.OpenAPI)
+	assert.Equal(t, &openapi.Schema{Type: "object", Required: []string{"amount"}, Properties: map[string]*openapi.Schema{
+		"amount": {Type: "string"},
+		"kind":   {Type: "string", Enum: []string{"credit", "debit"}},
+		"labels": {Type: "array", Items: &openapi.Schema{Type: "string"}},
+	}}, doc.Components.Schemas["itemReqBody"])
+	assert.Equal(t, &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+		"id":         {Type: "string", Format: "uuid"},
+		"amount":     {Type: "string", Format: "decimal"},
+		"created_at": {Type: "string", Format: "date-time"},
+		"note":       {Type: "string"},
+		"tags":       {Type: "object", AdditionalProperties: &openapi.Schema{Type: "string"}},
+		"parent":     {Ref: "#/components/schemas/item"},
+	}}, doc.Components.Schemas["item"])
+	operation := doc.Paths["/item"]["post"]
+	require.NotNil(t, operation)
+	assert.Equal(t, "#/components/schemas/itemReqBody",
+		operation.RequestBody.Content[openapi.MIMEApplicationJSON].Schema.Ref)
+	assert.Equal(t, "Created", operation.Responses["201"].Description)
+}
+
+func TestBuild__DocumentsParametersAndContentTypes(t *testing.T) {
+	operations := []openapi.Operation{
+		{
+			Method:  http.MethodGet,
+			Path:    "/item/:id/export",
+			Query:   []openapi.Parameter{{Name: "format", Required: true}},
+			Headers: []openapi.Parameter{{Name: "If-None-Match"}},
+			Responses: map[int]openapi.Response{
+				http.StatusOK: {Content: openapi.Text("text/csv")},
+				http.StatusUnprocessableEntity: {
+					Content: openapi.JSON(openapi.OneOf{itemError{}, item{}}),
+				},
+				http.StatusNoContent: {Description: "Nothing to export"},
+			},
+		},
+	}
+
+	doc, err := openapi.Build(openapi.Info{Title: "Items", Version: "1"}, nil, operations)
+
+	require.NoError(t, err)
+	operation := doc.Paths["/item/{id}/export"]["get"]
+	require.NotNil(t, operation)
+	assert.Equal(t, []openapi.Parameter{
+		{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		{Name: "format", In: "query", Required: true, Schema: &openapi.Schema{Type: "string"}},
+		{Name: "If-None-Match", In: "header", Schema: &openapi.Schema{Type: "string"}},
+	}, operation.Parameters)
+	assert.Equal(t, map[string]openapi.MediaType{"text/csv": {Schema: &openapi.Schema{Type: "string"}}},
+		operation.Responses["200"].Content)
+	assert.Len(t, operation.Responses["422"].Content[openapi.MIMEApplicationJSON].Schema.OneOf, 2)
+	assert.Equal(t, openapi.ResponseObject{Description: "Nothing to export"}, operation.Responses["204"])
+	assert.Equal(t, []string{"GET /item/{id}/export"}, doc.Operations())
+}
+
+func TestBuild__FailsOnDuplicatedOperation(t *testing.T) {
+	operation := openapi.Operation{
+		Method:    http.MethodGet,
+		Path:      "/item/:id",
+		Responses: map[int]openapi.Response{http.StatusOK: {}},
+	}
+
+	_, err := openapi.Build(openapi.Info{}, nil, []openapi.Operation{operation, operation})
+
+	assert.ErrorContains(t, err, "duplicated operation GET /item/:id")
+}
+
+func TestBuild__FailsOnUndocumentedResponses(t *testing.T) {
+	operation := openapi.Operation{Method: http.MethodGet, Path: "/item"}
+
+	_, err := openapi.Build(openapi.Info{}, nil, []openapi.Operation{operation})
+
+	assert.ErrorContains(t, err, "no response documented")
+}
+
+// packageItem is the item declared at the package level, shadowed in some tests
+var packageItem = item{}
+
+func TestBuild__FailsOnConflictingSchemaNames(t *testing.T) {
+	type item struct {
+		Name string `json:"name"`
+	}
+	operations := []openapi.Operation{
+		{
+			Method:    http.MethodGet,
+			Path:      "/item",
+			Responses: map[int]openapi.Response{http.StatusOK: {Content: openapi.JSON(item{})}},
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/items",
+			Responses: map[int]openapi.Response{http.StatusOK: {Content: openapi.JSON(packageItem)}},
+		},
+	}
+
+	_, err := openapi.Build(openapi.Info{}, nil, operations)
+
+	assert.ErrorContains(t, err, "are both documented as schema item")
+}
+
+func TestPathOf(t *testing.T) {
+	assert.Equal(t, "/transaction/{id}/category", openapi.PathOf("/transaction/:id/category"))
+	assert.Equal(t, "/rules", openapi.PathOf("/rules"))
+}
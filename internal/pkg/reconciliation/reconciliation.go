@@ -0,0 +1,124 @@
+package reconciliation
+
+import (
+	"teya_home_assignment/internal/pkg/ledger"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultDateTolerance is how far apart a settlement date and a transaction creation time may be to match.
+// Acquirers usually settle on the next business day.
+const DefaultDateTolerance = 72 * time.Hour
+
+// Match pairs a settlement line with the ledger transaction it settles
+type Match struct {
+	Line        SettlementLine
+	Transaction ledger.Transaction
+}
+
+// Report is the outcome of reconciling a settlement file against the ledger
+type Report struct {
+	// Matched lines agree with their transaction on amount
+	Matched []Match
+	// AmountMismatches lines share a reference with a transaction but not its amount
+	AmountMismatches []Match
+	// UnmatchedInLedger are transactions within the settlement window which are missing from the file
+	UnmatchedInLedger []ledger.Transaction
+	// UnmatchedInFile are settlement lines without a corresponding ledger transaction
+	UnmatchedInFile []SettlementLine
+}
+
+// Reconcile matches settlement lines to the ledger transactions created within the period covered by the file,
+// widened by the date tolerance.
+// A line is matched by its reference first, either the transaction's reference or its external ID.
+// Lines whose reference is unknown fall back to the transaction with the same amount closest in time.
+func Reconcile(ledgerService *ledger.Ledger, lines []SettlementLine, dateTolerance time.Duration) (*Report, error) {
+	report := &Report{}
+	if len(lines) == 0 {
+		return report, nil
+	}
+	from, to := lines[0].Date, lines[0].Date
+	for _, line := range lines[1:] {
+		if line.Date.Before(from) {
+			from = line.Date
+		}
+		if line.Date.After(to) {
+			to = line.Date
+		}
+	}
+	transactions, err := ledgerService.GetTransactionsBetween(from.Add(-dateTolerance), to.Add(dateTolerance+time.Nanosecond))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get transactions to reconcile")
+	}
+	// entries posted by the ledger itself, like fees and interest, are never settled by the acquirer
+	var candidates []ledger.Transaction
+	for _, transaction := range transactions {
+		if transaction.Kind == "" {
+			candidates = append(candidates, transaction)
+		}
+	}
+
+	matched := make([]bool, len(candidates))
+	var unreferenced []SettlementLine
+	for _, line := range lines {
+		idx := findByReference(candidates, matched, line.Reference)
+		if idx < 0 {
+			unreferenced = append(unreferenced, line)
+			continue
+		}
+		matched[idx] = true
+		match := Match{Line: line, Transaction: candidates[idx]}
+		if line.Amount.Equal(candidates[idx].Amount) {
+			report.Matched = append(report.Matched, match)
+		} else {
+			report.AmountMismatches = append(report.AmountMismatches, match)
+		}
+	}
+	// fallback matching runs after all references were consumed so it can not steal a referenced transaction
+	for _, line := range unreferenced {
+		idx := findByAmountAndDate(candidates, matched, line, dateTolerance)
+		if idx < 0 {
+			report.UnmatchedInFile = append(report.UnmatchedInFile, line)
+			continue
+		}
+		matched[idx] = true
+		report.Matched = append(report.Matched, Match{Line: line, Transaction: candidates[idx]})
+	}
+	for i, transaction := range candidates {
+		if !matched[i] {
+			report.UnmatchedInLedger = append(report.UnmatchedInLedger, transaction)
+		}
+	}
+	return report, nil
+}
+
+func findByReference(candidates []ledger.Transaction, matched []bool, reference string) int {
+	if reference == "" {
+		return -1
+	}
+	for i, transaction := range candidates {
+		if !matched[i] && (transaction.Reference == reference || transaction.ExternalID.String() == reference) {
+			return i
+		}
+	}
+	return -1
+}
+
+func findByAmountAndDate(candidates []ledger.Transaction, matched []bool, line SettlementLine, tolerance time.Duration) int {
+	best := -1
+	var bestDistance time.Duration
+	for i, transaction := range candidates {
+		if matched[i] || !transaction.Amount.Equal(line.Amount) {
+			continue
+		}
+		distance := transaction.CreatedAt.Sub(line.Date).Abs()
+		if distance > tolerance {
+			continue
+		}
+		if best < 0 || distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+	return best
+}
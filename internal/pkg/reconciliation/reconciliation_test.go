@@ -0,0 +1,156 @@
+package reconciliation_test
+
+import (
+	"strings"
+	"testing"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/reconciliation"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var day = time.Date(2025, time.March, 3, 0, 0, 0, 0, time.UTC)
+
+type testTransaction struct {
+	amount    string
+	reference string
+	createdAt time.Time
+}
+
+func newTestLedger(t *testing.T, transactions ...testTransaction) *ledger.Ledger {
+	t.Helper()
+	var now time.Time
+	ledgerInstance, err := ledger.NewLedger(ledger.WithClock(func() time.Time { return now }))
+	require.NoError(t, err)
+	for _, transaction := range transactions {
+		now = transaction.createdAt
+		var opts []ledger.TransactionOption
+		if transaction.reference != "" {
+			opts = append(opts, ledger.WithReference(transaction.reference))
+		}
+		require.NoError(t, ledgerInstance.AddTransaction(decimal.RequireFromString(transaction.amount), opts...))
+	}
+	return ledgerInstance
+}
+
+func parseLines(t *testing.T, csv string) []reconciliation.SettlementLine {
+	t.Helper()
+	lines, err := reconciliation.ParseSettlementFile(strings.NewReader(csv))
+	require.NoError(t, err)
+	return lines
+}
+
+func TestReconciliation_ParseSettlementFile__ReadsColumnsByHeader(t *testing.T) {
+	// Arrange
+	csv := "date,amount,reference\n2025-03-03,10.50,REF-1\n2025-03-04T10:00:00Z,-3,\n"
+
+	// Act
+	lines, err := reconciliation.ParseSettlementFile(strings.NewReader(csv))
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "REF-1", lines[0].Reference)
+	assert.True(t, decimal.RequireFromString("10.5").Equal(lines[0].Amount))
+	assert.Equal(t, day, lines[0].Date)
+	assert.Equal(t, 3, lines[1].Line)
+	assert.Empty(t, lines[1].Reference)
+}
+
+func TestReconciliation_ParseSettlementFile__RejectsMissingColumn(t *testing.T) {
+	// Act
+	_, err := reconciliation.ParseSettlementFile(strings.NewReader("reference,amount\nREF-1,10\n"))
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestReconciliation_ParseSettlementFile__RejectsInvalidAmount(t *testing.T) {
+	// Act
+	_, err := reconciliation.ParseSettlementFile(strings.NewReader("reference,amount,date\nREF-1,ten,2025-03-03\n"))
+
+	// Assert
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestReconciliation_Reconcile__MatchesByReference(t *testing.T) {
+	// Arrange
+	ledgerInstance := newTestLedger(t,
+		testTransaction{amount: "10", reference: "REF-1", createdAt: day.Add(10 * time.Hour)},
+		testTransaction{amount: "10", reference: "REF-2", createdAt: day.Add(11 * time.Hour)},
+	)
+	lines := parseLines(t, "reference,amount,date\nREF-2,10,2025-03-03\nREF-1,10,2025-03-03\n")
+
+	// Act
+	report, err := reconciliation.Reconcile(ledgerInstance, lines, reconciliation.DefaultDateTolerance)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, report.Matched, 2)
+	assert.Equal(t, "REF-2", report.Matched[0].Transaction.Reference)
+	assert.Equal(t, "REF-1", report.Matched[1].Transaction.Reference)
+	assert.Empty(t, report.AmountMismatches)
+	assert.Empty(t, report.UnmatchedInLedger)
+	assert.Empty(t, report.UnmatchedInFile)
+}
+
+func TestReconciliation_Reconcile__ReportsAmountMismatch(t *testing.T) {
+	// Arrange
+	ledgerInstance := newTestLedger(t,
+		testTransaction{amount: "10", reference: "REF-1", createdAt: day.Add(10 * time.Hour)},
+	)
+	lines := parseLines(t, "reference,amount,date\nREF-1,9.99,2025-03-03\n")
+
+	// Act
+	report, err := reconciliation.Reconcile(ledgerInstance, lines, reconciliation.DefaultDateTolerance)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, report.Matched)
+	require.Len(t, report.AmountMismatches, 1)
+	assert.Equal(t, "REF-1", report.AmountMismatches[0].Transaction.Reference)
+}
+
+func TestReconciliation_Reconcile__FallsBackToAmountWithinDateTolerance(t *testing.T) {
+	// Arrange
+	ledgerInstance := newTestLedger(t,
+		testTransaction{amount: "25", createdAt: day.Add(-5 * 24 * time.Hour)},
+		testTransaction{amount: "25", createdAt: day.Add(-20 * time.Hour)},
+		testTransaction{amount: "25", createdAt: day.Add(-2 * time.Hour)},
+	)
+	lines := parseLines(t, "reference,amount,date\nACQ-123,25,2025-03-03\n")
+
+	// Act
+	report, err := reconciliation.Reconcile(ledgerInstance, lines, 24*time.Hour)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, report.Matched, 1)
+	assert.Equal(t, uint64(3), report.Matched[0].Transaction.ID)
+	// the transaction 5 days before the settlement is outside the reconciled window
+	require.Len(t, report.UnmatchedInLedger, 1)
+	assert.Equal(t, uint64(2), report.UnmatchedInLedger[0].ID)
+}
+
+func TestReconciliation_Reconcile__ReportsUnmatchedItems(t *testing.T) {
+	// Arrange
+	ledgerInstance := newTestLedger(t,
+		testTransaction{amount: "10", reference: "REF-1", createdAt: day.Add(10 * time.Hour)},
+		testTransaction{amount: "-4", createdAt: day.Add(12 * time.Hour)},
+	)
+	lines := parseLines(t, "reference,amount,date\nREF-1,10,2025-03-03\nREF-9,7,2025-03-03\n")
+
+	// Act
+	report, err := reconciliation.Reconcile(ledgerInstance, lines, reconciliation.DefaultDateTolerance)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, report.Matched, 1)
+	require.Len(t, report.UnmatchedInFile, 1)
+	assert.Equal(t, "REF-9", report.UnmatchedInFile[0].Reference)
+	require.Len(t, report.UnmatchedInLedger, 1)
+	assert.Equal(t, uint64(2), report.UnmatchedInLedger[0].ID)
+}
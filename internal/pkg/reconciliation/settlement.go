@@ -0,0 +1,79 @@
+package reconciliation
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+const settlementDateFormat = "2006-01-02"
+
+var settlementColumns = []string{"reference", "amount", "date"}
+
+// SettlementLine is a single entry of the acquirer's settlement file
+type SettlementLine struct {
+	// Line is the 1-based line number in the settlement file, header included
+	Line      int
+	Reference string
+	Amount    decimal.Decimal
+	Date      time.Time
+}
+
+// ParseSettlementFile reads a CSV settlement file. The first row is a header naming the reference, amount
+// and date columns, in any order. Dates are given as YYYY-MM-DD or RFC 3339 timestamps.
+func ParseSettlementFile(r io.Reader) ([]SettlementLine, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("settlement file is empty")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read settlement file header")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range settlementColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, errors.Errorf("settlement file header is missing the %v column", name)
+		}
+	}
+
+	var lines []SettlementLine
+	for lineNumber := 2; ; lineNumber++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read settlement file line %d", lineNumber)
+		}
+		amount, err := decimal.NewFromString(strings.TrimSpace(record[columns["amount"]]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid amount on settlement file line %d", lineNumber)
+		}
+		date, err := parseSettlementDate(strings.TrimSpace(record[columns["date"]]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid date on settlement file line %d", lineNumber)
+		}
+		lines = append(lines, SettlementLine{
+			Line:      lineNumber,
+			Reference: strings.TrimSpace(record[columns["reference"]]),
+			Amount:    amount,
+			Date:      date,
+		})
+	}
+}
+
+func parseSettlementDate(value string) (time.Time, error) {
+	if date, err := time.Parse(settlementDateFormat, value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,156 @@
+package report
+
+import (
+	"sort"
+	"sync"
+	"teya_home_assignment/internal/pkg/ledger"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// SlotDuration is the granularity of the rollups. Every time zone offset in use is a multiple of 15 minutes,
+// so the day, week and month boundaries of any time zone fall on a slot boundary.
+const SlotDuration = 15 * time.Minute
+
+// MaxBuckets bounds the number of buckets of a single report
+const MaxBuckets = 1000
+
+// Interval is the size of the buckets of a report
+type Interval string
+
+const (
+	Day   Interval = "day"
+	Week  Interval = "week"
+	Month Interval = "month"
+)
+
+// Bucket aggregates the transactions created within [From, To). Debits are summed as a positive amount.
+type Bucket struct {
+	From           time.Time
+	To             time.Time
+	Count          int
+	Credits        decimal.Decimal
+	Debits         decimal.Decimal
+	ClosingBalance decimal.Decimal
+}
+
+func (b Bucket) Net() decimal.Decimal {
+	return b.Credits.Sub(b.Debits)
+}
+
+// slot aggregates the transactions created within a SlotDuration, along with the balance at its end
+type slot struct {
+	start   time.Time
+	count   int
+	credits decimal.Decimal
+	debits  decimal.Decimal
+	closing decimal.Decimal
+}
+
+// Rollup maintains the aggregates of the ledger incrementally, so reports never scan the transaction history.
+// It is registered on the ledger as a posted observer before any transaction is added.
+type Rollup struct {
+	mu sync.RWMutex
+	// slots holds the non empty slots in chronological order, the ledger adds transactions in creation order
+	slots []slot
+}
+
+func NewRollup() *Rollup {
+	return &Rollup{}
+}
+
+// Observe is a ledger.PostedObserver adding the posted entries to their slot
+func (r *Rollup) Observe(posted []ledger.Transaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, transaction := range posted {
+		start := transaction.CreatedAt.Truncate(SlotDuration)
+		last := len(r.slots) - 1
+		if last < 0 || !r.slots[last].start.Equal(start) {
+			closing := decimal.Zero
+			if last >= 0 {
+				closing = r.slots[last].closing
+			}
+			r.slots = append(r.slots, slot{start: start, credits: decimal.Zero, debits: decimal.Zero, closing: closing})
+			last++
+		}
+		current := &r.slots[last]
+		current.count++
+		if transaction.Amount.IsNegative() {
+			current.debits = current.debits.Add(transaction.Amount.Abs())
+		} else {
+			current.credits = current.credits.Add(transaction.Amount)
+		}
+		current.closing = current.closing.Add(transaction.Amount)
+	}
+}
+
+// Aggregate returns the buckets of the given interval covering [from, to), with boundaries at midnight in the
+// given location, weeks starting on Monday. The first and last buckets are cut to the period. Empty buckets are
+// included, carrying the closing balance over. from and to must be aligned on a SlotDuration.
+func (r *Rollup) Aggregate(from, to time.Time, interval Interval, location *time.Location) ([]Bucket, error) {
+	switch interval {
+	case Day, Week, Month:
+	default:
+		return nil, errors.Errorf("unknown interval %q", interval)
+	}
+	if !from.Before(to) {
+		return nil, errors.New("invalid period: from must be before to")
+	}
+	if !from.Truncate(SlotDuration).Equal(from) || !to.Truncate(SlotDuration).Equal(to) {
+		return nil, errors.Errorf("invalid period: boundaries must be aligned on %v", SlotDuration)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	idx := sort.Search(len(r.slots), func(i int) bool {
+		return !r.slots[i].start.Before(from)
+	})
+	balance := decimal.Zero
+	if idx > 0 {
+		balance = r.slots[idx-1].closing
+	}
+	var buckets []Bucket
+	for start := from; start.Before(to); {
+		if len(buckets) == MaxBuckets {
+			return nil, errors.Errorf("invalid period: more than %v buckets", MaxBuckets)
+		}
+		end := nextBoundary(start, interval, location)
+		if end.After(to) {
+			end = to
+		}
+		bucket := Bucket{
+			From:           start.In(location),
+			To:             end.In(location),
+			Credits:        decimal.Zero,
+			Debits:         decimal.Zero,
+			ClosingBalance: balance,
+		}
+		for ; idx < len(r.slots) && r.slots[idx].start.Before(end); idx++ {
+			bucket.Count += r.slots[idx].count
+			bucket.Credits = bucket.Credits.Add(r.slots[idx].credits)
+			bucket.Debits = bucket.Debits.Add(r.slots[idx].debits)
+			bucket.ClosingBalance = r.slots[idx].closing
+		}
+		balance = bucket.ClosingBalance
+		buckets = append(buckets, bucket)
+		start = end
+	}
+	return buckets, nil
+}
+
+// nextBoundary returns the start of the bucket following the one containing t
+func nextBoundary(t time.Time, interval Interval, location *time.Location) time.Time {
+	local := t.In(location)
+	year, month, day := local.Date()
+	switch interval {
+	case Week:
+		daysSinceMonday := (int(local.Weekday()) + 6) % 7
+		return time.Date(year, month, day+7-daysSinceMonday, 0, 0, 0, 0, location)
+	case Month:
+		return time.Date(year, month+1, 1, 0, 0, 0, 0, location)
+	}
+	return time.Date(year, month, day+1, 0, 0, 0, 0, location)
+}
@@ -0,0 +1,179 @@
+package report_test
+
+import (
+	"math/rand"
+	"testing"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/report"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRolledUpLedger creates a ledger maintaining a rollup, whose clock is controlled by the returned pointer
+func newRolledUpLedger(t *testing.T) (*ledger.Ledger, *report.Rollup, *time.Time) {
+	t.Helper()
+	rollup := report.NewRollup()
+	now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ledgerInstance, err := ledger.NewLedger(
+		ledger.WithClock(func() time.Time { return now }),
+		ledger.WithPostedObserver(rollup.Observe),
+	)
+	require.NoError(t, err)
+	return ledgerInstance, rollup, &now
+}
+
+func addAt(t *testing.T, ledgerInstance *ledger.Ledger, now *time.Time, at time.Time, amount string) {
+	t.Helper()
+	*now = at
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.RequireFromString(amount)))
+}
+
+func assertDecimal(t *testing.T, expected string, actual decimal.Decimal) {
+	t.Helper()
+	assert.True(t, decimal.RequireFromString(expected).Equal(actual), "expected %v, got %v", expected, actual)
+}
+
+func TestRollup_Aggregate__SumsDailyBuckets(t *testing.T) {
+	// Arrange
+	ledgerInstance, rollup, now := newRolledUpLedger(t)
+	addAt(t, ledgerInstance, now, time.Date(2025, time.January, 1, 10, 0, 0, 0, time.UTC), "100")
+	addAt(t, ledgerInstance, now, time.Date(2025, time.January, 2, 9, 0, 0, 0, time.UTC), "50")
+	addAt(t, ledgerInstance, now, time.Date(2025, time.January, 2, 18, 0, 0, 0, time.UTC), "-20.5")
+	addAt(t, ledgerInstance, now, time.Date(2025, time.January, 4, 12, 0, 0, 0, time.UTC), "-10")
+
+	// Act
+	buckets, err := rollup.Aggregate(
+		time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, time.January, 5, 0, 0, 0, 0, time.UTC),
+		report.Day, time.UTC)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, buckets, 3)
+	assert.Equal(t, time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC), buckets[0].From)
+	assert.Equal(t, 2, buckets[0].Count)
+	assertDecimal(t, "50", buckets[0].Credits)
+	assertDecimal(t, "20.5", buckets[0].Debits)
+	assertDecimal(t, "29.5", buckets[0].Net())
+	assertDecimal(t, "129.5", buckets[0].ClosingBalance)
+	assert.Equal(t, 0, buckets[1].Count)
+	assertDecimal(t, "129.5", buckets[1].ClosingBalance)
+	assert.Equal(t, 1, buckets[2].Count)
+	assertDecimal(t, "119.5", buckets[2].ClosingBalance)
+}
+
+func TestRollup_Aggregate__UsesLocalDayBoundaries(t *testing.T) {
+	// Arrange
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	ledgerInstance, rollup, now := newRolledUpLedger(t)
+	// 00:30 on the 6th in Berlin
+	addAt(t, ledgerInstance, now, time.Date(2025, time.January, 5, 23, 30, 0, 0, time.UTC), "10")
+
+	// Act
+	buckets, err := rollup.Aggregate(
+		time.Date(2025, time.January, 5, 0, 0, 0, 0, berlin),
+		time.Date(2025, time.January, 7, 0, 0, 0, 0, berlin),
+		report.Day, berlin)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, buckets, 2)
+	assert.Equal(t, 0, buckets[0].Count)
+	assert.Equal(t, 1, buckets[1].Count)
+	assert.Equal(t, "2025-01-06T00:00:00+01:00", buckets[1].From.Format(time.RFC3339))
+}
+
+func TestRollup_Aggregate__HandlesDaylightSavingTime(t *testing.T) {
+	// Arrange
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	_, rollup, _ := newRolledUpLedger(t)
+
+	// Act
+	buckets, err := rollup.Aggregate(
+		time.Date(2025, time.March, 30, 0, 0, 0, 0, berlin),
+		time.Date(2025, time.April, 1, 0, 0, 0, 0, berlin),
+		report.Day, berlin)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, buckets, 2)
+	assert.Equal(t, 23*time.Hour, buckets[0].To.Sub(buckets[0].From))
+	assert.Equal(t, 24*time.Hour, buckets[1].To.Sub(buckets[1].From))
+}
+
+func TestRollup_Aggregate__CutsWeeksAndMonthsToPeriod(t *testing.T) {
+	// Arrange
+	_, rollup, _ := newRolledUpLedger(t)
+	// Wednesday
+	from := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, time.February, 15, 0, 0, 0, 0, time.UTC)
+
+	// Act
+	weeks, errWeeks := rollup.Aggregate(from, to, report.Week, time.UTC)
+	months, errMonths := rollup.Aggregate(from, to, report.Month, time.UTC)
+
+	// Assert
+	require.NoError(t, errWeeks)
+	assert.Len(t, weeks, 7)
+	assert.Equal(t, time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC), weeks[0].To)
+	assert.Equal(t, time.Monday, weeks[1].From.Weekday())
+	assert.Equal(t, to, weeks[6].To)
+	require.NoError(t, errMonths)
+	require.Len(t, months, 2)
+	assert.Equal(t, time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC), months[1].From)
+	assert.Equal(t, to, months[1].To)
+}
+
+func TestRollup_Aggregate__MatchesFullScan(t *testing.T) {
+	// Arrange
+	ledgerInstance, rollup, now := newRolledUpLedger(t)
+	random := rand.New(rand.NewSource(1))
+	at := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 500; i++ {
+		at = at.Add(time.Duration(random.Intn(180)) * time.Minute)
+		addAt(t, ledgerInstance, now, at, decimal.New(random.Int63n(20000)-10000, -2).String())
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	from := time.Date(2025, time.January, 10, 0, 0, 0, 0, newYork)
+	to := time.Date(2025, time.February, 20, 0, 0, 0, 0, newYork)
+
+	// Act
+	buckets, err := rollup.Aggregate(from, to, report.Day, newYork)
+
+	// Assert
+	require.NoError(t, err)
+	for _, bucket := range buckets {
+		transactions, err := ledgerInstance.GetTransactionsBetween(bucket.From, bucket.To)
+		require.NoError(t, err)
+		net := decimal.Zero
+		for _, transaction := range transactions {
+			net = net.Add(transaction.Amount)
+		}
+		closing, err := ledgerInstance.GetBalanceAt(bucket.To)
+		require.NoError(t, err)
+		assert.Equal(t, len(transactions), bucket.Count)
+		assert.True(t, net.Equal(bucket.Net()), "bucket %v: expected net %v, got %v", bucket.From, net, bucket.Net())
+		assert.True(t, closing.Equal(bucket.ClosingBalance))
+	}
+}
+
+func TestRollup_Aggregate__RejectsInvalidPeriods(t *testing.T) {
+	_, rollup, _ := newRolledUpLedger(t)
+	from := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	_, errUnaligned := rollup.Aggregate(from, from.Add(time.Hour+time.Minute), report.Day, time.UTC)
+	_, errEmpty := rollup.Aggregate(from, from, report.Day, time.UTC)
+	_, errTooLong := rollup.Aggregate(from, from.AddDate(10, 0, 0), report.Day, time.UTC)
+	_, errInterval := rollup.Aggregate(from, from.AddDate(0, 0, 1), "hour", time.UTC)
+
+	assert.Error(t, errUnaligned)
+	assert.Error(t, errEmpty)
+	assert.Error(t, errTooLong)
+	assert.Error(t, errInterval)
+}
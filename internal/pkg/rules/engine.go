@@ -0,0 +1,260 @@
+package rules
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"teya_home_assignment/internal/pkg/ledger"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// DecisionAccept is the decision for a transaction no rule acts on
+const DecisionAccept = "accept"
+
+var ErrRuleNotFound = errors.New("rule not found")
+
+// RuleViolationError is returned when a transaction is rejected by a rule
+type RuleViolationError struct {
+	Match Match
+}
+
+func (e *RuleViolationError) Error() string {
+	return fmt.Sprintf("rejected by rule %v", e.Match.Name)
+}
+
+// Match is a rule that matched a transaction
+type Match struct {
+	RuleID uuid.UUID
+	Name   string
+	Action Action
+}
+
+// EvaluationError is a rule that failed to evaluate against a transaction, e.g. because a metadata key
+// it reads is missing. A rule which fails to evaluate does not match.
+type EvaluationError struct {
+	RuleID uuid.UUID
+	Name   string
+	Err    error
+}
+
+// Result is the outcome of evaluating the rules against a transaction.
+// The decision is the most severe action of the matched rules: reject, then review, then flag.
+type Result struct {
+	Decision string
+	Matches  []Match
+	Errors   []EvaluationError
+}
+
+// Flag records a transaction that was posted although it matched flagging rules
+type Flag struct {
+	TransactionID uuid.UUID
+	CreatedAt     time.Time
+	Matches       []Match
+}
+
+// Engine evaluates the rules against the transactions posted by clients. It is registered on the ledger both as
+// a posting check and as a posted observer, so the account state the rules see is consistent with the ledger.
+// Entries posted by the ledger itself, like fees and interest, are not evaluated.
+type Engine struct {
+	mu      sync.Mutex
+	rules   map[uuid.UUID]*Rule
+	account Account
+	flags   []Flag
+	// pending holds the flag of the transaction being posted, recorded once it was added to the ledger
+	pending *Flag
+	reviews map[uuid.UUID]*Review
+	// held maps the idempotency keys of the transactions pending review to their review, so a transaction
+	// submitted again with the same key is not held twice
+	held map[string]uuid.UUID
+	// approving holds the idempotency keys of the reviewed transactions being posted, which skip the rules
+	approving map[string]bool
+}
+
+func NewEngine() *Engine {
+	return &Engine{
+		rules:     make(map[uuid.UUID]*Rule),
+		reviews:   make(map[uuid.UUID]*Review),
+		held:      make(map[string]uuid.UUID),
+		approving: make(map[string]bool),
+	}
+}
+
+// Create compiles and registers a rule
+func (e *Engine) Create(rule Rule) (Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return Rule{}, errors.Wrap(err, "invalid rule")
+	}
+	rule.ID = uuid.New()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules[rule.ID] = &rule
+	return rule, nil
+}
+
+func (e *Engine) Get(id uuid.UUID) (Rule, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	rule, exists := e.rules[id]
+	if !exists {
+		return Rule{}, ErrRuleNotFound
+	}
+	return *rule, nil
+}
+
+// List returns all rules ordered by name
+func (e *Engine) List() []Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.sortedRules()
+}
+
+func (e *Engine) Update(id uuid.UUID, rule Rule) (Rule, error) {
+	if err := rule.Validate(); err != nil {
+		return Rule{}, errors.Wrap(err, "invalid rule")
+	}
+	rule.ID = id
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, exists := e.rules[id]; !exists {
+		return Rule{}, ErrRuleNotFound
+	}
+	e.rules[id] = &rule
+	return rule, nil
+}
+
+func (e *Engine) Delete(id uuid.UUID) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, exists := e.rules[id]; !exists {
+		return ErrRuleNotFound
+	}
+	delete(e.rules, id)
+	return nil
+}
+
+// GetAccount returns the account state the next transaction will be evaluated against
+func (e *Engine) GetAccount() Account {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.account
+}
+
+// GetFlags returns the flagged transactions in the order they were posted
+func (e *Engine) GetFlags() []Flag {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Flag{}, e.flags...)
+}
+
+// Test evaluates a sample transaction without posting it, against the given rule if any
+// or against the registered rules otherwise
+func (e *Engine) Test(rule *Rule, transaction ledger.Transaction, account Account) (Result, error) {
+	if rule == nil {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		return evaluateRules(e.sortedRules(), transaction, account), nil
+	}
+	if err := rule.Validate(); err != nil {
+		return Result{}, errors.Wrap(err, "invalid rule")
+	}
+	return evaluateRules([]Rule{*rule}, transaction, account), nil
+}
+
+// Check is a ledger.PostingCheck applying the rules to a transaction. It rejects the transaction with a
+// RuleViolationError or holds it for review with a ReviewRequiredError.
+func (e *Engine) Check(transaction ledger.Transaction) error {
+	if transaction.Kind != "" {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pending = nil
+	if e.approving[transaction.IdempotencyKey] {
+		return nil
+	}
+	if id, held := e.held[transaction.IdempotencyKey]; held {
+		return &ReviewRequiredError{Review: *e.reviews[id]}
+	}
+	result := evaluateRules(e.sortedRules(), transaction, e.account)
+	for _, evalErr := range result.Errors {
+		slog.Warn("rule could not be evaluated", "rule_id", evalErr.RuleID, "rule", evalErr.Name, "error", evalErr.Err)
+	}
+	switch Action(result.Decision) {
+	case ActionReject:
+		for _, match := range result.Matches {
+			if match.Action == ActionReject {
+				return &RuleViolationError{Match: match}
+			}
+		}
+	case ActionReview:
+		review := newReview(transaction, result.Matches)
+		e.reviews[review.ID] = review
+		if transaction.IdempotencyKey != "" {
+			e.held[transaction.IdempotencyKey] = review.ID
+		}
+		return &ReviewRequiredError{Review: *review}
+	case ActionFlag:
+		e.pending = &Flag{TransactionID: transaction.ExternalID, CreatedAt: transaction.CreatedAt, Matches: result.Matches}
+	}
+	return nil
+}
+
+// Observe is a ledger.PostedObserver keeping the account state and recording flagged transactions
+func (e *Engine) Observe(posted []ledger.Transaction) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, transaction := range posted {
+		e.account.Balance = e.account.Balance.Add(transaction.Amount)
+		e.account.TransactionCount++
+		if e.pending != nil && e.pending.TransactionID == transaction.ExternalID {
+			e.flags = append(e.flags, *e.pending)
+			e.pending = nil
+		}
+	}
+}
+
+func (e *Engine) sortedRules() []Rule {
+	rules := make([]Rule, 0, len(e.rules))
+	for _, rule := range e.rules {
+		rules = append(rules, *rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Name < rules[j].Name
+	})
+	return rules
+}
+
+func evaluateRules(rules []Rule, transaction ledger.Transaction, account Account) Result {
+	result := Result{Decision: DecisionAccept, Matches: []Match{}, Errors: []EvaluationError{}}
+	for _, rule := range rules {
+		matched, err := rule.Matches(transaction, account)
+		if err != nil {
+			result.Errors = append(result.Errors, EvaluationError{RuleID: rule.ID, Name: rule.Name, Err: err})
+			continue
+		}
+		if !matched {
+			continue
+		}
+		result.Matches = append(result.Matches, Match{RuleID: rule.ID, Name: rule.Name, Action: rule.Action})
+		if severity(rule.Action) > severity(Action(result.Decision)) {
+			result.Decision = string(rule.Action)
+		}
+	}
+	return result
+}
+
+func severity(action Action) int {
+	switch action {
+	case ActionReject:
+		return 3
+	case ActionReview:
+		return 2
+	case ActionFlag:
+		return 1
+	}
+	return 0
+}
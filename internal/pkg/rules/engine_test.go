@@ -0,0 +1,262 @@
+package rules_test
+
+import (
+	"testing"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/rules"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRulesLedger(t *testing.T, ruleSet ...rules.Rule) (*ledger.Ledger, *rules.Engine) {
+	t.Helper()
+	engine := rules.NewEngine()
+	for _, rule := range ruleSet {
+		_, err := engine.Create(rule)
+		require.NoError(t, err)
+	}
+	ledgerInstance, err := ledger.NewLedger(
+		ledger.WithPostingCheck(engine.Check),
+		ledger.WithPostedObserver(engine.Observe),
+	)
+	require.NoError(t, err)
+	return ledgerInstance, engine
+}
+
+var onlineChannel = ledger.WithMetadata(map[string]string{"channel": "online"})
+
+func TestEngine_Create__RejectsInvalidExpressions(t *testing.T) {
+	engine := rules.NewEngine()
+	for name, expression := range map[string]string{
+		"syntax error":     "amount <",
+		"unknown variable": "merchant == \"acme\"",
+		"not a bool":       "amount * 2.0",
+		"type mismatch":    "amount < \"500\"",
+		"empty":            "",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := engine.Create(rules.Rule{Name: "rule", Expression: expression, Action: rules.ActionReject})
+
+			assert.ErrorContains(t, err, "invalid rule")
+		})
+	}
+	assert.Empty(t, engine.List())
+}
+
+func TestEngine_Check__RejectsMatchingTransaction(t *testing.T) {
+	// Arrange
+	ledgerInstance, _ := newRulesLedger(t, rules.Rule{
+		Name:       "large online debit",
+		Expression: `amount < -500 && metadata.channel == "online"`,
+		Action:     rules.ActionReject,
+	})
+
+	// Act
+	err := ledgerInstance.AddTransaction(decimal.NewFromInt(-501), onlineChannel)
+
+	// Assert
+	var violation *rules.RuleViolationError
+	require.ErrorAs(t, err, &violation)
+	assert.Equal(t, "large online debit", violation.Match.Name)
+	assert.Empty(t, ledgerInstance.TransactionHistory)
+	assert.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(-501),
+		ledger.WithMetadata(map[string]string{"channel": "pos"})))
+	assert.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(-500), onlineChannel))
+}
+
+func TestEngine_Check__EvaluatesAgainstAccountState(t *testing.T) {
+	// Arrange
+	ledgerInstance, _ := newRulesLedger(t, rules.Rule{
+		Name:       "no overdraft",
+		Expression: "account.balance + amount < 0",
+		Action:     rules.ActionReject,
+	})
+	require.NoError(t, ledgerInstance.AddTransaction(decimal.NewFromInt(100)))
+
+	// Act
+	errWithinBalance := ledgerInstance.AddTransaction(decimal.NewFromInt(-60))
+	errOverdraft := ledgerInstance.AddTransaction(decimal.NewFromInt(-60))
+
+	// Assert
+	assert.NoError(t, errWithinBalance)
+	var violation *rules.RuleViolationError
+	assert.ErrorAs(t, errOverdraft, &violation)
+}
+
+func TestEngine_Check__RecordsFlaggedTransactions(t *testing.T) {
+	// Arrange
+	ledgerInstance, engine := newRulesLedger(t, rules.Rule{
+		Name:       "online",
+		Expression: `"channel" in metadata && metadata.channel == "online"`,
+		Action:     rules.ActionFlag,
+	})
+
+	// Act
+	postedFlagged, errFlagged := ledgerInstance.PostTransaction(decimal.NewFromInt(10), onlineChannel)
+	_, errNotFlagged := ledgerInstance.PostTransaction(decimal.NewFromInt(10))
+
+	// Assert
+	require.NoError(t, errFlagged)
+	require.NoError(t, errNotFlagged)
+	flags := engine.GetFlags()
+	require.Len(t, flags, 1)
+	assert.Equal(t, postedFlagged[0].ExternalID, flags[0].TransactionID)
+	assert.Equal(t, "online", flags[0].Matches[0].Name)
+}
+
+func TestEngine_Check__RejectTakesPrecedence(t *testing.T) {
+	// Arrange
+	ledgerInstance, engine := newRulesLedger(t,
+		rules.Rule{Name: "flag debits", Expression: "amount < 0.0", Action: rules.ActionFlag},
+		rules.Rule{Name: "review debits", Expression: "amount < 0.0", Action: rules.ActionReview},
+		rules.Rule{Name: "reject large debits", Expression: "amount < -1000.0", Action: rules.ActionReject},
+	)
+
+	// Act
+	err := ledgerInstance.AddTransaction(decimal.NewFromInt(-2000))
+
+	// Assert
+	var violation *rules.RuleViolationError
+	require.ErrorAs(t, err, &violation)
+	assert.Equal(t, "reject large debits", violation.Match.Name)
+	assert.Empty(t, engine.ListReviews(""))
+	assert.Empty(t, engine.GetFlags())
+}
+
+func TestEngine_Approve__PostsHeldTransactionOnce(t *testing.T) {
+	// Arrange
+	ledgerInstance, engine := newRulesLedger(t, rules.Rule{
+		Name:       "review large debits",
+		Expression: "amount < -100.0",
+		Action:     rules.ActionReview,
+	})
+	err := ledgerInstance.AddTransaction(decimal.NewFromInt(-150), ledger.WithReference("REF-1"), onlineChannel)
+	var reviewRequired *rules.ReviewRequiredError
+	require.ErrorAs(t, err, &reviewRequired)
+	require.Empty(t, ledgerInstance.TransactionHistory)
+
+	// Act
+	review, posted, err := engine.Approve(ledgerInstance, reviewRequired.Review.ID)
+	_, _, errApprovedTwice := engine.Approve(ledgerInstance, reviewRequired.Review.ID)
+	_, errDeclineApproved := engine.Decline(reviewRequired.Review.ID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, rules.ReviewApproved, review.Status)
+	assert.Equal(t, posted[0].ExternalID, review.Posted)
+	require.Len(t, ledgerInstance.TransactionHistory, 1)
+	assert.True(t, decimal.NewFromInt(-150).Equal(ledgerInstance.TransactionHistory[0].Amount))
+	assert.Equal(t, "REF-1", ledgerInstance.TransactionHistory[0].Reference)
+	assert.Equal(t, "online", ledgerInstance.TransactionHistory[0].Metadata["channel"])
+	assert.ErrorIs(t, errApprovedTwice, rules.ErrReviewClosed)
+	assert.ErrorIs(t, errDeclineApproved, rules.ErrReviewClosed)
+	assert.Empty(t, engine.ListReviews(rules.ReviewPending))
+}
+
+func TestEngine_Check__HoldsTransactionOncePerIdempotencyKey(t *testing.T) {
+	// Arrange
+	ledgerInstance, engine := newRulesLedger(t, rules.Rule{
+		Name:       "review everything",
+		Expression: "true",
+		Action:     rules.ActionReview,
+	})
+	var first, second *rules.ReviewRequiredError
+	require.ErrorAs(t, ledgerInstance.AddTransaction(decimal.NewFromInt(10), ledger.WithIdempotencyKey("key")), &first)
+
+	// Act
+	err := ledgerInstance.AddTransaction(decimal.NewFromInt(10), ledger.WithIdempotencyKey("key"))
+
+	// Assert
+	require.ErrorAs(t, err, &second)
+	assert.Equal(t, first.Review.ID, second.Review.ID)
+	assert.Len(t, engine.ListReviews(""), 1)
+}
+
+func TestEngine_Approve__ReplaysTransactionSubmittedAgain(t *testing.T) {
+	// Arrange
+	ledgerInstance, engine := newRulesLedger(t, rules.Rule{
+		Name:       "review everything",
+		Expression: "true",
+		Action:     rules.ActionReview,
+	})
+	var reviewRequired *rules.ReviewRequiredError
+	require.ErrorAs(t, ledgerInstance.AddTransaction(decimal.NewFromInt(10), ledger.WithIdempotencyKey("key")), &reviewRequired)
+	_, _, err := engine.Approve(ledgerInstance, reviewRequired.Review.ID)
+	require.NoError(t, err)
+
+	// Act
+	err = ledgerInstance.AddTransaction(decimal.NewFromInt(10), ledger.WithIdempotencyKey("key"))
+
+	// Assert
+	assert.ErrorIs(t, err, ledger.ErrDuplicateTransaction)
+	assert.Len(t, ledgerInstance.TransactionHistory, 1)
+	assert.Len(t, engine.ListReviews(""), 1)
+}
+
+func TestEngine_Decline__EvaluatesTransactionSubmittedAgain(t *testing.T) {
+	// Arrange
+	ledgerInstance, engine := newRulesLedger(t, rules.Rule{
+		Name:       "review everything",
+		Expression: "true",
+		Action:     rules.ActionReview,
+	})
+	var declined, second *rules.ReviewRequiredError
+	require.ErrorAs(t, ledgerInstance.AddTransaction(decimal.NewFromInt(10), ledger.WithIdempotencyKey("key")), &declined)
+	_, err := engine.Decline(declined.Review.ID)
+	require.NoError(t, err)
+
+	// Act
+	err = ledgerInstance.AddTransaction(decimal.NewFromInt(10), ledger.WithIdempotencyKey("key"))
+
+	// Assert
+	require.ErrorAs(t, err, &second)
+	assert.NotEqual(t, declined.Review.ID, second.Review.ID)
+	assert.Equal(t, rules.ReviewPending, second.Review.Status)
+}
+
+func TestEngine_Decline__DropsHeldTransaction(t *testing.T) {
+	// Arrange
+	ledgerInstance, engine := newRulesLedger(t, rules.Rule{
+		Name:       "review everything",
+		Expression: "true",
+		Action:     rules.ActionReview,
+	})
+	var reviewRequired *rules.ReviewRequiredError
+	require.ErrorAs(t, ledgerInstance.AddTransaction(decimal.NewFromInt(10)), &reviewRequired)
+
+	// Act
+	review, err := engine.Decline(reviewRequired.Review.ID)
+	_, _, errApproveDeclined := engine.Approve(ledgerInstance, reviewRequired.Review.ID)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, rules.ReviewDeclined, review.Status)
+	assert.ErrorIs(t, errApproveDeclined, rules.ErrReviewClosed)
+	assert.Empty(t, ledgerInstance.TransactionHistory)
+}
+
+func TestEngine_Test__ReportsDecisionAndEvaluationErrors(t *testing.T) {
+	// Arrange
+	engine := rules.NewEngine()
+	sample := ledger.Transaction{Amount: decimal.NewFromInt(-600)}
+	candidate := rules.Rule{
+		Name:       "large online debit",
+		Expression: `amount < -500 && metadata.channel == "online"`,
+		Action:     rules.ActionReject,
+	}
+
+	// Act
+	withoutChannel, errWithoutChannel := engine.Test(&candidate, sample, rules.Account{})
+	sample.Metadata = map[string]string{"channel": "online"}
+	online, errOnline := engine.Test(&candidate, sample, rules.Account{})
+
+	// Assert
+	require.NoError(t, errWithoutChannel)
+	assert.Equal(t, rules.DecisionAccept, withoutChannel.Decision)
+	assert.Len(t, withoutChannel.Errors, 1)
+	require.NoError(t, errOnline)
+	assert.Equal(t, string(rules.ActionReject), online.Decision)
+	assert.Empty(t, online.Errors)
+}
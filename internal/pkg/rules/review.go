@@ -0,0 +1,150 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"teya_home_assignment/internal/pkg/ledger"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "pending"
+	ReviewApproved ReviewStatus = "approved"
+	ReviewDeclined ReviewStatus = "declined"
+)
+
+var (
+	ErrReviewNotFound = errors.New("review not found")
+	ErrReviewClosed   = errors.New("review is not pending")
+)
+
+// ReviewRequiredError is returned when a transaction is held for review instead of being posted
+type ReviewRequiredError struct {
+	Review Review
+}
+
+func (e *ReviewRequiredError) Error() string {
+	return fmt.Sprintf("held for review %v", e.Review.ID)
+}
+
+// Review is a transaction held by a review rule. It is posted to the ledger once approved.
+type Review struct {
+	ID uuid.UUID
+	// Transaction is the held transaction, CreatedAt is when it was held
+	Transaction ledger.Transaction
+	Matches     []Match
+	Status      ReviewStatus
+	// Posted is the transaction posted once the review was approved
+	Posted uuid.UUID
+}
+
+func newReview(transaction ledger.Transaction, matches []Match) *Review {
+	return &Review{
+		ID:          uuid.New(),
+		Transaction: transaction,
+		Matches:     matches,
+		Status:      ReviewPending,
+	}
+}
+
+// idempotencyKey makes sure a reviewed transaction is posted at most once. It is the key the transaction was
+// submitted with, so submitting it again once approved is answered as a replay, or one derived from the review.
+func (r *Review) idempotencyKey() string {
+	if r.Transaction.IdempotencyKey != "" {
+		return r.Transaction.IdempotencyKey
+	}
+	return "review:" + r.ID.String()
+}
+
+func (e *Engine) GetReview(id uuid.UUID) (Review, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	review, exists := e.reviews[id]
+	if !exists {
+		return Review{}, ErrReviewNotFound
+	}
+	return *review, nil
+}
+
+// ListReviews returns the reviews in the order the transactions were held, optionally filtered by status
+func (e *Engine) ListReviews(status ReviewStatus) []Review {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	reviews := make([]Review, 0, len(e.reviews))
+	for _, review := range e.reviews {
+		if status == "" || review.Status == status {
+			reviews = append(reviews, *review)
+		}
+	}
+	sort.Slice(reviews, func(i, j int) bool {
+		return reviews[i].Transaction.CreatedAt.Before(reviews[j].Transaction.CreatedAt)
+	})
+	return reviews
+}
+
+// Approve posts a held transaction to the ledger. The rules are not evaluated again,
+// the other posting checks like the spending limits still apply.
+func (e *Engine) Approve(ledgerService *ledger.Ledger, id uuid.UUID) (Review, []ledger.Transaction, error) {
+	e.mu.Lock()
+	review, exists := e.reviews[id]
+	if !exists {
+		e.mu.Unlock()
+		return Review{}, nil, ErrReviewNotFound
+	}
+	key := review.idempotencyKey()
+	if review.Status != ReviewPending || e.approving[key] {
+		e.mu.Unlock()
+		return Review{}, nil, ErrReviewClosed
+	}
+	e.approving[key] = true
+	held := review.Transaction
+	e.mu.Unlock()
+
+	// the engine must not be locked while posting, the ledger calls back into Check and Observe
+	posted, err := ledgerService.PostTransaction(held.Amount,
+		ledger.WithReference(held.Reference),
+		ledger.WithDescription(held.Description),
+		ledger.WithMetadata(held.Metadata),
+		ledger.WithIdempotencyKey(key),
+	)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.approving, key)
+	if err != nil {
+		return Review{}, nil, errors.Wrap(err, "failed to post reviewed transaction")
+	}
+	review.Status = ReviewApproved
+	review.Posted = posted[0].ExternalID
+	e.release(review)
+	return *review, posted, nil
+}
+
+// Decline drops a held transaction
+func (e *Engine) Decline(id uuid.UUID) (Review, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	review, exists := e.reviews[id]
+	if !exists {
+		return Review{}, ErrReviewNotFound
+	}
+	if review.Status != ReviewPending || e.approving[review.idempotencyKey()] {
+		return Review{}, ErrReviewClosed
+	}
+	review.Status = ReviewDeclined
+	e.release(review)
+	return *review, nil
+}
+
+// release forgets the idempotency key of a decided review, a transaction submitted again with the key is not
+// answered with the review anymore: it is a replay of the posted transaction once approved, and it is evaluated
+// again once declined
+func (e *Engine) release(review *Review) {
+	if review.Transaction.IdempotencyKey != "" {
+		delete(e.held, review.Transaction.IdempotencyKey)
+	}
+}
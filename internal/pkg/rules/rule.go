@@ -0,0 +1,135 @@
+package rules
+
+import (
+	"sync"
+	"teya_home_assignment/internal/pkg/ledger"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Action is what happens to a transaction matched by a rule
+type Action string
+
+const (
+	// ActionReject rejects the transaction
+	ActionReject Action = "reject"
+	// ActionReview holds the transaction until it is approved or declined
+	ActionReview Action = "review"
+	// ActionFlag posts the transaction and records it was flagged by the rule
+	ActionFlag Action = "flag"
+)
+
+// costLimit bounds the work a single expression may do while evaluated under the ledger lock
+const costLimit = 10000
+
+// Rule is a CEL expression evaluated against every transaction posted by a client and the account state.
+// The expression must evaluate to a bool, true applies the action to the transaction. Available variables:
+//   - amount (double), reference (string), description (string), metadata (map(string, string)),
+//     created_at (timestamp) of the transaction
+//   - account.balance (double) and account.transaction_count (int) before the transaction is added
+type Rule struct {
+	ID         uuid.UUID
+	Name       string
+	Expression string
+	Action     Action
+
+	program cel.Program
+}
+
+// Account is the state of the account a transaction is evaluated against
+type Account struct {
+	Balance          decimal.Decimal
+	TransactionCount int
+}
+
+var newEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("amount", cel.DoubleType),
+		cel.Variable("reference", cel.StringType),
+		cel.Variable("description", cel.StringType),
+		cel.Variable("metadata", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("created_at", cel.TimestampType),
+		cel.Variable("account", cel.MapType(cel.StringType, cel.DynType)),
+		// lets amounts be compared to integer literals, as in amount < -500
+		cel.CrossTypeNumericComparisons(true),
+	)
+})
+
+// Validate checks the rule and compiles its expression
+func (r *Rule) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	switch r.Action {
+	case ActionReject, ActionReview, ActionFlag:
+	default:
+		return errors.Errorf("unknown action %q", r.Action)
+	}
+	program, err := compile(r.Expression)
+	if err != nil {
+		return err
+	}
+	r.program = program
+	return nil
+}
+
+// Matches evaluates the rule against the transaction
+func (r *Rule) Matches(transaction ledger.Transaction, account Account) (bool, error) {
+	return evaluate(r.program, transaction, account)
+}
+
+func compile(expression string) (cel.Program, error) {
+	if expression == "" {
+		return nil, errors.New("expression is required")
+	}
+	env, err := newEnv()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create expression environment")
+	}
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, errors.Wrap(issues.Err(), "invalid expression")
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, errors.Errorf("invalid expression: must evaluate to a bool, not %v", ast.OutputType())
+	}
+	program, err := env.Program(ast, cel.CostLimit(costLimit))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid expression")
+	}
+	return program, nil
+}
+
+func evaluate(program cel.Program, transaction ledger.Transaction, account Account) (bool, error) {
+	metadata := transaction.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	createdAt := transaction.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now().UTC()
+	}
+	out, _, err := program.Eval(map[string]any{
+		"amount":      transaction.Amount.InexactFloat64(),
+		"reference":   transaction.Reference,
+		"description": transaction.Description,
+		"metadata":    metadata,
+		"created_at":  createdAt,
+		"account": map[string]any{
+			"balance":           account.Balance.InexactFloat64(),
+			"transaction_count": account.TransactionCount,
+		},
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to evaluate expression")
+	}
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, errors.Errorf("expression evaluated to %v instead of a bool", out.Type())
+	}
+	return matched, nil
+}
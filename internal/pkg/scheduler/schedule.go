@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+	"github.com/shopspring/decimal"
+)
+
+// MinInterval bounds how often a schedule may post, so catching up after downtime stays cheap
+const MinInterval = time.Minute
+
+// Schedule describes a recurring transaction. Occurrences are defined either by a standard 5 fields cron
+// expression (evaluated in UTC) or by a fixed interval counted from StartAt.
+// EndAt is exclusive, a zero value means the schedule never ends.
+type Schedule struct {
+	ID          uuid.UUID       `json:"id"`
+	Cron        string          `json:"cron,omitempty"`
+	Interval    time.Duration   `json:"interval,omitempty"`
+	StartAt     time.Time       `json:"start_at"`
+	EndAt       time.Time       `json:"end_at"`
+	Amount      decimal.Decimal `json:"amount"`
+	Description string          `json:"description,omitempty"`
+
+	cronSchedule cron.Schedule
+}
+
+// Validate checks the schedule rule and prepares it for evaluation
+func (s *Schedule) Validate() error {
+	if s.StartAt.IsZero() {
+		return errors.New("start time is required")
+	}
+	if !s.EndAt.IsZero() && !s.StartAt.Before(s.EndAt) {
+		return errors.New("end time must be after start time")
+	}
+	if s.Amount.IsZero() {
+		return errors.New("amount must not be zero")
+	}
+	switch {
+	case s.Cron != "" && s.Interval != 0:
+		return errors.New("either a cron expression or an interval must be set, not both")
+	case s.Cron != "":
+		cronSchedule, err := cron.ParseStandard(s.Cron)
+		if err != nil {
+			return errors.Wrap(err, "invalid cron expression")
+		}
+		// the 5 fields expressions can not post more than once a minute, unlike @every
+		if every, ok := cronSchedule.(cron.ConstantDelaySchedule); ok && every.Delay < MinInterval {
+			return errors.Errorf("cron expression must not run more often than every %v", MinInterval)
+		}
+		s.cronSchedule = cronSchedule
+	case s.Interval < MinInterval:
+		return errors.Errorf("interval must be at least %v", MinInterval)
+	}
+	return nil
+}
+
+// Next returns the first occurrence strictly after the given time, or false once the schedule ended
+func (s *Schedule) Next(after time.Time) (time.Time, bool) {
+	var next time.Time
+	if after.Before(s.StartAt) {
+		after = s.StartAt.Add(-time.Nanosecond)
+	}
+	if s.cronSchedule != nil {
+		next = s.cronSchedule.Next(after.UTC())
+	} else {
+		elapsed := after.Sub(s.StartAt)
+		next = s.StartAt.Add((elapsed/s.Interval + 1) * s.Interval)
+		if elapsed < 0 {
+			next = s.StartAt
+		}
+	}
+	if next.IsZero() || (!s.EndAt.IsZero() && !next.Before(s.EndAt)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// NextRuns previews up to count occurrences strictly after the given time
+func (s *Schedule) NextRuns(after time.Time, count int) []time.Time {
+	runs := make([]time.Time, 0, count)
+	for len(runs) < count {
+		next, ok := s.Next(after)
+		if !ok {
+			break
+		}
+		runs = append(runs, next)
+		after = next
+	}
+	return runs
+}
+
+// idempotencyKey identifies an occurrence of the schedule in the ledger
+func (s *Schedule) idempotencyKey(occurrence time.Time) string {
+	return s.idempotencyKeyPrefix() + occurrence.UTC().Format(time.RFC3339)
+}
+
+// idempotencyKeyPrefix starts the idempotency keys of all the occurrences of the schedule
+func (s *Schedule) idempotencyKeyPrefix() string {
+	return "schedule:" + s.ID.String() + ":"
+}
@@ -0,0 +1,341 @@
+package scheduler
+
+import (
+	stderrors "errors"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/rules"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// DefaultTickInterval is how often the scheduler looks for due occurrences
+const DefaultTickInterval = 10 * time.Second
+
+// MaxOccurrencesPerRun bounds how many occurrences of a schedule are posted by a single run, so catching up with a
+// schedule which started long ago does not hold the scheduler. The remaining occurrences are posted by the next runs.
+const MaxOccurrencesPerRun = 100
+
+var (
+	ErrScheduleNotFound = errors.New("schedule not found")
+	ErrScheduleExists   = errors.New("schedule already exists")
+)
+
+// Scheduler posts the occurrences of recurring transactions to the ledger once they are due.
+// Every occurrence is posted with an idempotency key derived from the schedule and the occurrence time,
+// so an occurrence is never posted twice by the scheduler. The schedules are persisted in the store if any, the
+// cursors are rebuilt from the occurrences posted to the ledger.
+type Scheduler struct {
+	ledgerService *ledger.Ledger
+	clock         func() time.Time
+	tickInterval  time.Duration
+	store         Store
+
+	mu        sync.Mutex
+	schedules map[uuid.UUID]*Schedule
+	// cursors hold the last occurrence handled per schedule
+	cursors map[uuid.UUID]time.Time
+
+	stop    chan struct{}
+	done    chan struct{}
+	running atomic.Bool
+}
+
+// Store persists the schedules, so they survive restarts
+type Store interface {
+	// LoadSchedules returns the schedules saved last
+	LoadSchedules() ([]Schedule, error)
+	// SaveSchedules replaces the schedules saved so far. It is called while the scheduler is locked, before the
+	// schedules change, an error leaves them unchanged.
+	SaveSchedules(schedules []Schedule) error
+}
+
+type Option func(s *Scheduler)
+
+// WithClock overrides the time source used to decide which occurrences are due
+func WithClock(clock func() time.Time) Option {
+	return func(s *Scheduler) {
+		s.clock = clock
+	}
+}
+
+// WithStore persists the schedules in the store. The schedules already persisted are restored by
+// Scheduler.Restore.
+func WithStore(store Store) Option {
+	return func(s *Scheduler) {
+		s.store = store
+	}
+}
+
+// WithTickInterval overrides how often the scheduler looks for due occurrences
+func WithTickInterval(interval time.Duration) Option {
+	return func(s *Scheduler) {
+		s.tickInterval = interval
+	}
+}
+
+func NewScheduler(ledgerService *ledger.Ledger, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		ledgerService: ledgerService,
+		clock:         time.Now,
+		tickInterval:  DefaultTickInterval,
+		schedules:     make(map[uuid.UUID]*Schedule),
+		cursors:       make(map[uuid.UUID]time.Time),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Create registers a schedule. A new ID is generated unless the schedule already has one.
+func (s *Scheduler) Create(schedule Schedule) (Schedule, error) {
+	if err := schedule.Validate(); err != nil {
+		return Schedule{}, errors.Wrap(err, "invalid schedule")
+	}
+	if schedule.ID == uuid.Nil {
+		schedule.ID = uuid.New()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.schedules[schedule.ID]; exists {
+		return Schedule{}, ErrScheduleExists
+	}
+	if err := s.save(schedule.ID, &schedule); err != nil {
+		return Schedule{}, err
+	}
+	s.schedules[schedule.ID] = &schedule
+	return schedule, nil
+}
+
+func (s *Scheduler) Get(id uuid.UUID) (Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	schedule, exists := s.schedules[id]
+	if !exists {
+		return Schedule{}, ErrScheduleNotFound
+	}
+	return *schedule, nil
+}
+
+// List returns all schedules ordered by their start time
+func (s *Scheduler) List() []Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list()
+}
+
+func (s *Scheduler) list() []Schedule {
+	schedules := make([]Schedule, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		schedules = append(schedules, *schedule)
+	}
+	sortByStart(schedules)
+	return schedules
+}
+
+func sortByStart(schedules []Schedule) {
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].StartAt.Before(schedules[j].StartAt)
+	})
+}
+
+// save persists the schedules with the schedule of the ID replaced, or removed when nil
+func (s *Scheduler) save(id uuid.UUID, schedule *Schedule) error {
+	if s.store == nil {
+		return nil
+	}
+	schedules := make([]Schedule, 0, len(s.schedules)+1)
+	for existingID, existing := range s.schedules {
+		if existingID != id {
+			schedules = append(schedules, *existing)
+		}
+	}
+	if schedule != nil {
+		schedules = append(schedules, *schedule)
+	}
+	sortByStart(schedules)
+	return errors.Wrap(s.store.SaveSchedules(schedules), "failed to persist schedules")
+}
+
+// Update replaces the rule, amount and description of a schedule.
+// Occurrences that were already posted are kept, the cursor is not rewound.
+func (s *Scheduler) Update(id uuid.UUID, schedule Schedule) (Schedule, error) {
+	if err := schedule.Validate(); err != nil {
+		return Schedule{}, errors.Wrap(err, "invalid schedule")
+	}
+	schedule.ID = id
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.schedules[id]; !exists {
+		return Schedule{}, ErrScheduleNotFound
+	}
+	if err := s.save(id, &schedule); err != nil {
+		return Schedule{}, err
+	}
+	s.schedules[id] = &schedule
+	return schedule, nil
+}
+
+func (s *Scheduler) Delete(id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.schedules[id]; !exists {
+		return ErrScheduleNotFound
+	}
+	if err := s.save(id, nil); err != nil {
+		return err
+	}
+	delete(s.schedules, id)
+	delete(s.cursors, id)
+	return nil
+}
+
+// Restore adds the schedules persisted in the store and moves the cursor of every schedule to its last occurrence
+// posted to the ledger, so the occurrences missed while the scheduler was not running are posted by the next run.
+// It is called once the ledger was restored, before the scheduler is started.
+func (s *Scheduler) Restore() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store != nil {
+		if len(s.schedules) > 0 {
+			return errors.New("failed to restore schedules: schedules were already created")
+		}
+		schedules, err := s.store.LoadSchedules()
+		if err != nil {
+			return errors.Wrap(err, "failed to restore schedules")
+		}
+		for _, schedule := range schedules {
+			if err := schedule.Validate(); err != nil {
+				return errors.Wrapf(err, "failed to restore schedule %v", schedule.ID)
+			}
+			s.schedules[schedule.ID] = &schedule
+		}
+	}
+	for id, schedule := range s.schedules {
+		cursor, err := s.lastPostedOccurrence(schedule)
+		if err != nil {
+			return errors.Wrapf(err, "failed to restore the cursor of schedule %v", id)
+		}
+		if !cursor.IsZero() {
+			s.cursors[id] = cursor
+		}
+	}
+	return nil
+}
+
+// lastPostedOccurrence returns the time of the last occurrence of the schedule posted to the ledger, a zero time if
+// none was
+func (s *Scheduler) lastPostedOccurrence(schedule *Schedule) (time.Time, error) {
+	prefix := schedule.idempotencyKeyPrefix()
+	last, exists := s.ledgerService.FindLastTransaction(func(transaction ledger.Transaction) bool {
+		return strings.HasPrefix(transaction.IdempotencyKey, prefix)
+	})
+	if !exists {
+		return time.Time{}, nil
+	}
+	occurrence, err := time.Parse(time.RFC3339, strings.TrimPrefix(last.IdempotencyKey, prefix))
+	return occurrence, errors.Wrapf(err, "invalid schedule idempotency key %q", last.IdempotencyKey)
+}
+
+// NextRuns previews up to count upcoming occurrences of a schedule
+func (s *Scheduler) NextRuns(id uuid.UUID, count int) ([]time.Time, error) {
+	schedule, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return schedule.NextRuns(s.clock(), count), nil
+}
+
+// RunDue posts the occurrences that are due at the given time and were not posted yet, up to MaxOccurrencesPerRun
+// per schedule. An occurrence that can not be posted is retried on the next run, without holding back the other
+// schedules.
+func (s *Scheduler) RunDue(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var failures []error
+	for id, schedule := range s.schedules {
+		if err := s.runScheduleDue(id, schedule, now); err != nil {
+			failures = append(failures, err)
+		}
+	}
+	return errors.Wrap(stderrors.Join(failures...), "could not post due occurrences")
+}
+
+func (s *Scheduler) runScheduleDue(id uuid.UUID, schedule *Schedule, now time.Time) error {
+	cursor, exists := s.cursors[id]
+	if !exists {
+		cursor = schedule.StartAt.Add(-time.Nanosecond)
+	}
+	for handled := 0; ; handled++ {
+		occurrence, ok := schedule.Next(cursor)
+		if !ok || occurrence.After(now) {
+			return nil
+		}
+		if handled == MaxOccurrencesPerRun {
+			slog.Warn("schedule is behind, the next occurrences are posted by the next runs",
+				"schedule_id", id, "occurrence", occurrence)
+			return nil
+		}
+		err := s.ledgerService.AddTransaction(schedule.Amount,
+			ledger.WithDescription(schedule.Description),
+			ledger.WithIdempotencyKey(schedule.idempotencyKey(occurrence)))
+		// an occurrence held for review is handled, it is posted under its idempotency key once approved
+		var reviewErr *rules.ReviewRequiredError
+		switch {
+		case err == nil:
+			slog.Info("posted scheduled occurrence", "schedule_id", id, "occurrence", occurrence)
+		case errors.As(err, &reviewErr):
+			slog.Info("scheduled occurrence held for review", "schedule_id", id, "occurrence", occurrence,
+				"review_id", reviewErr.Review.ID)
+		case !errors.Is(err, ledger.ErrDuplicateTransaction):
+			return errors.Wrapf(err, "could not post occurrence %v of schedule %v", occurrence, id)
+		}
+		cursor = occurrence
+		s.cursors[id] = cursor
+	}
+}
+
+// Start runs the scheduler in the background until Stop is called
+func (s *Scheduler) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	s.running.Store(true)
+	go func() {
+		defer close(s.done)
+		defer s.running.Store(false)
+		ticker := time.NewTicker(s.tickInterval)
+		defer ticker.Stop()
+		for {
+			if err := s.RunDue(s.clock()); err != nil {
+				slog.Error("failed to run due schedules", "error", err)
+			}
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop signals the background loop to exit and waits for the occurrences being posted to complete
+func (s *Scheduler) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
+
+// Running tells whether the background loop is running
+func (s *Scheduler) Running() bool {
+	return s.running.Load()
+}
@@ -0,0 +1,232 @@
+package scheduler_test
+
+import (
+	"testing"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/rules"
+	"teya_home_assignment/internal/pkg/scheduler"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2025, time.January, 1, 9, 0, 0, 0, time.UTC)
+
+type memoryStore struct {
+	schedules []scheduler.Schedule
+	err       error
+}
+
+func (m *memoryStore) LoadSchedules() ([]scheduler.Schedule, error) {
+	return m.schedules, nil
+}
+
+func (m *memoryStore) SaveSchedules(schedules []scheduler.Schedule) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.schedules = schedules
+	return nil
+}
+
+func TestSchedule_Validate__RejectsInvalidRules(t *testing.T) {
+	testCases := map[string]scheduler.Schedule{
+		"missing rule":       {StartAt: start, Amount: decimal.NewFromInt(1)},
+		"cron and interval":  {StartAt: start, Amount: decimal.NewFromInt(1), Cron: "0 9 * * *", Interval: time.Hour},
+		"invalid cron":       {StartAt: start, Amount: decimal.NewFromInt(1), Cron: "every day"},
+		"short interval":     {StartAt: start, Amount: decimal.NewFromInt(1), Interval: time.Second},
+		"short cron spacing": {StartAt: start, Amount: decimal.NewFromInt(1), Cron: "@every 1s"},
+		"zero amount":        {StartAt: start, Interval: time.Hour},
+		"end before start":   {StartAt: start, EndAt: start.Add(-time.Hour), Amount: decimal.NewFromInt(1), Interval: time.Hour},
+	}
+	for name, schedule := range testCases {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			err := schedule.Validate()
+
+			// Assert
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSchedule_NextRuns__FollowsInterval(t *testing.T) {
+	// Arrange
+	schedule := scheduler.Schedule{StartAt: start, Interval: 24 * time.Hour, Amount: decimal.NewFromInt(-10)}
+	require.NoError(t, schedule.Validate())
+
+	// Act
+	runs := schedule.NextRuns(start.Add(-time.Hour), 3)
+
+	// Assert
+	assert.Equal(t, []time.Time{start, start.AddDate(0, 0, 1), start.AddDate(0, 0, 2)}, runs)
+}
+
+func TestSchedule_NextRuns__FollowsCronAndStopsAtEnd(t *testing.T) {
+	// Arrange
+	schedule := scheduler.Schedule{
+		StartAt: start,
+		EndAt:   time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC),
+		Cron:    "0 8 1 * *",
+		Amount:  decimal.NewFromInt(-10),
+	}
+	require.NoError(t, schedule.Validate())
+
+	// Act
+	runs := schedule.NextRuns(start, 5)
+
+	// Assert
+	assert.Equal(t, []time.Time{
+		time.Date(2025, time.February, 1, 8, 0, 0, 0, time.UTC),
+	}, runs)
+}
+
+func TestScheduler_RunDue__PostsEachOccurrenceOnce(t *testing.T) {
+	// Arrange
+	ledgerInstance, err := ledger.NewLedger()
+	require.NoError(t, err)
+	schedulerInstance := scheduler.NewScheduler(ledgerInstance)
+	_, err = schedulerInstance.Create(scheduler.Schedule{
+		StartAt:     start,
+		Interval:    time.Hour,
+		Amount:      decimal.NewFromInt(-5),
+		Description: "subscription",
+	})
+	require.NoError(t, err)
+
+	// Act
+	require.NoError(t, schedulerInstance.RunDue(start.Add(150*time.Minute)))
+	require.NoError(t, schedulerInstance.RunDue(start.Add(150*time.Minute)))
+
+	// Assert
+	require.Len(t, ledgerInstance.TransactionHistory, 3)
+	for _, transaction := range ledgerInstance.TransactionHistory {
+		assert.True(t, decimal.NewFromInt(-5).Equal(transaction.Amount))
+		assert.Equal(t, "subscription", transaction.Description)
+		assert.NotEmpty(t, transaction.IdempotencyKey)
+	}
+}
+
+func TestScheduler_RunDue__SkipsOccurrencesAlreadyInLedger(t *testing.T) {
+	// Arrange
+	ledgerInstance, err := ledger.NewLedger()
+	require.NoError(t, err)
+	schedulerInstance := scheduler.NewScheduler(ledgerInstance)
+	schedule, err := schedulerInstance.Create(scheduler.Schedule{StartAt: start, Interval: time.Hour, Amount: decimal.NewFromInt(1)})
+	require.NoError(t, err)
+	require.NoError(t, schedulerInstance.RunDue(start.Add(30*time.Minute)))
+	// a schedule created again has no cursor and is replayed from its start
+	recreated := scheduler.NewScheduler(ledgerInstance)
+	_, err = recreated.Create(schedule)
+	require.NoError(t, err)
+
+	// Act
+	err = recreated.RunDue(start.Add(90 * time.Minute))
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, ledgerInstance.TransactionHistory, 2)
+	assert.NotEqual(t, ledgerInstance.TransactionHistory[0].IdempotencyKey, ledgerInstance.TransactionHistory[1].IdempotencyKey)
+}
+
+func TestScheduler_RunDue__CatchesUpOverSeveralRuns(t *testing.T) {
+	// Arrange
+	ledgerInstance, err := ledger.NewLedger()
+	require.NoError(t, err)
+	schedulerInstance := scheduler.NewScheduler(ledgerInstance)
+	_, err = schedulerInstance.Create(scheduler.Schedule{StartAt: start, Interval: time.Minute, Amount: decimal.NewFromInt(1)})
+	require.NoError(t, err)
+	// the occurrences from the start up to 150 minutes later
+	now := start.Add(150 * time.Minute)
+
+	// Act
+	require.NoError(t, schedulerInstance.RunDue(now))
+	postedByFirstRun := len(ledgerInstance.TransactionHistory)
+	require.NoError(t, schedulerInstance.RunDue(now))
+
+	// Assert
+	assert.Equal(t, scheduler.MaxOccurrencesPerRun, postedByFirstRun)
+	assert.Len(t, ledgerInstance.TransactionHistory, 151)
+}
+
+func TestScheduler_RunDue__MovesPastOccurrencesHeldForReview(t *testing.T) {
+	// Arrange
+	engine := rules.NewEngine()
+	_, err := engine.Create(rules.Rule{Name: "review everything", Expression: "true", Action: rules.ActionReview})
+	require.NoError(t, err)
+	ledgerInstance, err := ledger.NewLedger(ledger.WithPostingCheck(engine.Check), ledger.WithPostedObserver(engine.Observe))
+	require.NoError(t, err)
+	schedulerInstance := scheduler.NewScheduler(ledgerInstance)
+	_, err = schedulerInstance.Create(scheduler.Schedule{StartAt: start, Interval: time.Hour, Amount: decimal.NewFromInt(1)})
+	require.NoError(t, err)
+	require.NoError(t, schedulerInstance.RunDue(start))
+	held := engine.ListReviews(rules.ReviewPending)
+	require.Len(t, held, 1)
+	_, _, err = engine.Approve(ledgerInstance, held[0].ID)
+	require.NoError(t, err)
+
+	// Act
+	err = schedulerInstance.RunDue(start.Add(30 * time.Minute))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, engine.ListReviews(""), 1)
+	require.Len(t, ledgerInstance.TransactionHistory, 1)
+	assert.Equal(t, held[0].Transaction.IdempotencyKey, ledgerInstance.TransactionHistory[0].IdempotencyKey)
+}
+
+func TestScheduler_Restore__ResumesAfterLastPostedOccurrence(t *testing.T) {
+	// Arrange
+	ledgerInstance, err := ledger.NewLedger()
+	require.NoError(t, err)
+	store := &memoryStore{}
+	schedulerInstance := scheduler.NewScheduler(ledgerInstance, scheduler.WithStore(store))
+	schedule, err := schedulerInstance.Create(scheduler.Schedule{StartAt: start, Interval: time.Minute, Amount: decimal.NewFromInt(1)})
+	require.NoError(t, err)
+	now := start.Add(150 * time.Minute)
+	require.NoError(t, schedulerInstance.RunDue(now))
+	restarted := scheduler.NewScheduler(ledgerInstance, scheduler.WithStore(store))
+
+	// Act
+	err = restarted.Restore()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []scheduler.Schedule{schedule}, restarted.List())
+	// the occurrences posted before the restart are not handled again, the next run posts the following ones
+	require.NoError(t, restarted.RunDue(now))
+	assert.Len(t, ledgerInstance.TransactionHistory, 151)
+}
+
+func TestScheduler_Create__KeepsSchedulesWhenStoreFails(t *testing.T) {
+	// Arrange
+	ledgerInstance, err := ledger.NewLedger()
+	require.NoError(t, err)
+	store := &memoryStore{err: errors.New("disk full")}
+	schedulerInstance := scheduler.NewScheduler(ledgerInstance, scheduler.WithStore(store))
+
+	// Act
+	_, err = schedulerInstance.Create(scheduler.Schedule{StartAt: start, Interval: time.Hour, Amount: decimal.NewFromInt(1)})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Empty(t, schedulerInstance.List())
+	assert.Empty(t, store.schedules)
+}
+
+func TestScheduler_Delete__ReturnsNotFoundForUnknownSchedule(t *testing.T) {
+	// Arrange
+	ledgerInstance, err := ledger.NewLedger()
+	require.NoError(t, err)
+	schedulerInstance := scheduler.NewScheduler(ledgerInstance)
+
+	// Act
+	err = schedulerInstance.Delete(uuid.New())
+
+	// Assert
+	assert.ErrorIs(t, err, scheduler.ErrScheduleNotFound)
+}
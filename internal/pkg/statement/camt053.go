@@ -0,0 +1,221 @@
+package statement
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	CAMT053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+	camtDateTimeFormat = "2006-01-02T15:04:05Z"
+	camtDateFormat     = "2006-01-02"
+
+	camtCredit = "CRDT"
+	camtDebit  = "DBIT"
+
+	camtOpeningBookedBalance = "OPBD"
+	camtClosingBookedBalance = "CLBD"
+	camtBookedEntryStatus    = "BOOK"
+	camtProprietaryTxCode    = "LEDGER"
+)
+
+// camtDocument models the subset of the camt.053.001.02 schema produced by the ledger.
+// Field order follows the schema sequences, which encoding/xml preserves.
+type camtDocument struct {
+	XMLName       xml.Name           `xml:"Document"`
+	Namespace     string             `xml:"xmlns,attr"`
+	BkToCstmrStmt camtBankToCustomer `xml:"BkToCstmrStmt"`
+}
+
+type camtBankToCustomer struct {
+	GrpHdr camtGroupHeader `xml:"GrpHdr"`
+	Stmt   camtStatement   `xml:"Stmt"`
+}
+
+type camtGroupHeader struct {
+	MsgId   string `xml:"MsgId"`
+	CreDtTm string `xml:"CreDtTm"`
+}
+
+type camtStatement struct {
+	Id        string                 `xml:"Id"`
+	CreDtTm   string                 `xml:"CreDtTm"`
+	FrToDt    camtFromToDate         `xml:"FrToDt"`
+	Acct      camtAccount            `xml:"Acct"`
+	Bal       []camtBalance          `xml:"Bal"`
+	TxsSummry camtTransactionSummary `xml:"TxsSummry"`
+	Ntry      []camtEntry            `xml:"Ntry"`
+}
+
+type camtFromToDate struct {
+	FrDtTm string `xml:"FrDtTm"`
+	ToDtTm string `xml:"ToDtTm"`
+}
+
+type camtAccount struct {
+	Id  camtAccountID `xml:"Id"`
+	Ccy string        `xml:"Ccy"`
+}
+
+type camtAccountID struct {
+	Othr camtGenericID `xml:"Othr"`
+}
+
+type camtGenericID struct {
+	Id string `xml:"Id"`
+}
+
+type camtAmount struct {
+	Ccy   string `xml:"Ccy,attr"`
+	Value string `xml:",chardata"`
+}
+
+type camtBalance struct {
+	Tp        camtBalanceType `xml:"Tp"`
+	Amt       camtAmount      `xml:"Amt"`
+	CdtDbtInd string          `xml:"CdtDbtInd"`
+	Dt        camtDateChoice  `xml:"Dt"`
+}
+
+type camtBalanceType struct {
+	CdOrPrtry camtCode `xml:"CdOrPrtry"`
+}
+
+type camtCode struct {
+	Cd string `xml:"Cd"`
+}
+
+type camtDateChoice struct {
+	Dt   string `xml:"Dt,omitempty"`
+	DtTm string `xml:"DtTm,omitempty"`
+}
+
+type camtTransactionSummary struct {
+	TtlNtries    camtTotalEntries `xml:"TtlNtries"`
+	TtlCdtNtries camtNumberAndSum `xml:"TtlCdtNtries"`
+	TtlDbtNtries camtNumberAndSum `xml:"TtlDbtNtries"`
+}
+
+type camtTotalEntries struct {
+	NbOfNtries    string `xml:"NbOfNtries"`
+	Sum           string `xml:"Sum"`
+	TtlNetNtryAmt string `xml:"TtlNetNtryAmt"`
+	CdtDbtInd     string `xml:"CdtDbtInd"`
+}
+
+type camtNumberAndSum struct {
+	NbOfNtries string `xml:"NbOfNtries"`
+	Sum        string `xml:"Sum"`
+}
+
+type camtEntry struct {
+	NtryRef     string                  `xml:"NtryRef"`
+	Amt         camtAmount              `xml:"Amt"`
+	CdtDbtInd   string                  `xml:"CdtDbtInd"`
+	Sts         string                  `xml:"Sts"`
+	BookgDt     camtDateChoice          `xml:"BookgDt"`
+	ValDt       camtDateChoice          `xml:"ValDt"`
+	AcctSvcrRef string                  `xml:"AcctSvcrRef"`
+	BkTxCd      camtBankTransactionCode `xml:"BkTxCd"`
+}
+
+type camtBankTransactionCode struct {
+	Prtry camtCode `xml:"Prtry"`
+}
+
+// EncodeCAMT053 writes the statement as an ISO 20022 camt.053.001.02 bank to customer statement
+func EncodeCAMT053(w io.Writer, s *Statement) error {
+	doc := camtDocument{
+		Namespace: CAMT053Namespace,
+		BkToCstmrStmt: camtBankToCustomer{
+			GrpHdr: camtGroupHeader{
+				MsgId:   s.ID(),
+				CreDtTm: s.CreatedAt.Format(camtDateTimeFormat),
+			},
+			Stmt: camtStatement{
+				Id:      s.ID(),
+				CreDtTm: s.CreatedAt.Format(camtDateTimeFormat),
+				FrToDt: camtFromToDate{
+					FrDtTm: s.From.Format(camtDateTimeFormat),
+					ToDtTm: s.To.Format(camtDateTimeFormat),
+				},
+				Acct: camtAccount{
+					Id:  camtAccountID{Othr: camtGenericID{Id: s.Account.ID}},
+					Ccy: s.Account.Currency,
+				},
+				Bal: []camtBalance{
+					s.camtBalance(camtOpeningBookedBalance, s.OpeningBalance, s.From.Format(camtDateFormat)),
+					s.camtBalance(camtClosingBookedBalance, s.ClosingBalance, s.LastDay().Format(camtDateFormat)),
+				},
+				TxsSummry: s.camtSummary(),
+				Ntry:      make([]camtEntry, len(s.Transactions)),
+			},
+		},
+	}
+	for i, transaction := range s.Transactions {
+		bookingDate := camtDateChoice{DtTm: transaction.CreatedAt.Format(camtDateTimeFormat)}
+		doc.BkToCstmrStmt.Stmt.Ntry[i] = camtEntry{
+			NtryRef:     strconv.FormatUint(transaction.ID, 10),
+			Amt:         camtAmount{Ccy: s.Account.Currency, Value: formatAmount(transaction.Amount)},
+			CdtDbtInd:   camtIndicator(transaction.Amount),
+			Sts:         camtBookedEntryStatus,
+			BookgDt:     bookingDate,
+			ValDt:       bookingDate,
+			AcctSvcrRef: camtReference(transaction.ExternalID),
+			BkTxCd:      camtBankTransactionCode{Prtry: camtCode{Cd: camtProprietaryTxCode}},
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return errors.Wrap(err, "could not write camt.053 header")
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return errors.Wrap(err, "could not encode camt.053 document")
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (s *Statement) camtBalance(code string, amount decimal.Decimal, date string) camtBalance {
+	return camtBalance{
+		Tp:        camtBalanceType{CdOrPrtry: camtCode{Cd: code}},
+		Amt:       camtAmount{Ccy: s.Account.Currency, Value: formatAmount(amount)},
+		CdtDbtInd: camtIndicator(amount),
+		Dt:        camtDateChoice{Dt: date},
+	}
+}
+
+func (s *Statement) camtSummary() camtTransactionSummary {
+	net := s.Totals.Net()
+	return camtTransactionSummary{
+		TtlNtries: camtTotalEntries{
+			NbOfNtries:    strconv.Itoa(len(s.Transactions)),
+			Sum:           formatAmount(s.Totals.Credits.Add(s.Totals.Debits)),
+			TtlNetNtryAmt: formatAmount(net),
+			CdtDbtInd:     camtIndicator(net),
+		},
+		TtlCdtNtries: camtNumberAndSum{NbOfNtries: strconv.Itoa(s.Totals.CreditsCount), Sum: formatAmount(s.Totals.Credits)},
+		TtlDbtNtries: camtNumberAndSum{NbOfNtries: strconv.Itoa(s.Totals.DebitsCount), Sum: formatAmount(s.Totals.Debits)},
+	}
+}
+
+// camtReference strips the dashes of the UUID so it fits the 35 characters limit of Max35Text
+func camtReference(id uuid.UUID) string {
+	return strings.ReplaceAll(id.String(), "-", "")
+}
+
+func camtIndicator(amount decimal.Decimal) string {
+	if amount.IsNegative() {
+		return camtDebit
+	}
+	return camtCredit
+}
@@ -0,0 +1,155 @@
+package statement
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ofxHeader = `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+
+	ofxDateTimeFormat = "20060102150405.000"
+	ofxUTCSuffix      = "[0:GMT]"
+
+	ofxSuccessCode     = "0"
+	ofxInfoSeverity    = "INFO"
+	ofxLanguage        = "ENG"
+	ofxCheckingAccount = "CHECKING"
+	ofxCredit          = "CREDIT"
+	ofxDebit           = "DEBIT"
+)
+
+// ofxDocument models the subset of the OFX 2.2 bank statement response produced by the ledger.
+// OFX has no notion of an opening balance, so only the closing balance is reported as LEDGERBAL.
+// Amounts are signed, OFX uses the sign rather than an indicator to tell credits from debits.
+type ofxDocument struct {
+	XMLName        xml.Name          `xml:"OFX"`
+	SignOnMsgsRsV1 ofxSignOnMessages `xml:"SIGNONMSGSRSV1"`
+	BankMsgsRsV1   ofxBankMessages   `xml:"BANKMSGSRSV1"`
+}
+
+type ofxSignOnMessages struct {
+	SonRs ofxSignOnResponse `xml:"SONRS"`
+}
+
+type ofxSignOnResponse struct {
+	Status   ofxStatus `xml:"STATUS"`
+	DtServer string    `xml:"DTSERVER"`
+	Language string    `xml:"LANGUAGE"`
+}
+
+type ofxStatus struct {
+	Code     string `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxBankMessages struct {
+	StmtTrnRs ofxStatementTransaction `xml:"STMTTRNRS"`
+}
+
+type ofxStatementTransaction struct {
+	TrnUID string               `xml:"TRNUID"`
+	Status ofxStatus            `xml:"STATUS"`
+	StmtRs ofxStatementResponse `xml:"STMTRS"`
+}
+
+type ofxStatementResponse struct {
+	CurDef       string             `xml:"CURDEF"`
+	BankAcctFrom ofxBankAccount     `xml:"BANKACCTFROM"`
+	BankTranList ofxTransactionList `xml:"BANKTRANLIST"`
+	LedgerBal    ofxBalance         `xml:"LEDGERBAL"`
+}
+
+type ofxBankAccount struct {
+	BankID   string `xml:"BANKID"`
+	AcctID   string `xml:"ACCTID"`
+	AcctType string `xml:"ACCTTYPE"`
+}
+
+type ofxTransactionList struct {
+	DtStart string           `xml:"DTSTART"`
+	DtEnd   string           `xml:"DTEND"`
+	StmtTrn []ofxTransaction `xml:"STMTTRN"`
+}
+
+type ofxTransaction struct {
+	TrnType  string `xml:"TRNTYPE"`
+	DtPosted string `xml:"DTPOSTED"`
+	TrnAmt   string `xml:"TRNAMT"`
+	FitID    string `xml:"FITID"`
+	Name     string `xml:"NAME"`
+}
+
+type ofxBalance struct {
+	BalAmt string `xml:"BALAMT"`
+	DtAsOf string `xml:"DTASOF"`
+}
+
+// EncodeOFX writes the statement as an OFX 2.2 bank statement response
+func EncodeOFX(w io.Writer, s *Statement) error {
+	success := ofxStatus{Code: ofxSuccessCode, Severity: ofxInfoSeverity}
+	doc := ofxDocument{
+		SignOnMsgsRsV1: ofxSignOnMessages{
+			SonRs: ofxSignOnResponse{
+				Status:   success,
+				DtServer: ofxDateTime(s.CreatedAt),
+				Language: ofxLanguage,
+			},
+		},
+		BankMsgsRsV1: ofxBankMessages{
+			StmtTrnRs: ofxStatementTransaction{
+				TrnUID: s.ID(),
+				Status: success,
+				StmtRs: ofxStatementResponse{
+					CurDef: s.Account.Currency,
+					BankAcctFrom: ofxBankAccount{
+						BankID:   s.Account.BankID,
+						AcctID:   s.Account.ID,
+						AcctType: ofxCheckingAccount,
+					},
+					BankTranList: ofxTransactionList{
+						DtStart: ofxDateTime(s.From),
+						DtEnd:   ofxDateTime(s.To),
+						StmtTrn: make([]ofxTransaction, len(s.Transactions)),
+					},
+					LedgerBal: ofxBalance{
+						BalAmt: formatSignedAmount(s.ClosingBalance),
+						DtAsOf: ofxDateTime(s.To),
+					},
+				},
+			},
+		},
+	}
+	for i, transaction := range s.Transactions {
+		trnType := ofxCredit
+		if transaction.Amount.IsNegative() {
+			trnType = ofxDebit
+		}
+		doc.BankMsgsRsV1.StmtTrnRs.StmtRs.BankTranList.StmtTrn[i] = ofxTransaction{
+			TrnType:  trnType,
+			DtPosted: ofxDateTime(transaction.CreatedAt),
+			TrnAmt:   formatSignedAmount(transaction.Amount),
+			FitID:    transaction.ExternalID.String(),
+			Name:     "Transaction " + strconv.FormatUint(transaction.ID, 10),
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header+ofxHeader); err != nil {
+		return errors.Wrap(err, "could not write ofx header")
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return errors.Wrap(err, "could not encode ofx document")
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func ofxDateTime(t time.Time) string {
+	return t.UTC().Format(ofxDateTimeFormat) + ofxUTCSuffix
+}
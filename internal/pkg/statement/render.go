@@ -0,0 +1,78 @@
+package statement
+
+import (
+	htmltemplate "html/template"
+	"io"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	renderDateFormat     = "2006-01-02"
+	renderDateTimeFormat = "2006-01-02 15:04"
+)
+
+const textTemplate = `Account statement {{ .Account.ID }}
+Period: {{ date .From }} - {{ date .LastDay }} ({{ .Account.Currency }})
+
+Opening balance: {{ amount .OpeningBalance }}
+
+{{ printf "%-16s  %-6s  %-36s  %14s" "Date" "ID" "Reference" "Amount" }}
+{{- range .Transactions }}
+{{ printf "%-16s  %-6d  %-36s  %14s" (datetime .CreatedAt) .ID .ExternalID (amount .Amount) }}
+{{- else }}
+No transactions in this period
+{{- end }}
+
+Total credits ({{ .Totals.CreditsCount }}): {{ amount .Totals.Credits }}
+Total debits ({{ .Totals.DebitsCount }}): {{ amount .Totals.Debits }}
+Closing balance: {{ amount .ClosingBalance }}
+`
+
+const htmlTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Account statement {{ .Account.ID }}</title>
+</head>
+<body style="font-family: Arial, sans-serif; font-size: 14px;">
+<h2>Account statement {{ .Account.ID }}</h2>
+<p>Period: {{ date .From }} - {{ date .LastDay }} ({{ .Account.Currency }})</p>
+<p>Opening balance: <strong>{{ amount .OpeningBalance }}</strong></p>
+<table style="border-collapse: collapse;" cellpadding="4">
+<tr><th align="left">Date</th><th align="left">ID</th><th align="left">Reference</th><th align="right">Amount</th></tr>
+{{- range .Transactions }}
+<tr><td>{{ datetime .CreatedAt }}</td><td>{{ .ID }}</td><td>{{ .ExternalID }}</td><td align="right">{{ amount .Amount }}</td></tr>
+{{- else }}
+<tr><td colspan="4">No transactions in this period</td></tr>
+{{- end }}
+</table>
+<p>Total credits ({{ .Totals.CreditsCount }}): {{ amount .Totals.Credits }}<br>
+Total debits ({{ .Totals.DebitsCount }}): {{ amount .Totals.Debits }}</p>
+<p>Closing balance: <strong>{{ amount .ClosingBalance }}</strong></p>
+</body>
+</html>
+`
+
+var renderFuncs = map[string]any{
+	"amount":   formatSignedAmount,
+	"date":     func(t time.Time) string { return t.Format(renderDateFormat) },
+	"datetime": func(t time.Time) string { return t.Format(renderDateTimeFormat) },
+}
+
+var (
+	textStatement = texttemplate.Must(texttemplate.New("statement").Funcs(renderFuncs).Parse(textTemplate))
+	htmlStatement = htmltemplate.Must(htmltemplate.New("statement").Funcs(renderFuncs).Parse(htmlTemplate))
+)
+
+// RenderText writes a plain text version of the statement, suitable for an email body
+func RenderText(w io.Writer, s *Statement) error {
+	return errors.Wrap(textStatement.Execute(w, s), "could not render text statement")
+}
+
+// RenderHTML writes an HTML version of the statement, styled inline so it can be emailed as is
+func RenderHTML(w io.Writer, s *Statement) error {
+	return errors.Wrap(htmlStatement.Execute(w, s), "could not render html statement")
+}
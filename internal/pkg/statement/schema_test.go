@@ -0,0 +1,48 @@
+package statement_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The XSDs are vendored under testdata/xsd: the ISO 20022 camt.053.001.02 message schema and the OFX 2.2 schemas,
+// whose entry point is OFX2_Protocol.xsd. The documents are validated against them with xmllint, the same as by
+// `make validate-statements`.
+
+const (
+	camt053XSD = "camt.053.001.02.xsd"
+	ofxXSD     = "OFX2_Protocol.xsd"
+	// ofxNamespace is the target namespace of the OFX 2.2 schemas, only the root element is qualified
+	ofxNamespace = "http://ofx.net/types/2003/04"
+)
+
+// validateXSD validates the document against the vendored schema. Only the validation is skipped where xmllint is
+// not installed, the rest of the test still runs.
+func validateXSD(t *testing.T, document []byte, schema string) {
+	t.Helper()
+	schemaPath := filepath.Join("testdata", "xsd", schema)
+	require.FileExists(t, schemaPath)
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Logf("xmllint is not installed, %v is not validated against %v", t.Name(), schema)
+		return
+	}
+	documentPath := filepath.Join(t.TempDir(), "document.xml")
+	require.NoError(t, os.WriteFile(documentPath, document, 0o600))
+
+	output, err := exec.Command(xmllint, "--noout", "--nonet", "--schema", schemaPath, documentPath).CombinedOutput()
+
+	require.NoError(t, err, "%s", output)
+}
+
+// qualifyOFX puts the root element of the OFX document in the namespace of the OFX 2.2 schemas. The OFX files are
+// exchanged with an unqualified root, as the specification shows them.
+func qualifyOFX(document []byte) []byte {
+	document = bytes.Replace(document, []byte("<OFX>"), []byte(`<ofx:OFX xmlns:ofx="`+ofxNamespace+`">`), 1)
+	return bytes.Replace(document, []byte("</OFX>"), []byte("</ofx:OFX>"), 1)
+}
@@ -0,0 +1,102 @@
+package statement
+
+import (
+	"teya_home_assignment/internal/pkg/ledger"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+)
+
+// Account identifies the ledger account a statement is issued for
+type Account struct {
+	ID       string
+	BankID   string
+	Currency string
+}
+
+// Statement is a snapshot of the ledger activity within [From, To)
+type Statement struct {
+	Account        Account
+	From           time.Time
+	To             time.Time
+	CreatedAt      time.Time
+	OpeningBalance decimal.Decimal
+	ClosingBalance decimal.Decimal
+	Totals         Totals
+	Transactions   []ledger.Transaction
+}
+
+// Totals summarizes the transactions of a statement. Debits are summed as a positive amount.
+type Totals struct {
+	CreditsCount int
+	Credits      decimal.Decimal
+	DebitsCount  int
+	Debits       decimal.Decimal
+}
+
+// Net returns the balance change over the statement period
+func (t Totals) Net() decimal.Decimal {
+	return t.Credits.Sub(t.Debits)
+}
+
+func New(ledgerService *ledger.Ledger, account Account, from, to time.Time) (*Statement, error) {
+	if !from.Before(to) {
+		return nil, errors.Errorf("invalid statement period: from(%v) must be before to(%v)", from, to)
+	}
+	openingBalance, err := ledgerService.GetBalanceAt(from)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not calculate opening balance")
+	}
+	transactions, err := ledgerService.GetTransactionsBetween(from, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get statement transactions")
+	}
+	totals := Totals{Credits: decimal.Zero, Debits: decimal.Zero}
+	for _, transaction := range transactions {
+		if transaction.Amount.IsNegative() {
+			totals.DebitsCount++
+			totals.Debits = totals.Debits.Add(transaction.Amount.Abs())
+		} else {
+			totals.CreditsCount++
+			totals.Credits = totals.Credits.Add(transaction.Amount)
+		}
+	}
+	return &Statement{
+		Account:        account,
+		From:           from.UTC(),
+		To:             to.UTC(),
+		CreatedAt:      time.Now().UTC(),
+		OpeningBalance: openingBalance,
+		ClosingBalance: openingBalance.Add(totals.Net()),
+		Totals:         totals,
+		Transactions:   transactions,
+	}, nil
+}
+
+// ID returns a stable identifier of the statement derived from its account and the first and last days of its period
+func (s *Statement) ID() string {
+	return s.Account.ID + "-" + s.From.Format("20060102") + "-" + s.LastDay().Format("20060102")
+}
+
+// LastDay returns the last instant covered by the statement, as its period end is exclusive
+func (s *Statement) LastDay() time.Time {
+	return s.To.Add(-time.Nanosecond)
+}
+
+// formatAmount renders the absolute value of an amount with at least 2 decimal places without losing precision
+func formatAmount(amount decimal.Decimal) string {
+	places := int32(2)
+	if -amount.Exponent() > places {
+		places = -amount.Exponent()
+	}
+	return amount.Abs().StringFixed(places)
+}
+
+// formatSignedAmount renders an amount like formatAmount, keeping the minus sign of debits
+func formatSignedAmount(amount decimal.Decimal) string {
+	if amount.IsNegative() {
+		return "-" + formatAmount(amount)
+	}
+	return formatAmount(amount)
+}
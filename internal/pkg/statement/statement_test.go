@@ -0,0 +1,186 @@
+package statement_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"teya_home_assignment/internal/pkg/ledger"
+	"teya_home_assignment/internal/pkg/statement"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+var (
+	testAccount = statement.Account{ID: "LEDGER-0001", BankID: "TEYA", Currency: "EUR"}
+	periodStart = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	periodEnd   = time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// newTestLedger creates a ledger with a transaction before, during and after January 2025
+func newTestLedger(t *testing.T) *ledger.Ledger {
+	t.Helper()
+	timestamps := []time.Time{
+		time.Date(2024, time.December, 31, 12, 0, 0, 0, time.UTC),
+		time.Date(2025, time.January, 5, 9, 30, 0, 0, time.UTC),
+		time.Date(2025, time.January, 17, 18, 45, 0, 0, time.UTC),
+		time.Date(2025, time.January, 31, 23, 59, 59, 0, time.UTC),
+		time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC),
+	}
+	next := 0
+	ledgerInstance, err := ledger.NewLedger(ledger.WithClock(func() time.Time {
+		now := timestamps[next]
+		next++
+		return now
+	}))
+	require.NoError(t, err)
+	for _, amount := range []string{"100.00", "25.50", "-40.25", "0.125", "999.99"} {
+		require.NoError(t, ledgerInstance.AddTransaction(decimal.RequireFromString(amount)))
+	}
+	// external IDs are random, pin them so the documents are reproducible
+	for i := range ledgerInstance.TransactionHistory {
+		ledgerInstance.TransactionHistory[i].ExternalID = uuid.MustParse(
+			fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1))
+	}
+	return ledgerInstance
+}
+
+func newTestStatement(t *testing.T) *statement.Statement {
+	t.Helper()
+	s, err := statement.New(newTestLedger(t), testAccount, periodStart, periodEnd)
+	require.NoError(t, err)
+	s.CreatedAt = periodEnd
+	return s
+}
+
+func assertGolden(t *testing.T, name string, actual []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		require.NoError(t, os.WriteFile(path, actual, 0o644))
+	}
+	expected, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(actual))
+}
+
+func TestStatement_New__ComputesBalancesForPeriod(t *testing.T) {
+	// Arrange
+	ledgerInstance := newTestLedger(t)
+
+	// Act
+	s, err := statement.New(ledgerInstance, testAccount, periodStart, periodEnd)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, decimal.RequireFromString("100").Equal(s.OpeningBalance), s.OpeningBalance.String())
+	assert.True(t, decimal.RequireFromString("85.375").Equal(s.ClosingBalance), s.ClosingBalance.String())
+	require.Len(t, s.Transactions, 3)
+	assert.Equal(t, uint64(2), s.Transactions[0].ID)
+	assert.Equal(t, uint64(4), s.Transactions[2].ID)
+}
+
+func TestStatement_New__RejectsInvalidPeriod(t *testing.T) {
+	// Arrange
+	ledgerInstance := newTestLedger(t)
+
+	// Act
+	_, err := statement.New(ledgerInstance, testAccount, periodEnd, periodStart)
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestStatement_EncodeCAMT053__MatchesGoldenFile(t *testing.T) {
+	// Arrange
+	s := newTestStatement(t)
+	var buf bytes.Buffer
+
+	// Act
+	err := statement.EncodeCAMT053(&buf, s)
+
+	// Assert
+	require.NoError(t, err)
+	assertGolden(t, "statement.camt053.xml", buf.Bytes())
+	validateXSD(t, buf.Bytes(), camt053XSD)
+}
+
+func TestStatement_EncodeOFX__MatchesGoldenFile(t *testing.T) {
+	// Arrange
+	s := newTestStatement(t)
+	var buf bytes.Buffer
+
+	// Act
+	err := statement.EncodeOFX(&buf, s)
+
+	// Assert
+	require.NoError(t, err)
+	assertGolden(t, "statement.ofx", buf.Bytes())
+	validateXSD(t, qualifyOFX(buf.Bytes()), ofxXSD)
+}
+
+func TestStatement_EncodeCAMT053__ValidForEmptyPeriod(t *testing.T) {
+	// Arrange
+	s, err := statement.New(newTestLedger(t), testAccount,
+		periodStart.AddDate(-1, 0, 0), periodStart.AddDate(-1, 1, 0))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+
+	// Act
+	err = statement.EncodeCAMT053(&buf, s)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Empty(t, s.Transactions)
+	validateXSD(t, buf.Bytes(), camt053XSD)
+}
+
+func TestStatement_New__ComputesTotals(t *testing.T) {
+	// Arrange
+	ledgerInstance := newTestLedger(t)
+
+	// Act
+	s, err := statement.New(ledgerInstance, testAccount, periodStart, periodEnd)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.Totals.CreditsCount)
+	assert.True(t, decimal.RequireFromString("25.625").Equal(s.Totals.Credits), s.Totals.Credits.String())
+	assert.Equal(t, 1, s.Totals.DebitsCount)
+	assert.True(t, decimal.RequireFromString("40.25").Equal(s.Totals.Debits), s.Totals.Debits.String())
+	assert.True(t, s.OpeningBalance.Add(s.Totals.Net()).Equal(s.ClosingBalance))
+}
+
+func TestStatement_RenderText__MatchesGoldenFile(t *testing.T) {
+	// Arrange
+	s := newTestStatement(t)
+	var buf bytes.Buffer
+
+	// Act
+	err := statement.RenderText(&buf, s)
+
+	// Assert
+	require.NoError(t, err)
+	assertGolden(t, "statement.txt", buf.Bytes())
+}
+
+func TestStatement_RenderHTML__MatchesGoldenFile(t *testing.T) {
+	// Arrange
+	s := newTestStatement(t)
+	var buf bytes.Buffer
+
+	// Act
+	err := statement.RenderHTML(&buf, s)
+
+	// Assert
+	require.NoError(t, err)
+	assertGolden(t, "statement.html", buf.Bytes())
+}
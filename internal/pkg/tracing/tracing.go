@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Exporter is where the spans are sent to
+type Exporter string
+
+const (
+	// ExporterNone disables tracing, the trace context is still propagated
+	ExporterNone Exporter = "none"
+	// ExporterStdout writes the spans to stdout as JSON
+	ExporterStdout Exporter = "stdout"
+	// ExporterFile writes the spans to a file as JSON, so traces can be inspected offline
+	ExporterFile Exporter = "file"
+	// ExporterOTLP sends the spans to an OTLP/HTTP collector, configured with the standard
+	// OTEL_EXPORTER_OTLP_* environment variables
+	ExporterOTLP Exporter = "otlp"
+)
+
+type Config struct {
+	Exporter    Exporter
+	ServiceName string
+	// File is the path the spans are appended to with ExporterFile
+	File string
+	// OTLPEndpoint and OTLPHeaders override the standard OTEL_EXPORTER_OTLP_* environment variables when set
+	OTLPEndpoint string
+	OTLPHeaders  map[string]string
+}
+
+// ParseExporter parses the name of an exporter
+func ParseExporter(name string) (Exporter, error) {
+	exporter := Exporter(strings.ToLower(name))
+	switch exporter {
+	case ExporterNone, ExporterStdout, ExporterFile, ExporterOTLP:
+		return exporter, nil
+	}
+	return "", errors.Errorf("unknown trace exporter %q", name)
+}
+
+// Setup installs the global tracer provider and the W3C trace context propagator. The returned function flushes
+// the pending spans and releases the exporter, it must be called before exiting.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if config.Exporter == ExporterNone {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closeOutput, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create %v trace exporter", config.Exporter)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(config.ServiceName),
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create trace resource")
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		if err := provider.Shutdown(ctx); err != nil {
+			return errors.Wrap(err, "failed to flush spans")
+		}
+		return closeOutput()
+	}, nil
+}
+
+// newExporter returns the exporter along with a function closing its output
+func newExporter(ctx context.Context, config Config) (sdktrace.SpanExporter, func() error, error) {
+	noop := func() error { return nil }
+	switch config.Exporter {
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		return exporter, noop, err
+	case ExporterFile:
+		file, err := os.OpenFile(config.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to open trace file")
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		return exporter, file.Close, err
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if config.OTLPEndpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(config.OTLPEndpoint))
+		}
+		if len(config.OTLPHeaders) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(config.OTLPHeaders))
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		return exporter, noop, err
+	}
+	return nil, nil, errors.Errorf("unknown trace exporter %q", config.Exporter)
+}
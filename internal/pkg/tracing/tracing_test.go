@@ -0,0 +1,61 @@
+package tracing_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"teya_home_assignment/internal/pkg/tracing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func TestSetup__ExportsSpansToFile(t *testing.T) {
+	// Arrange
+	file := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    tracing.ExporterFile,
+		ServiceName: "test",
+		File:        file,
+	})
+	require.NoError(t, err)
+
+	// Act
+	_, span := otel.Tracer("test").Start(context.Background(), "operation")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	// Assert
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"Name":"operation"`)
+	assert.Contains(t, string(content), span.SpanContext().TraceID().String())
+}
+
+func TestSetup__PropagatesW3CTraceContext(t *testing.T) {
+	// Arrange
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{Exporter: tracing.ExporterNone})
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+	headers := propagation.MapCarrier{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+
+	// Act
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), headers)
+	injected := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, injected)
+
+	// Assert
+	assert.Equal(t, headers["traceparent"], injected["traceparent"])
+}
+
+func TestParseExporter__RejectsUnknownExporter(t *testing.T) {
+	exporter, err := tracing.ParseExporter("OTLP")
+	_, errUnknown := tracing.ParseExporter("jaeger")
+
+	require.NoError(t, err)
+	assert.Equal(t, tracing.ExporterOTLP, exporter)
+	assert.Error(t, errUnknown)
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	livenessPath  = "/health/live"
+	readinessPath = "/health/ready"
+	limitsPath    = "/admin/limits"
+)
+
+// Liveness tells whether the webserver process is live
+func (c *Client) Liveness(ctx context.Context, opts ...CallOption) (*HealthRespBody, error) {
+	resp := &HealthRespBody{}
+	if err := c.get(ctx, livenessPath, nil, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Readiness returns the status of the components the webserver depends on. It is not an error for the webserver
+// not to be ready: the components are returned either way, with the status of the webserver set to down.
+func (c *Client) Readiness(ctx context.Context, opts ...CallOption) (*HealthRespBody, error) {
+	req := newRequest(http.MethodGet, readinessPath)
+	// the probe answers right away, there is no point in waiting for the webserver to get ready
+	req.idempotent = false
+	resp, err := c.send(ctx, req, opts)
+	if err != nil {
+		return nil, err
+	}
+	if resp.status != http.StatusOK && resp.status != http.StatusServiceUnavailable {
+		return nil, newError(req, resp)
+	}
+	health := &HealthRespBody{}
+	if err := json.Unmarshal(resp.body, health); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode response of %v %v", req.method, req.path)
+	}
+	return health, nil
+}
+
+// GetLimits returns the limits applying to the transactions created
+func (c *Client) GetLimits(ctx context.Context, opts ...CallOption) (*LimitsBody, error) {
+	resp := &LimitsBody{}
+	if err := c.get(ctx, limitsPath, nil, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SetLimits replaces the limits applying to the transactions created
+func (c *Client) SetLimits(ctx context.Context, body LimitsBody, opts ...CallOption) (*LimitsBody, error) {
+	resp := &LimitsBody{}
+	if err := c.sendJSON(ctx, http.MethodPut, limitsPath, body, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
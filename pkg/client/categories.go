@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const categoryRulePath = "/category/rule"
+
+// CreateCategoryRule registers a rule assigning a category to the matching transactions
+func (c *Client) CreateCategoryRule(
+	ctx context.Context,
+	body CategoryRuleReqBody,
+	opts ...CallOption,
+) (*CategoryRule, error) {
+	resp := &CategoryRule{}
+	if err := c.sendJSON(ctx, http.MethodPost, categoryRulePath, body, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListCategoryRules returns the registered category rules
+func (c *Client) ListCategoryRules(ctx context.Context, opts ...CallOption) ([]CategoryRule, error) {
+	resp := &CategoryRulesRespBody{}
+	if err := c.get(ctx, categoryRulePath, nil, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp.Rules, nil
+}
+
+// GetCategoryRule returns the category rule with the given ID
+func (c *Client) GetCategoryRule(ctx context.Context, id uuid.UUID, opts ...CallOption) (*CategoryRule, error) {
+	resp := &CategoryRule{}
+	if err := c.get(ctx, resourcePath(categoryRulePath, id), nil, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateCategoryRule replaces the category rule with the given ID
+func (c *Client) UpdateCategoryRule(
+	ctx context.Context,
+	id uuid.UUID,
+	body CategoryRuleReqBody,
+	opts ...CallOption,
+) (*CategoryRule, error) {
+	resp := &CategoryRule{}
+	if err := c.sendJSON(ctx, http.MethodPut, resourcePath(categoryRulePath, id), body, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteCategoryRule removes the category rule with the given ID
+func (c *Client) DeleteCategoryRule(ctx context.Context, id uuid.UUID, opts ...CallOption) error {
+	return c.delete(ctx, resourcePath(categoryRulePath, id), opts)
+}
@@ -0,0 +1,279 @@
+// Package client is the Go client of the ledger API. Every endpoint has a typed method taking and returning the
+// request and response bodies of the API. The creation of transactions is made safe to retry with an idempotency
+// key generated for every call, the idempotent requests are retried with backoff on network errors and 5xx
+// responses, and the errors answered by the API are returned as *Error.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// APIBasePath is the path of the versioned API, relative to the base URL of the webserver
+const APIBasePath = "/api/v1"
+
+// Doer sends HTTP requests, like *http.Client
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DoerFunc adapts a function to a Doer, e.g. the Test method of a fiber app
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// RetryPolicy tells how the idempotent requests are retried on network errors and 5xx responses. The backoff
+// doubles after every attempt, up to MaxBackoff, and is jittered so clients do not retry in lockstep.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one, 1 disables the retries
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy tries the requests 3 times over about a second
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+}
+
+// Client calls the ledger API. It is safe for concurrent use.
+type Client struct {
+	baseURL           *url.URL
+	doer              Doer
+	retryPolicy       RetryPolicy
+	newIdempotencyKey func() string
+}
+
+type Option func(c *Client)
+
+// WithDoer sends the requests with the given Doer rather than http.DefaultClient
+func WithDoer(doer Doer) Option {
+	return func(c *Client) {
+		c.doer = doer
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// WithIdempotencyKeys generates the idempotency keys of the transactions created without one, random UUIDs by
+// default
+func WithIdempotencyKeys(generate func() string) Option {
+	return func(c *Client) {
+		c.newIdempotencyKey = generate
+	}
+}
+
+// New creates a client of the webserver at the given base URL, e.g. http://localhost:8000
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid base URL")
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return nil, errors.Errorf("invalid base URL %q: scheme and host are required", baseURL)
+	}
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/") + APIBasePath
+	c := &Client{
+		baseURL:           parsed,
+		doer:              http.DefaultClient,
+		retryPolicy:       DefaultRetryPolicy,
+		newIdempotencyKey: uuid.NewString,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retryPolicy.MaxAttempts < 1 {
+		return nil, errors.New("invalid retry policy: at least one attempt is required")
+	}
+	return c, nil
+}
+
+// CallOption customizes a single call
+type CallOption func(req *request)
+
+// WithRequestID sends the X-Request-ID of the call, to look it up in the logs of the webserver
+func WithRequestID(requestID string) CallOption {
+	return func(req *request) {
+		req.header.Set("X-Request-ID", requestID)
+	}
+}
+
+// WithIdempotencyKey creates a transaction with the given idempotency key rather than a generated one, so it is
+// created once even across processes, e.g. with a key derived from the order it pays
+func WithIdempotencyKey(key string) CallOption {
+	return func(req *request) {
+		req.header.Set(IdempotencyKeyHeader, key)
+	}
+}
+
+// WithIfMatch creates a transaction only if the ledger is still at the version of the ETag, as answered with the
+// balance by GetBalance or with a created transaction. The call fails with ErrVersionMismatch once the ledger moved
+// on, e.g. because another transaction was created since the balance was checked.
+func WithIfMatch(etag string) CallOption {
+	return func(req *request) {
+		req.header.Set(IfMatchHeader, etag)
+	}
+}
+
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   []byte
+	// idempotent requests are retried, as sending them again has no other effect than sending them once
+	idempotent bool
+}
+
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func newRequest(method, path string) *request {
+	return &request{
+		method:     method,
+		path:       path,
+		query:      url.Values{},
+		header:     http.Header{},
+		idempotent: method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete,
+	}
+}
+
+// withJSON sets the body of the request to the JSON encoding of v
+func (r *request) withJSON(v any) (*request, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode request body")
+	}
+	r.body = body
+	r.header.Set("Content-Type", "application/json")
+	return r, nil
+}
+
+// send sends the request, retrying it if idempotent. The response is returned whatever its status.
+func (c *Client) send(ctx context.Context, req *request, opts []CallOption) (response, error) {
+	for _, opt := range opts {
+		opt(req)
+	}
+	attempts := 1
+	if req.idempotent {
+		attempts = c.retryPolicy.MaxAttempts
+	}
+	backoff := c.retryPolicy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		resp, err := c.sendOnce(ctx, req)
+		retryable := err != nil || resp.status >= http.StatusInternalServerError &&
+			resp.status != http.StatusNotImplemented
+		if !retryable || attempt == attempts || ctx.Err() != nil {
+			if err != nil && attempt > 1 {
+				return response{}, errors.Wrapf(err, "failed after %d attempts", attempt)
+			}
+			return resp, err
+		}
+		// full jitter: the wait is drawn between zero and the backoff
+		timer := time.NewTimer(rand.N(backoff + 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return response{}, errors.Wrap(ctx.Err(), "gave up retrying")
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, c.retryPolicy.MaxBackoff)
+	}
+}
+
+func (c *Client) sendOnce(ctx context.Context, req *request) (response, error) {
+	target := *c.baseURL
+	target.Path += req.path
+	target.RawQuery = req.query.Encode()
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target.String(), body)
+	if err != nil {
+		return response{}, errors.Wrap(err, "failed to create request")
+	}
+	httpReq.Header = req.header.Clone()
+	httpResp, err := c.doer.Do(httpReq)
+	if err != nil {
+		return response{}, errors.Wrapf(err, "%v %v failed", req.method, req.path)
+	}
+	defer httpResp.Body.Close()
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return response{}, errors.Wrapf(err, "failed to read response of %v %v", req.method, req.path)
+	}
+	return response{status: httpResp.StatusCode, header: httpResp.Header, body: respBody}, nil
+}
+
+// call sends the request and decodes the JSON body of a successful response into out, unless nil. Any other
+// response is returned as an *Error.
+func (c *Client) call(ctx context.Context, req *request, out any, opts []CallOption) (response, error) {
+	resp, err := c.send(ctx, req, opts)
+	if err != nil {
+		return response{}, err
+	}
+	if resp.status >= http.StatusBadRequest {
+		return resp, newError(req, resp)
+	}
+	if out != nil {
+		if err := json.Unmarshal(resp.body, out); err != nil {
+			return resp, errors.Wrapf(err, "failed to decode response of %v %v", req.method, req.path)
+		}
+	}
+	return resp, nil
+}
+
+// get sends a GET request with the given query and decodes the response into out
+func (c *Client) get(ctx context.Context, path string, query url.Values, out any, opts []CallOption) error {
+	req := newRequest(http.MethodGet, path)
+	if query != nil {
+		req.query = query
+	}
+	_, err := c.call(ctx, req, out, opts)
+	return err
+}
+
+// sendJSON sends a request with a JSON body and decodes the response into out
+func (c *Client) sendJSON(ctx context.Context, method, path string, in, out any, opts []CallOption) error {
+	req, err := newRequest(method, path).withJSON(in)
+	if err != nil {
+		return err
+	}
+	_, err = c.call(ctx, req, out, opts)
+	return err
+}
+
+// delete sends a DELETE request, answered without content
+func (c *Client) delete(ctx context.Context, path string, opts []CallOption) error {
+	_, err := c.call(ctx, newRequest(http.MethodDelete, path), nil, opts)
+	return err
+}
+
+// resourcePath returns the path of the resource with the given ID under the collection path
+func resourcePath(collection string, id fmt.Stringer, subresource ...string) string {
+	return strings.Join(append([]string{collection, url.PathEscape(id.String())}, subresource...), "/")
+}
@@ -0,0 +1,455 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"teya_home_assignment/internal/app/webserver/config"
+	"teya_home_assignment/internal/app/webserver/server"
+	"teya_home_assignment/pkg/client"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// noWait retries right away, so the tests do not wait for the backoff
+var noWait = client.RetryPolicy{MaxAttempts: 3}
+
+// flakyDoer calls a webserver in process, failing the given number of attempts first
+type flakyDoer struct {
+	next client.Doer
+	// status is answered to the failed attempts once the webserver handled them, as if the response was lost.
+	// The attempts fail with a network error before reaching the webserver when 0.
+	status   int
+	failures atomic.Int32
+	calls    atomic.Int32
+}
+
+func (d *flakyDoer) Do(req *http.Request) (*http.Response, error) {
+	d.calls.Add(1)
+	if d.failures.Add(-1) < 0 {
+		return d.next.Do(req)
+	}
+	if d.status == 0 {
+		return nil, errors.New("connection reset by peer")
+	}
+	if _, err := d.next.Do(req); err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: d.status, Body: http.NoBody, Header: http.Header{}}, nil
+}
+
+// fail fails the next attempts with the given status
+func (d *flakyDoer) fail(attempts int, status int) {
+	d.status = status
+	d.failures.Store(int32(attempts))
+	d.calls.Store(0)
+}
+
+// newClient returns a client calling a started webserver in process, charging 1.5% + 0.20, at least 0.25, on the
+// credits
+func newClient(t *testing.T, opts ...client.Option) (*client.Client, *flakyDoer) {
+	t.Helper()
+	cfg := config.Default()
+	require.NoError(t, cfg.Fees.Schedules.Set(`[{name: Merchant service charge, currency: EUR, applies_to: credits,
+  percentage: 0.015, fixed: 0.20, min: 0.25, rounding: half_up}]`))
+	webserver, err := server.New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, webserver.Start())
+	t.Cleanup(func() {
+		webserver.Shutdown(context.Background())
+	})
+	doer := &flakyDoer{next: client.DoerFunc(func(req *http.Request) (*http.Response, error) {
+		return webserver.App().Test(req, -1)
+	})}
+	opts = append([]client.Option{client.WithDoer(doer), client.WithRetryPolicy(noWait)}, opts...)
+	c, err := client.New("http://ledger", opts...)
+	require.NoError(t, err)
+	return c, doer
+}
+
+func TestNew__InvalidBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"", "ledger:8000", "/api", "://ledger"} {
+		t.Run(baseURL, func(t *testing.T) {
+			// Act
+			_, err := client.New(baseURL)
+
+			// Assert
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestClient_CreateTransaction(t *testing.T) {
+	// Arrange
+	c, _ := newClient(t)
+	ctx := context.Background()
+
+	// Act
+	result, err := c.CreateTransaction(ctx, client.NewTransactionReqBody{Amount: "100", Reference: "INV-1"})
+
+	// Assert
+	require.NoError(t, err)
+	require.NotNil(t, result.Posted)
+	assert.Nil(t, result.Review)
+	assert.False(t, result.Replayed)
+	assert.Equal(t, "100", result.Posted.Transaction.Amount.String())
+	assert.Equal(t, "INV-1", result.Posted.Transaction.Reference)
+	require.Len(t, result.Posted.Fees, 1)
+	balance, err := c.GetBalance(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "98.3", balance.Balance)
+}
+
+func TestClient_CreateTransaction__ReplaysSameIdempotencyKey(t *testing.T) {
+	// Arrange
+	c, _ := newClient(t)
+	ctx := context.Background()
+	body := client.NewTransactionReqBody{Amount: "100", Reference: "INV-1"}
+	first, err := c.CreateTransaction(ctx, body, client.WithIdempotencyKey("order-1"))
+	require.NoError(t, err)
+
+	// Act
+	replayed, err := c.CreateTransaction(ctx, body, client.WithIdempotencyKey("order-1"))
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, replayed.Replayed)
+	assert.Equal(t, first.Posted, replayed.Posted)
+	page, err := c.ListTransactions(ctx, client.TransactionQuery{})
+	require.NoError(t, err)
+	assert.Len(t, page.Transactions, 2, "the transaction and its fee are posted once")
+}
+
+func TestClient_CreateTransaction__ReusedIdempotencyKey(t *testing.T) {
+	// Arrange
+	c, _ := newClient(t)
+	ctx := context.Background()
+	key := client.WithIdempotencyKey("order-1")
+	_, err := c.CreateTransaction(ctx, client.NewTransactionReqBody{Amount: "100"}, key)
+	require.NoError(t, err)
+
+	// Act
+	_, err = c.CreateTransaction(ctx, client.NewTransactionReqBody{Amount: "200"}, key)
+
+	// Assert
+	assert.ErrorIs(t, err, client.ErrIdempotencyKeyReused)
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnprocessableEntity, apiErr.Status)
+}
+
+func TestClient_CreateTransaction__IfMatch(t *testing.T) {
+	// Arrange
+	c, _ := newClient(t)
+	ctx := context.Background()
+	balance, err := c.GetBalance(ctx)
+	require.NoError(t, err)
+	first, err := c.CreateTransaction(ctx, client.NewTransactionReqBody{Amount: "100"}, client.WithIfMatch(balance.ETag))
+	require.NoError(t, err)
+
+	// Act
+	_, staleErr := c.CreateTransaction(ctx, client.NewTransactionReqBody{Amount: "50"},
+		client.WithIfMatch(balance.ETag))
+	_, err = c.CreateTransaction(ctx, client.NewTransactionReqBody{Amount: "50"}, client.WithIfMatch(first.ETag))
+
+	// Assert
+	assert.Equal(t, `"0"`, balance.ETag)
+	assert.Equal(t, `"2"`, first.ETag, "the version moves on with the transaction and its fee")
+	assert.ErrorIs(t, staleErr, client.ErrVersionMismatch)
+	var apiErr *client.Error
+	require.ErrorAs(t, staleErr, &apiErr)
+	assert.Equal(t, http.StatusPreconditionFailed, apiErr.Status)
+	require.NoError(t, err)
+	balance, err = c.GetBalance(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "147.35", balance.Balance)
+	assert.Equal(t, `"4"`, balance.ETag)
+}
+
+func TestClient_CreateTransaction__RetriesWithSameIdempotencyKey(t *testing.T) {
+	for name, status := range map[string]int{"network error": 0, "bad gateway": http.StatusBadGateway} {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			c, doer := newClient(t)
+			ctx := context.Background()
+			doer.fail(1, status)
+
+			// Act
+			result, err := c.CreateTransaction(ctx, client.NewTransactionReqBody{Amount: "100"})
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, int32(2), doer.calls.Load())
+			require.NotNil(t, result.Posted)
+			balance, err := c.GetBalance(ctx)
+			require.NoError(t, err)
+			// the transaction is only replayed when the first attempt reached the webserver
+			assert.Equal(t, status != 0, result.Replayed)
+			assert.Equal(t, "98.3", balance.Balance, "the transaction is posted once")
+		})
+	}
+}
+
+func TestClient_CreateTransaction__HeldForReview(t *testing.T) {
+	// Arrange
+	c, doer := newClient(t)
+	ctx := context.Background()
+	_, err := c.CreateRule(ctx, client.RuleReqBody{
+		Name: "large credits", Expression: "amount > 500.0", Action: "review",
+	})
+	require.NoError(t, err)
+	// the first attempt is held for review, but its response is lost
+	doer.fail(1, http.StatusGatewayTimeout)
+
+	// Act
+	result, err := c.CreateTransaction(ctx, client.NewTransactionReqBody{Amount: "1000"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Nil(t, result.Posted)
+	require.NotNil(t, result.Review)
+	assert.Equal(t, client.ReviewPending, result.Review.Status)
+	reviews, err := c.ListReviews(ctx, client.ReviewPending)
+	require.NoError(t, err)
+	require.Len(t, reviews, 1)
+	approved, err := c.ApproveReview(ctx, reviews[0].ID)
+	require.NoError(t, err)
+	assert.NotNil(t, approved.Posted)
+	_, err = c.ApproveReview(ctx, reviews[0].ID)
+	assert.ErrorIs(t, err, client.ErrReviewClosed)
+}
+
+func TestClient_Send__RetriesIdempotentRequests(t *testing.T) {
+	// Arrange
+	c, doer := newClient(t)
+	doer.fail(2, http.StatusServiceUnavailable)
+
+	// Act
+	balance, err := c.GetBalance(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "0", balance.Balance)
+	assert.Equal(t, int32(3), doer.calls.Load())
+}
+
+func TestClient_Send__GivesUpAfterMaxAttempts(t *testing.T) {
+	// Arrange
+	c, doer := newClient(t)
+	doer.fail(3, http.StatusServiceUnavailable)
+
+	// Act
+	_, err := c.GetBalance(context.Background())
+
+	// Assert
+	assert.ErrorIs(t, err, client.ErrServiceUnavailable)
+	assert.Equal(t, int32(3), doer.calls.Load())
+}
+
+func TestClient_Send__DoesNotRetryOtherWrites(t *testing.T) {
+	// Arrange
+	c, doer := newClient(t)
+	doer.fail(1, http.StatusBadGateway)
+
+	// Act
+	_, err := c.CreateRule(context.Background(), client.RuleReqBody{
+		Name: "large credits", Expression: "amount > 500.0", Action: "flag",
+	})
+
+	// Assert
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadGateway, apiErr.Status)
+	assert.Equal(t, client.CodeInternal, apiErr.Code)
+	assert.Equal(t, int32(1), doer.calls.Load())
+}
+
+func TestClient_Send__StopsRetryingWhenContextDone(t *testing.T) {
+	// Arrange
+	c, doer := newClient(t, client.WithRetryPolicy(client.RetryPolicy{
+		MaxAttempts: 10, InitialBackoff: time.Hour, MaxBackoff: time.Hour,
+	}))
+	doer.fail(10, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// Act
+	_, err := c.GetBalance(ctx)
+
+	// Assert
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, doer.calls.Load(), int32(10))
+}
+
+func TestClient_Transactions__IteratesOverPages(t *testing.T) {
+	// Arrange
+	c, doer := newClient(t)
+	ctx := context.Background()
+	for i := range 5 {
+		_, err := c.CreateTransaction(ctx, client.NewTransactionReqBody{Amount: strconv.Itoa(100 * (i + 1))})
+		require.NoError(t, err)
+	}
+	all, err := c.ListTransactions(ctx, client.TransactionQuery{Limit: 100})
+	require.NoError(t, err)
+	require.Len(t, all.Transactions, 10, "every transaction is charged a fee")
+	doer.fail(0, 0)
+
+	// Act
+	var transactions []client.Transaction
+	for transaction, err := range c.Transactions(ctx, client.TransactionQuery{Limit: 3}) {
+		require.NoError(t, err)
+		transactions = append(transactions, transaction)
+	}
+
+	// Assert
+	assert.Equal(t, all.Transactions, transactions)
+	assert.Equal(t, int32(4), doer.calls.Load())
+}
+
+func TestClient_Transactions__StopsOnError(t *testing.T) {
+	// Arrange
+	c, _ := newClient(t)
+
+	// Act
+	var errs []error
+	for _, err := range c.Transactions(context.Background(), client.TransactionQuery{Limit: 1_000_000}) {
+		errs = append(errs, err)
+	}
+
+	// Assert
+	require.Len(t, errs, 1)
+	var apiErr *client.Error
+	require.ErrorAs(t, errs[0], &apiErr)
+	assert.Equal(t, client.CodeInvalidParameter, apiErr.Code)
+	require.Len(t, apiErr.Errors, 1)
+	assert.Equal(t, "limit", apiErr.Errors[0].Field)
+}
+
+func TestClient__TypedErrors(t *testing.T) {
+	// Arrange
+	c, _ := newClient(t)
+	ctx := context.Background()
+
+	// Act
+	_, validationErr := c.CreateTransaction(ctx, client.NewTransactionReqBody{Amount: "lots"})
+	_, notFoundErr := c.GetRule(ctx, uuid.New())
+
+	// Assert
+	assert.ErrorIs(t, validationErr, client.ErrValidationFailed)
+	var apiErr *client.Error
+	require.ErrorAs(t, validationErr, &apiErr)
+	assert.Equal(t, http.MethodPost, apiErr.Method)
+	assert.Equal(t, "/transaction", apiErr.Path)
+	require.Len(t, apiErr.Errors, 1)
+	assert.Equal(t, "amount", apiErr.Errors[0].Field)
+	require.ErrorAs(t, notFoundErr, &apiErr)
+	assert.True(t, apiErr.NotFound())
+	assert.Equal(t, client.CodeRuleNotFound, apiErr.Code)
+}
+
+func TestClient_SetLimits(t *testing.T) {
+	// Arrange
+	c, _ := newClient(t)
+	ctx := context.Background()
+	_, err := c.SetLimits(ctx, client.LimitsBody{MaxTransactionAmount: "50"})
+	require.NoError(t, err)
+
+	// Act
+	_, err = c.CreateTransaction(ctx, client.NewTransactionReqBody{Amount: "100"})
+
+	// Assert
+	assert.ErrorIs(t, err, client.ErrLimitExceeded)
+	limits, err := c.GetLimits(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "50", limits.MaxTransactionAmount)
+}
+
+func TestClient_CategoryRules(t *testing.T) {
+	// Arrange
+	c, _ := newClient(t)
+	ctx := context.Background()
+	result, err := c.CreateTransaction(ctx, client.NewTransactionReqBody{Amount: "30", Description: "Coffee beans"})
+	require.NoError(t, err)
+
+	// Act
+	rule, err := c.CreateCategoryRule(ctx, client.CategoryRuleReqBody{
+		Category: "supplies", DescriptionPattern: "(?i)coffee",
+	})
+	require.NoError(t, err)
+	category, err := c.GetTransactionCategory(ctx, result.Posted.Transaction.ID)
+	require.NoError(t, err)
+	rules, err := c.ListCategoryRules(ctx)
+	require.NoError(t, err)
+	deleteErr := c.DeleteCategoryRule(ctx, rule.ID)
+
+	// Assert
+	assert.Equal(t, "supplies", category.Category)
+	assert.Equal(t, []client.CategoryRule{*rule}, rules)
+	assert.NoError(t, deleteErr)
+}
+
+func TestClient_Schedules(t *testing.T) {
+	// Arrange
+	c, _ := newClient(t)
+	ctx := context.Background()
+	schedule, err := c.CreateSchedule(ctx, client.ScheduleReqBody{
+		Amount:   "-10",
+		Interval: "24h",
+		StartAt:  time.Now().Add(time.Hour).UTC().Truncate(time.Second),
+	})
+	require.NoError(t, err)
+
+	// Act
+	nextRuns, err := c.GetScheduleNextRuns(ctx, schedule.ID, 3)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, nextRuns.NextRuns, 3)
+	require.NoError(t, c.DeleteSchedule(ctx, schedule.ID))
+	_, err = c.GetSchedule(ctx, schedule.ID)
+	var apiErr *client.Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.True(t, apiErr.NotFound())
+}
+
+func TestClient_GetStatement(t *testing.T) {
+	// Arrange
+	c, _ := newClient(t)
+	ctx := context.Background()
+	_, err := c.CreateTransaction(ctx, client.NewTransactionReqBody{Amount: "100"})
+	require.NoError(t, err)
+	period := client.Period{From: time.Now().Add(-time.Hour), To: time.Now().Add(time.Hour)}
+
+	// Act
+	statement, err := c.GetStatement(ctx, period)
+	require.NoError(t, err)
+	exported, exportErr := c.ExportStatement(ctx, period, client.StatementFormatCAMT053)
+
+	// Assert
+	assert.Equal(t, "98.3", statement.ClosingBalance)
+	require.NoError(t, exportErr)
+	assert.Contains(t, string(exported), "<Document")
+}
+
+func TestClient_Health(t *testing.T) {
+	// Arrange
+	c, _ := newClient(t)
+	ctx := context.Background()
+
+	// Act
+	liveness, livenessErr := c.Liveness(ctx)
+	readiness, readinessErr := c.Readiness(ctx)
+
+	// Assert
+	require.NoError(t, livenessErr)
+	assert.Equal(t, "up", liveness.Status)
+	require.NoError(t, readinessErr)
+	assert.Equal(t, "up", readiness.Status)
+	assert.Contains(t, readiness.Components, "ledger")
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"teya_home_assignment/internal/app/webserver/api"
+)
+
+// ErrorCode identifies the kind of error answered by the API, see the Code constants
+type ErrorCode = api.ErrorCode
+
+// The error codes answered by the API
+const (
+	CodeMalformedBody        = api.CodeMalformedBody
+	CodeValidationFailed     = api.CodeValidationFailed
+	CodeInvalidParameter     = api.CodeInvalidParameter
+	CodeInvalidRequest       = api.CodeInvalidRequest
+	CodeNotFound             = api.CodeNotFound
+	CodeRuleNotFound         = api.CodeRuleNotFound
+	CodeReviewNotFound       = api.CodeReviewNotFound
+	CodeReviewClosed         = api.CodeReviewClosed
+	CodeDuplicate            = api.CodeDuplicate
+	CodeIdempotencyKeyReused = api.CodeIdempotencyKeyReused
+	CodeVersionMismatch      = api.CodeVersionMismatch
+	CodeCategoryRuleNotFound = api.CodeCategoryRuleNotFound
+	CodeAnnotationNotFound   = api.CodeAnnotationNotFound
+	CodeTransactionNotFound  = api.CodeTransactionNotFound
+	CodeScheduleNotFound     = api.CodeScheduleNotFound
+	CodeScheduleExists       = api.CodeScheduleExists
+	CodeLimitExceeded        = api.CodeLimitExceeded
+	CodeRuleViolation        = api.CodeRuleViolation
+	CodeMethodNotAllowed     = api.CodeMethodNotAllowed
+	CodeBodyTooLarge         = api.CodeBodyTooLarge
+	CodeServiceUnavailable   = api.CodeServiceUnavailable
+	CodeInternal             = api.CodeInternal
+)
+
+// Sentinel errors matching the *Error of the same code with errors.Is, e.g. errors.Is(err, client.ErrLimitExceeded)
+var (
+	ErrValidationFailed     = &Error{Problem: Problem{Code: CodeValidationFailed}}
+	ErrLimitExceeded        = &Error{Problem: Problem{Code: CodeLimitExceeded}}
+	ErrRuleViolation        = &Error{Problem: Problem{Code: CodeRuleViolation}}
+	ErrIdempotencyKeyReused = &Error{Problem: Problem{Code: CodeIdempotencyKeyReused}}
+	ErrVersionMismatch      = &Error{Problem: Problem{Code: CodeVersionMismatch}}
+	ErrReviewClosed         = &Error{Problem: Problem{Code: CodeReviewClosed}}
+	ErrServiceUnavailable   = &Error{Problem: Problem{Code: CodeServiceUnavailable}}
+)
+
+// Error is returned when the API answered a request with an error, along with the problem details it sent
+type Error struct {
+	Method string
+	Path   string
+	Problem
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("%v %v: %d %v", e.Method, e.Path, e.Status, e.Code)
+	if e.Detail != "" {
+		message += ": " + e.Detail
+	}
+	return message
+}
+
+// Is matches the errors with the same code
+func (e *Error) Is(target error) bool {
+	targetErr, ok := target.(*Error)
+	return ok && targetErr.Code == e.Code
+}
+
+// NotFound tells whether the resource the request refers to does not exist, whatever the kind of resource
+func (e *Error) NotFound() bool {
+	return e.Status == http.StatusNotFound
+}
+
+// newError returns the error of a failed response. The responses without problem details, e.g. of a proxy in front
+// of the webserver, are classified by their status.
+func newError(req *request, resp response) *Error {
+	apiErr := &Error{Method: req.method, Path: req.path}
+	if err := json.Unmarshal(resp.body, &apiErr.Problem); err != nil || apiErr.Code == "" {
+		code := statusCode(resp.status)
+		apiErr.Problem = Problem{Type: api.ProblemTypePrefix + string(code), Title: http.StatusText(resp.status), Code: code}
+	}
+	apiErr.Status = resp.status
+	return apiErr
+}
+
+func statusCode(status int) ErrorCode {
+	switch {
+	case status == http.StatusNotFound:
+		return CodeNotFound
+	case status == http.StatusServiceUnavailable:
+		return CodeServiceUnavailable
+	case status >= http.StatusInternalServerError:
+		return CodeInternal
+	}
+	return CodeInvalidRequest
+}
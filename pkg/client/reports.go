@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	statementPath       = "/account/statement"
+	statementExportPath = "/account/statement/export"
+	reconciliationPath  = "/reconciliation"
+	aggregateReportPath = "/report/aggregate"
+	interestAccrualPath = "/interest/accrual"
+)
+
+// The formats of the statements, GetStatement returns the JSON one
+const (
+	StatementFormatText    = "text"
+	StatementFormatHTML    = "html"
+	StatementFormatCAMT053 = "camt053"
+	StatementFormatOFX     = "ofx"
+)
+
+// The intervals of the aggregate reports
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// Period is the period [From, To) of a statement or of an interest accrual
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+func (p Period) values() url.Values {
+	return url.Values{"from": {p.From.Format(time.RFC3339Nano)}, "to": {p.To.Format(time.RFC3339Nano)}}
+}
+
+// GetStatement returns the statement of the account over the period
+func (c *Client) GetStatement(ctx context.Context, period Period, opts ...CallOption) (*StatementRespBody, error) {
+	resp := &StatementRespBody{}
+	if err := c.get(ctx, statementPath, period.values(), resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RenderStatement returns the statement of the account over the period in the text or HTML format
+func (c *Client) RenderStatement(
+	ctx context.Context,
+	period Period,
+	format string,
+	opts ...CallOption,
+) ([]byte, error) {
+	return c.getDocument(ctx, statementPath, period, format, opts)
+}
+
+// ExportStatement returns the statement of the account over the period in the CAMT.053 or OFX format
+func (c *Client) ExportStatement(
+	ctx context.Context,
+	period Period,
+	format string,
+	opts ...CallOption,
+) ([]byte, error) {
+	return c.getDocument(ctx, statementExportPath, period, format, opts)
+}
+
+func (c *Client) getDocument(
+	ctx context.Context,
+	path string,
+	period Period,
+	format string,
+	opts []CallOption,
+) ([]byte, error) {
+	req := newRequest(http.MethodGet, path)
+	req.query = period.values()
+	req.query.Set("format", format)
+	resp, err := c.call(ctx, req, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	return resp.body, nil
+}
+
+// Reconcile reconciles the ledger with the settlement CSV file. The settlements may be dateToleranceDays apart
+// from the transactions they match, the tolerance of the webserver applies when negative.
+func (c *Client) Reconcile(
+	ctx context.Context,
+	settlement []byte,
+	dateToleranceDays int,
+	opts ...CallOption,
+) (*ReconciliationReportRespBody, error) {
+	req := newRequest(http.MethodPost, reconciliationPath)
+	if dateToleranceDays >= 0 {
+		req.query.Set("date_tolerance_days", strconv.Itoa(dateToleranceDays))
+	}
+	req.header.Set("Content-Type", "text/csv")
+	req.body = settlement
+	// reconciling changes nothing, so it is safe to retry
+	req.idempotent = true
+	resp := &ReconciliationReportRespBody{}
+	if _, err := c.call(ctx, req, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// AggregateQuery selects the days of an aggregate report, both inclusive, and how they are bucketed
+type AggregateQuery struct {
+	From time.Time
+	To   time.Time
+	// Interval is one of the Interval constants, a day when empty
+	Interval string
+	// TimeZone is the IANA time zone the days are in, UTC when empty
+	TimeZone string
+}
+
+// GetAggregateReport aggregates the transactions by interval
+func (c *Client) GetAggregateReport(
+	ctx context.Context,
+	query AggregateQuery,
+	opts ...CallOption,
+) (*AggregateReportRespBody, error) {
+	values := url.Values{"from": {query.From.Format(time.DateOnly)}, "to": {query.To.Format(time.DateOnly)}}
+	if query.Interval != "" {
+		values.Set("interval", query.Interval)
+	}
+	if query.TimeZone != "" {
+		values.Set("tz", query.TimeZone)
+	}
+	resp := &AggregateReportRespBody{}
+	if err := c.get(ctx, aggregateReportPath, values, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetInterestAccrual recomputes the interest accrued over the period, along with the interest posted for it
+func (c *Client) GetInterestAccrual(
+	ctx context.Context,
+	period Period,
+	opts ...CallOption,
+) (*InterestAccrualRespBody, error) {
+	resp := &InterestAccrualRespBody{}
+	if err := c.get(ctx, interestAccrualPath, period.values(), resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+)
+
+const (
+	rulesPath  = "/rules"
+	reviewPath = "/review"
+)
+
+// The statuses of the reviews, to list the reviews of a single status
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewDeclined = "declined"
+)
+
+// CreateRule registers a rule checked against every transaction created
+func (c *Client) CreateRule(ctx context.Context, body RuleReqBody, opts ...CallOption) (*Rule, error) {
+	resp := &Rule{}
+	if err := c.sendJSON(ctx, http.MethodPost, rulesPath, body, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListRules returns the registered rules
+func (c *Client) ListRules(ctx context.Context, opts ...CallOption) ([]Rule, error) {
+	resp := &RulesRespBody{}
+	if err := c.get(ctx, rulesPath, nil, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp.Rules, nil
+}
+
+// GetRule returns the rule with the given ID
+func (c *Client) GetRule(ctx context.Context, id uuid.UUID, opts ...CallOption) (*Rule, error) {
+	resp := &Rule{}
+	if err := c.get(ctx, resourcePath(rulesPath, id), nil, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateRule replaces the rule with the given ID
+func (c *Client) UpdateRule(ctx context.Context, id uuid.UUID, body RuleReqBody, opts ...CallOption) (*Rule, error) {
+	resp := &Rule{}
+	if err := c.sendJSON(ctx, http.MethodPut, resourcePath(rulesPath, id), body, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteRule removes the rule with the given ID
+func (c *Client) DeleteRule(ctx context.Context, id uuid.UUID, opts ...CallOption) error {
+	return c.delete(ctx, resourcePath(rulesPath, id), opts)
+}
+
+// TestRule evaluates a sample transaction against the given rule, or the registered rules, without posting it
+func (c *Client) TestRule(ctx context.Context, body RuleTestReqBody, opts ...CallOption) (*RuleTestRespBody, error) {
+	resp := &RuleTestRespBody{}
+	if err := c.sendJSON(ctx, http.MethodPost, rulesPath+"/test", body, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListFlaggedTransactions returns the transactions posted despite matching a rule flagging them
+func (c *Client) ListFlaggedTransactions(ctx context.Context, opts ...CallOption) ([]FlaggedTransaction, error) {
+	resp := &FlaggedTransactionsRespBody{}
+	if err := c.get(ctx, rulesPath+"/flagged", nil, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp.Flagged, nil
+}
+
+// ListReviews returns the transactions held for review with the given status, or all of them when empty
+func (c *Client) ListReviews(ctx context.Context, status string, opts ...CallOption) ([]Review, error) {
+	var query url.Values
+	if status != "" {
+		query = url.Values{"status": {status}}
+	}
+	resp := &ReviewsRespBody{}
+	if err := c.get(ctx, reviewPath, query, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp.Reviews, nil
+}
+
+// GetReview returns the review with the given ID
+func (c *Client) GetReview(ctx context.Context, id uuid.UUID, opts ...CallOption) (*Review, error) {
+	resp := &Review{}
+	if err := c.get(ctx, resourcePath(reviewPath, id), nil, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ApproveReview posts the transaction held for review. It is not retried, as the review of a first attempt
+// approved already would be answered with ErrReviewClosed.
+func (c *Client) ApproveReview(ctx context.Context, id uuid.UUID, opts ...CallOption) (*Review, error) {
+	return c.closeReview(ctx, id, "approve", opts)
+}
+
+// DeclineReview rejects the transaction held for review
+func (c *Client) DeclineReview(ctx context.Context, id uuid.UUID, opts ...CallOption) (*Review, error) {
+	return c.closeReview(ctx, id, "decline", opts)
+}
+
+func (c *Client) closeReview(ctx context.Context, id uuid.UUID, decision string, opts []CallOption) (*Review, error) {
+	resp := &Review{}
+	req := newRequest(http.MethodPost, resourcePath(reviewPath, id, decision))
+	if _, err := c.call(ctx, req, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
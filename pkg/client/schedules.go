@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const schedulePath = "/schedule"
+
+// CreateSchedule registers a recurring transaction
+func (c *Client) CreateSchedule(ctx context.Context, body ScheduleReqBody, opts ...CallOption) (*Schedule, error) {
+	resp := &Schedule{}
+	if err := c.sendJSON(ctx, http.MethodPost, schedulePath, body, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ListSchedules returns the registered schedules
+func (c *Client) ListSchedules(ctx context.Context, opts ...CallOption) ([]Schedule, error) {
+	resp := &SchedulesRespBody{}
+	if err := c.get(ctx, schedulePath, nil, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp.Schedules, nil
+}
+
+// GetSchedule returns the schedule with the given ID
+func (c *Client) GetSchedule(ctx context.Context, id uuid.UUID, opts ...CallOption) (*Schedule, error) {
+	resp := &Schedule{}
+	if err := c.get(ctx, resourcePath(schedulePath, id), nil, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateSchedule replaces the schedule with the given ID
+func (c *Client) UpdateSchedule(
+	ctx context.Context,
+	id uuid.UUID,
+	body ScheduleReqBody,
+	opts ...CallOption,
+) (*Schedule, error) {
+	resp := &Schedule{}
+	if err := c.sendJSON(ctx, http.MethodPut, resourcePath(schedulePath, id), body, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteSchedule removes the schedule with the given ID
+func (c *Client) DeleteSchedule(ctx context.Context, id uuid.UUID, opts ...CallOption) error {
+	return c.delete(ctx, resourcePath(schedulePath, id), opts)
+}
+
+// GetScheduleNextRuns returns the next count runs of the schedule, the default count of the webserver when 0
+func (c *Client) GetScheduleNextRuns(
+	ctx context.Context,
+	id uuid.UUID,
+	count int,
+	opts ...CallOption,
+) (*ScheduleNextRunsRespBody, error) {
+	var query url.Values
+	if count > 0 {
+		query = url.Values{"count": {strconv.Itoa(count)}}
+	}
+	resp := &ScheduleNextRunsRespBody{}
+	if err := c.get(ctx, resourcePath(schedulePath, id, "next"), query, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	transactionPath = "/transaction"
+	accountPath     = "/account"
+)
+
+// CreateTransactionResult is the outcome of a transaction: either posted along with its fees, or held for review
+// by a rule
+type CreateTransactionResult struct {
+	Posted *NewTransactionRespBody
+	Review *Review
+	// Replayed tells the transaction was already created with the same idempotency key, by a previous attempt
+	Replayed bool
+	// ETag is the version of the ledger once the transaction was posted, to create the next one with WithIfMatch.
+	// It is empty when the transaction is held for review or replayed.
+	ETag string
+}
+
+// Balance is the balance of the account, along with the version of the ledger it was calculated at
+type Balance struct {
+	GetBalanceRespBody
+	// ETag is the version of the ledger, to create a transaction against this balance with WithIfMatch
+	ETag string
+}
+
+// CreateTransaction creates a transaction with a generated idempotency key unless given one with
+// WithIdempotencyKey, so it is retried like the idempotent requests without ever being posted twice
+func (c *Client) CreateTransaction(
+	ctx context.Context,
+	body NewTransactionReqBody,
+	opts ...CallOption,
+) (*CreateTransactionResult, error) {
+	req, err := newRequest(http.MethodPost, transactionPath).withJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	// the key is set before the call options, which may override it, so every attempt sends the same one
+	req.header.Set(IdempotencyKeyHeader, c.newIdempotencyKey())
+	req.idempotent = true
+	resp, err := c.call(ctx, req, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	result := &CreateTransactionResult{
+		Replayed: resp.header.Get(IdempotentReplayedHeader) == "true",
+		ETag:     resp.header.Get(ETagHeader),
+	}
+	var out any
+	if resp.status == http.StatusAccepted {
+		result.Review = &Review{}
+		out = result.Review
+	} else {
+		result.Posted = &NewTransactionRespBody{}
+		out = result.Posted
+	}
+	if err := json.Unmarshal(resp.body, out); err != nil {
+		return nil, errors.Wrap(err, "failed to decode created transaction")
+	}
+	return result, nil
+}
+
+// TransactionQuery selects a page of the transaction history, in the order the transactions were posted
+type TransactionQuery struct {
+	Offset int
+	// Limit is the size of the page, the default limit of the webserver when 0
+	Limit int
+	// Category only selects the transactions of the category when set
+	Category string
+}
+
+func (q TransactionQuery) values() url.Values {
+	query := url.Values{"offset": {strconv.Itoa(q.Offset)}}
+	if q.Limit > 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Category != "" {
+		query.Set("category", q.Category)
+	}
+	return query
+}
+
+// ListTransactions returns a page of the transaction history
+func (c *Client) ListTransactions(
+	ctx context.Context,
+	query TransactionQuery,
+	opts ...CallOption,
+) (*PaginatedTransactionsResponse, error) {
+	resp := &PaginatedTransactionsResponse{}
+	if err := c.get(ctx, transactionPath, query.values(), resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Transactions iterates over the transaction history from the query offset, fetching the pages of the query limit
+// as needed. The iteration stops after the first error.
+func (c *Client) Transactions(
+	ctx context.Context,
+	query TransactionQuery,
+	opts ...CallOption,
+) iter.Seq2[Transaction, error] {
+	return func(yield func(Transaction, error) bool) {
+		for {
+			page, err := c.ListTransactions(ctx, query, opts...)
+			if err != nil {
+				yield(Transaction{}, err)
+				return
+			}
+			for _, transaction := range page.Transactions {
+				if !yield(transaction, nil) {
+					return
+				}
+			}
+			// the empty pages end the iteration too, should transactions be missing from the total
+			if !page.Pagination.HasMore || len(page.Transactions) == 0 {
+				return
+			}
+			query.Offset += len(page.Transactions)
+		}
+	}
+}
+
+// GetBalance returns the balance of the account
+func (c *Client) GetBalance(ctx context.Context, opts ...CallOption) (*Balance, error) {
+	balance := &Balance{}
+	resp, err := c.call(ctx, newRequest(http.MethodGet, accountPath), &balance.GetBalanceRespBody, opts)
+	if err != nil {
+		return nil, err
+	}
+	balance.ETag = resp.header.Get(ETagHeader)
+	return balance, nil
+}
+
+// GetTransactionCategory returns the category of the transaction, either annotated or assigned by the rules
+func (c *Client) GetTransactionCategory(
+	ctx context.Context,
+	transactionID uuid.UUID,
+	opts ...CallOption,
+) (*TransactionCategory, error) {
+	resp := &TransactionCategory{}
+	if err := c.get(ctx, resourcePath(transactionPath, transactionID, "category"), nil, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SetTransactionCategory annotates the transaction with a category, overriding the category rules
+func (c *Client) SetTransactionCategory(
+	ctx context.Context,
+	transactionID uuid.UUID,
+	body CategoryReqBody,
+	opts ...CallOption,
+) (*TransactionCategory, error) {
+	resp := &TransactionCategory{}
+	path := resourcePath(transactionPath, transactionID, "category")
+	if err := c.sendJSON(ctx, http.MethodPut, path, body, resp, opts); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteTransactionCategory removes the annotation of the transaction, so it is categorized by the rules again
+func (c *Client) DeleteTransactionCategory(ctx context.Context, transactionID uuid.UUID, opts ...CallOption) error {
+	return c.delete(ctx, resourcePath(transactionPath, transactionID, "category"), opts)
+}
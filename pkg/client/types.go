@@ -0,0 +1,68 @@
+package client
+
+import "teya_home_assignment/internal/app/webserver/api"
+
+// The request and response bodies of the API
+type (
+	Problem    = api.Problem
+	FieldError = api.FieldError
+
+	Transaction                   = api.Transaction
+	NewTransactionReqBody         = api.NewTransactionReqBody
+	NewTransactionRespBody        = api.NewTransactionRespBody
+	PaginatedTransactionsResponse = api.PaginatedTransactionsResponse
+	Pagination                    = api.Pagination
+	GetBalanceRespBody            = api.GetBalanceRespBody
+
+	StatementRespBody            = api.StatementRespBody
+	StatementTotals              = api.StatementTotals
+	ReconciliationReportRespBody = api.ReconciliationReportRespBody
+	ReconciliationMatch          = api.ReconciliationMatch
+	SettlementLine               = api.SettlementLine
+	AggregateReportRespBody      = api.AggregateReportRespBody
+	AggregateBucket              = api.AggregateBucket
+	InterestAccrualRespBody      = api.InterestAccrualRespBody
+	InterestDailyAccrual         = api.InterestDailyAccrual
+
+	ScheduleReqBody          = api.ScheduleReqBody
+	Schedule                 = api.Schedule
+	SchedulesRespBody        = api.SchedulesRespBody
+	ScheduleNextRunsRespBody = api.ScheduleNextRunsRespBody
+
+	LimitsBody = api.LimitsBody
+
+	RuleReqBody         = api.RuleReqBody
+	Rule                = api.Rule
+	RulesRespBody       = api.RulesRespBody
+	RuleTestReqBody     = api.RuleTestReqBody
+	RuleTestTransaction = api.RuleTestTransaction
+	RuleTestAccount     = api.RuleTestAccount
+	RuleTestRespBody    = api.RuleTestRespBody
+	RuleMatch           = api.RuleMatch
+	RuleEvaluationError = api.RuleEvaluationError
+	Review              = api.Review
+	ReviewsRespBody     = api.ReviewsRespBody
+	FlaggedTransaction  = api.FlaggedTransaction
+
+	FlaggedTransactionsRespBody = api.FlaggedTransactionsRespBody
+
+	CategoryRuleReqBody   = api.CategoryRuleReqBody
+	CategoryRule          = api.CategoryRule
+	CategoryRulesRespBody = api.CategoryRulesRespBody
+	CategoryReqBody       = api.CategoryReqBody
+	TransactionCategory   = api.TransactionCategory
+
+	HealthRespBody  = api.HealthRespBody
+	ComponentHealth = api.ComponentHealth
+)
+
+const (
+	// IdempotencyKeyHeader carries the idempotency key of the transactions created, see WithIdempotencyKey
+	IdempotencyKeyHeader = api.IdempotencyKeyHeader
+	// IdempotentReplayedHeader is set on the responses replayed for a known idempotency key
+	IdempotentReplayedHeader = api.IdempotentReplayedHeader
+	// ETagHeader tells the version of the ledger answered with the balance and the transactions created
+	ETagHeader = "ETag"
+	// IfMatchHeader carries the version a transaction is created against, see WithIfMatch
+	IfMatchHeader = "If-Match"
+)